@@ -2,13 +2,22 @@ package teal
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
 
 	"github.com/kencx/teal/validator"
 )
 
+// Minimum similarity of authors suggested as duplicates, see AuthorSimilarity
+const DuplicateAuthorSimilarity = 0.85
+
 type Author struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
+	// other names of the author, added by merging authors. Books added with
+	// an alias are given the author instead
+	Aliases []string `json:"aliases,omitempty" db:"-"`
+	Version int64    `json:"version"`
 }
 
 func (a Author) String() string {
@@ -18,3 +27,94 @@ func (a Author) String() string {
 func (a *Author) Validate(v *validator.Validator) {
 	v.Check(a.Name != "", "name", "value is missing")
 }
+
+// A pair of authors that may be the same person
+type AuthorDuplicate struct {
+	Author     *Author `json:"author"`
+	Duplicate  *Author `json:"duplicate"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Normalize an author name for comparison. Names are lower-cased, and
+// punctuation and repeated spaces are removed, so "S.A. Corey" and
+// "S. A.  Corey" are both "s a corey"
+func NormalizeAuthorName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// How similar two author names are, from 0 to 1. Names that are the same once
+// normalized are 1. Names whose words are all in the other name, with the same
+// surname, are at least 0.9, e.g. "S.A. Corey" and "James S.A. Corey".
+// Otherwise it is 1 less the edit distance over the length of the longer name
+func AuthorSimilarity(a, b string) float64 {
+	a, b = NormalizeAuthorName(a), NormalizeAuthorName(b)
+	if a == b {
+		return 1
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longer := len(ra)
+	if len(rb) > longer {
+		longer = len(rb)
+	}
+	similarity := 1 - float64(editDistance(ra, rb))/float64(longer)
+
+	if similarity < 0.9 && sameSurname(a, b) && (containsWords(a, b) || containsWords(b, a)) {
+		similarity = 0.9
+	}
+	return similarity
+}
+
+func sameSurname(a, b string) bool {
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	return wa[len(wa)-1] == wb[len(wb)-1]
+}
+
+// whether all words of b are in a
+func containsWords(a, b string) bool {
+	words := make(map[string]int)
+	for _, w := range strings.Fields(a) {
+		words[w]++
+	}
+	for _, w := range strings.Fields(b) {
+		if words[w] == 0 {
+			return false
+		}
+		words[w]--
+	}
+	return true
+}
+
+// the Levenshtein distance of a and b
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -57,3 +57,51 @@ func TestValidateAuthor(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeAuthorName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"S.A. Corey", "s a corey"},
+		{"S. A.  Corey", "s a corey"},
+		{" James S.A. Corey ", "james s a corey"},
+		{"Ursula K. Le Guin", "ursula k le guin"},
+		{"...", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeAuthorName(tt.name); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"S.A. Corey", "S. A. Corey", 1, 1},
+		{"S.A. Corey", "James S.A. Corey", 0.9, 0.9},
+		{"Pierce Brown", "Pierce Browne", 0.9, 0.95},
+		{"Pierce Brown", "Dan Brown", 0, DuplicateAuthorSimilarity},
+		{"John Doe", "Ken Adams", 0, 0.5},
+		{"John Doe", "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			got := AuthorSimilarity(tt.a, tt.b)
+			if got < tt.min || got > tt.max {
+				t.Errorf("got %v, want between %v and %v", got, tt.min, tt.max)
+			}
+			if got != AuthorSimilarity(tt.b, tt.a) {
+				t.Errorf("similarity of %q and %q is not symmetric", tt.a, tt.b)
+			}
+		})
+	}
+}
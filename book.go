@@ -4,23 +4,35 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"strings"
+	"time"
 
 	"github.com/kencx/teal/validator"
 )
 
 type Book struct {
-	ID            int64        `json:"id" db:"id"`
-	Title         string       `json:"title" db:"title"`
-	Description   NullString   `json:"description,omitempty" db:"description"`
-	Author        []string     `json:"author"`
-	ISBN          string       `json:"isbn" db:"isbn"`
-	NumOfPages    int          `json:"num_of_pages" db:"numOfPages"`
-	Rating        int          `json:"rating" db:"rating"`
-	State         string       `json:"state" db:"state"`
-	DateAdded     sql.NullTime `json:"-" db:"dateAdded"`
-	DateUpdated   sql.NullTime `json:"-" db:"dateUpdated"`
-	DateCompleted sql.NullTime `json:"-" db:"dateCompleted"`
+	ID            int64         `json:"id" db:"id"`
+	UserID        int64         `json:"-" db:"user_id"`
+	Title         string        `json:"title" db:"title"`
+	Description   NullString    `json:"description,omitempty" db:"description"`
+	Author        []string      `json:"author"`
+	Contributors  []Contributor `json:"contributors"`
+	Categories    []string      `json:"categories"`
+	Series        []SeriesEntry `json:"series"`
+	Tags          []string      `json:"tags"`
+	ISBN          string        `json:"isbn" db:"isbn"`
+	ISBN10        string        `json:"isbn10,omitempty" db:"isbn10"`
+	Cover         string        `json:"cover,omitempty" db:"cover"`
+	NumOfPages    int           `json:"num_of_pages" db:"numOfPages"`
+	Rating        int           `json:"rating" db:"rating"`
+	State         string        `json:"state" db:"state"`
+	Progress      *Progress     `json:"progress,omitempty"`
+	DateAdded     sql.NullTime  `json:"-" db:"dateAdded"`
+	DateUpdated   sql.NullTime  `json:"-" db:"dateUpdated"`
+	DateStarted   sql.NullTime  `json:"-" db:"dateStarted"`
+	DateCompleted sql.NullTime  `json:"-" db:"dateCompleted"`
+	DateDeleted   *time.Time    `json:"date_deleted,omitempty" db:"dateDeleted"`
+	Version       int64         `json:"version" db:"version"`
 }
 
 func (b Book) String() string {
@@ -39,7 +51,7 @@ func (n NullString) MarshalJSON() ([]byte, error) {
 	return json.Marshal(nil)
 }
 
-func (n NullString) UnmarshalJSON(data []byte) error {
+func (n *NullString) UnmarshalJSON(data []byte) error {
 	var s *string
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
@@ -54,18 +66,35 @@ func (n NullString) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-var isbnRgx = regexp.MustCompile(`[0-9]+`)
-
 func (b *Book) Validate(v *validator.Validator) {
 	v.Check(b.Title != "", "title", "value is missing")
 
-	v.Check(len(b.Author) != 0, "author", "value is missing")
+	// authors can be given as contributors instead
+	v.Check(len(b.Author) != 0 || b.hasContributingAuthor(), "author", "value is missing")
+	for _, c := range b.Contributors {
+		c.Validate(v)
+	}
 
 	v.Check(b.ISBN != "", "isbn", "value is missing")
-	v.Check(validator.Matches(b.ISBN, isbnRgx), "isbn", "incorrect format")
+	if _, err := ParseISBN(b.ISBN); err != nil {
+		v.AddError("isbn", err.Error())
+	}
+
+	for _, t := range b.Tags {
+		v.Check(strings.TrimSpace(t) != "", "tags", "tag must not be empty")
+	}
+
+	for _, s := range b.Series {
+		s.Validate(v)
+	}
 
 	v.Check(b.NumOfPages >= 0, "numOfPages", "must be >= 0")
 
 	v.Check(b.Rating >= 0, "rating", "must be >= 0")
 	v.Check(b.Rating <= 10, "rating", "must be <= 10")
+
+	// empty state defaults to want-to-read
+	if b.State != "" {
+		v.Check(IsValidState(b.State), "state", fmt.Sprintf("must be one of %s", strings.Join(States, ", ")))
+	}
 }
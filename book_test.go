@@ -15,14 +15,14 @@ func TestValidateBook(t *testing.T) {
 		name: "success",
 		book: &Book{
 			Title:  "FooBar",
-			ISBN:   "100",
+			ISBN:   "9780316129084",
 			Author: []string{"John Doe"},
 		},
 		err: nil,
 	}, {
 		name: "no title",
 		book: &Book{
-			ISBN:   "100",
+			ISBN:   "9780316129084",
 			Author: []string{"John Doe"},
 		},
 		err: map[string]string{"title": "value is missing"},
@@ -30,7 +30,7 @@ func TestValidateBook(t *testing.T) {
 		name: "nil author",
 		book: &Book{
 			Title:  "Foo Bar",
-			ISBN:   "100",
+			ISBN:   "9780316129084",
 			Author: nil,
 		},
 		err: map[string]string{"author": "value is missing"},
@@ -38,7 +38,7 @@ func TestValidateBook(t *testing.T) {
 		name: "zero length author",
 		book: &Book{
 			Title:  "Foo Bar",
-			ISBN:   "100",
+			ISBN:   "9780316129084",
 			Author: []string{},
 		},
 		err: map[string]string{"author": "value is missing"},
@@ -57,6 +57,30 @@ func TestValidateBook(t *testing.T) {
 			Author: []string{"John Doe"},
 		},
 		err: map[string]string{"isbn": "incorrect format"},
+	}, {
+		name: "isbn with letters",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "abc1",
+			Author: []string{"John Doe"},
+		},
+		err: map[string]string{"isbn": "incorrect format"},
+	}, {
+		name: "isbn checksum fail",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "9780316129085",
+			Author: []string{"John Doe"},
+		},
+		err: map[string]string{"isbn": "incorrect check digit"},
+	}, {
+		name: "hyphenated isbn10",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "0-8044-2957-X",
+			Author: []string{"John Doe"},
+		},
+		err: nil,
 	}, {
 		name: "multiple errors",
 		book: &Book{
@@ -65,6 +89,69 @@ func TestValidateBook(t *testing.T) {
 			Author: nil,
 		},
 		err: map[string]string{"author": "value is missing", "isbn": "incorrect format"},
+	}, {
+		name: "valid state",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "9780316129084",
+			Author: []string{"John Doe"},
+			State:  StateDidNotFinish,
+		},
+		err: nil,
+	}, {
+		name: "invalid state",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "9780316129084",
+			Author: []string{"John Doe"},
+			State:  "unread",
+		},
+		err: map[string]string{"state": "must be one of want-to-read, reading, read, did-not-finish, on-hold"},
+	}, {
+		name: "series with fractional position",
+		book: &Book{
+			Title:  "Gods of Risk",
+			ISBN:   "9780316129084",
+			Author: []string{"S.A. Corey"},
+			Series: []SeriesEntry{{Name: "The Expanse", Position: 2.5}},
+		},
+		err: nil,
+	}, {
+		name: "series with no name",
+		book: &Book{
+			Title:  "Gods of Risk",
+			ISBN:   "9780316129084",
+			Author: []string{"S.A. Corey"},
+			Series: []SeriesEntry{{Position: 2.5}},
+		},
+		err: map[string]string{"series": "name is missing"},
+	}, {
+		name: "series with negative position",
+		book: &Book{
+			Title:  "Gods of Risk",
+			ISBN:   "9780316129084",
+			Author: []string{"S.A. Corey"},
+			Series: []SeriesEntry{{Name: "The Expanse", Position: -1}},
+		},
+		err: map[string]string{"series": "position must be >= 0"},
+	}, {
+		name: "tags",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "9780316129084",
+			Author: []string{"John Doe"},
+			Tags:   []string{"space", "favourite"},
+		},
+		err: nil,
+	}, {
+		name: "empty tag",
+		book: &Book{
+			Title:  "Foo Bar",
+			ISBN:   "9780316129084",
+			Author: []string{"John Doe"},
+			Tags:   []string{"space", " "},
+		},
+		err: map[string]string{"tags": "tag must not be empty"},
 	}}
 
 	for _, tt := range tests {
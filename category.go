@@ -1,17 +1,36 @@
 package teal
 
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/kencx/teal/validator"
+)
+
 type Category struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Books       []Book `json:"books,omitempty"`
-	DateAdded   string `json:"-"`
-	DateUpdated string `json:"-"`
+	ID          int64        `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	ParentID    int64        `json:"parent_id,omitempty" db:"parent_id"`
+	Children    []*Category  `json:"children,omitempty"`
+	DateAdded   sql.NullTime `json:"-" db:"dateAdded"`
+	DateUpdated sql.NullTime `json:"-" db:"dateUpdated"`
 }
 
 type CategoryService interface {
-	GetCategories() ([]*Category, error)
-	GetCategory(id int) (*Category, error)
-	CreateCategory(b *Category) error
-	UpdateCategory(id int) error
-	DeleteCategory(id int) error
+	Get(userID, id int64) (*Category, error)
+	GetByName(userID int64, name string) (*Category, error)
+	GetAll(userID int64) ([]*Category, error)
+	Create(userID int64, c *Category) (*Category, error)
+	Update(userID, id int64, c *Category) (*Category, error)
+	Delete(userID, id int64) error
+}
+
+func (c Category) String() string {
+	return fmt.Sprintf(`[name=%s parent=%d]`, c.Name, c.ParentID)
+}
+
+func (c *Category) Validate(v *validator.Validator) {
+	v.Check(c.Name != "", "name", "value is missing")
+	v.Check(c.ParentID >= 0, "parent_id", "must be >= 0")
+	v.Check(c.ID == 0 || c.ParentID != c.ID, "parent_id", "category cannot be its own parent")
 }
@@ -0,0 +1,81 @@
+package teal
+
+import (
+	"testing"
+
+	"github.com/kencx/teal/validator"
+)
+
+func TestValidateCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		category *Category
+		err      map[string]string
+	}{{
+		name: "success",
+		category: &Category{
+			Name: "Fiction",
+		},
+		err: nil,
+	}, {
+		name: "success with parent",
+		category: &Category{
+			Name:     "Sci-Fi",
+			ParentID: 1,
+		},
+		err: nil,
+	}, {
+		name: "no name",
+		category: &Category{
+			Name: "",
+		},
+		err: map[string]string{"name": "value is missing"},
+	}, {
+		name: "negative parent",
+		category: &Category{
+			Name:     "Sci-Fi",
+			ParentID: -1,
+		},
+		err: map[string]string{"parent_id": "must be >= 0"},
+	}, {
+		name: "own parent",
+		category: &Category{
+			ID:       2,
+			Name:     "Sci-Fi",
+			ParentID: 2,
+		},
+		err: map[string]string{"parent_id": "category cannot be its own parent"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.category.Validate(v)
+
+			if !v.Valid() && tt.err == nil {
+				t.Fatalf("expected no err, got %v", v.Errors)
+			}
+
+			if v.Valid() && tt.err != nil {
+				t.Fatalf("expected err with %q, got nil", tt.err)
+			}
+
+			if !v.Valid() && tt.err != nil {
+				if len(v.Errors) != len(tt.err) {
+					t.Fatalf("got %d errs, want %d errs", len(v.Errors), len(tt.err))
+				}
+
+				for k, v := range v.Errors {
+					s, ok := tt.err[k]
+					if !ok {
+						t.Fatalf("err field missing %q", k)
+					}
+
+					if v != s {
+						t.Fatalf("got %v, want %v error", v, s)
+					}
+				}
+			}
+		})
+	}
+}
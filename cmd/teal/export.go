@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kencx/teal/exporter"
+	"github.com/kencx/teal/storage"
+)
+
+const exportUsage = `Usage: teal export [-dsn DSN] -user USERNAME [-o FILE] <format>
+
+Formats:
+  json       All book details, as returned by the API with dates
+  csv        One row per book, for spreadsheets
+  goodreads  Goodreads library export CSV
+`
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dsn := fs.String("dsn", getDSN(), dsnUsage)
+	username := fs.String("user", "", "Username of the library to export")
+	output := fs.String("o", "", "File to write to, standard output if not given")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), exportUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("export: format required")
+	}
+	if *username == "" {
+		fs.Usage()
+		return fmt.Errorf("export: user required")
+	}
+
+	format := fs.Arg(0)
+	if !exporter.IsValidFormat(format) {
+		fs.Usage()
+		return fmt.Errorf("export: unknown format %q, must be one of %s", format, strings.Join(exporter.Formats, ", "))
+	}
+
+	db, err := storage.Open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer storage.Close(db)
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	if err := m.Check(); err != nil {
+		return fmt.Errorf("%v, run 'teal migrate up'", err)
+	}
+
+	store := storage.NewStore(db)
+	user, err := store.Users.GetByUsername(*username)
+	if err != nil {
+		return fmt.Errorf("export: user %q: %v", *username, err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	if err := exporter.Export(w, store.Books, user.ID, format); err != nil {
+		return err
+	}
+	return w.Flush()
+}
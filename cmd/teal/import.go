@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kencx/teal/importer"
+	"github.com/kencx/teal/storage"
+)
+
+const importUsage = `Usage: teal import [-dsn DSN] -user USERNAME [-dry-run] <format> FILE
+
+Formats:
+  goodreads  Goodreads library export CSV
+  calibre    Calibre library directory or its metadata.db
+`
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dsn := fs.String("dsn", getDSN(), dsnUsage)
+	username := fs.String("user", "", "Username of the library to import into")
+	dryRun := fs.Bool("dry-run", false, "Report what would be imported without creating books")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), importUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("import: format and file required")
+	}
+	if *username == "" {
+		fs.Usage()
+		return fmt.Errorf("import: user required")
+	}
+
+	format, path := fs.Arg(0), fs.Arg(1)
+	var read func(string) ([]*importer.Record, error)
+	switch format {
+	case "goodreads":
+		read = readGoodreads
+	case "calibre":
+		read = importer.ReadCalibre
+	default:
+		fs.Usage()
+		return fmt.Errorf("import: unknown format %q", format)
+	}
+
+	records, err := read(path)
+	if err != nil {
+		return err
+	}
+
+	db, err := storage.Open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer storage.Close(db)
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	if err := m.Check(); err != nil {
+		return fmt.Errorf("%v, run 'teal migrate up'", err)
+	}
+
+	store := storage.NewStore(db)
+	user, err := store.Users.GetByUsername(*username)
+	if err != nil {
+		return fmt.Errorf("import: user %q: %v", *username, err)
+	}
+
+	report, err := importer.Import(store.Books, user.ID, records, *dryRun)
+	if err != nil {
+		return err
+	}
+	return printImportReport(report)
+}
+
+func readGoodreads(path string) ([]*importer.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return importer.ReadGoodreads(f)
+}
+
+func printImportReport(report *importer.Report) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ROW\tSTATUS\tISBN\tTITLE\tERRORS\tSKIPPED")
+	for _, res := range report.Results {
+		var errs []string
+		for k, msg := range res.Errors {
+			errs = append(errs, fmt.Sprintf("%s: %s", k, msg))
+		}
+		sort.Strings(errs)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", res.Row, res.Status, res.ISBN, res.Title,
+			strings.Join(errs, "; "), strings.Join(res.Skipped, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Dry run, would import"
+	}
+	fmt.Printf("\n%s %d books, skipped %d duplicates, rejected %d invalid, %d failed\n",
+		verb, report.New, report.Duplicates, report.Invalid, report.Failed)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal/migrations"
+	"github.com/kencx/teal/storage"
+)
+
+const migrateUsage = `Usage: teal migrate [-dsn DSN] [-owner USERNAME] <command>
+
+Commands:
+  up        apply all pending migrations
+  down      roll back the last applied migration
+  status    list migrations and whether they are applied
+  to N      migrate up or down to version N, 0 rolls back all migrations
+
+Books added before books had owners, when there were no users, are given to
+the -owner user by migration 19. Create the user with 'teal useradd' first.
+`
+
+// migrator with the migrations of the database's driver
+func newMigrator(db *sqlx.DB) (*storage.Migrator, error) {
+	fsys, err := migrations.For(db.DriverName())
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewMigrator(db, fsys)
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := fs.String("dsn", getDSN(), dsnUsage)
+	owner := fs.String("owner", "", "Username of the user to give books without an owner to")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("migrate: command required")
+	}
+
+	db, err := storage.Open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer storage.Close(db)
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	m.Owner = *owner
+
+	switch cmd := fs.Arg(0); cmd {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Down()
+	case "to":
+		if fs.NArg() != 2 {
+			return fmt.Errorf("migrate: to requires a version")
+		}
+		version, perr := strconv.Atoi(fs.Arg(1))
+		if perr != nil || version < 0 {
+			return fmt.Errorf("migrate: invalid version %q", fs.Arg(1))
+		}
+		err = m.To(version)
+	case "status":
+		return printMigrationStatus(m)
+	default:
+		fs.Usage()
+		return fmt.Errorf("migrate: unknown command %q", cmd)
+	}
+	if errors.Is(err, storage.ErrNoBookOwner) {
+		return fmt.Errorf("%v, run 'teal migrate -owner USERNAME %s'", err, strings.Join(fs.Args(), " "))
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database at version %d\n", version)
+	return nil
+}
+
+func printMigrationStatus(m *storage.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, s := range status {
+		applied := "pending"
+		if s.Applied {
+			applied = s.DateApplied.Time.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nDatabase at version %d, latest is %d\n", version, m.Latest())
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/storage"
+	"github.com/kencx/teal/validator"
+)
+
+const userAddUsage = `Usage: teal useradd [-dsn DSN] -username USERNAME -name NAME
+
+Create a user without starting the server. The password is read from standard
+input. Use it to create the owner of books that have none before running
+'teal migrate -owner USERNAME up'.
+`
+
+func runUserAdd(args []string) error {
+	fs := flag.NewFlagSet("useradd", flag.ExitOnError)
+	dsn := fs.String("dsn", getDSN(), dsnUsage)
+	username := fs.String("username", "", "Username of the new user")
+	name := fs.String("name", "", "Name of the new user")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), userAddUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("useradd: read password failed: %v", err)
+	}
+
+	input := teal.InputUser{
+		Name:     *name,
+		Username: *username,
+		Password: strings.TrimRight(password, "\r\n"),
+	}
+	v := validator.New()
+	input.Validate(v)
+	if !v.Valid() {
+		fs.Usage()
+		return fmt.Errorf("useradd: %s", formatErrors(v.Errors))
+	}
+
+	user := teal.User{
+		Name:     input.Name,
+		Username: input.Username,
+	}
+	if err := user.SetPassword(input.Password); err != nil {
+		return err
+	}
+
+	db, err := storage.Open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer storage.Close(db)
+
+	store := storage.NewStore(db)
+	result, err := store.Users.Create(&user)
+	if err != nil {
+		return fmt.Errorf("useradd: %v", err)
+	}
+	fmt.Printf("User %q created with id %d\n", result.Username, result.ID)
+	return nil
+}
+
+// validation errors as "key: message" pairs, sorted by key
+func formatErrors(errs map[string]string) string {
+	var pairs []string
+	for k, v := range errs {
+		pairs = append(pairs, k+": "+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
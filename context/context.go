@@ -14,6 +14,9 @@ const (
 	authorIdKey = baseKey("author")
 	userIdKey   = baseKey("userId")
 	userKey     = baseKey("user")
+
+	categoryIdKey = baseKey("category")
+	seriesIdKey   = baseKey("series")
 )
 
 func WithBook(ctx context.Context, value *teal.Book) context.Context {
@@ -63,3 +66,27 @@ func GetUser(ctx context.Context) (*teal.User, error) {
 	}
 	return value, nil
 }
+
+func WithCategoryID(ctx context.Context, value int64) context.Context {
+	return context.WithValue(ctx, categoryIdKey, value)
+}
+
+func GetCategoryID(ctx context.Context) (int64, error) {
+	value, ok := ctx.Value(categoryIdKey).(int64)
+	if !ok {
+		return -1, fmt.Errorf("ctx: failed to get CategoryID from context")
+	}
+	return value, nil
+}
+
+func WithSeriesID(ctx context.Context, value int64) context.Context {
+	return context.WithValue(ctx, seriesIdKey, value)
+}
+
+func GetSeriesID(ctx context.Context) (int64, error) {
+	value, ok := ctx.Value(seriesIdKey).(int64)
+	if !ok {
+		return -1, fmt.Errorf("ctx: failed to get SeriesID from context")
+	}
+	return value, nil
+}
@@ -0,0 +1,126 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kencx/teal/validator"
+)
+
+// Roles of the contributors of a book
+const (
+	RoleAuthor      = "author"
+	RoleTranslator  = "translator"
+	RoleEditor      = "editor"
+	RoleIllustrator = "illustrator"
+	RoleNarrator    = "narrator"
+)
+
+var Roles = []string{RoleAuthor, RoleTranslator, RoleEditor, RoleIllustrator, RoleNarrator}
+
+func IsValidRole(role string) bool {
+	return contains(Roles, role)
+}
+
+// A person who contributed to a book in a role. Contributors are stored as
+// authors, so the same person can be the author of one book and the translator
+// of another
+type Contributor struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (c *Contributor) Validate(v *validator.Validator) {
+	v.Check(strings.TrimSpace(c.Name) != "", "contributors", "name is missing")
+	// an empty role is an author
+	v.Check(c.Role == "" || IsValidRole(c.Role), "contributors", fmt.Sprintf("role must be one of %s", strings.Join(Roles, ", ")))
+}
+
+// Reconcile a book's authors with its contributors, which list the authors in
+// order together with the other roles.
+//
+// Books without contributors are given their authors as contributors.
+// Otherwise the contributors take precedence, unless the authors were changed
+// from before, which is nil for new books, and no longer match them.
+// Contributors without a role are authors, and duplicates are dropped
+func (b *Book) NormalizeContributors(before *Book) {
+	if len(b.Contributors) == 0 {
+		b.Contributors = nil
+		b.SetAuthors(b.Author)
+		return
+	}
+
+	seen := make(map[Contributor]bool)
+	contributors := make([]Contributor, 0, len(b.Contributors))
+	for _, c := range b.Contributors {
+		if c.Role == "" {
+			c.Role = RoleAuthor
+		}
+		if !seen[c] {
+			seen[c] = true
+			contributors = append(contributors, c)
+		}
+	}
+	b.Contributors = contributors
+
+	if before != nil && !sameNames(b.Author, before.Author) &&
+		!sameNames(b.Author, b.ContributorNames(RoleAuthor)) {
+		b.SetAuthors(b.Author)
+		return
+	}
+	b.Author = b.ContributorNames(RoleAuthor)
+}
+
+// Replace the book's authors, keeping its contributors in other roles after
+// them
+func (b *Book) SetAuthors(names []string) {
+	contributors := make([]Contributor, 0, len(names)+len(b.Contributors))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			contributors = append(contributors, Contributor{Name: name, Role: RoleAuthor})
+		}
+	}
+	for _, c := range b.Contributors {
+		if c.Role != RoleAuthor && c.Role != "" {
+			contributors = append(contributors, c)
+		}
+	}
+
+	b.Contributors = contributors
+	b.Author = b.ContributorNames(RoleAuthor)
+}
+
+// The names of the book's contributors in the given role, in order
+func (b *Book) ContributorNames(role string) []string {
+	var names []string
+	for _, c := range b.Contributors {
+		if c.Role == role {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func (b *Book) hasContributingAuthor() bool {
+	for _, c := range b.Contributors {
+		if c.Role == RoleAuthor || c.Role == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// whether a and b contain the same names in the same order
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package teal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kencx/teal/validator"
+)
+
+func TestValidateContributors(t *testing.T) {
+	tests := []struct {
+		name string
+		book *Book
+		err  map[string]string
+	}{{
+		name: "authors as contributors",
+		book: &Book{
+			Title:        "FooBar",
+			ISBN:         "9780316129084",
+			Contributors: []Contributor{{Name: "John Doe"}, {Name: "Jane Doe", Role: RoleTranslator}},
+		},
+		err: nil,
+	}, {
+		name: "no author",
+		book: &Book{
+			Title:        "FooBar",
+			ISBN:         "9780316129084",
+			Contributors: []Contributor{{Name: "Jane Doe", Role: RoleTranslator}},
+		},
+		err: map[string]string{"author": "value is missing"},
+	}, {
+		name: "no name",
+		book: &Book{
+			Title:        "FooBar",
+			ISBN:         "9780316129084",
+			Author:       []string{"John Doe"},
+			Contributors: []Contributor{{Role: RoleEditor}},
+		},
+		err: map[string]string{"contributors": "name is missing"},
+	}, {
+		name: "invalid role",
+		book: &Book{
+			Title:        "FooBar",
+			ISBN:         "9780316129084",
+			Author:       []string{"John Doe"},
+			Contributors: []Contributor{{Name: "Jane Doe", Role: "foo"}},
+		},
+		err: map[string]string{"contributors": "role must be one of author, translator, editor, illustrator, narrator"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.book.Validate(v)
+
+			if !v.Valid() && tt.err == nil {
+				t.Fatalf("expected no err, got %v", v.Errors)
+			}
+
+			if v.Valid() && tt.err != nil {
+				t.Fatalf("expected err with %q, got nil", tt.err)
+			}
+
+			if !v.Valid() && tt.err != nil {
+				for k, v := range v.Errors {
+					s, ok := tt.err[k]
+					if !ok {
+						t.Fatalf("err field missing %q", k)
+					}
+
+					if v != s {
+						t.Fatalf("got %v, want %v error", v, s)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeContributors(t *testing.T) {
+	translator := Contributor{Name: "Jane Doe", Role: RoleTranslator}
+	before := &Book{
+		Author:       []string{"John Doe"},
+		Contributors: []Contributor{{Name: "John Doe", Role: RoleAuthor}, translator},
+	}
+
+	tests := []struct {
+		name   string
+		book   *Book
+		before *Book
+		want   []Contributor
+	}{{
+		name: "authors only",
+		book: &Book{Author: []string{"John Doe", "Ken Adams"}},
+		want: []Contributor{{Name: "John Doe", Role: RoleAuthor}, {Name: "Ken Adams", Role: RoleAuthor}},
+	}, {
+		name: "contributors only",
+		book: &Book{Contributors: []Contributor{{Name: "Ken Adams"}, translator, {Name: "John Doe"}}},
+		want: []Contributor{{Name: "Ken Adams", Role: RoleAuthor}, translator, {Name: "John Doe", Role: RoleAuthor}},
+	}, {
+		name: "duplicate contributors",
+		book: &Book{Contributors: []Contributor{{Name: "John Doe"}, {Name: "John Doe", Role: RoleAuthor}, translator}},
+		want: []Contributor{{Name: "John Doe", Role: RoleAuthor}, translator},
+	}, {
+		name: "contributors take precedence",
+		book: &Book{
+			Author:       []string{"John Doe"},
+			Contributors: []Contributor{{Name: "Ken Adams"}},
+		},
+		want: []Contributor{{Name: "Ken Adams", Role: RoleAuthor}},
+	}, {
+		name: "authors changed",
+		book: &Book{
+			Author:       []string{"Ken Adams"},
+			Contributors: before.Contributors,
+		},
+		before: before,
+		want:   []Contributor{{Name: "Ken Adams", Role: RoleAuthor}, translator},
+	}, {
+		name: "contributors changed",
+		book: &Book{
+			Author:       before.Author,
+			Contributors: []Contributor{{Name: "Ken Adams"}, translator},
+		},
+		before: before,
+		want:   []Contributor{{Name: "Ken Adams", Role: RoleAuthor}, translator},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.book.NormalizeContributors(tt.before)
+			if !reflect.DeepEqual(tt.book.Contributors, tt.want) {
+				t.Errorf("got %v, want %v", tt.book.Contributors, tt.want)
+			}
+			if !reflect.DeepEqual(tt.book.Author, tt.book.ContributorNames(RoleAuthor)) {
+				t.Errorf("got authors %v, want %v", tt.book.Author, tt.book.ContributorNames(RoleAuthor))
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package covers stores book cover images and their thumbnails on local disk.
+// Images are named by the SHA-256 hash of their content, so books with the
+// same cover share its files
+package covers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Sizes of a cover. Thumbnails fit within a square of their size in pixels
+const (
+	Original = "original"
+	Small    = "small"
+	Medium   = "medium"
+	Large    = "large"
+)
+
+var (
+	Sizes = []string{Small, Medium, Large, Original}
+
+	thumbnailSizes = map[string]int{
+		Small:  128,
+		Medium: 256,
+		Large:  512,
+	}
+
+	// file extension of each accepted image type
+	extensions = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/webp": ".webp",
+	}
+)
+
+const (
+	// MaxBytes is the maximum size of an uploaded cover
+	MaxBytes = 10 << 20
+	// maximum number of pixels of an uploaded cover. Decoded images take 4
+	// bytes per pixel, so a cover takes at most 64 MB of memory
+	maxPixels = 4000 * 4000
+
+	thumbnailQuality = 85
+
+	// covers younger than this are never pruned
+	pruneGrace = time.Hour
+)
+
+var (
+	ErrUnsupportedImage = errors.New("cover must be a JPEG, PNG or WebP image")
+	ErrImageTooLarge    = fmt.Errorf("cover must be at most %d megapixels", maxPixels/1000000)
+
+	hashRgx = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// Covers in a directory on local disk
+type Store struct {
+	Dir string
+}
+
+// Open the cover store in dir, creating the directory if it does not exist
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("covers: %v", err)
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// Save a JPEG, PNG or WebP image with its thumbnails. Returns the hash that
+// names the cover
+func (s *Store) Save(data []byte) (string, error) {
+	ext, ok := extensions[http.DetectContentType(data)]
+	if !ok {
+		return "", ErrUnsupportedImage
+	}
+
+	// check the dimensions before decoding the whole image
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return "", ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if err := os.MkdirAll(s.dir(hash), 0o755); err != nil {
+		return "", fmt.Errorf("covers: %v", err)
+	}
+
+	if err := writeFile(s.path(hash, Original, ext), data); err != nil {
+		return "", err
+	}
+	for size, px := range thumbnailSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail(img, px), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return "", fmt.Errorf("covers: %v", err)
+		}
+		if err := writeFile(s.path(hash, size, ".jpg"), buf.Bytes()); err != nil {
+			return "", err
+		}
+	}
+	return hash, nil
+}
+
+// Path of a cover's file of the given size. Returns os.ErrNotExist if the cover
+// does not exist
+func (s *Store) Path(hash, size string) (string, error) {
+	if !hashRgx.MatchString(hash) {
+		return "", os.ErrNotExist
+	}
+	if size != Original {
+		return s.path(hash, size, ".jpg"), nil
+	}
+
+	for _, ext := range extensions {
+		path := s.path(hash, Original, ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// Delete a cover and its thumbnails
+func (s *Store) Delete(hash string) error {
+	if !hashRgx.MatchString(hash) {
+		return os.ErrNotExist
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dir(hash), hash+"*"))
+	if err != nil {
+		return fmt.Errorf("covers: %v", err)
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return fmt.Errorf("covers: %v", err)
+		}
+	}
+	return nil
+}
+
+// Delete the covers that are not in use, except covers saved within the last
+// hour, which may not have been assigned to their book yet. Returns the number
+// of covers deleted
+func (s *Store) Prune(inUse []string) (int, error) {
+	keep := make(map[string]bool, len(inUse))
+	for _, hash := range inUse {
+		keep[hash] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*", "*-"+Original+".*"))
+	if err != nil {
+		return 0, fmt.Errorf("covers: %v", err)
+	}
+
+	count := 0
+	for _, f := range files {
+		hash := strings.SplitN(filepath.Base(f), "-", 2)[0]
+		if !hashRgx.MatchString(hash) || keep[hash] {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil || time.Since(info.ModTime()) < pruneGrace {
+			continue
+		}
+		if err := s.Delete(hash); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func IsValidSize(size string) bool {
+	_, ok := thumbnailSizes[size]
+	return ok || size == Original
+}
+
+// covers are spread over directories by the first two characters of their hash
+func (s *Store) dir(hash string) string {
+	return filepath.Join(s.Dir, hash[:2])
+}
+
+func (s *Store) path(hash, size, ext string) string {
+	return filepath.Join(s.dir(hash), hash+"-"+size+ext)
+}
+
+// write a file atomically, so partially written covers are never served
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("covers: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("covers: %v", err)
+	}
+	return nil
+}
+
+// scale img to fit within px by px, without enlarging it. Transparent areas
+// are white, as JPEG has no transparency
+func thumbnail(img image.Image, px int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > px || h > px {
+		if w >= h {
+			w, h = px, max(1, h*px/b.Dx())
+		} else {
+			w, h = max(1, w*px/b.Dy()), px
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
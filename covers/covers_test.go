@@ -0,0 +1,225 @@
+package covers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := New(filepath.Join(t.TempDir(), "covers"))
+	checkErr(t, err)
+	return s
+}
+
+func testImage(t *testing.T, w, h int, encode func(*bytes.Buffer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	checkErr(t, encode(&buf, img))
+	return buf.Bytes()
+}
+
+// a JPEG whose header claims the given size, so the limits are tested without
+// encoding a large image
+func testJPEGHeader(t *testing.T, w, h int) []byte {
+	t.Helper()
+	data := testImage(t, 8, 8, encodeJPEG)
+	// the start of frame marker is followed by its length, precision, height
+	// and width
+	i := bytes.Index(data, []byte{0xff, 0xc0})
+	if i == -1 {
+		t.Fatalf("no start of frame marker")
+	}
+	binary.BigEndian.PutUint16(data[i+5:], uint16(h))
+	binary.BigEndian.PutUint16(data[i+7:], uint16(w))
+	return data
+}
+
+func encodePNG(buf *bytes.Buffer, img image.Image) error {
+	return png.Encode(buf, img)
+}
+
+func encodeJPEG(buf *bytes.Buffer, img image.Image) error {
+	return jpeg.Encode(buf, img, nil)
+}
+
+func imageSize(t *testing.T, path string) (int, int) {
+	t.Helper()
+	f, err := os.Open(path)
+	checkErr(t, err)
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	checkErr(t, err)
+	return cfg.Width, cfg.Height
+}
+
+func TestSave(t *testing.T) {
+	s := testStore(t)
+	data := testImage(t, 600, 900, encodePNG)
+
+	hash, err := s.Save(data)
+	checkErr(t, err)
+	if !hashRgx.MatchString(hash) {
+		t.Fatalf("got hash %q", hash)
+	}
+
+	path, err := s.Path(hash, Original)
+	checkErr(t, err)
+	if filepath.Ext(path) != ".png" {
+		t.Errorf("got original %q, want .png", path)
+	}
+	got, err := os.ReadFile(path)
+	checkErr(t, err)
+	if !bytes.Equal(got, data) {
+		t.Errorf("original was changed")
+	}
+
+	tests := []struct {
+		size string
+		w, h int
+	}{
+		{Small, 85, 128},
+		{Medium, 170, 256},
+		{Large, 341, 512},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			path, err := s.Path(hash, tt.size)
+			checkErr(t, err)
+			w, h := imageSize(t, path)
+			if w != tt.w || h != tt.h {
+				t.Errorf("got %dx%d, want %dx%d", w, h, tt.w, tt.h)
+			}
+		})
+	}
+}
+
+func TestSaveSameImage(t *testing.T) {
+	s := testStore(t)
+	data := testImage(t, 100, 50, encodeJPEG)
+
+	first, err := s.Save(data)
+	checkErr(t, err)
+	second, err := s.Save(data)
+	checkErr(t, err)
+	if first != second {
+		t.Errorf("got hashes %q and %q", first, second)
+	}
+}
+
+func TestSaveSmallImage(t *testing.T) {
+	s := testStore(t)
+	hash, err := s.Save(testImage(t, 100, 50, encodeJPEG))
+	checkErr(t, err)
+
+	// thumbnails are not enlarged
+	path, err := s.Path(hash, Large)
+	checkErr(t, err)
+	w, h := imageSize(t, path)
+	if w != 100 || h != 50 {
+		t.Errorf("got %dx%d, want 100x50", w, h)
+	}
+}
+
+func TestSaveInvalid(t *testing.T) {
+	s := testStore(t)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"text", []byte("not an image"), ErrUnsupportedImage},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), ErrUnsupportedImage},
+		{"truncated", testImage(t, 20, 20, encodePNG)[:40], ErrUnsupportedImage},
+		{"too large", testJPEGHeader(t, 4001, 4000), ErrImageTooLarge},
+		{"too wide", testJPEGHeader(t, 65535, 245), ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Save(tt.data)
+			if err != tt.err {
+				t.Errorf("got err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPathNotExist(t *testing.T) {
+	s := testStore(t)
+
+	for _, hash := range []string{"", "../../etc/passwd", "ab", string(bytes.Repeat([]byte("a"), 64))} {
+		if _, err := s.Path(hash, Original); err != os.ErrNotExist {
+			t.Errorf("%q: got err %v, want os.ErrNotExist", hash, err)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s := testStore(t)
+	hash, err := s.Save(testImage(t, 20, 20, encodePNG))
+	checkErr(t, err)
+
+	checkErr(t, s.Delete(hash))
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*", "*"))
+	checkErr(t, err)
+	if len(files) != 0 {
+		t.Errorf("got files %v after delete", files)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := testStore(t)
+	used, err := s.Save(testImage(t, 20, 20, encodePNG))
+	checkErr(t, err)
+	unused, err := s.Save(testImage(t, 30, 20, encodePNG))
+	checkErr(t, err)
+	recent, err := s.Save(testImage(t, 40, 20, encodePNG))
+	checkErr(t, err)
+
+	old := time.Now().Add(-2 * pruneGrace)
+	for _, hash := range []string{used, unused} {
+		path, err := s.Path(hash, Original)
+		checkErr(t, err)
+		checkErr(t, os.Chtimes(path, old, old))
+	}
+
+	count, err := s.Prune([]string{used})
+	checkErr(t, err)
+	if count != 1 {
+		t.Errorf("got %d pruned, want 1", count)
+	}
+
+	var got []string
+	for _, hash := range []string{used, unused, recent} {
+		if _, err := s.Path(hash, Original); err == nil {
+			got = append(got, hash)
+		}
+	}
+	if want := []string{used, recent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got covers %v, want %v", got, want)
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+const testContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>`
+
+const testOPF3 = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">urn:uuid:0b7a2c58-2b1f-4a4e-9d1c-6f0c1f6f0d2a</dc:identifier>
+    <dc:identifier>urn:isbn:0-316-12908-9</dc:identifier>
+    <dc:title>Leviathan Wakes</dc:title>
+    <dc:creator id="c1">James S.A. Corey</dc:creator>
+    <meta refines="#c1" property="role" scheme="marc:relators">aut</meta>
+    <dc:creator id="c2">Jane Doe</dc:creator>
+    <meta refines="#c2" property="role" scheme="marc:relators">ill</meta>
+    <dc:description>&lt;p&gt;Humanity has colonized the solar system.&lt;/p&gt;</dc:description>
+  </metadata>
+  <manifest>
+    <item id="cover" href="images/cover%20art.jpg" media-type="image/jpeg" properties="cover-image"/>
+    <item id="text" href="text.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+</package>`
+
+const testOPF2 = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:opf="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Red Rising</dc:title>
+    <dc:creator opf:role="aut">Pierce Brown</dc:creator>
+    <dc:creator opf:role="edt">John Doe</dc:creator>
+    <dc:identifier opf:scheme="ISBN">9780345539786</dc:identifier>
+    <meta name="cover" content="cover-image"/>
+  </metadata>
+  <manifest>
+    <item id="cover-image" href="cover.png" media-type="image/png"/>
+  </manifest>
+</package>`
+
+// an EPUB with the given files, besides the mimetype and container
+func testEPUB(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	checkErr(t, err)
+	_, err = w.Write([]byte(epubMediaType))
+	checkErr(t, err)
+
+	files["META-INF/container.xml"] = testContainer
+	for name, content := range files {
+		w, err := zw.Create(name)
+		checkErr(t, err)
+		_, err = w.Write([]byte(content))
+		checkErr(t, err)
+	}
+	checkErr(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReadEPUB3(t *testing.T) {
+	data := testEPUB(t, map[string]string{
+		"OEBPS/content.opf":          testOPF3,
+		"OEBPS/images/cover art.jpg": "cover",
+	})
+
+	got, err := ReadEPUB(bytes.NewReader(data), int64(len(data)))
+	checkErr(t, err)
+
+	want := &EPUB{
+		Metadata: teal.Metadata{
+			ISBN:        "9780316129084",
+			Provider:    EPUBProvider,
+			Title:       "Leviathan Wakes",
+			Author:      []string{"James S.A. Corey"},
+			Description: "Humanity has colonized the solar system.",
+		},
+		Cover: []byte("cover"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReadEPUB2(t *testing.T) {
+	data := testEPUB(t, map[string]string{
+		"OEBPS/content.opf": testOPF2,
+		"OEBPS/cover.png":   "cover",
+	})
+
+	got, err := ReadEPUB(bytes.NewReader(data), int64(len(data)))
+	checkErr(t, err)
+	assertEqual(t, got.Title, "Red Rising")
+	assertEqual(t, got.ISBN, "9780345539786")
+	assertEqual(t, len(got.Author), 1)
+	assertEqual(t, got.Author[0], "Pierce Brown")
+	assertEqual(t, string(got.Cover), "cover")
+}
+
+func TestReadEPUBInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"no package", map[string]string{}},
+		{"invalid package", map[string]string{"OEBPS/content.opf": "<package"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testEPUB(t, tt.files)
+			_, err := ReadEPUB(bytes.NewReader(data), int64(len(data)))
+			if err == nil {
+				t.Errorf("expected err")
+			}
+		})
+	}
+
+	_, err := ReadEPUB(bytes.NewReader([]byte("%PDF-1.7")), 8)
+	if err == nil {
+		t.Errorf("expected err")
+	}
+}
+
+func TestEPUBISBN(t *testing.T) {
+	tests := []struct {
+		scheme, value, want string
+	}{
+		{"", "urn:isbn:9780316129084", "9780316129084"},
+		{"", "ISBN:0316129089", "9780316129084"},
+		{"ISBN", "978-0-316-12908-4", "9780316129084"},
+		{"", "9780316129084", "9780316129084"},
+		{"", "urn:uuid:0b7a2c58-2b1f-4a4e-9d1c-6f0c1f6f0d2a", ""},
+		{"UUID", "9780316129084", ""},
+		{"ISBN", "9780316129085", ""},
+	}
+	for _, tt := range tests {
+		assertEqual(t, epubISBN(tt.scheme, tt.value), tt.want)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	mobi := make([]byte, 100)
+	copy(mobi[60:], "BOOKMOBI")
+	zipFile := func() []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		_, err := zw.Create("file.txt")
+		checkErr(t, err)
+		checkErr(t, zw.Close())
+		return buf.Bytes()
+	}()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"epub", testEPUB(t, map[string]string{}), teal.FormatEPUB, nil},
+		{"pdf", []byte("%PDF-1.7\n"), teal.FormatPDF, nil},
+		{"mobi", mobi, teal.FormatMOBI, nil},
+		{"zip", zipFile, "", ErrUnsupportedFormat},
+		{"text", []byte("hello"), "", ErrUnsupportedFormat},
+		{"empty", []byte{}, "", ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != tt.err {
+				t.Fatalf("got err %v, want %v", err, tt.err)
+			}
+			assertEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "ebooks"))
+	checkErr(t, err)
+
+	data := []byte("%PDF-1.7\ncontent")
+	got, err := s.Save(bytes.NewReader(data))
+	checkErr(t, err)
+	assertEqual(t, got.Format, teal.FormatPDF)
+	assertEqual(t, got.Size, int64(len(data)))
+
+	// the same file has the same checksum
+	again, err := s.Save(bytes.NewReader(data))
+	checkErr(t, err)
+	assertEqual(t, again.Checksum, got.Checksum)
+
+	f, err := s.Open(got.Checksum, got.Format)
+	checkErr(t, err)
+	info, err := f.Stat()
+	checkErr(t, err)
+	f.Close()
+	assertEqual(t, info.Size(), int64(len(data)))
+
+	_, err = s.Save(bytes.NewReader([]byte("not an ebook")))
+	if err != ErrUnsupportedFormat {
+		t.Errorf("got err %v, want %v", err, ErrUnsupportedFormat)
+	}
+	// unsupported uploads are not kept
+	tmp, err := filepath.Glob(filepath.Join(s.Dir, "*.tmp"))
+	checkErr(t, err)
+	assertEqual(t, len(tmp), 0)
+
+	checkErr(t, s.Delete(got.Checksum, got.Format))
+	_, err = s.Open(got.Checksum, got.Format)
+	if !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+
+	_, err = s.Open("../../etc/passwd", teal.FormatPDF)
+	if err != os.ErrNotExist {
+		t.Errorf("got err %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s, err := New(t.TempDir())
+	checkErr(t, err)
+
+	used, err := s.Save(bytes.NewReader([]byte("%PDF-1.7\nused")))
+	checkErr(t, err)
+	unused, err := s.Save(bytes.NewReader([]byte("%PDF-1.7\nunused")))
+	checkErr(t, err)
+	recent, err := s.Save(bytes.NewReader([]byte("%PDF-1.7\nrecent")))
+	checkErr(t, err)
+
+	old := time.Now().Add(-2 * pruneGrace)
+	for _, f := range []*Stored{used, unused} {
+		checkErr(t, os.Chtimes(s.path(f.Checksum, f.Format), old, old))
+	}
+
+	count, err := s.Prune([]string{used.Checksum})
+	checkErr(t, err)
+	assertEqual(t, count, 1)
+
+	for _, tt := range []struct {
+		f      *Stored
+		exists bool
+	}{{used, true}, {unused, false}, {recent, true}} {
+		f, err := s.Open(tt.f.Checksum, tt.f.Format)
+		if err == nil {
+			f.Close()
+		}
+		assertEqual(t, err == nil, tt.exists)
+	}
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package ebook
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/util"
+)
+
+const (
+	// EPUBProvider is the provider of metadata read from EPUBs
+	EPUBProvider = "epub"
+
+	// maximum size of the container and package documents
+	maxPackageBytes = 4 << 20
+	// maximum size of an embedded cover
+	maxCoverBytes = 10 << 20
+)
+
+var ErrInvalidEPUB = errors.New("invalid EPUB")
+
+// The metadata of an EPUB package and its embedded cover image, if any
+type EPUB struct {
+	teal.Metadata
+	Cover []byte
+}
+
+// META-INF/container.xml, which locates the package document
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// the OPF package document. Elements are matched by their local name, so both
+// the dc: and opf: namespaces are accepted
+type epubPackage struct {
+	Metadata struct {
+		Titles   []string `xml:"title"`
+		Creators []struct {
+			ID   string `xml:"id,attr"`
+			Role string `xml:"role,attr"`
+			Name string `xml:",chardata"`
+		} `xml:"creator"`
+		Identifiers []struct {
+			Scheme string `xml:"scheme,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"identifier"`
+		Descriptions []string `xml:"description"`
+		Meta         []struct {
+			// EPUB 2
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+			// EPUB 3
+			Refines  string `xml:"refines,attr"`
+			Property string `xml:"property,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+}
+
+// Read the package metadata of an EPUB: its title, authors, ISBN, description
+// and cover image
+func ReadEPUB(r io.ReaderAt, size int64) (*EPUB, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEPUB, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := readXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	opf := ""
+	for _, rf := range container.Rootfiles {
+		if rf.MediaType == "" || rf.MediaType == "application/oebps-package+xml" {
+			opf = rf.FullPath
+			break
+		}
+	}
+	if opf == "" {
+		return nil, fmt.Errorf("%w: no package document", ErrInvalidEPUB)
+	}
+
+	var pkg epubPackage
+	if err := readXML(files, opf, &pkg); err != nil {
+		return nil, err
+	}
+
+	md := pkg.Metadata
+	e := &EPUB{}
+	e.Provider = EPUBProvider
+	if len(md.Titles) > 0 {
+		e.Title = strings.TrimSpace(md.Titles[0])
+	}
+	if len(md.Descriptions) > 0 {
+		e.Description = util.HTMLText(md.Descriptions[0])
+	}
+
+	// EPUB 3 roles refine the creator by its ID
+	roles := make(map[string]string)
+	for _, m := range md.Meta {
+		if m.Property == "role" && strings.HasPrefix(m.Refines, "#") {
+			roles[m.Refines[1:]] = strings.TrimSpace(m.Value)
+		}
+	}
+	var creators []string
+	for _, c := range md.Creators {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			continue
+		}
+		creators = append(creators, name)
+
+		role := c.Role
+		if role == "" {
+			role = roles[c.ID]
+		}
+		if role == "" || role == "aut" {
+			e.Author = append(e.Author, name)
+		}
+	}
+	// books without authors credit their editors or other creators
+	if len(e.Author) == 0 {
+		e.Author = creators
+	}
+
+	for _, id := range md.Identifiers {
+		if isbn := epubISBN(id.Scheme, id.Value); isbn != "" {
+			e.ISBN = isbn
+			break
+		}
+	}
+
+	if href := epubCover(&pkg); href != "" {
+		// hrefs are URLs relative to the package document
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		name := path.Join(path.Dir(opf), href)
+		if f, ok := files[name]; ok {
+			// a missing or unreadable cover does not invalidate the EPUB
+			if data, err := readZipFile(f, maxCoverBytes); err == nil {
+				e.Cover = data
+			}
+		}
+	}
+	return e, nil
+}
+
+// the ISBN-13 of an identifier, such as urn:isbn:9780316129084 or an
+// identifier with the ISBN scheme. Returns "" for other identifiers
+func epubISBN(scheme, value string) string {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+	for _, prefix := range []string{"urn:isbn:", "isbn:"} {
+		if strings.HasPrefix(lower, prefix) {
+			value = value[len(prefix):]
+			scheme = "isbn"
+			break
+		}
+	}
+	if !strings.EqualFold(scheme, "isbn") && scheme != "" {
+		return ""
+	}
+
+	isbn, err := teal.ParseISBN(value)
+	if err != nil {
+		return ""
+	}
+	return isbn
+}
+
+// the href of the cover image, from the cover-image property of EPUB 3 or the
+// cover meta of EPUB 2
+func epubCover(pkg *epubPackage) string {
+	for _, item := range pkg.Manifest {
+		for _, p := range strings.Fields(item.Properties) {
+			if p == "cover-image" {
+				return item.Href
+			}
+		}
+	}
+
+	for _, m := range pkg.Metadata.Meta {
+		if m.Name != "cover" {
+			continue
+		}
+		for _, item := range pkg.Manifest {
+			if item.ID == m.Content && strings.HasPrefix(item.MediaType, "image/") {
+				return item.Href
+			}
+		}
+	}
+	return ""
+}
+
+func readXML(files map[string]*zip.File, name string, dest interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: missing %s", ErrInvalidEPUB, name)
+	}
+	data, err := readZipFile(f, maxPackageBytes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEPUB, err)
+	}
+	if err := xml.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEPUB, name, err)
+	}
+	return nil
+}
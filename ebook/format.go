@@ -0,0 +1,59 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/kencx/teal"
+)
+
+const epubMediaType = "application/epub+zip"
+
+// Detect the format of an ebook from its content. Returns ErrUnsupportedFormat
+// if it is not an EPUB, PDF or MOBI
+func DetectFormat(r io.ReaderAt, size int64) (string, error) {
+	header := make([]byte, 68)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("%PDF-")):
+		return teal.FormatPDF, nil
+
+	// PalmDOC header, followed by the type and creator
+	case len(header) == 68 && string(header[60:68]) == "BOOKMOBI":
+		return teal.FormatMOBI, nil
+
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")) && isEPUB(r, size):
+		return teal.FormatEPUB, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// EPUBs are ZIP archives with a mimetype file
+func isEPUB(r io.ReaderAt, size int64) bool {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if f.Name != "mimetype" {
+			continue
+		}
+		data, err := readZipFile(f, 64)
+		return err == nil && strings.TrimSpace(string(data)) == epubMediaType
+	}
+	return false
+}
+
+// read a file of a ZIP archive, of at most max bytes
+func readZipFile(f *zip.File, max int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, max))
+}
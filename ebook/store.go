@@ -0,0 +1,167 @@
+// Package ebook stores ebook files on local disk and reads the metadata of
+// EPUBs. Files are named by the SHA-256 checksum of their content, so the same
+// file is only stored once
+package ebook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+const (
+	// MaxBytes is the maximum size of an uploaded ebook
+	MaxBytes = 256 << 20
+
+	// files younger than this are never pruned
+	pruneGrace = time.Hour
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("file must be an EPUB, PDF or MOBI ebook")
+
+	checksumRgx = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	// file extension of each format
+	extensions = map[string]string{
+		teal.FormatEPUB: ".epub",
+		teal.FormatPDF:  ".pdf",
+		teal.FormatMOBI: ".mobi",
+	}
+
+	// media type of each format
+	ContentTypes = map[string]string{
+		teal.FormatEPUB: "application/epub+zip",
+		teal.FormatPDF:  "application/pdf",
+		teal.FormatMOBI: "application/x-mobipocket-ebook",
+	}
+)
+
+// Ebook files in a directory on local disk
+type Store struct {
+	Dir string
+}
+
+// Open the ebook store in dir, creating the directory if it does not exist
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ebook: %v", err)
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// A stored ebook file
+type Stored struct {
+	Checksum string
+	Format   string
+	Size     int64
+}
+
+// Save an EPUB, PDF or MOBI file read from r. Returns ErrUnsupportedFormat for
+// other files
+func (s *Store) Save(r io.Reader) (*Stored, error) {
+	tmp, err := os.CreateTemp(s.Dir, "upload-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("ebook: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return nil, fmt.Errorf("ebook: %v", err)
+	}
+
+	format, err := DetectFormat(tmp, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("ebook: %v", err)
+	}
+
+	f := &Stored{
+		Checksum: hex.EncodeToString(h.Sum(nil)),
+		Format:   format,
+		Size:     size,
+	}
+	if err := os.MkdirAll(s.dir(f.Checksum), 0o755); err != nil {
+		return nil, fmt.Errorf("ebook: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(f.Checksum, format)); err != nil {
+		return nil, fmt.Errorf("ebook: %v", err)
+	}
+	return f, nil
+}
+
+// Open a stored file. Returns os.ErrNotExist if the file does not exist
+func (s *Store) Open(checksum, format string) (*os.File, error) {
+	if !checksumRgx.MatchString(checksum) || extensions[format] == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(s.path(checksum, format))
+}
+
+// Delete a stored file
+func (s *Store) Delete(checksum, format string) error {
+	if !checksumRgx.MatchString(checksum) || extensions[format] == "" {
+		return os.ErrNotExist
+	}
+	if err := os.Remove(s.path(checksum, format)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ebook: %v", err)
+	}
+	return nil
+}
+
+// Delete the files that are not in use, except files saved within the last
+// hour, which may not have been attached to their book yet. Returns the number
+// of files deleted
+func (s *Store) Prune(inUse []string) (int, error) {
+	keep := make(map[string]bool, len(inUse))
+	for _, checksum := range inUse {
+		keep[checksum] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*", "*"))
+	if err != nil {
+		return 0, fmt.Errorf("ebook: %v", err)
+	}
+
+	count := 0
+	for _, f := range files {
+		name := filepath.Base(f)
+		checksum := strings.TrimSuffix(name, filepath.Ext(name))
+		if !checksumRgx.MatchString(checksum) || keep[checksum] {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil || time.Since(info.ModTime()) < pruneGrace {
+			continue
+		}
+		if err := os.Remove(f); err != nil {
+			return count, fmt.Errorf("ebook: %v", err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// files are spread over directories by the first two characters of their
+// checksum
+func (s *Store) dir(checksum string) string {
+	return filepath.Join(s.Dir, checksum[:2])
+}
+
+func (s *Store) path(checksum, format string) string {
+	return filepath.Join(s.dir(checksum), checksum+extensions[format])
+}
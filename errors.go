@@ -9,6 +9,16 @@ var (
 	ErrNoRows       = errors.New("no items found")
 
 	ErrDuplicateUsername = errors.New("username already exists")
+	ErrDuplicateBook     = errors.New("book with the same ISBN already exists")
+	ErrDuplicateCategory = errors.New("category already exists")
+	ErrInvalidParent     = errors.New("invalid parent category")
+	ErrDuplicateSeries   = errors.New("series already exists")
+	ErrDuplicateTag      = errors.New("tag already exists")
+	ErrSharedItem        = errors.New("the item is used by books of other users")
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrNotReading        = errors.New("book is not being read")
+	ErrPageOutOfRange    = errors.New("page is greater than the number of pages")
+	ErrVersionConflict   = errors.New("the item has been modified since it was retrieved")
 
 	ErrNoAuthHeader  = errors.New("no authentication headers")
 	ErrInvalidCreds  = errors.New("invalid credentials")
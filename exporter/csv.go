@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kencx/teal"
+)
+
+// Separates the values of list columns. Author names may contain commas
+const listSep = "; "
+
+var csvColumns = []string{
+	"id",
+	"title",
+	"authors",
+	"isbn",
+	"description",
+	"num_of_pages",
+	"rating",
+	"state",
+	"categories",
+	"series",
+	"tags",
+	"date_added",
+	"date_started",
+	"date_completed",
+}
+
+// Writes one row per book. Series are written as name #position
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Write(books []*teal.Book) error {
+	if !e.header {
+		if err := e.w.Write(csvColumns); err != nil {
+			return err
+		}
+		e.header = true
+	}
+
+	for _, b := range books {
+		var series []string
+		for _, s := range b.Series {
+			series = append(series, fmt.Sprintf("%s #%s", s.Name, strconv.FormatFloat(s.Position, 'f', -1, 64)))
+		}
+
+		if err := e.w.Write([]string{
+			strconv.FormatInt(b.ID, 10),
+			b.Title,
+			strings.Join(b.Author, listSep),
+			b.ISBN,
+			b.Description.String,
+			strconv.Itoa(b.NumOfPages),
+			strconv.Itoa(b.Rating),
+			b.State,
+			strings.Join(b.Categories, listSep),
+			strings.Join(series, listSep),
+			strings.Join(b.Tags, listSep),
+			formatDate(b.DateAdded, dateFormat),
+			formatDate(b.DateStarted, dateFormat),
+			formatDate(b.DateCompleted, dateFormat),
+		}); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	// the header of an empty library
+	if !e.header {
+		return e.Write(nil)
+	}
+	return nil
+}
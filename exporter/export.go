@@ -0,0 +1,95 @@
+// Package exporter writes a user's library in formats for backups,
+// spreadsheets and other applications
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kencx/teal"
+)
+
+// Books of a user's library that are exported
+type BookStore interface {
+	GetAll(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error)
+}
+
+// Export formats
+const (
+	FormatJSON      = "json"
+	FormatCSV       = "csv"
+	FormatGoodreads = "goodreads"
+)
+
+var Formats = []string{FormatJSON, FormatCSV, FormatGoodreads}
+
+// Dates are exported as YYYY-MM-DD, except in the Goodreads format
+const dateFormat = "2006-01-02"
+
+// Writes books in an export format. Write is called once for each page of
+// books, Close after the last page
+type encoder interface {
+	Write(books []*teal.Book) error
+	Close() error
+}
+
+// Content-Type and file extension of each format
+var (
+	ContentTypes = map[string]string{
+		FormatJSON:      "application/json",
+		FormatCSV:       "text/csv",
+		FormatGoodreads: "text/csv",
+	}
+	Extensions = map[string]string{
+		FormatJSON:      "json",
+		FormatCSV:       "csv",
+		FormatGoodreads: "csv",
+	}
+)
+
+func IsValidFormat(format string) bool {
+	_, ok := ContentTypes[format]
+	return ok
+}
+
+// Write all books of a user's library to w in the given format, in order of
+// ID. Books are retrieved and written a page at a time
+func Export(w io.Writer, store BookStore, userID int64, format string) error {
+	var enc encoder
+	switch format {
+	case FormatJSON:
+		enc = newJSONEncoder(w)
+	case FormatCSV:
+		enc = newCSVEncoder(w)
+	case FormatGoodreads:
+		enc = newGoodreadsEncoder(w)
+	default:
+		return fmt.Errorf("export: unknown format %q, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+
+	f := &teal.BookFilter{Page: teal.Page{Limit: teal.MaxPageSize, Sort: "id"}}
+	for {
+		books, info, err := store.GetAll(userID, f)
+		if err != nil && err != teal.ErrNoRows {
+			return err
+		}
+		if err := enc.Write(books); err != nil {
+			return err
+		}
+
+		if info.Next == nil {
+			break
+		}
+		f.After = info.Next
+	}
+	return enc.Close()
+}
+
+func formatDate(t sql.NullTime, layout string) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(layout)
+}
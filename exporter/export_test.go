@@ -0,0 +1,241 @@
+package exporter
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/importer"
+	"github.com/kencx/teal/mock"
+)
+
+var (
+	testAdded     = time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC)
+	testCompleted = time.Date(2022, 2, 3, 20, 0, 0, 0, time.UTC)
+
+	testBooks = []*teal.Book{{
+		ID:            1,
+		Title:         "Leviathan Wakes",
+		Description:   teal.NullString{NullString: sql.NullString{String: "Space, the final frontier", Valid: true}},
+		Author:        []string{"James S.A. Corey"},
+		Categories:    []string{"Science Fiction"},
+		Series:        []teal.SeriesEntry{{Name: "The Expanse", Position: 1}},
+		Tags:          []string{"space", "favourites"},
+		ISBN:          "9780316129084",
+		ISBN10:        "0316129089",
+		NumOfPages:    561,
+		Rating:        9,
+		State:         teal.StateRead,
+		DateAdded:     sql.NullTime{Time: testAdded, Valid: true},
+		DateCompleted: sql.NullTime{Time: testCompleted, Valid: true},
+	}, {
+		ID:        2,
+		Title:     "Pro Git",
+		Author:    []string{"Scott Chacon", "Ben Straub"},
+		Series:    []teal.SeriesEntry{{Name: "Apress", Position: 2.5}},
+		ISBN:      "1484200772",
+		State:     teal.StateReading,
+		DateAdded: sql.NullTime{Time: testAdded, Valid: true},
+	}, {
+		ID:     3,
+		Title:  "Dune",
+		Author: []string{"Frank Herbert"},
+		ISBN:   "9780441013593",
+		State:  teal.StateDidNotFinish,
+	}}
+)
+
+// a store that returns books, ordered by id, in pages of limit
+func testStore(books []*teal.Book, limit int) (*mock.BookStore, *[]int64) {
+	var cursors []int64
+	return &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			start := 0
+			if f.After != nil {
+				cursors = append(cursors, f.After.ID)
+				for start < len(books) && books[start].ID <= f.After.ID {
+					start++
+				}
+			} else {
+				cursors = append(cursors, 0)
+			}
+
+			info := teal.PageInfo{Total: len(books)}
+			if start >= len(books) {
+				return nil, info, teal.ErrNoRows
+			}
+			end := start + limit
+			if end < len(books) {
+				info.Next = &teal.Cursor{Sort: f.Sort, ID: books[end-1].ID}
+			} else {
+				end = len(books)
+			}
+			return books[start:end], info, nil
+		},
+	}, &cursors
+}
+
+func TestExportPages(t *testing.T) {
+	store, cursors := testStore(testBooks, 2)
+
+	var buf bytes.Buffer
+	err := Export(&buf, store, 1, FormatCSV)
+	checkErr(t, err)
+
+	// the second page starts after the last book of the first
+	if !reflect.DeepEqual(*cursors, []int64{0, 2}) {
+		t.Errorf("got cursors %v, want %v", *cursors, []int64{0, 2})
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	checkErr(t, err)
+	assertEqual(t, len(rows), 4)
+}
+
+func TestExportJSON(t *testing.T) {
+	store, _ := testStore(testBooks, 2)
+
+	var buf bytes.Buffer
+	err := Export(&buf, store, 1, FormatJSON)
+	checkErr(t, err)
+
+	var got struct {
+		Books []struct {
+			ID            int64              `json:"id"`
+			Title         string             `json:"title"`
+			Author        []string           `json:"author"`
+			Series        []teal.SeriesEntry `json:"series"`
+			State         string             `json:"state"`
+			DateAdded     *time.Time         `json:"date_added"`
+			DateCompleted *time.Time         `json:"date_completed"`
+		} `json:"books"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &got)
+	checkErr(t, err)
+
+	assertEqual(t, len(got.Books), 3)
+	assertEqual(t, got.Books[0].Title, "Leviathan Wakes")
+	assertEqual(t, got.Books[0].DateAdded.Equal(testAdded), true)
+	assertEqual(t, got.Books[0].DateCompleted.Equal(testCompleted), true)
+	assertEqual(t, got.Books[1].DateCompleted == nil, true)
+	assertEqual(t, got.Books[1].Series[0].Position, 2.5)
+	assertEqual(t, got.Books[2].DateAdded == nil, true)
+}
+
+func TestExportCSV(t *testing.T) {
+	store, _ := testStore(testBooks, 2)
+
+	var buf bytes.Buffer
+	err := Export(&buf, store, 1, FormatCSV)
+	checkErr(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	checkErr(t, err)
+
+	if !reflect.DeepEqual(rows[0], csvColumns) {
+		t.Errorf("got header %v, want %v", rows[0], csvColumns)
+	}
+	want := []string{"1", "Leviathan Wakes", "James S.A. Corey", "9780316129084", "Space, the final frontier",
+		"561", "9", "read", "Science Fiction", "The Expanse #1", "space; favourites", "2022-01-02", "", "2022-02-03"}
+	if !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("got %v, want %v", rows[1], want)
+	}
+	assertEqual(t, rows[2][2], "Scott Chacon; Ben Straub")
+	assertEqual(t, rows[2][9], "Apress #2.5")
+	assertEqual(t, rows[3][11], "")
+}
+
+func TestExportGoodreads(t *testing.T) {
+	store, _ := testStore(testBooks, 2)
+
+	var buf bytes.Buffer
+	err := Export(&buf, store, 1, FormatGoodreads)
+	checkErr(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	checkErr(t, err)
+	assertEqual(t, rows[1][3], "Corey, James S.A.")
+	assertEqual(t, rows[1][5], `="0316129089"`)
+	assertEqual(t, rows[1][6], `="9780316129084"`)
+	assertEqual(t, rows[1][7], "5")
+	assertEqual(t, rows[1][16], "space, favourites")
+	assertEqual(t, rows[2][5], `="1484200772"`)
+	assertEqual(t, rows[2][18], "currently-reading")
+
+	// exports can be imported again
+	records, err := importer.ReadGoodreads(&buf)
+	checkErr(t, err)
+	assertEqual(t, len(records), 3)
+
+	for i, rec := range records {
+		want := testBooks[i]
+		assertEqual(t, len(rec.Errors), 0)
+		assertEqual(t, rec.Book.Title, want.Title)
+		assertEqual(t, rec.Book.ISBN, want.ISBN)
+		assertEqual(t, rec.Book.State, want.State)
+		assertEqual(t, rec.Book.NumOfPages, want.NumOfPages)
+		if !reflect.DeepEqual(rec.Book.Author, want.Author) {
+			t.Errorf("got %v, want %v", rec.Book.Author, want.Author)
+		}
+	}
+	assertEqual(t, records[0].Book.DateCompleted.Time, time.Date(2022, 2, 3, 0, 0, 0, 0, time.UTC))
+	assertEqual(t, records[0].Book.Rating, 10)
+}
+
+func TestExportEmpty(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, "{\"books\": []}\n"},
+		{FormatCSV, "id,title,authors,isbn,description,num_of_pages,rating,state,categories,series,tags,date_added,date_started,date_completed\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			store, _ := testStore(nil, 2)
+
+			var buf bytes.Buffer
+			err := Export(&buf, store, 1, tt.format)
+			checkErr(t, err)
+			assertEqual(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestExportError(t *testing.T) {
+	store := &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			return nil, teal.PageInfo{}, errors.New("db: failed")
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, store, 1, FormatJSON); err == nil {
+		t.Errorf("expected error")
+	}
+	assertEqual(t, buf.Len(), 0)
+
+	if err := Export(&buf, store, 1, "xml"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kencx/teal"
+)
+
+// Goodreads dates are formatted as 2006/01/02
+const goodreadsDate = "2006/01/02"
+
+// The columns of a Goodreads library export
+var goodreadsColumns = []string{
+	"Book Id",
+	"Title",
+	"Author",
+	"Author l-f",
+	"Additional Authors",
+	"ISBN",
+	"ISBN13",
+	"My Rating",
+	"Average Rating",
+	"Publisher",
+	"Binding",
+	"Number of Pages",
+	"Year Published",
+	"Original Publication Year",
+	"Date Read",
+	"Date Added",
+	"Bookshelves",
+	"Bookshelves with positions",
+	"Exclusive Shelf",
+	"My Review",
+	"Spoiler",
+	"Private Notes",
+	"Read Count",
+	"Owned Copies",
+}
+
+// Goodreads exclusive shelf of each reading state
+var goodreadsShelves = map[string]string{
+	teal.StateWantToRead:   "to-read",
+	teal.StateReading:      "currently-reading",
+	teal.StateRead:         "read",
+	teal.StateDidNotFinish: "did-not-finish",
+	teal.StateOnHold:       "on-hold",
+}
+
+// Writes books as a Goodreads library export, which Goodreads and other
+// applications can import. Ratings are halved to 0 to 5, rounding up, and tags
+// are bookshelves
+type goodreadsEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newGoodreadsEncoder(w io.Writer) *goodreadsEncoder {
+	return &goodreadsEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *goodreadsEncoder) Write(books []*teal.Book) error {
+	if !e.header {
+		if err := e.w.Write(goodreadsColumns); err != nil {
+			return err
+		}
+		e.header = true
+	}
+
+	for _, b := range books {
+		var author, authorLF, additional string
+		if len(b.Author) > 0 {
+			author = b.Author[0]
+			authorLF = lastFirst(author)
+			additional = strings.Join(b.Author[1:], ", ")
+		}
+
+		// Goodreads exports ISBNs as formulas so spreadsheets keep them as text
+		isbn, isbn13 := b.ISBN10, b.ISBN
+		if len(b.ISBN) != 13 {
+			isbn, isbn13 = b.ISBN, ""
+		}
+		isbn, isbn13 = `="`+isbn+`"`, `="`+isbn13+`"`
+
+		shelf, ok := goodreadsShelves[b.State]
+		if !ok {
+			shelf = "to-read"
+		}
+
+		var dateRead, readCount string
+		if b.State == teal.StateRead {
+			dateRead = formatDate(b.DateCompleted, goodreadsDate)
+			readCount = "1"
+		} else {
+			readCount = "0"
+		}
+
+		var pages string
+		if b.NumOfPages > 0 {
+			pages = strconv.Itoa(b.NumOfPages)
+		}
+
+		if err := e.w.Write([]string{
+			strconv.FormatInt(b.ID, 10),
+			b.Title,
+			author,
+			authorLF,
+			additional,
+			isbn,
+			isbn13,
+			strconv.Itoa((b.Rating + 1) / 2),
+			"",
+			"",
+			"",
+			pages,
+			"",
+			"",
+			dateRead,
+			formatDate(b.DateAdded, goodreadsDate),
+			strings.Join(b.Tags, ", "),
+			"",
+			shelf,
+			"",
+			"",
+			"",
+			readCount,
+			"0",
+		}); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *goodreadsEncoder) Close() error {
+	if !e.header {
+		return e.Write(nil)
+	}
+	return nil
+}
+
+// Author name as last name, first names
+func lastFirst(name string) string {
+	i := strings.LastIndex(name, " ")
+	if i == -1 {
+		return name
+	}
+	return name[i+1:] + ", " + name[:i]
+}
@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+// A book as it is exported in JSON, with the dates the API omits
+type jsonBook struct {
+	*teal.Book
+	DateAdded     *time.Time `json:"date_added,omitempty"`
+	DateUpdated   *time.Time `json:"date_updated,omitempty"`
+	DateStarted   *time.Time `json:"date_started,omitempty"`
+	DateCompleted *time.Time `json:"date_completed,omitempty"`
+}
+
+// Writes {"books": [...]}, one book per line
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) *jsonEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) Write(books []*teal.Book) error {
+	for _, b := range books {
+		data, err := json.Marshal(jsonBook{
+			Book:          b,
+			DateAdded:     nullTime(b.DateAdded.Time, b.DateAdded.Valid),
+			DateUpdated:   nullTime(b.DateUpdated.Time, b.DateUpdated.Valid),
+			DateStarted:   nullTime(b.DateStarted.Time, b.DateStarted.Valid),
+			DateCompleted: nullTime(b.DateCompleted.Time, b.DateCompleted.Valid),
+		})
+		if err != nil {
+			return err
+		}
+
+		sep := ",\n  "
+		if e.count == 0 {
+			sep = "{\"books\": [\n  "
+		}
+		if _, err := io.WriteString(e.w, sep); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(data); err != nil {
+			return err
+		}
+		e.count++
+	}
+	return nil
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]}\n"
+	if e.count == 0 {
+		end = "{\"books\": []}\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+func nullTime(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
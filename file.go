@@ -0,0 +1,46 @@
+package teal
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Formats of ebook files
+const (
+	FormatEPUB = "epub"
+	FormatPDF  = "pdf"
+	FormatMOBI = "mobi"
+)
+
+var ErrDuplicateFile = errors.New("file is already attached to a book")
+
+// An ebook file attached to a book. Files are identified by the SHA-256
+// checksum of their content, and a user can only attach a file once
+type File struct {
+	ID        int64     `json:"id" db:"id"`
+	BookID    int64     `json:"book_id" db:"book_id"`
+	UserID    int64     `json:"-" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Format    string    `json:"format" db:"format"`
+	Size      int64     `json:"size" db:"size"`
+	Checksum  string    `json:"checksum" db:"checksum"`
+	DateAdded time.Time `json:"date_added" db:"dateAdded"`
+}
+
+func (f File) String() string {
+	return fmt.Sprintf(`[book=%d name=%s format=%s size=%d checksum=%s]`,
+		f.BookID, f.Name, f.Format, f.Size, f.Checksum)
+}
+
+// Ebook files attached to a user's books
+type FileService interface {
+	Get(userID, bookID, id int64) (*File, error)
+	GetAll(userID, bookID int64) ([]*File, error)
+	GetByBooks(userID int64, bookIDs []int64) (map[int64][]*File, error)
+	GetByChecksum(userID int64, checksum string) (*File, error)
+	Create(userID int64, f *File) (*File, error)
+	Delete(userID, bookID, id int64) error
+	InUse(checksum string) (bool, error)
+	Checksums() ([]string, error)
+}
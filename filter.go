@@ -0,0 +1,155 @@
+package teal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kencx/teal/validator"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+var (
+	// Fields books can be sorted by
+	BookSortFields = []string{"id", "title", "rating", "numOfPages", "dateAdded", "dateUpdated", "dateCompleted"}
+
+	// Fields authors can be sorted by
+	AuthorSortFields = []string{"id", "name"}
+)
+
+// Sort of search results, which are ordered by rank
+const SortRank = "rank"
+
+// Page of a listing. Sort is the field to sort by, prefixed with "-" for
+// descending order. After is the cursor of the previous page, nil for the first
+// page
+type Page struct {
+	Limit int
+	After *Cursor
+	Sort  string
+}
+
+// Position of a page in a listing: the sort value and id of the last item of
+// the previous page. Value is nil if the sort value is NULL. A cursor is only
+// valid for the sort it was made with
+type Cursor struct {
+	Sort  string
+	Value *string
+	ID    int64
+}
+
+// Total number of items matching a listing, and the cursor of the next page.
+// Next is nil on the last page
+type PageInfo struct {
+	Total int
+	Next  *Cursor
+}
+
+// Sort field and whether the order is descending
+func (p Page) SortBy() (string, bool) {
+	if strings.HasPrefix(p.Sort, "-") {
+		return p.Sort[1:], true
+	}
+	return p.Sort, false
+}
+
+func (p *Page) Validate(v *validator.Validator, fields []string) {
+	p.validate(v, fields, p.Sort)
+}
+
+// validate a page whose cursor must have been made with the given sort
+func (p *Page) validate(v *validator.Validator, fields []string, sort string) {
+	v.Check(p.Limit > 0, "limit", "must be > 0")
+	v.Check(p.Limit <= MaxPageSize, "limit", fmt.Sprintf("must be <= %d", MaxPageSize))
+
+	if p.Sort != "" {
+		field, _ := p.SortBy()
+		v.Check(contains(fields, field), "sort", fmt.Sprintf("must be one of %s", strings.Join(fields, ", ")))
+	}
+	if p.After != nil {
+		v.Check(p.After.Sort == sort, "cursor", "does not match sort")
+	}
+}
+
+// Filters for listing books. Unset fields do not filter. Date ranges are
+// inclusive of both days. Author matches contributors in Role, which defaults
+// to RoleAuthor. Query is a full-text search, see BookService.Search
+type BookFilter struct {
+	Page
+	Query    string
+	Author   string
+	Role     string
+	Category string
+	Tags     []string
+	MatchAll bool
+	State    string
+
+	MinRating *int
+	MaxRating *int
+	MinPages  *int
+	MaxPages  *int
+
+	AddedAfter      time.Time
+	AddedBefore     time.Time
+	CompletedAfter  time.Time
+	CompletedBefore time.Time
+}
+
+func (f *BookFilter) Validate(v *validator.Validator) {
+	if f.Query != "" {
+		v.Check(f.Sort == "", "sort", "must be empty, search results are sorted by rank")
+		f.Page.validate(v, BookSortFields, SortRank)
+	} else {
+		f.Page.Validate(v, BookSortFields)
+	}
+
+	if f.Role != "" {
+		v.Check(IsValidRole(f.Role), "role", fmt.Sprintf("must be one of %s", strings.Join(Roles, ", ")))
+	}
+
+	checkRange(v, f.MinRating, f.MaxRating, "min_rating", "max_rating")
+	checkRange(v, f.MinPages, f.MaxPages, "min_pages", "max_pages")
+
+	checkDates(v, f.AddedAfter, f.AddedBefore, "added_after", "added_before")
+	checkDates(v, f.CompletedAfter, f.CompletedBefore, "completed_after", "completed_before")
+}
+
+// Filters for listing authors
+type AuthorFilter struct {
+	Page
+}
+
+func (f *AuthorFilter) Validate(v *validator.Validator) {
+	f.Page.Validate(v, AuthorSortFields)
+}
+
+func checkRange(v *validator.Validator, min, max *int, minKey, maxKey string) {
+	if min != nil {
+		v.Check(*min >= 0, minKey, "must be >= 0")
+	}
+	if max != nil {
+		v.Check(*max >= 0, maxKey, "must be >= 0")
+	}
+	if min != nil && max != nil {
+		v.Check(*min <= *max, minKey, fmt.Sprintf("must be <= %s", maxKey))
+	}
+}
+
+func checkDates(v *validator.Validator, after, before time.Time, afterKey, beforeKey string) {
+	if !after.IsZero() && !before.IsZero() {
+		v.Check(!after.After(before), afterKey, fmt.Sprintf("must not be after %s", beforeKey))
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
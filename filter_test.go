@@ -0,0 +1,128 @@
+package teal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kencx/teal/validator"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestSortBy(t *testing.T) {
+	field, desc := Page{Sort: "-rating"}.SortBy()
+	if field != "rating" || !desc {
+		t.Errorf("got %q %v, want %q %v", field, desc, "rating", true)
+	}
+
+	field, desc = Page{Sort: "title"}.SortBy()
+	if field != "title" || desc {
+		t.Errorf("got %q %v, want %q %v", field, desc, "title", false)
+	}
+}
+
+func TestValidateBookFilter(t *testing.T) {
+	jan := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter *BookFilter
+		err    map[string]string
+	}{{
+		name:   "success",
+		filter: &BookFilter{Page: Page{Limit: 10, Sort: "-dateAdded"}, MinRating: intPtr(2), MaxRating: intPtr(8)},
+		err:    nil,
+	}, {
+		name:   "no limit",
+		filter: &BookFilter{},
+		err:    map[string]string{"limit": "must be > 0"},
+	}, {
+		name:   "limit too large",
+		filter: &BookFilter{Page: Page{Limit: MaxPageSize + 1}},
+		err:    map[string]string{"limit": "must be <= 500"},
+	}, {
+		name:   "unknown sort",
+		filter: &BookFilter{Page: Page{Limit: 10, Sort: "-isbn"}},
+		err:    map[string]string{"sort": "must be one of id, title, rating, numOfPages, dateAdded, dateUpdated, dateCompleted"},
+	}, {
+		name:   "negative pages",
+		filter: &BookFilter{Page: Page{Limit: 10}, MinPages: intPtr(-1)},
+		err:    map[string]string{"min_pages": "must be >= 0"},
+	}, {
+		name:   "min rating above max",
+		filter: &BookFilter{Page: Page{Limit: 10}, MinRating: intPtr(8), MaxRating: intPtr(2)},
+		err:    map[string]string{"min_rating": "must be <= max_rating"},
+	}, {
+		name:   "dates out of order",
+		filter: &BookFilter{Page: Page{Limit: 10}, CompletedAfter: feb, CompletedBefore: jan},
+		err:    map[string]string{"completed_after": "must not be after completed_before"},
+	}, {
+		name:   "cursor of sort",
+		filter: &BookFilter{Page: Page{Limit: 10, Sort: "-rating", After: &Cursor{Sort: "-rating", ID: 3}}},
+		err:    nil,
+	}, {
+		name:   "cursor of another sort",
+		filter: &BookFilter{Page: Page{Limit: 10, Sort: "title", After: &Cursor{Sort: "-rating", ID: 3}}},
+		err:    map[string]string{"cursor": "does not match sort"},
+	}, {
+		name:   "search cursor",
+		filter: &BookFilter{Page: Page{Limit: 10, After: &Cursor{Sort: SortRank, ID: 3}}, Query: "foo"},
+		err:    nil,
+	}, {
+		name:   "listing cursor in search",
+		filter: &BookFilter{Page: Page{Limit: 10, After: &Cursor{ID: 3}}, Query: "foo"},
+		err:    map[string]string{"cursor": "does not match sort"},
+	}, {
+		name:   "sorted search",
+		filter: &BookFilter{Page: Page{Limit: 10, Sort: "title"}, Query: "foo"},
+		err:    map[string]string{"sort": "must be empty, search results are sorted by rank"},
+	}, {
+		name:   "same day",
+		filter: &BookFilter{Page: Page{Limit: 10}, AddedAfter: jan, AddedBefore: jan},
+		err:    nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.filter.Validate(v)
+
+			if !v.Valid() && tt.err == nil {
+				t.Fatalf("expected no err, got %v", v.Errors)
+			}
+
+			if v.Valid() && tt.err != nil {
+				t.Fatalf("expected err with %q, got nil", tt.err)
+			}
+
+			if !v.Valid() && tt.err != nil {
+				for k, v := range v.Errors {
+					s, ok := tt.err[k]
+					if !ok {
+						t.Fatalf("err field missing %q", k)
+					}
+
+					if v != s {
+						t.Fatalf("got %v, want %v error", v, s)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAuthorFilter(t *testing.T) {
+	v := validator.New()
+	f := &AuthorFilter{Page: Page{Limit: 10, Sort: "rating"}}
+	f.Validate(v)
+
+	if v.Valid() {
+		t.Fatalf("expected err with sort, got nil")
+	}
+	if got := v.Errors["sort"]; got != "must be one of id, name" {
+		t.Errorf("got %v, want %v error", got, "must be one of id, name")
+	}
+}
@@ -5,8 +5,8 @@ go 1.18
 require (
 	github.com/gorilla/mux v1.8.0
 	github.com/jmoiron/sqlx v1.3.5
+	github.com/lib/pq v1.10.5
 	github.com/mattn/go-sqlite3 v1.14.13
 	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
+	golang.org/x/image v0.18.0
 )
-
-require github.com/lib/pq v1.10.5 // indirect
@@ -11,21 +11,27 @@ import (
 )
 
 type AuthorStore interface {
-	Get(id int64) (*teal.Author, error)
-	GetByName(name string) (*teal.Author, error)
-	GetAll() ([]*teal.Author, error)
-	Create(b *teal.Author) (*teal.Author, error)
-	Update(id int64, b *teal.Author) (*teal.Author, error)
-	Delete(id int64) error
+	Get(userID, id int64) (*teal.Author, error)
+	GetByName(userID int64, name string) (*teal.Author, error)
+	GetAll(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error)
+	Create(userID int64, b *teal.Author) (*teal.Author, error)
+	Update(userID, id, version int64, b *teal.Author) (*teal.Author, error)
+	Delete(userID, id int64) error
+	Merge(userID, id, into int64) (*teal.Author, error)
+	GetDuplicates(userID int64, minSimilarity float64) ([]*teal.AuthorDuplicate, error)
 }
 
 func (s *Server) GetAuthor(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	id := HandleInt64("id", rw, r)
 	if id == -1 {
 		return
 	}
 
-	a, err := s.Authors.Get(id)
+	a, err := s.Authors.Get(userID, id)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Author %d does not exist", id)
 		response.NotFound(rw, r, err)
@@ -45,13 +51,18 @@ func (s *Server) GetAuthor(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("Author %d retrieved: %v", id, a)
+	setETag(rw, a.Version)
 	response.OK(rw, r, res)
 }
 
 func (s *Server) GetAuthorByName(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	name := HandleString("name", r)
 
-	a, err := s.Authors.GetByName(name)
+	a, err := s.Authors.GetByName(userID, name)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Author %q does not exist", name)
 		response.NotFound(rw, r, err)
@@ -71,12 +82,27 @@ func (s *Server) GetAuthorByName(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("Author %q retrieved: %v", name, a)
+	setETag(rw, a.Version)
 	response.OK(rw, r, res)
 }
 
 func (s *Server) GetAllAuthors(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 
-	a, err := s.Authors.GetAll()
+	v := validator.New()
+	f := &teal.AuthorFilter{Page: readPage(r, v)}
+	if v.Valid() {
+		f.Validate(v)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	a, info, err := s.Authors.GetAll(userID, f)
 	if err == teal.ErrNoRows {
 		s.InfoLog.Println("No authors retrieved")
 		response.NoContent(rw, r)
@@ -88,18 +114,26 @@ func (s *Server) GetAllAuthors(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := util.ToJSON(response.Envelope{"authors": a})
+	res, err := util.ToJSON(response.Envelope{
+		"authors": a,
+		"total":   info.Total,
+		"links":   pageLinks(r, info),
+	})
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
 		response.InternalServerError(rw, r, err)
 		return
 	}
 
-	s.InfoLog.Printf("%d authors retrieved: %v", len(a), a)
+	s.InfoLog.Printf("%d of %d authors retrieved: %v", len(a), info.Total, a)
 	response.OK(rw, r, res)
 }
 
 func (s *Server) AddAuthor(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 
 	// marshal payload to struct
 	var author teal.Author
@@ -118,7 +152,7 @@ func (s *Server) AddAuthor(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.Authors.Create(&author)
+	result, err := s.Authors.Create(userID, &author)
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
 		response.InternalServerError(rw, r, err)
@@ -136,11 +170,20 @@ func (s *Server) AddAuthor(rw http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) UpdateAuthor(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	id := HandleInt64("id", rw, r)
 	if id == -1 {
 		return
 	}
 
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
 	// marshal payload to struct
 	var author teal.Author
 	err := request.Read(rw, r, &author)
@@ -159,12 +202,23 @@ func (s *Server) UpdateAuthor(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.Authors.Update(id, &author)
+	result, err := s.Authors.Update(userID, id, version, &author)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Author %d does not exist", id)
 		response.InternalServerError(rw, r, err)
 		return
 	}
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Author %d has been modified", id)
+		current, err := s.Authors.Get(userID, id)
+		if err != nil {
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+		s.preconditionFailed(rw, r, teal.ErrVersionConflict, "authors", current, current.Version)
+		return
+	}
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
 		response.InternalServerError(rw, r, err)
@@ -179,21 +233,203 @@ func (s *Server) UpdateAuthor(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("Author %d updated: %v", id, result)
+	setETag(rw, result.Version)
+	response.OK(rw, r, body)
+}
+
+// Partially update an author with a JSON Merge Patch or JSON Patch. The
+// patched author must be valid
+func (s *Server) PatchAuthor(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
+	current, err := s.Authors.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Author %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	if version != 0 && version != current.Version {
+		s.InfoLog.Printf("Author %d has been modified", id)
+		s.preconditionFailed(rw, r, teal.ErrVersionConflict, "authors", current, current.Version)
+		return
+	}
+
+	author := *current
+	if err := request.Patch(rw, r, &author); err != nil {
+		s.patchError(rw, r, err)
+		return
+	}
+
+	v := validator.New()
+	author.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	// the patch was applied to the current author, which must not change
+	// before it is saved
+	result, err := s.Authors.Update(userID, id, current.Version, &author)
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Author %d has been modified", id)
+		current, err := s.Authors.Get(userID, id)
+		if err != nil {
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+		s.preconditionFailed(rw, r, teal.ErrVersionConflict, "authors", current, current.Version)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	body, err := util.ToJSON(response.Envelope{"authors": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Author %d patched: %v", id, result)
+	setETag(rw, result.Version)
 	response.OK(rw, r, body)
 }
 
+// Merge an author into another author, see teal.Author.Aliases
+func (s *Server) MergeAuthor(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	var input struct {
+		Into int64 `json:"into"`
+	}
+	err := request.Read(rw, r, &input)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Into > 0, "into", "value is missing")
+	v.Check(input.Into != id, "into", "cannot merge author into itself")
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Authors.Merge(userID, id, input.Into)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Author %d or %d does not exist", id, input.Into)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	body, err := util.ToJSON(response.Envelope{"authors": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Author %d merged into %v", id, result)
+	setETag(rw, result.Version)
+	response.OK(rw, r, body)
+}
+
+// List pairs of authors with similar names, which may be the same person
+func (s *Server) GetDuplicateAuthors(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	minSimilarity := teal.DuplicateAuthorSimilarity
+	if f := readFloat(r, "min_similarity", v); f != nil {
+		minSimilarity = *f
+		v.Check(minSimilarity > 0 && minSimilarity <= 1, "min_similarity", "must be > 0 and <= 1")
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	duplicates, err := s.Authors.GetDuplicates(userID, minSimilarity)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Println("No duplicate authors found")
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"duplicates": duplicates})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d duplicate authors found", len(duplicates))
+	response.OK(rw, r, res)
+}
+
 func (s *Server) DeleteAuthor(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	id := HandleInt64("id", rw, r)
 	if id == -1 {
 		return
 	}
 
-	err := s.Authors.Delete(id)
+	err := s.Authors.Delete(userID, id)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Author %d does not exist", id)
 		response.NotFound(rw, r, err)
 		return
 	}
+	if err == teal.ErrSharedItem {
+		s.InfoLog.Printf("Author %d has books of other users", id)
+		response.Conflict(rw, r, err)
+		return
+	}
 
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
@@ -22,7 +22,7 @@ var (
 
 func TestGetAuthor(t *testing.T) {
 	testServer.Authors = &mock.AuthorStore{
-		GetAuthorFn: func(id int64) (*teal.Author, error) {
+		GetAuthorFn: func(userID, id int64) (*teal.Author, error) {
 			return testAuthor1, nil
 		},
 	}
@@ -48,7 +48,7 @@ func TestGetAuthor(t *testing.T) {
 
 func TestGetAuthorByName(t *testing.T) {
 	testServer.Authors = &mock.AuthorStore{
-		GetAuthorByNameFn: func(name string) (*teal.Author, error) {
+		GetAuthorByNameFn: func(userID int64, name string) (*teal.Author, error) {
 			return testAuthor1, nil
 		},
 	}
@@ -75,8 +75,8 @@ func TestGetAuthorByName(t *testing.T) {
 func TestGetAllAuthors(t *testing.T) {
 
 	testServer.Authors = &mock.AuthorStore{
-		GetAllAuthorsFn: func() ([]*teal.Author, error) {
-			return testAuthors, nil
+		GetAllAuthorsFn: func(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error) {
+			return testAuthors, teal.PageInfo{Total: len(testAuthors)}, nil
 		},
 	}
 
@@ -88,14 +88,18 @@ func TestGetAllAuthors(t *testing.T) {
 	w, err := testResponse(t, tc)
 	checkErr(t, err)
 
-	var env map[string][]*teal.Author
+	var env struct {
+		Authors []*teal.Author `json:"authors"`
+		Total   int            `json:"total"`
+	}
 	err = json.NewDecoder(w.Body).Decode(&env)
 	checkErr(t, err)
 
-	got := env["authors"]
+	got := env.Authors
 	for i, v := range got {
 		assertEqual(t, v.Name, testAuthors[i].Name)
 	}
+	assertEqual(t, env.Total, len(testAuthors))
 	assertEqual(t, w.Code, http.StatusOK)
 	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
 }
@@ -103,8 +107,8 @@ func TestGetAllAuthors(t *testing.T) {
 func TestGetAllAuthorsNil(t *testing.T) {
 
 	testServer.Authors = &mock.AuthorStore{
-		GetAllAuthorsFn: func() ([]*teal.Author, error) {
-			return nil, teal.ErrNoRows
+		GetAllAuthorsFn: func(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error) {
+			return nil, teal.PageInfo{}, teal.ErrNoRows
 		},
 	}
 
@@ -125,7 +129,7 @@ func TestAddAuthor(t *testing.T) {
 	checkErr(t, err)
 
 	testServer.Authors = &mock.AuthorStore{
-		CreateAuthorFn: func(a *teal.Author) (*teal.Author, error) {
+		CreateAuthorFn: func(userID int64, a *teal.Author) (*teal.Author, error) {
 			return testAuthor1, nil
 		},
 	}
@@ -155,7 +159,7 @@ func TestAddAuthorFailValidation(t *testing.T) {
 	checkErr(t, err)
 
 	testServer.Authors = &mock.AuthorStore{
-		CreateAuthorFn: func(a *teal.Author) (*teal.Author, error) {
+		CreateAuthorFn: func(userID int64, a *teal.Author) (*teal.Author, error) {
 			return testAuthor1, nil
 		},
 	}
@@ -176,7 +180,7 @@ func TestUpdateAuthor(t *testing.T) {
 	checkErr(t, err)
 
 	testServer.Authors = &mock.AuthorStore{
-		UpdateAuthorFn: func(id int64, a *teal.Author) (*teal.Author, error) {
+		UpdateAuthorFn: func(userID, id, version int64, a *teal.Author) (*teal.Author, error) {
 			return testAuthor2, nil
 		},
 	}
@@ -204,7 +208,7 @@ func TestUpdateAuthor(t *testing.T) {
 func TestDeleteAuthor(t *testing.T) {
 
 	testServer.Authors = &mock.AuthorStore{
-		DeleteAuthorFn: func(id int64) error {
+		DeleteAuthorFn: func(userID, id int64) error {
 			return nil
 		},
 	}
@@ -219,3 +223,139 @@ func TestDeleteAuthor(t *testing.T) {
 	checkErr(t, err)
 	assertEqual(t, w.Code, http.StatusOK)
 }
+
+func TestPatchAuthor(t *testing.T) {
+	var got *teal.Author
+	testServer.Authors = &mock.AuthorStore{
+		GetAuthorFn: func(userID, id int64) (*teal.Author, error) {
+			return &teal.Author{ID: id, Name: "John Doe", Version: 1}, nil
+		},
+		UpdateAuthorFn: func(userID, id, version int64, a *teal.Author) (*teal.Author, error) {
+			got = a
+			a.Version = version + 1
+			return a, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPatch,
+		url:    "/api/authors/1/",
+		data:   []byte(`{"name": "Jane Doe"}`),
+		params: map[string]string{"id": "1"},
+		fn:     testServer.PatchAuthor,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.Header().Get("ETag"), `"2"`)
+	assertEqual(t, got.Name, "Jane Doe")
+
+	tc.data = []byte(`{"name": ""}`)
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusUnprocessableEntity)
+}
+
+func TestMergeAuthor(t *testing.T) {
+	testServer.Authors = &mock.AuthorStore{
+		MergeAuthorFn: func(userID, id, into int64) (*teal.Author, error) {
+			if into != 2 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return &teal.Author{ID: into, Name: "James S.A. Corey", Aliases: []string{"S.A. Corey"}, Version: 2}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/authors/1/merge/",
+		data:   []byte(`{"into": 2}`),
+		params: map[string]string{"id": "1"},
+		fn:     testServer.MergeAuthor,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Author
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["authors"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.Header().Get("ETag"), `"2"`)
+	assertEqual(t, got.ID, 2)
+	assertEqual(t, got.Aliases[0], "S.A. Corey")
+
+	tc.data = []byte(`{"into": 10}`)
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+
+	tc.data = []byte(`{"into": 1}`)
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "into", "cannot merge author into itself")
+}
+
+func TestGetDuplicateAuthors(t *testing.T) {
+	var got float64
+	var gotUser int64
+	testServer.Authors = &mock.AuthorStore{
+		GetDuplicateAuthorsFn: func(userID int64, minSimilarity float64) ([]*teal.AuthorDuplicate, error) {
+			got = minSimilarity
+			gotUser = userID
+			if minSimilarity == 1 {
+				return nil, teal.ErrNoRows
+			}
+			return []*teal.AuthorDuplicate{{
+				Author:     &teal.Author{ID: 1, Name: "S.A. Corey"},
+				Duplicate:  &teal.Author{ID: 2, Name: "S. A. Corey"},
+				Similarity: 1,
+			}}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/authors/duplicates/",
+		fn:     testServer.GetDuplicateAuthors,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env struct {
+		Duplicates []*teal.AuthorDuplicate `json:"duplicates"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, got, teal.DuplicateAuthorSimilarity)
+	assertEqual(t, gotUser, testAuthUser.ID)
+	assertEqual(t, len(env.Duplicates), 1)
+	assertEqual(t, env.Duplicates[0].Duplicate.Name, "S. A. Corey")
+
+	tc.url = "/api/authors/duplicates/?min_similarity=1"
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+
+	tc.url = "/api/authors/duplicates/?min_similarity=2"
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "min_similarity", "must be > 0 and <= 1")
+
+	tc.url = "/api/authors/duplicates/?min_similarity=foo"
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "min_similarity", "must be a number")
+}
+
+func TestAuthorMergeRoutes(t *testing.T) {
+	for _, path := range []string{"/api/authors/1/merge", "/api/authors/1/merge/"} {
+		assertRoute(t, http.MethodPost, path, "/api/authors/{id:[0-9]+}/merge")
+	}
+	for _, path := range []string{"/api/authors/duplicates", "/api/authors/duplicates/"} {
+		assertRoute(t, http.MethodGet, path, "/api/authors/duplicates")
+	}
+}
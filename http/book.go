@@ -1,7 +1,10 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/kencx/teal"
 	"github.com/kencx/teal/http/request"
@@ -10,16 +13,21 @@ import (
 	"github.com/kencx/teal/validator"
 )
 
+// Books of a user's library
 type BookStore interface {
-	Get(id int64) (*teal.Book, error)
-	GetByISBN(isbn string) (*teal.Book, error)
-	GetByTitle(title string) (*teal.Book, error)
-	GetAll() ([]*teal.Book, error)
-	Create(b *teal.Book) (*teal.Book, error)
-	Update(id int64, b *teal.Book) (*teal.Book, error)
-	Delete(id int64) error
-
-	GetByAuthor(name string) ([]*teal.Book, error)
+	Get(userID, id int64) (*teal.Book, error)
+	GetByISBN(userID int64, isbn string) (*teal.Book, error)
+	GetByTitle(userID int64, title string) (*teal.Book, error)
+	GetAll(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error)
+	Create(userID int64, b *teal.Book) (*teal.Book, error)
+	Update(userID, id, version int64, b *teal.Book) (*teal.Book, error)
+	UpdateState(userID, id, version int64, state string) (*teal.Book, error)
+	Delete(userID, id, version int64) error
+	SetCover(userID, id, version int64, cover string) (*teal.Book, error)
+	CoverInUse(cover string) (bool, error)
+	Covers() ([]string, error)
+
+	Search(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error)
 }
 
 func hasQueryParam(param string, r *http.Request) bool {
@@ -28,12 +36,16 @@ func hasQueryParam(param string, r *http.Request) bool {
 }
 
 func (s *Server) GetBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	id := HandleInt64("id", rw, r)
 	if id == -1 {
 		return
 	}
 
-	b, err := s.Books.Get(id)
+	b, err := s.Books.Get(userID, id)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Book %d does not exist", id)
 		response.NotFound(rw, r, err)
@@ -53,13 +65,18 @@ func (s *Server) GetBook(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("Book %d retrieved: %v", id, b)
+	setETag(rw, b.Version)
 	response.OK(rw, r, res)
 }
 
 func (s *Server) GetBookByISBN(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	isbn := HandleString("isbn", r)
 
-	b, err := s.Books.GetByISBN(isbn)
+	b, err := s.Books.GetByISBN(userID, isbn)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Book isbn=%q does not exist", isbn)
 		response.NotFound(rw, r, err)
@@ -79,20 +96,32 @@ func (s *Server) GetBookByISBN(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("Book isbn=%q retrieved: %v", isbn, b)
+	setETag(rw, b.Version)
 	response.OK(rw, r, res)
 }
 
 func (s *Server) GetAllBooks(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 
-	var b []*teal.Book
-	var err error
+	if hasQueryParam("q", r) {
+		s.SearchBooks(rw, r)
+		return
+	}
 
-	if hasQueryParam("author", r) {
-		b, err = s.Books.GetByAuthor(r.URL.Query().Get("author"))
-	} else {
-		b, err = s.Books.GetAll()
+	v := validator.New()
+	f := readBookFilter(r, v)
+	if v.Valid() {
+		f.Validate(v)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
 	}
 
+	b, info, err := s.Books.GetAll(userID, f)
 	if err == teal.ErrNoRows {
 		s.InfoLog.Println("No books retrieved")
 		response.NoContent(rw, r)
@@ -104,18 +133,79 @@ func (s *Server) GetAllBooks(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := util.ToJSON(response.Envelope{"books": b})
+	res, err := util.ToJSON(response.Envelope{
+		"books": b,
+		"total": info.Total,
+		"links": pageLinks(r, info),
+	})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d of %d books retrieved: %v", len(b), info.Total, b)
+	response.OK(rw, r, res)
+}
+
+// Full-text search of books by title, description and author names, among the
+// books matching the other filters. Results are ordered by rank with
+// highlighted matches, unless sorted
+func (s *Server) SearchBooks(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	f := readBookFilter(r, v)
+	if v.Valid() {
+		f.Validate(v)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	b, info, err := s.Books.Search(userID, f)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Printf("No books matched %q", f.Query)
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{
+		"books": b,
+		"total": info.Total,
+		"links": pageLinks(r, info),
+	})
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
 		response.InternalServerError(rw, r, err)
 		return
 	}
 
-	s.InfoLog.Printf("%d books retrieved: %v", len(b), b)
+	s.InfoLog.Printf("%d of %d books matched %q", len(b), info.Total, f.Query)
 	response.OK(rw, r, res)
 }
 
 func (s *Server) AddBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	enrich := readBool(r, "enrich", v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
 
 	// marshal payload to struct
 	var book teal.Book
@@ -126,14 +216,23 @@ func (s *Server) AddBook(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	v := validator.New()
+	// fields missing from the payload are filled from metadata providers
+	if enrich {
+		s.enrichBook(&book)
+	}
+
 	book.Validate(v)
 	if !v.Valid() {
 		response.ValidationError(rw, r, v.Errors)
 		return
 	}
 
-	result, err := s.Books.Create(&book)
+	result, err := s.Books.Create(userID, &book)
+	if err == teal.ErrDuplicateBook {
+		s.InfoLog.Printf("Book %s already exists", book.ISBN)
+		response.Conflict(rw, r, err)
+		return
+	}
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
 		response.BadRequest(rw, r, err)
@@ -151,11 +250,20 @@ func (s *Server) AddBook(rw http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) UpdateBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	id := HandleInt64("id", rw, r)
 	if id == -1 {
 		return
 	}
 
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
 	// marshal payload to struct
 	var book teal.Book
 	err := request.Read(rw, r, &book)
@@ -174,12 +282,27 @@ func (s *Server) UpdateBook(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.Books.Update(id, &book)
+	result, err := s.Books.Update(userID, id, version, &book)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Book %d does not exist", id)
 		response.NotFound(rw, r, err)
 		return
 	}
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Book %d has been modified", id)
+		s.bookConflict(rw, r, userID, id, err)
+		return
+	}
+	if err == teal.ErrDuplicateBook {
+		s.InfoLog.Printf("Book %s already exists", book.ISBN)
+		response.Conflict(rw, r, err)
+		return
+	}
+	if errors.Is(err, teal.ErrInvalidTransition) {
+		v.AddError("state", err.Error())
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
 		response.InternalServerError(rw, r, err)
@@ -194,21 +317,193 @@ func (s *Server) UpdateBook(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("Book %d updated: %v", id, result)
+	setETag(rw, result.Version)
+	response.OK(rw, r, body)
+}
+
+// Partially update a book with a JSON Merge Patch or JSON Patch. The patched
+// book must be valid
+func (s *Server) PatchBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
+	current, err := s.Books.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	if version != 0 && version != current.Version {
+		s.InfoLog.Printf("Book %d has been modified", id)
+		s.preconditionFailed(rw, r, teal.ErrVersionConflict, "books", current, current.Version)
+		return
+	}
+
+	book := *current
+	if err := request.Patch(rw, r, &book); err != nil {
+		s.patchError(rw, r, err)
+		return
+	}
+
+	v := validator.New()
+	book.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	// the patch was applied to the current book, which must not change before
+	// it is saved
+	result, err := s.Books.Update(userID, id, current.Version, &book)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Book %d has been modified", id)
+		s.bookConflict(rw, r, userID, id, err)
+		return
+	}
+	if err == teal.ErrDuplicateBook {
+		s.InfoLog.Printf("Book %s already exists", book.ISBN)
+		response.Conflict(rw, r, err)
+		return
+	}
+	if errors.Is(err, teal.ErrInvalidTransition) {
+		v.AddError("state", err.Error())
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	body, err := util.ToJSON(response.Envelope{"books": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Book %d patched: %v", id, result)
+	setETag(rw, result.Version)
+	response.OK(rw, r, body)
+}
+
+// Move a book to a new reading state. Only allowed transitions are accepted
+func (s *Server) UpdateBookState(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
+	var input struct {
+		State string `json:"state"`
+	}
+	err := request.Read(rw, r, &input)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.State != "", "state", "value is missing")
+	if input.State != "" {
+		v.Check(teal.IsValidState(input.State), "state", fmt.Sprintf("must be one of %s", strings.Join(teal.States, ", ")))
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Books.UpdateState(userID, id, version, input.State)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Book %d has been modified", id)
+		s.bookConflict(rw, r, userID, id, err)
+		return
+	}
+	if errors.Is(err, teal.ErrInvalidTransition) {
+		v.AddError("state", err.Error())
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	body, err := util.ToJSON(response.Envelope{"books": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Book %d moved to %s", id, result.State)
+	setETag(rw, result.Version)
 	response.OK(rw, r, body)
 }
 
 func (s *Server) DeleteBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
 	id := HandleInt64("id", rw, r)
 	if id == -1 {
 		return
 	}
 
-	err := s.Books.Delete(id)
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
+	err := s.Books.Delete(userID, id, version)
 	if err == teal.ErrDoesNotExist {
 		s.InfoLog.Printf("Book %d does not exist", id)
 		response.NotFound(rw, r, err)
 		return
 	}
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Book %d has been modified", id)
+		s.bookConflict(rw, r, userID, id, err)
+		return
+	}
 
 	if err != nil {
 		s.ErrLog.Printf("err: %v", err)
@@ -216,6 +511,22 @@ func (s *Server) DeleteBook(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.InfoLog.Printf("Book %d deleted", id)
+	s.InfoLog.Printf("Book %d moved to trash", id)
 	response.OK(rw, r, nil)
 }
+
+// respond with the current representation of a book that failed a
+// precondition
+func (s *Server) bookConflict(rw http.ResponseWriter, r *http.Request, userID, id int64, err error) {
+	current, gerr := s.Books.Get(userID, id)
+	if gerr == teal.ErrDoesNotExist {
+		response.NotFound(rw, r, gerr)
+		return
+
+	} else if gerr != nil {
+		s.ErrLog.Printf("err: %v", gerr)
+		response.InternalServerError(rw, r, gerr)
+		return
+	}
+	s.preconditionFailed(rw, r, err, "books", current, current.Version)
+}
@@ -2,7 +2,10 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/kencx/teal"
@@ -14,24 +17,30 @@ var (
 	testBook1 = &teal.Book{
 		Title:  "FooBar",
 		Author: []string{"John Doe"},
-		ISBN:   "100",
+		ISBN:   "9780316129084",
 	}
 	testBook2 = &teal.Book{
 		Title:  "FooBar",
 		Author: []string{"John Doe"},
-		ISBN:   "101",
+		ISBN:   "9780441013593",
 	}
 	testBook3 = &teal.Book{
 		Title:  "FooBar",
 		Author: []string{"John Doe"},
-		ISBN:   "102",
+		ISBN:   "9780345539786",
 	}
 	testBooks = []*teal.Book{testBook1, testBook2, testBook3}
 )
 
+type booksEnvelope struct {
+	Books []*teal.Book `json:"books"`
+	Total int          `json:"total"`
+	Links links        `json:"links"`
+}
+
 func TestGetBook(t *testing.T) {
 	testServer.Books = &mock.BookStore{
-		GetBookFn: func(id int64) (*teal.Book, error) {
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
 			return testBook1, nil
 		},
 	}
@@ -57,9 +66,50 @@ func TestGetBook(t *testing.T) {
 	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
 }
 
+func TestGetBookOfOtherUser(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			if userID != testAuthUser.ID {
+				return nil, teal.ErrDoesNotExist
+			}
+			return testBook1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetBook,
+		user:   &teal.User{ID: 2, Username: "benadams"},
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestGetBookUnauthenticated(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			return testBook1, nil
+		},
+	}
+
+	// no user in the request context
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetBook,
+	}
+	w, err := middlewareTestResponse(t, tc, func(next http.Handler) http.Handler { return next })
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusUnauthorized, "no authentication headers")
+}
+
 func TestGetBookNil(t *testing.T) {
 	testServer.Books = &mock.BookStore{
-		GetBookFn: func(id int64) (*teal.Book, error) {
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
 			return nil, teal.ErrDoesNotExist
 		},
 	}
@@ -78,15 +128,15 @@ func TestGetBookNil(t *testing.T) {
 
 func TestGetBookByISBN(t *testing.T) {
 	testServer.Books = &mock.BookStore{
-		GetBookByISBNFn: func(isbn string) (*teal.Book, error) {
+		GetBookByISBNFn: func(userID int64, isbn string) (*teal.Book, error) {
 			return testBook1, nil
 		},
 	}
 
 	tc := &testCase{
 		method: http.MethodGet,
-		url:    "/api/books/100",
-		params: map[string]string{"isbn": "100"},
+		url:    "/api/books/9780316129084",
+		params: map[string]string{"isbn": "9780316129084"},
 		fn:     testServer.GetBookByISBN,
 	}
 	w, err := testResponse(t, tc)
@@ -106,8 +156,8 @@ func TestGetBookByISBN(t *testing.T) {
 
 func TestGetAllBooks(t *testing.T) {
 	testServer.Books = &mock.BookStore{
-		GetAllBooksFn: func() ([]*teal.Book, error) {
-			return testBooks, nil
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			return testBooks, teal.PageInfo{Total: len(testBooks)}, nil
 		},
 	}
 
@@ -120,11 +170,11 @@ func TestGetAllBooks(t *testing.T) {
 	w, err := testResponse(t, tc)
 	checkErr(t, err)
 
-	var env map[string][]*teal.Book
+	var env booksEnvelope
 	err = json.NewDecoder(w.Body).Decode(&env)
 	checkErr(t, err)
 
-	got := env["books"]
+	got := env.Books
 	for i, v := range got {
 		assertEqual(t, v.Title, testBooks[i].Title)
 		assertEqual(t, v.Author[0], testBooks[i].Author[0])
@@ -136,8 +186,8 @@ func TestGetAllBooks(t *testing.T) {
 
 func TestGetAllBooksNil(t *testing.T) {
 	testServer.Books = &mock.BookStore{
-		GetAllBooksFn: func() ([]*teal.Book, error) {
-			return nil, teal.ErrNoRows
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			return nil, teal.PageInfo{}, teal.ErrNoRows
 		},
 	}
 
@@ -153,9 +203,11 @@ func TestGetAllBooksNil(t *testing.T) {
 }
 
 func TestQueryBooksFromAuthor(t *testing.T) {
+	var gotFilter *teal.BookFilter
 	testServer.Books = &mock.BookStore{
-		GetByAuthorFn: func(name string) ([]*teal.Book, error) {
-			return testBooks, nil
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotFilter = f
+			return testBooks, teal.PageInfo{Total: len(testBooks)}, nil
 		},
 	}
 
@@ -169,19 +221,47 @@ func TestQueryBooksFromAuthor(t *testing.T) {
 	w, err := testResponse(t, tc)
 	checkErr(t, err)
 
-	var env map[string][]*teal.Book
+	var env booksEnvelope
 	err = json.NewDecoder(w.Body).Decode(&env)
 	checkErr(t, err)
 
-	got := env["books"]
+	got := env.Books
 	assertEqual(t, w.Code, http.StatusOK)
 	assertObjectEqual(t, got, testBooks)
+	assertEqual(t, gotFilter.Author, "John Doe")
+}
+
+func TestQueryBooksFromContributor(t *testing.T) {
+	var gotFilter *teal.BookFilter
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotFilter = f
+			return testBooks, teal.PageInfo{Total: len(testBooks)}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/?author=John+Doe&role=translator",
+		fn:     testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, gotFilter.Author, "John Doe")
+	assertEqual(t, gotFilter.Role, teal.RoleTranslator)
+
+	tc.url = "/api/books/?author=John+Doe&role=foo"
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "role", "must be one of author, translator, editor, illustrator, narrator")
 }
 
 func TestNilQueryBooksFromAuthor(t *testing.T) {
 	testServer.Books = &mock.BookStore{
-		GetByAuthorFn: func(name string) ([]*teal.Book, error) {
-			return nil, teal.ErrNoRows
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			return nil, teal.PageInfo{}, teal.ErrNoRows
 		},
 	}
 
@@ -202,7 +282,7 @@ func TestAddBook(t *testing.T) {
 	checkErr(t, err)
 
 	testServer.Books = &mock.BookStore{
-		CreateBookFn: func(b *teal.Book) (*teal.Book, error) {
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
 			return testBook1, nil
 		},
 	}
@@ -229,17 +309,39 @@ func TestAddBook(t *testing.T) {
 	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
 }
 
+func TestAddBookDuplicate(t *testing.T) {
+	want, err := util.ToJSON(testBook1)
+	checkErr(t, err)
+
+	testServer.Books = &mock.BookStore{
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
+			return nil, teal.ErrDuplicateBook
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/books",
+		data:   want,
+		fn:     testServer.AddBook,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusConflict, "book with the same ISBN already exists")
+}
+
 func TestAddBookFailValidation(t *testing.T) {
 	failBook := &teal.Book{
 		Title:  "",
 		Author: []string{"John Doe"},
-		ISBN:   "12345",
+		ISBN:   "9780316129060",
 	}
 	want, err := util.ToJSON(failBook)
 	checkErr(t, err)
 
 	testServer.Books = &mock.BookStore{
-		CreateBookFn: func(b *teal.Book) (*teal.Book, error) {
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
 			return failBook, nil
 		},
 	}
@@ -261,7 +363,7 @@ func TestUpdateBook(t *testing.T) {
 	checkErr(t, err)
 
 	testServer.Books = &mock.BookStore{
-		UpdateBookFn: func(id int64, b *teal.Book) (*teal.Book, error) {
+		UpdateBookFn: func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
 			return testBook2, nil
 		},
 	}
@@ -294,7 +396,7 @@ func TestUpdateBookNil(t *testing.T) {
 	checkErr(t, err)
 
 	testServer.Books = &mock.BookStore{
-		UpdateBookFn: func(id int64, b *teal.Book) (*teal.Book, error) {
+		UpdateBookFn: func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
 			return nil, teal.ErrDoesNotExist
 		},
 	}
@@ -316,13 +418,13 @@ func TestUpdateBookFailValidation(t *testing.T) {
 	failBook := &teal.Book{
 		Title:  "",
 		Author: []string{"John Doe"},
-		ISBN:   "12345",
+		ISBN:   "9780316129060",
 	}
 	want, err := util.ToJSON(failBook)
 	checkErr(t, err)
 
 	testServer.Books = &mock.BookStore{
-		UpdateBookFn: func(id int64, b *teal.Book) (*teal.Book, error) {
+		UpdateBookFn: func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
 			return failBook, nil
 		},
 	}
@@ -343,7 +445,7 @@ func TestUpdateBookFailValidation(t *testing.T) {
 func TestDeleteBook(t *testing.T) {
 
 	testServer.Books = &mock.BookStore{
-		DeleteBookFn: func(id int64) error {
+		DeleteBookFn: func(userID, id, version int64) error {
 			return nil
 		},
 	}
@@ -364,7 +466,7 @@ func TestDeleteBook(t *testing.T) {
 func TestDeleteBookNil(t *testing.T) {
 
 	testServer.Books = &mock.BookStore{
-		DeleteBookFn: func(id int64) error {
+		DeleteBookFn: func(userID, id, version int64) error {
 			return teal.ErrDoesNotExist
 		},
 	}
@@ -380,3 +482,474 @@ func TestDeleteBookNil(t *testing.T) {
 	checkErr(t, err)
 	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
 }
+
+func TestQueryBooksFromCategory(t *testing.T) {
+	var gotFilter *teal.BookFilter
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotFilter = f
+			return testBooks, teal.PageInfo{Total: len(testBooks)}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/?category=Sci-Fi",
+		fn:     testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env booksEnvelope
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env.Books
+	assertEqual(t, w.Code, http.StatusOK)
+	assertObjectEqual(t, got, testBooks)
+	assertEqual(t, gotFilter.Category, "Sci-Fi")
+}
+
+func TestQueryBooksFromTags(t *testing.T) {
+	var gotFilter *teal.BookFilter
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotFilter = f
+			return testBooks, teal.PageInfo{Total: len(testBooks)}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/?tag=space&tag=favourite&match=all",
+		fn:     testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env booksEnvelope
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env.Books
+	assertEqual(t, w.Code, http.StatusOK)
+	assertObjectEqual(t, got, testBooks)
+	assertObjectEqual(t, gotFilter.Tags, []string{"space", "favourite"})
+	assertEqual(t, gotFilter.MatchAll, true)
+}
+
+func TestSearchBooks(t *testing.T) {
+	var gotFilter *teal.BookFilter
+	want := []*teal.SearchResult{{
+		Book: testBook1,
+		Rank: 1.5,
+		Highlight: teal.Highlight{
+			Title: "<mark>Leviathan</mark> Wakes",
+		},
+	}}
+	testServer.Books = &mock.BookStore{
+		SearchFn: func(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error) {
+			gotFilter = f
+			return want, teal.PageInfo{Total: 3, Next: &teal.Cursor{Sort: teal.SortRank, ID: 1}}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/?q=expanse+leviathan&author=foo&limit=1",
+		fn:     testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env struct {
+		Books []*teal.SearchResult `json:"books"`
+		Total int                  `json:"total"`
+		Links links                `json:"links"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	assertEqual(t, w.Code, http.StatusOK)
+	assertObjectEqual(t, env.Books, want)
+	assertEqual(t, env.Total, 3)
+
+	// the query is combined with the other filters and the page
+	assertEqual(t, gotFilter.Query, "expanse leviathan")
+	assertEqual(t, gotFilter.Author, "foo")
+	assertEqual(t, gotFilter.Limit, 1)
+
+	next, err := url.Parse(env.Links.Next)
+	checkErr(t, err)
+	assertEqual(t, next.Query().Get("q"), "expanse leviathan")
+	assertEqual(t, next.Query().Get("cursor"), encodeCursor(&teal.Cursor{Sort: teal.SortRank, ID: 1}))
+}
+
+func TestSearchBooksNoResults(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		SearchFn: func(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error) {
+			return nil, teal.PageInfo{}, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/?q=foo",
+		fn:     testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+}
+
+func TestGetAllBooksPage(t *testing.T) {
+	rating := "4"
+	after := &teal.Cursor{Sort: "-rating", Value: &rating, ID: 7}
+	next := &teal.Cursor{Sort: "-rating", Value: &rating, ID: 2}
+
+	var gotFilter *teal.BookFilter
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotFilter = f
+			return testBooks[:2], teal.PageInfo{Total: 10, Next: next}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url: "/api/books/?limit=2&cursor=" + encodeCursor(after) + "&sort=-rating&state=read" +
+			"&min_rating=3&max_pages=500&added_after=2022-01-01&completed_before=2022-06-30",
+		fn: testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env booksEnvelope
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, len(env.Books), 2)
+	assertEqual(t, env.Total, 10)
+
+	assertEqual(t, gotFilter.Limit, 2)
+	assertObjectEqual(t, gotFilter.After, after)
+	assertEqual(t, gotFilter.Sort, "-rating")
+	assertEqual(t, gotFilter.State, "read")
+	assertEqual(t, *gotFilter.MinRating, 3)
+	assertEqual(t, *gotFilter.MaxPages, 500)
+	if gotFilter.MaxRating != nil {
+		t.Errorf("got max rating %d, want nil", *gotFilter.MaxRating)
+	}
+	assertEqual(t, gotFilter.AddedAfter.Format(dateFormat), "2022-01-01")
+	assertEqual(t, gotFilter.CompletedBefore.Format(dateFormat), "2022-06-30")
+
+	u, err := url.Parse(env.Links.Next)
+	checkErr(t, err)
+	assertEqual(t, u.Path, "/api/books/")
+	assertEqual(t, u.Query().Get("cursor"), encodeCursor(next))
+	assertEqual(t, u.Query().Get("sort"), "-rating")
+	assertEqual(t, u.Query().Get("state"), "read")
+}
+
+func TestGetAllBooksDefaultPage(t *testing.T) {
+	var gotFilter *teal.BookFilter
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotFilter = f
+			return testBooks, teal.PageInfo{Total: len(testBooks)}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/",
+		fn:     testServer.GetAllBooks,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env booksEnvelope
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	assertEqual(t, gotFilter.Limit, teal.DefaultPageSize)
+	if gotFilter.After != nil {
+		t.Errorf("got cursor %v, want nil", gotFilter.After)
+	}
+	assertEqual(t, env.Links.Next, "")
+}
+
+func TestGetAllBooksInvalidQuery(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			t.Fatalf("GetAll should not be called")
+			return nil, teal.PageInfo{}, nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		key     string
+		message string
+	}{
+		{name: "limit not integer", query: "limit=ten", key: "limit", message: "must be an integer"},
+		{name: "limit too large", query: "limit=1000", key: "limit", message: "must be <= 500"},
+		{name: "limit zero", query: "limit=0", key: "limit", message: "must be > 0"},
+		{name: "invalid cursor", query: "cursor=foo", key: "cursor", message: "invalid cursor"},
+		{name: "cursor of another sort", query: "sort=title&cursor=" + encodeCursor(&teal.Cursor{Sort: "-rating", ID: 1}), key: "cursor", message: "does not match sort"},
+		{name: "unknown sort", query: "sort=-isbn", key: "sort", message: "must be one of " + strings.Join(teal.BookSortFields, ", ")},
+		{name: "invalid rating", query: "min_rating=high", key: "min_rating", message: "must be an integer"},
+		{name: "invalid range", query: "min_pages=500&max_pages=100", key: "min_pages", message: "must be <= max_pages"},
+		{name: "invalid date", query: "added_after=01-01-2022", key: "added_after", message: "must be a date in YYYY-MM-DD format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodGet,
+				url:    "/api/books/?" + tt.query,
+				fn:     testServer.GetAllBooks,
+			}
+
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertValidationError(t, w, tt.key, tt.message)
+		})
+	}
+}
+
+func TestUpdateBookState(t *testing.T) {
+	var gotState string
+	testServer.Books = &mock.BookStore{
+		UpdateStateFn: func(userID, id, version int64, state string) (*teal.Book, error) {
+			gotState = state
+			return &teal.Book{ID: id, Title: "FooBar", State: state}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/books/1/state/",
+		data:   []byte(`{"state": "reading"}`),
+		params: map[string]string{"id": "1"},
+		fn:     testServer.UpdateBookState,
+	}
+
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Book
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["books"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, gotState, teal.StateReading)
+	assertEqual(t, got.State, teal.StateReading)
+}
+
+func TestUpdateBookStateRoute(t *testing.T) {
+	assertRoute(t, http.MethodPost, "/api/books/1/state", "/api/books/{id:[0-9]+}/state")
+	assertRoute(t, http.MethodPost, "/api/books/1/state/", "/api/books/{id:[0-9]+}/state")
+}
+
+func TestUpdateBookStateFail(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		UpdateStateFn: func(userID, id, version int64, state string) (*teal.Book, error) {
+			if id == 10 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return nil, fmt.Errorf("%w: cannot change from read to on-hold", teal.ErrInvalidTransition)
+		},
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		data    string
+		key     string
+		message string
+	}{{
+		name:    "no state",
+		id:      "1",
+		data:    `{}`,
+		key:     "state",
+		message: "value is missing",
+	}, {
+		name:    "invalid state",
+		id:      "1",
+		data:    `{"state": "unread"}`,
+		key:     "state",
+		message: "must be one of want-to-read, reading, read, did-not-finish, on-hold",
+	}, {
+		name:    "transition not allowed",
+		id:      "1",
+		data:    `{"state": "on-hold"}`,
+		key:     "state",
+		message: "invalid state transition: cannot change from read to on-hold",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodPost,
+				url:    "/api/books/" + tt.id + "/state/",
+				data:   []byte(tt.data),
+				params: map[string]string{"id": tt.id},
+				fn:     testServer.UpdateBookState,
+			}
+
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertValidationError(t, w, tt.key, tt.message)
+		})
+	}
+
+	t.Run("not exists", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPost,
+			url:    "/api/books/10/state/",
+			data:   []byte(`{"state": "reading"}`),
+			params: map[string]string{"id": "10"},
+			fn:     testServer.UpdateBookState,
+		}
+
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+	})
+}
+
+func TestPatchBook(t *testing.T) {
+	var got *teal.Book
+	var gotVersion int64
+	testServer.Books = &mock.BookStore{
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			return &teal.Book{
+				ID:      id,
+				Title:   testBook1.Title,
+				ISBN:    testBook1.ISBN,
+				Author:  testBook1.Author,
+				Rating:  2,
+				Version: 3,
+			}, nil
+		},
+		UpdateBookFn: func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+			got, gotVersion = b, version
+			b.Version = version + 1
+			return b, nil
+		},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+	}{{
+		name: "merge patch",
+		data: `{"rating": 5}`,
+	}, {
+		name:        "json patch",
+		contentType: "application/json-patch+json",
+		data:        `[{"op": "replace", "path": "/rating", "value": 5}]`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodPatch,
+				url:    "/api/books/1/",
+				data:   []byte(tt.data),
+				params: map[string]string{"id": "1"},
+				fn:     testServer.PatchBook,
+			}
+			if tt.contentType != "" {
+				tc.headers = map[string]string{"Content-Type": tt.contentType}
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+
+			assertEqual(t, w.Code, http.StatusOK)
+			assertEqual(t, w.Header().Get("ETag"), `"4"`)
+			assertEqual(t, gotVersion, 3)
+			assertEqual(t, got.Rating, 5)
+			assertEqual(t, got.Title, testBook1.Title)
+			assertEqual(t, got.ISBN, testBook1.ISBN)
+			assertObjectEqual(t, got.Author, testBook1.Author)
+		})
+	}
+}
+
+func TestPatchBookInvalid(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			if id == 10 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return &teal.Book{ID: id, Title: testBook1.Title, ISBN: testBook1.ISBN, Author: testBook1.Author, Version: 3}, nil
+		},
+		UpdateBookFn: func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+			t.Fatalf("book %d updated", id)
+			return nil, nil
+		},
+	}
+
+	tests := []struct {
+		name        string
+		id          string
+		contentType string
+		ifMatch     string
+		data        string
+		status      int
+	}{
+		{name: "not exists", id: "10", data: `{"rating": 5}`, status: http.StatusNotFound},
+		{name: "invalid result", id: "1", data: `{"title": null}`, status: http.StatusUnprocessableEntity},
+		{name: "unknown field", id: "1", data: `{"foo": 1}`, status: http.StatusBadRequest},
+		{name: "stale version", id: "1", ifMatch: `"2"`, data: `{"rating": 5}`, status: http.StatusPreconditionFailed},
+		{name: "unsupported type", id: "1", contentType: "text/plain", data: `{"rating": 5}`, status: http.StatusUnsupportedMediaType},
+		{
+			name:        "test failed",
+			id:          "1",
+			contentType: "application/json-patch+json",
+			data:        `[{"op": "test", "path": "/rating", "value": 5}]`,
+			status:      http.StatusConflict,
+		},
+		{
+			name:        "invalid path",
+			id:          "1",
+			contentType: "application/json-patch+json",
+			data:        `[{"op": "remove", "path": "/foo"}]`,
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method:  http.MethodPatch,
+				url:     "/api/books/" + tt.id + "/",
+				data:    []byte(tt.data),
+				params:  map[string]string{"id": tt.id},
+				headers: map[string]string{},
+				fn:      testServer.PatchBook,
+			}
+			if tt.contentType != "" {
+				tc.headers["Content-Type"] = tt.contentType
+			}
+			if tt.ifMatch != "" {
+				tc.headers["If-Match"] = tt.ifMatch
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertEqual(t, w.Code, tt.status)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/request"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type CategoryStore interface {
+	teal.CategoryService
+}
+
+func (s *Server) GetCategory(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	c, err := s.Categories.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Category %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"categories": c})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Category %d retrieved: %v", id, c)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) GetAllCategories(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	c, err := s.Categories.GetAll(userID)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Println("No categories retrieved")
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"categories": c})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d categories retrieved: %v", len(c), c)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) AddCategory(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var category teal.Category
+	err := request.Read(rw, r, &category)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	// validate payload
+	v := validator.New()
+	category.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Categories.Create(userID, &category)
+	if err != nil {
+		switch {
+		case errors.Is(err, teal.ErrDuplicateCategory):
+			v.AddError("name", "this category already exists")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		case errors.Is(err, teal.ErrInvalidParent):
+			v.AddError("parent_id", "parent category does not exist")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		default:
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	body, err := util.ToJSON(response.Envelope{"categories": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	s.InfoLog.Printf("New category created: %v", result)
+	response.Created(rw, r, body)
+}
+
+func (s *Server) UpdateCategory(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var category teal.Category
+	err := request.Read(rw, r, &category)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+	category.ID = id
+
+	// validate payload
+	// PUT should require all fields
+	v := validator.New()
+	category.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Categories.Update(userID, id, &category)
+	if err != nil {
+		switch {
+		case errors.Is(err, teal.ErrDoesNotExist):
+			s.InfoLog.Printf("Category %d does not exist", id)
+			response.NotFound(rw, r, err)
+			return
+		case errors.Is(err, teal.ErrDuplicateCategory):
+			v.AddError("name", "this category already exists")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		case errors.Is(err, teal.ErrInvalidParent):
+			v.AddError("parent_id", "parent category does not exist or is a subcategory")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		default:
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	body, err := util.ToJSON(response.Envelope{"categories": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Category %d updated: %v", id, result)
+	response.OK(rw, r, body)
+}
+
+func (s *Server) DeleteCategory(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	err := s.Categories.Delete(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Category %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Category %d deleted", id)
+	response.OK(rw, r, nil)
+}
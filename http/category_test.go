@@ -0,0 +1,290 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+	"github.com/kencx/teal/util"
+)
+
+var (
+	testCategory1 = &teal.Category{
+		Name: "Fiction",
+	}
+	testCategory2 = &teal.Category{
+		Name:     "Sci-Fi",
+		ParentID: 1,
+	}
+	testCategories = []*teal.Category{testCategory1, testCategory2}
+)
+
+func TestGetCategory(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		GetCategoryFn: func(userID, id int64) (*teal.Category, error) {
+			return testCategory2, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/categories/2",
+		params: map[string]string{"id": "2"},
+		fn:     testServer.GetCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Category
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["categories"]
+	assertEqual(t, got.Name, testCategory2.Name)
+	assertEqual(t, got.ParentID, testCategory2.ParentID)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestGetCategoryUnauthenticated(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		GetCategoryFn: func(userID, id int64) (*teal.Category, error) {
+			return testCategory2, nil
+		},
+	}
+
+	// no user in the request context
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/categories/2",
+		params: map[string]string{"id": "2"},
+		fn:     testServer.GetCategory,
+	}
+	w, err := middlewareTestResponse(t, tc, func(next http.Handler) http.Handler { return next })
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusUnauthorized, "no authentication headers")
+}
+
+func TestGetCategoryNil(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		GetCategoryFn: func(userID, id int64) (*teal.Category, error) {
+			return nil, teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/categories/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestGetAllCategories(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		GetAllCategoriesFn: func(userID int64) ([]*teal.Category, error) {
+			return testCategories, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/categories/",
+		fn:     testServer.GetAllCategories,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.Category
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["categories"]
+	for i, v := range got {
+		assertEqual(t, v.Name, testCategories[i].Name)
+		assertEqual(t, v.ParentID, testCategories[i].ParentID)
+	}
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestGetAllCategoriesNil(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		GetAllCategoriesFn: func(userID int64) ([]*teal.Category, error) {
+			return nil, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/categories/",
+		fn:     testServer.GetAllCategories,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+}
+
+func TestAddCategory(t *testing.T) {
+	want, err := util.ToJSON(testCategory1)
+	checkErr(t, err)
+
+	testServer.Categories = &mock.CategoryStore{
+		CreateCategoryFn: func(userID int64, c *teal.Category) (*teal.Category, error) {
+			return testCategory1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/categories/",
+		data:   want,
+		fn:     testServer.AddCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Category
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["categories"]
+	assertEqual(t, got.Name, testCategory1.Name)
+	assertEqual(t, w.Code, http.StatusCreated)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestAddCategoryFailValidation(t *testing.T) {
+	want, err := util.ToJSON(&teal.Category{Name: ""})
+	checkErr(t, err)
+
+	testServer.Categories = &mock.CategoryStore{
+		CreateCategoryFn: func(userID int64, c *teal.Category) (*teal.Category, error) {
+			return testCategory1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/categories/",
+		data:   want,
+		fn:     testServer.AddCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "name", "value is missing")
+}
+
+func TestAddCategoryDuplicate(t *testing.T) {
+	want, err := util.ToJSON(testCategory1)
+	checkErr(t, err)
+
+	testServer.Categories = &mock.CategoryStore{
+		CreateCategoryFn: func(userID int64, c *teal.Category) (*teal.Category, error) {
+			return nil, teal.ErrDuplicateCategory
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/categories/",
+		data:   want,
+		fn:     testServer.AddCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "name", "this category already exists")
+}
+
+func TestUpdateCategory(t *testing.T) {
+	want, err := util.ToJSON(testCategory2)
+	checkErr(t, err)
+
+	testServer.Categories = &mock.CategoryStore{
+		UpdateCategoryFn: func(userID, id int64, c *teal.Category) (*teal.Category, error) {
+			return testCategory2, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/categories/2",
+		data:   want,
+		params: map[string]string{"id": "2"},
+		fn:     testServer.UpdateCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Category
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["categories"]
+	assertEqual(t, got.Name, testCategory2.Name)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestUpdateCategoryInvalidParent(t *testing.T) {
+	want, err := util.ToJSON(testCategory2)
+	checkErr(t, err)
+
+	testServer.Categories = &mock.CategoryStore{
+		UpdateCategoryFn: func(userID, id int64, c *teal.Category) (*teal.Category, error) {
+			return nil, teal.ErrInvalidParent
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/categories/3",
+		data:   want,
+		params: map[string]string{"id": "3"},
+		fn:     testServer.UpdateCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "parent_id", "parent category does not exist or is a subcategory")
+}
+
+func TestDeleteCategory(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		DeleteCategoryFn: func(userID, id int64) error {
+			return nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/categories/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.DeleteCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestDeleteCategoryNil(t *testing.T) {
+	testServer.Categories = &mock.CategoryStore{
+		DeleteCategoryFn: func(userID, id int64) error {
+			return teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/categories/10",
+		params: map[string]string{"id": "10"},
+		fn:     testServer.DeleteCategory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/covers"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+const (
+	// covers requested by their hash never change
+	immutableCache = "private, max-age=31536000, immutable"
+	// covers requested without their hash are revalidated with their ETag
+	revalidateCache = "private, no-cache"
+)
+
+// Serve a book's cover image in the given size, the original by default. With
+// v set to the book's cover hash, the image is cached indefinitely
+func (s *Server) GetBookCover(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = covers.Original
+	}
+	v := validator.New()
+	v.Check(covers.IsValidSize(size), "size", fmt.Sprintf("must be one of %s", strings.Join(covers.Sizes, ", ")))
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	b, err := s.Books.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	if b.Cover == "" {
+		s.InfoLog.Printf("Book %d has no cover", id)
+		response.NotFound(rw, r, errors.New("book has no cover"))
+		return
+	}
+
+	path, err := s.Covers.Path(b.Cover, size)
+	if err != nil {
+		s.ErrLog.Printf("err: cover %s of book %d: %v", b.Cover, id, err)
+		response.NotFound(rw, r, errors.New("book has no cover"))
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		s.ErrLog.Printf("err: cover %s of book %d: %v", b.Cover, id, err)
+		response.NotFound(rw, r, errors.New("book has no cover"))
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	cache := revalidateCache
+	if r.URL.Query().Get("v") == b.Cover {
+		cache = immutableCache
+	}
+	rw.Header().Set("Cache-Control", cache)
+	rw.Header().Set("ETag", fmt.Sprintf(`"%s-%s"`, b.Cover, size))
+	http.ServeContent(rw, r, filepath.Base(path), info.ModTime(), f)
+}
+
+// Upload a JPEG, PNG or WebP cover image for a book, as the file field of a
+// multipart form or as the request body. Thumbnails are created from the
+// image, and the book's previous cover is removed
+func (s *Server) PutBookCover(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
+	current, err := s.Books.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	file, _, err := readUpload(rw, r, covers.MaxBytes)
+	if err != nil {
+		s.InfoLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		s.InfoLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, fmt.Errorf("unable to read file: %v", err))
+		return
+	}
+
+	cover, err := s.Covers.Save(data)
+	if err == covers.ErrUnsupportedImage {
+		response.UnsupportedMediaType(rw, r, err)
+		return
+	}
+	if err == covers.ErrImageTooLarge {
+		response.ValidationError(rw, r, map[string]string{"file": err.Error()})
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.setBookCover(rw, r, userID, id, version, current.Cover, cover)
+}
+
+// Remove a book's cover
+func (s *Server) DeleteBookCover(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	version := HandleIfMatch(rw, r)
+	if version == -1 {
+		return
+	}
+
+	current, err := s.Books.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.setBookCover(rw, r, userID, id, version, current.Cover, "")
+}
+
+// set the cover of a book and respond with the updated book. The files of the
+// previous cover are removed if no other book has it
+func (s *Server) setBookCover(rw http.ResponseWriter, r *http.Request, userID, id, version int64, previous, cover string) {
+	result, err := s.Books.SetCover(userID, id, version, cover)
+	if err != nil && cover != "" && cover != previous {
+		s.removeCover(cover)
+	}
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err == teal.ErrVersionConflict {
+		s.InfoLog.Printf("Book %d has been modified", id)
+		s.bookConflict(rw, r, userID, id, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	if previous != "" && previous != cover {
+		s.removeCover(previous)
+	}
+
+	body, err := util.ToJSON(response.Envelope{"books": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	if cover == "" {
+		s.InfoLog.Printf("Cover of book %d removed", id)
+	} else {
+		s.InfoLog.Printf("Cover of book %d set to %s", id, cover)
+	}
+	setETag(rw, result.Version)
+	response.OK(rw, r, body)
+}
+
+// delete the files of a cover that no book has. Errors are only logged, as
+// unused covers are also removed by PruneCovers
+func (s *Server) removeCover(cover string) {
+	inUse, err := s.Books.CoverInUse(cover)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		return
+	}
+	if inUse {
+		return
+	}
+	if err := s.Covers.Delete(cover); err != nil {
+		s.ErrLog.Printf("err: %v", err)
+	}
+}
+
+// Delete the covers of books that were purged from the trash. Returns the
+// number of covers deleted
+func (s *Server) PruneCovers() (int, error) {
+	inUse, err := s.Books.Covers()
+	if err != nil {
+		return 0, err
+	}
+	return s.Covers.Prune(inUse)
+}
@@ -0,0 +1,290 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/covers"
+	"github.com/kencx/teal/mock"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	checkErr(t, err)
+	return buf.Bytes()
+}
+
+// a cover store with a saved cover
+func testCoverStore(t *testing.T) (*covers.Store, string) {
+	t.Helper()
+	s, err := covers.New(t.TempDir())
+	checkErr(t, err)
+	hash, err := s.Save(testPNG(t, 40, 60))
+	checkErr(t, err)
+	return s, hash
+}
+
+// a book store of book 1 at version 1, with the given cover, in which covers are only
+// used by that book
+func testCoverBookStore(cover *string) *mock.BookStore {
+	return &mock.BookStore{
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			if id != 1 {
+				return nil, teal.ErrDoesNotExist
+			}
+			b := *testBook1
+			b.ID, b.Version, b.Cover = 1, 1, *cover
+			return &b, nil
+		},
+		SetCoverFn: func(userID, id, version int64, c string) (*teal.Book, error) {
+			if version != 0 && version != 1 {
+				return nil, teal.ErrVersionConflict
+			}
+			*cover = c
+			b := *testBook1
+			b.ID, b.Version, b.Cover = 1, 2, c
+			return &b, nil
+		},
+		CoverInUseFn: func(c string) (bool, error) {
+			return c == *cover, nil
+		},
+	}
+}
+
+func coverExists(s *covers.Store, hash string) bool {
+	_, err := s.Path(hash, covers.Original)
+	return err == nil
+}
+
+func TestPutBookCover(t *testing.T) {
+	store, previous := testCoverStore(t)
+	cover := previous
+	testServer.Covers = store
+	testServer.Books = testCoverBookStore(&cover)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "cover.png")
+	checkErr(t, err)
+	_, err = part.Write(testPNG(t, 300, 200))
+	checkErr(t, err)
+	checkErr(t, mw.Close())
+
+	tc := &testCase{
+		method:  http.MethodPut,
+		url:     "/api/books/1/cover/",
+		headers: map[string]string{"Content-Type": mw.FormDataContentType()},
+		data:    body.Bytes(),
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.PutBookCover,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("ETag"), `"2"`)
+
+	var env map[string]teal.Book
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+	got := env["books"].Cover
+	if got == "" || got == previous {
+		t.Fatalf("got cover %q", got)
+	}
+	assertEqual(t, cover, got)
+
+	// the previous cover is no longer in use
+	assertEqual(t, coverExists(store, got), true)
+	assertEqual(t, coverExists(store, previous), false)
+}
+
+func TestPutBookCoverBody(t *testing.T) {
+	store, _ := testCoverStore(t)
+	cover := ""
+	testServer.Covers = store
+	testServer.Books = testCoverBookStore(&cover)
+
+	tc := &testCase{
+		method:  http.MethodPut,
+		url:     "/api/books/1/cover/",
+		headers: map[string]string{"Content-Type": "image/png"},
+		data:    testPNG(t, 10, 10),
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.PutBookCover,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, coverExists(store, cover), true)
+}
+
+func TestPutBookCoverInvalid(t *testing.T) {
+	store, previous := testCoverStore(t)
+	cover := previous
+	testServer.Covers = store
+	testServer.Books = testCoverBookStore(&cover)
+
+	t.Run("unsupported image", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPut,
+			url:    "/api/books/1/cover/",
+			data:   []byte("GIF89a"),
+			params: map[string]string{"id": "1"},
+			fn:     testServer.PutBookCover,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusUnsupportedMediaType, covers.ErrUnsupportedImage.Error())
+	})
+
+	t.Run("book does not exist", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPut,
+			url:    "/api/books/2/cover/",
+			data:   testPNG(t, 10, 10),
+			params: map[string]string{"id": "2"},
+			fn:     testServer.PutBookCover,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		data := testPNG(t, 20, 10)
+		tc := &testCase{
+			method:  http.MethodPut,
+			url:     "/api/books/1/cover/",
+			headers: map[string]string{"If-Match": `"5"`},
+			data:    data,
+			params:  map[string]string{"id": "1"},
+			fn:      testServer.PutBookCover,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertEqual(t, w.Code, http.StatusPreconditionFailed)
+		assertEqual(t, cover, previous)
+
+		// the uploaded cover is not kept
+		sum := sha256.Sum256(data)
+		assertEqual(t, coverExists(store, hex.EncodeToString(sum[:])), false)
+		assertEqual(t, coverExists(store, previous), true)
+	})
+}
+
+func TestGetBookCover(t *testing.T) {
+	store, cover := testCoverStore(t)
+	testServer.Covers = store
+	testServer.Books = testCoverBookStore(&cover)
+
+	tests := []struct {
+		name  string
+		url   string
+		cache string
+		etag  string
+		ctype string
+	}{
+		{"original", "/api/books/1/cover/", revalidateCache, `"` + cover + `-original"`, "image/png"},
+		{"small", "/api/books/1/cover/?size=small", revalidateCache, `"` + cover + `-small"`, "image/jpeg"},
+		{"versioned", "/api/books/1/cover/?size=large&v=" + cover, immutableCache, `"` + cover + `-large"`, "image/jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodGet,
+				url:    tt.url,
+				params: map[string]string{"id": "1"},
+				fn:     testServer.GetBookCover,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertEqual(t, w.Code, http.StatusOK)
+			assertEqual(t, w.HeaderMap.Get("Content-Type"), tt.ctype)
+			assertEqual(t, w.HeaderMap.Get("Cache-Control"), tt.cache)
+			assertEqual(t, w.HeaderMap.Get("ETag"), tt.etag)
+			if w.Body.Len() == 0 {
+				t.Errorf("got empty cover")
+			}
+		})
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		tc := &testCase{
+			method:  http.MethodGet,
+			url:     "/api/books/1/cover/?size=small",
+			headers: map[string]string{"If-None-Match": `"` + cover + `-small"`},
+			params:  map[string]string{"id": "1"},
+			fn:      testServer.GetBookCover,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertEqual(t, w.Code, http.StatusNotModified)
+	})
+}
+
+func TestGetBookCoverInvalid(t *testing.T) {
+	store, _ := testCoverStore(t)
+	cover := ""
+	testServer.Covers = store
+	testServer.Books = testCoverBookStore(&cover)
+
+	t.Run("invalid size", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodGet,
+			url:    "/api/books/1/cover/?size=huge",
+			params: map[string]string{"id": "1"},
+			fn:     testServer.GetBookCover,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertValidationError(t, w, "size", "must be one of small, medium, large, original")
+	})
+
+	t.Run("no cover", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodGet,
+			url:    "/api/books/1/cover/",
+			params: map[string]string{"id": "1"},
+			fn:     testServer.GetBookCover,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusNotFound, "book has no cover")
+	})
+}
+
+func TestDeleteBookCover(t *testing.T) {
+	store, previous := testCoverStore(t)
+	cover := previous
+	testServer.Covers = store
+	testServer.Books = testCoverBookStore(&cover)
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/books/1/cover/",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.DeleteBookCover,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, cover, "")
+	assertEqual(t, coverExists(store, previous), false)
+}
+
+func TestBookCoverRoutes(t *testing.T) {
+	for _, path := range []string{"/api/books/1/cover", "/api/books/1/cover/", "/api/books/1/cover?size=small"} {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			assertRoute(t, method, path, "/api/books/{id:[0-9]+}/cover")
+		}
+	}
+}
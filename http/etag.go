@@ -0,0 +1,48 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+)
+
+// Set the ETag header to the version of the resource
+func setETag(rw http.ResponseWriter, version int64) {
+	rw.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// Retrieve the version in the If-Match header. Returns 0 if the header is
+// absent or "*", which match any version. Only a single strong ETag is
+// supported
+func HandleIfMatch(rw http.ResponseWriter, r *http.Request) int64 {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+	if err != nil || version <= 0 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		response.BadRequest(rw, r, errors.New("unable to process If-Match header"))
+		return -1
+	}
+	return version
+}
+
+// Respond with 412 Precondition Failed and the current representation of the
+// resource in the given envelope key
+func (s *Server) preconditionFailed(rw http.ResponseWriter, r *http.Request, err error, key string, current interface{}, version int64) {
+	res, jerr := util.ToJSON(response.Envelope{"error": err.Error(), key: current})
+	if jerr != nil {
+		s.ErrLog.Printf("err: %v", jerr)
+		response.InternalServerError(rw, r, jerr)
+		return
+	}
+
+	setETag(rw, version)
+	response.PreconditionFailed(rw, r, res)
+}
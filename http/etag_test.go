@@ -0,0 +1,224 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+	"github.com/kencx/teal/util"
+)
+
+func TestHandleIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+	}{
+		{header: "", want: 0},
+		{header: "*", want: 0},
+		{header: `"3"`, want: 3},
+		{header: ` "12" `, want: 12},
+		{header: "3", want: -1},
+		{header: `W/"3"`, want: -1},
+		{header: `"foo"`, want: -1},
+		{header: `"0"`, want: -1},
+		{header: `"1", "2"`, want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			req.Header.Set("If-Match", tt.header)
+			rw := httptest.NewRecorder()
+
+			got := HandleIfMatch(rw, req)
+			assertEqual(t, got, tt.want)
+			if tt.want == -1 {
+				assertEqual(t, rw.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestGetBookETag(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			return &teal.Book{ID: id, Title: testBook1.Title, Version: 3}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/1/",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetBook,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.Header().Get("ETag"), `"3"`)
+}
+
+func TestUpdateBookIfMatch(t *testing.T) {
+	data, err := util.ToJSON(testBook2)
+	checkErr(t, err)
+
+	var gotVersion int64
+	testServer.Books = &mock.BookStore{
+		UpdateBookFn: func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+			gotVersion = version
+			if version != 3 {
+				return nil, teal.ErrVersionConflict
+			}
+			b.Version = 4
+			return b, nil
+		},
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			return &teal.Book{ID: id, Title: "current", Version: 5}, nil
+		},
+	}
+
+	tc := &testCase{
+		method:  http.MethodPut,
+		url:     "/api/books/1/",
+		data:    data,
+		headers: map[string]string{"If-Match": `"3"`},
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.UpdateBook,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, gotVersion, 3)
+	assertEqual(t, w.Header().Get("ETag"), `"4"`)
+
+	// stale version returns the current book
+	tc.headers = map[string]string{"If-Match": `"2"`}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusPreconditionFailed)
+	assertEqual(t, w.Header().Get("ETag"), `"5"`)
+
+	var env struct {
+		Error string     `json:"error"`
+		Books *teal.Book `json:"books"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+	assertEqual(t, env.Error, teal.ErrVersionConflict.Error())
+	assertEqual(t, env.Books.Title, "current")
+	assertEqual(t, env.Books.Version, 5)
+
+	// updates without If-Match are unconditional
+	tc.headers = nil
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusPreconditionFailed)
+	assertEqual(t, gotVersion, 0)
+}
+
+func TestDeleteBookIfMatch(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		DeleteBookFn: func(userID, id, version int64) error {
+			if version != 0 && version != 3 {
+				return teal.ErrVersionConflict
+			}
+			return nil
+		},
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			return &teal.Book{ID: id, Title: "current", Version: 3}, nil
+		},
+	}
+
+	tc := &testCase{
+		method:  http.MethodDelete,
+		url:     "/api/books/1/",
+		headers: map[string]string{"If-Match": `"2"`},
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.DeleteBook,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusPreconditionFailed)
+	assertEqual(t, w.Header().Get("ETag"), `"3"`)
+
+	tc.headers = map[string]string{"If-Match": `"3"`}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestUpdateBookStateIfMatch(t *testing.T) {
+	var gotVersion int64
+	testServer.Books = &mock.BookStore{
+		UpdateStateFn: func(userID, id, version int64, state string) (*teal.Book, error) {
+			gotVersion = version
+			if version != 0 && version != 3 {
+				return nil, teal.ErrVersionConflict
+			}
+			return &teal.Book{ID: id, State: state, Version: 4}, nil
+		},
+		GetBookFn: func(userID, id int64) (*teal.Book, error) {
+			return &teal.Book{ID: id, Title: "current", Version: 3}, nil
+		},
+	}
+
+	tc := &testCase{
+		method:  http.MethodPost,
+		url:     "/api/books/1/state/",
+		data:    []byte(`{"state": "reading"}`),
+		headers: map[string]string{"If-Match": `"2"`},
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.UpdateBookState,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusPreconditionFailed)
+	assertEqual(t, w.Header().Get("ETag"), `"3"`)
+
+	tc.headers = map[string]string{"If-Match": `"3"`}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, gotVersion, 3)
+	assertEqual(t, w.Header().Get("ETag"), `"4"`)
+
+	tc.headers = map[string]string{"If-Match": `3`}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusBadRequest)
+}
+
+func TestUpdateAuthorIfMatch(t *testing.T) {
+	data, err := util.ToJSON(&teal.Author{Name: "John Doe"})
+	checkErr(t, err)
+
+	testServer.Authors = &mock.AuthorStore{
+		UpdateAuthorFn: func(userID, id, version int64, a *teal.Author) (*teal.Author, error) {
+			return nil, teal.ErrVersionConflict
+		},
+		GetAuthorFn: func(userID, id int64) (*teal.Author, error) {
+			return &teal.Author{ID: id, Name: "Jane Doe", Version: 2}, nil
+		},
+	}
+
+	tc := &testCase{
+		method:  http.MethodPut,
+		url:     "/api/authors/1/",
+		data:    data,
+		headers: map[string]string{"If-Match": `"1"`},
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.UpdateAuthor,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusPreconditionFailed)
+	assertEqual(t, w.Header().Get("ETag"), `"2"`)
+
+	var env map[string]interface{}
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+	assertEqual(t, env["authors"].(map[string]interface{})["name"].(string), "Jane Doe")
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kencx/teal/exporter"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/validator"
+)
+
+// Export the user's library as an attachment. The format defaults to json.
+// Books are streamed as they are retrieved, so an error after the first page
+// can only be logged
+func (s *Server) Export(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exporter.FormatJSON
+	}
+
+	v := validator.New()
+	v.Check(exporter.IsValidFormat(format), "format", fmt.Sprintf("must be one of %s", strings.Join(exporter.Formats, ", ")))
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	filename := fmt.Sprintf("teal-%s-%s.%s", format, time.Now().UTC().Format(dateFormat), exporter.Extensions[format])
+	rw.Header().Set("Content-Type", exporter.ContentTypes[format])
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	w := &writeTracker{ResponseWriter: rw}
+	if err := exporter.Export(w, s.Books, userID, format); err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		if !w.written {
+			rw.Header().Del("Content-Disposition")
+			response.InternalServerError(rw, r, err)
+		}
+		return
+	}
+	s.InfoLog.Printf("Library exported as %s", format)
+}
+
+// Records whether the response has been started
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeTracker) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+)
+
+func TestExport(t *testing.T) {
+	var gotUser int64
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			gotUser = userID
+			return []*teal.Book{testBook1, testBook2}, teal.PageInfo{Total: 2}, nil
+		},
+	}
+
+	tests := []struct {
+		url         string
+		contentType string
+		prefix      string
+	}{
+		{"/api/export/", "application/json", `{"books": [`},
+		{"/api/export/?format=csv", "text/csv", "id,title,authors"},
+		{"/api/export/?format=goodreads", "text/csv", "Book Id,Title,Author"},
+	}
+
+	for _, tt := range tests {
+		tc := &testCase{
+			method: http.MethodGet,
+			url:    tt.url,
+			fn:     testServer.Export,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+
+		assertEqual(t, w.Code, http.StatusOK)
+		assertEqual(t, gotUser, testAuthUser.ID)
+		assertEqual(t, w.Header().Get("Content-Type"), tt.contentType)
+		assertEqual(t, strings.HasPrefix(w.Header().Get("Content-Disposition"), `attachment; filename="teal-`), true)
+		assertEqual(t, strings.HasPrefix(w.Body.String(), tt.prefix), true)
+		assertEqual(t, strings.Contains(w.Body.String(), testBook2.Title), true)
+	}
+}
+
+func TestExportInvalid(t *testing.T) {
+	testServer.Books = &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			return nil, teal.PageInfo{}, errors.New("db: failed")
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/export/?format=xml",
+		fn:     testServer.Export,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusUnprocessableEntity)
+
+	tc.url = "/api/export/"
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusInternalServerError)
+	assertEqual(t, w.Header().Get("Content-Disposition"), "")
+}
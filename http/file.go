@@ -0,0 +1,410 @@
+package http
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/ebook"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type FileStore interface {
+	teal.FileService
+}
+
+func (s *Server) GetBookFiles(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	files, err := s.Files.GetAll(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err == teal.ErrNoRows {
+		s.InfoLog.Printf("No files retrieved for book %d", id)
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"files": files})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d files retrieved for book %d", len(files), id)
+	response.OK(rw, r, res)
+}
+
+// Attach an EPUB, PDF or MOBI file to a book. The metadata of EPUBs fills in
+// the details and cover that the book is missing
+func (s *Server) AddBookFile(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	book, err := s.Books.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	stored, name, ok := s.saveEbook(rw, r)
+	if !ok {
+		return
+	}
+	if !s.checkDuplicateFile(rw, r, userID, stored) {
+		return
+	}
+
+	if stored.Format == teal.FormatEPUB {
+		e, err := s.readEPUB(stored)
+		if err != nil {
+			// the file is attached without its metadata
+			s.InfoLog.Printf("err: %v", err)
+		} else {
+			s.prefillBook(userID, book, e)
+		}
+	}
+
+	s.attachFile(rw, r, userID, book, stored, name, response.Envelope{})
+}
+
+// Upload an EPUB and add it to the library. The book is created from the
+// EPUB's metadata, unless the user has a book with its ISBN, to which it is
+// attached
+func (s *Server) AddFile(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	stored, name, ok := s.saveEbook(rw, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	if stored.Format != teal.FormatEPUB {
+		s.removeEbook(stored.Checksum, stored.Format)
+		v.AddError("file", "must be an EPUB, attach other formats to an existing book")
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+	if !s.checkDuplicateFile(rw, r, userID, stored) {
+		return
+	}
+
+	e, err := s.readEPUB(stored)
+	if err != nil {
+		s.removeEbook(stored.Checksum, stored.Format)
+		v.AddError("file", err.Error())
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	var book *teal.Book
+	if e.ISBN != "" {
+		book, err = s.Books.GetByISBN(userID, e.ISBN)
+		if err != nil && err != teal.ErrDoesNotExist {
+			s.removeEbook(stored.Checksum, stored.Format)
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	if book != nil {
+		s.prefillBook(userID, book, e)
+	} else {
+		b := e.Book()
+		b.Validate(v)
+		if !v.Valid() {
+			s.removeEbook(stored.Checksum, stored.Format)
+			response.ValidationError(rw, r, v.Errors)
+			return
+		}
+
+		book, err = s.Books.Create(userID, b)
+		if err != nil {
+			s.removeEbook(stored.Checksum, stored.Format)
+			s.ErrLog.Printf("err: %v", err)
+			response.BadRequest(rw, r, err)
+			return
+		}
+		s.InfoLog.Printf("New book created: %v", book)
+		s.setEmbeddedCover(userID, book, e)
+	}
+
+	s.attachFile(rw, r, userID, book, stored, name, response.Envelope{"books": book})
+}
+
+// Download a file of a book. Range requests are supported
+func (s *Server) GetBookFile(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	fid := HandleInt64("fid", rw, r)
+	if fid == -1 {
+		return
+	}
+
+	f, err := s.Files.Get(userID, id, fid)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("File %d of book %d does not exist", fid, id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	file, err := s.Ebooks.Open(f.Checksum, f.Format)
+	if err != nil {
+		s.ErrLog.Printf("err: file %d of book %d: %v", fid, id, err)
+		response.NotFound(rw, r, teal.ErrDoesNotExist)
+		return
+	}
+	defer file.Close()
+
+	rw.Header().Set("Content-Type", ebook.ContentTypes[f.Format])
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	rw.Header().Set("ETag", fmt.Sprintf(`"%s"`, f.Checksum))
+	http.ServeContent(rw, r, f.Name, f.DateAdded, file)
+}
+
+// Detach a file from a book. The file is deleted if no other book has it
+func (s *Server) DeleteBookFile(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	fid := HandleInt64("fid", rw, r)
+	if fid == -1 {
+		return
+	}
+
+	f, err := s.Files.Get(userID, id, fid)
+	if err == nil {
+		err = s.Files.Delete(userID, id, fid)
+	}
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("File %d of book %d does not exist", fid, id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.removeEbook(f.Checksum, f.Format)
+	s.InfoLog.Printf("File %d of book %d deleted", fid, id)
+	response.OK(rw, r, nil)
+}
+
+// store the uploaded ebook. Responds with an error and returns false if the
+// upload is not an EPUB, PDF or MOBI
+func (s *Server) saveEbook(rw http.ResponseWriter, r *http.Request) (*ebook.Stored, string, bool) {
+	file, name, err := readUpload(rw, r, ebook.MaxBytes)
+	if err != nil {
+		s.InfoLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return nil, "", false
+	}
+	defer file.Close()
+
+	stored, err := s.Ebooks.Save(file)
+	if err == ebook.ErrUnsupportedFormat {
+		response.UnsupportedMediaType(rw, r, err)
+		return nil, "", false
+	}
+	if err != nil {
+		// reading a body that is too large fails while it is saved
+		s.InfoLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, fmt.Errorf("unable to read file: %v", err))
+		return nil, "", false
+	}
+	return stored, name, true
+}
+
+// respond with 409 and return false if the user already has the file
+func (s *Server) checkDuplicateFile(rw http.ResponseWriter, r *http.Request, userID int64, stored *ebook.Stored) bool {
+	f, err := s.Files.GetByChecksum(userID, stored.Checksum)
+	if err == teal.ErrDoesNotExist {
+		return true
+	}
+	if err != nil {
+		s.removeEbook(stored.Checksum, stored.Format)
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return false
+	}
+	response.Conflict(rw, r, fmt.Errorf("file is already attached to book %d", f.BookID))
+	return false
+}
+
+func (s *Server) readEPUB(stored *ebook.Stored) (*ebook.EPUB, error) {
+	f, err := s.Ebooks.Open(stored.Checksum, stored.Format)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ebook.ReadEPUB(f, stored.Size)
+}
+
+// fill the book's missing details and cover from the EPUB. Failures are only
+// logged, as the file can be attached without them
+func (s *Server) prefillBook(userID int64, book *teal.Book, e *ebook.EPUB) {
+	prefilled := *book
+	e.Enrich(&prefilled)
+	if !reflect.DeepEqual(&prefilled, book) {
+		result, err := s.Books.Update(userID, book.ID, book.Version, &prefilled)
+		if err != nil {
+			s.ErrLog.Printf("err: %v", err)
+		} else {
+			*book = *result
+		}
+	}
+	s.setEmbeddedCover(userID, book, e)
+}
+
+// set the EPUB's cover as the book's cover, if the book has none
+func (s *Server) setEmbeddedCover(userID int64, book *teal.Book, e *ebook.EPUB) {
+	if book.Cover != "" || len(e.Cover) == 0 || s.Covers == nil {
+		return
+	}
+
+	cover, err := s.Covers.Save(e.Cover)
+	if err != nil {
+		s.InfoLog.Printf("err: cover of book %d: %v", book.ID, err)
+		return
+	}
+	result, err := s.Books.SetCover(userID, book.ID, 0, cover)
+	if err != nil {
+		s.removeCover(cover)
+		s.ErrLog.Printf("err: %v", err)
+		return
+	}
+	*book = *result
+}
+
+// attach a stored file to a book and respond with it, and the given envelope
+func (s *Server) attachFile(rw http.ResponseWriter, r *http.Request, userID int64, book *teal.Book, stored *ebook.Stored, name string, env response.Envelope) {
+	f, err := s.Files.Create(userID, &teal.File{
+		BookID:   book.ID,
+		Name:     fileName(name, book, stored.Format),
+		Format:   stored.Format,
+		Size:     stored.Size,
+		Checksum: stored.Checksum,
+	})
+	if err != nil {
+		s.removeEbook(stored.Checksum, stored.Format)
+	}
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", book.ID)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err == teal.ErrDuplicateFile {
+		response.Conflict(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	env["files"] = f
+	if _, ok := env["books"]; ok {
+		env["books"] = book
+	}
+	body, err := util.ToJSON(env)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	s.InfoLog.Printf("New file attached: %v", f)
+	response.Created(rw, r, body)
+}
+
+// delete a stored file that no user has. Errors are only logged, as unused
+// files are also removed by PruneEbooks
+func (s *Server) removeEbook(checksum, format string) {
+	inUse, err := s.Files.InUse(checksum)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		return
+	}
+	if inUse {
+		return
+	}
+	if err := s.Ebooks.Delete(checksum, format); err != nil {
+		s.ErrLog.Printf("err: %v", err)
+	}
+}
+
+// Delete the ebook files of books that were purged from the trash. Returns the
+// number of files deleted
+func (s *Server) PruneEbooks() (int, error) {
+	inUse, err := s.Files.Checksums()
+	if err != nil {
+		return 0, err
+	}
+	return s.Ebooks.Prune(inUse)
+}
+
+// the name of an uploaded file without its directory, or the book's title
+func fileName(name string, book *teal.Book, format string) string {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), `\`, "/"))
+	if name == "." || name == "/" {
+		name = strings.NewReplacer("/", "-", `\`, "-").Replace(book.Title) + "." + format
+	}
+	return name
+}
@@ -0,0 +1,454 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/ebook"
+	"github.com/kencx/teal/mock"
+)
+
+const testFileOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier>urn:isbn:%s</dc:identifier>
+    <dc:title>Leviathan Wakes</dc:title>
+    <dc:creator>James S.A. Corey</dc:creator>
+    <dc:description>Humanity has colonized the solar system.</dc:description>
+  </metadata>
+  <manifest>
+    <item id="cover" href="cover.png" media-type="image/png" properties="cover-image"/>
+  </manifest>
+</package>`
+
+var testPDF = []byte("%PDF-1.7\ncontent")
+
+// an EPUB of Leviathan Wakes with the given ISBN and a PNG cover
+func testEPUBFile(t *testing.T, isbn string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	files := []struct{ name, content string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`},
+		{"content.opf", fmt.Sprintf(testFileOPF, isbn)},
+		{"cover.png", string(testPNG(t, 20, 30))},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		checkErr(t, err)
+		_, err = w.Write([]byte(f.content))
+		checkErr(t, err)
+	}
+	checkErr(t, zw.Close())
+	return buf.Bytes()
+}
+
+func testEbookStore(t *testing.T) *ebook.Store {
+	t.Helper()
+	s, err := ebook.New(t.TempDir())
+	checkErr(t, err)
+	return s
+}
+
+// a file store of the given files, which are all of user 1
+func testFileStore(files *[]*teal.File) *mock.FileStore {
+	find := func(match func(f *teal.File) bool) (*teal.File, error) {
+		for _, f := range *files {
+			if match(f) {
+				return f, nil
+			}
+		}
+		return nil, teal.ErrDoesNotExist
+	}
+
+	return &mock.FileStore{
+		GetFileFn: func(userID, bookID, id int64) (*teal.File, error) {
+			return find(func(f *teal.File) bool { return f.BookID == bookID && f.ID == id })
+		},
+		GetFileByChecksumFn: func(userID int64, checksum string) (*teal.File, error) {
+			return find(func(f *teal.File) bool { return f.Checksum == checksum })
+		},
+		CreateFileFn: func(userID int64, f *teal.File) (*teal.File, error) {
+			f.ID = int64(len(*files) + 1)
+			f.DateAdded = time.Now()
+			*files = append(*files, f)
+			return f, nil
+		},
+		DeleteFileFn: func(userID, bookID, id int64) error {
+			for i, f := range *files {
+				if f.BookID == bookID && f.ID == id {
+					*files = append((*files)[:i], (*files)[i+1:]...)
+					return nil
+				}
+			}
+			return teal.ErrDoesNotExist
+		},
+		InUseFn: func(checksum string) (bool, error) {
+			_, err := find(func(f *teal.File) bool { return f.Checksum == checksum })
+			return err == nil, nil
+		},
+	}
+}
+
+func ebookExists(s *ebook.Store, data []byte, format string) bool {
+	f, err := s.Open(checksum(data), format)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+func multipartFile(t *testing.T, name string, data []byte) ([]byte, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", name)
+	checkErr(t, err)
+	_, err = part.Write(data)
+	checkErr(t, err)
+	checkErr(t, mw.Close())
+	return body.Bytes(), mw.FormDataContentType()
+}
+
+func TestAddBookFile(t *testing.T) {
+	var files []*teal.File
+	cover := ""
+	testServer.Books = testCoverBookStore(&cover)
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = testEbookStore(t)
+
+	body, ctype := multipartFile(t, `C:\books\foobar.pdf`, testPDF)
+	tc := &testCase{
+		method:  http.MethodPost,
+		url:     "/api/books/1/files/",
+		headers: map[string]string{"Content-Type": ctype},
+		data:    body,
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.AddBookFile,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusCreated)
+
+	var env map[string]teal.File
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+	got := env["files"]
+	assertEqual(t, got.BookID, 1)
+	assertEqual(t, got.Name, "foobar.pdf")
+	assertEqual(t, got.Format, teal.FormatPDF)
+	assertEqual(t, got.Size, int64(len(testPDF)))
+	assertEqual(t, len(files), 1)
+	assertEqual(t, ebookExists(testServer.Ebooks, testPDF, teal.FormatPDF), true)
+}
+
+func TestAddBookFileEPUB(t *testing.T) {
+	var files []*teal.File
+	var updated *teal.Book
+	cover := ""
+	books := testCoverBookStore(&cover)
+	books.UpdateBookFn = func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+		updated = b
+		return b, nil
+	}
+	store, _ := testCoverStore(t)
+	testServer.Covers = store
+	testServer.Books = books
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = testEbookStore(t)
+
+	tc := &testCase{
+		method:  http.MethodPost,
+		url:     "/api/books/1/files/",
+		headers: map[string]string{"Content-Disposition": `attachment; filename="leviathan.epub"`},
+		data:    testEPUBFile(t, "9780316129084"),
+		params:  map[string]string{"id": "1"},
+		fn:      testServer.AddBookFile,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusCreated)
+	assertEqual(t, len(files), 1)
+	assertEqual(t, files[0].Name, "leviathan.epub")
+
+	// only fields that are not set are filled
+	if updated == nil {
+		t.Fatalf("book was not updated")
+	}
+	assertEqual(t, updated.Title, testBook1.Title)
+	assertEqual(t, updated.Description.String, "Humanity has colonized the solar system.")
+	if cover == "" || !coverExists(store, cover) {
+		t.Errorf("got cover %q", cover)
+	}
+}
+
+func TestAddBookFileInvalid(t *testing.T) {
+	files := []*teal.File{{ID: 1, BookID: 2, Checksum: checksum(testPDF), Format: teal.FormatPDF}}
+	cover := ""
+	testServer.Books = testCoverBookStore(&cover)
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = testEbookStore(t)
+
+	t.Run("unsupported format", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPost,
+			url:    "/api/books/1/files/",
+			data:   []byte("plain text"),
+			params: map[string]string{"id": "1"},
+			fn:     testServer.AddBookFile,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusUnsupportedMediaType, ebook.ErrUnsupportedFormat.Error())
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPost,
+			url:    "/api/books/1/files/",
+			data:   testPDF,
+			params: map[string]string{"id": "1"},
+			fn:     testServer.AddBookFile,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusConflict, "file is already attached to book 2")
+		assertEqual(t, len(files), 1)
+	})
+
+	t.Run("book does not exist", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPost,
+			url:    "/api/books/3/files/",
+			data:   testPDF,
+			params: map[string]string{"id": "3"},
+			fn:     testServer.AddBookFile,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+	})
+}
+
+func TestBookFilesRoutes(t *testing.T) {
+	for _, path := range []string{"/api/books/1/files", "/api/books/1/files/"} {
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			assertRoute(t, method, path, "/api/books/{id:[0-9]+}/files")
+		}
+	}
+	assertRoute(t, http.MethodGet, "/api/books/1/files/2/", "/api/books/{id:[0-9]+}/files/{fid:[0-9]+}")
+}
+
+func TestAddFile(t *testing.T) {
+	var files []*teal.File
+	var created *teal.Book
+	cover := ""
+	testServer.Books = &mock.BookStore{
+		GetBookByISBNFn: func(userID int64, isbn string) (*teal.Book, error) {
+			return nil, teal.ErrDoesNotExist
+		},
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
+			created = b
+			b.ID = 5
+			return b, nil
+		},
+		SetCoverFn: func(userID, id, version int64, c string) (*teal.Book, error) {
+			cover = c
+			b := *created
+			b.Cover = c
+			return &b, nil
+		},
+	}
+	store, _ := testCoverStore(t)
+	testServer.Covers = store
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = testEbookStore(t)
+
+	body, ctype := multipartFile(t, "leviathan.epub", testEPUBFile(t, "0316129089"))
+	tc := &testCase{
+		method:  http.MethodPost,
+		url:     "/api/files/",
+		headers: map[string]string{"Content-Type": ctype},
+		data:    body,
+		fn:      testServer.AddFile,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusCreated)
+
+	var env struct {
+		Books teal.Book `json:"books"`
+		Files teal.File `json:"files"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+	assertEqual(t, env.Books.ID, 5)
+	assertEqual(t, env.Books.Title, "Leviathan Wakes")
+	assertEqual(t, env.Books.ISBN, "9780316129084")
+	assertEqual(t, env.Books.Cover, cover)
+	assertEqual(t, env.Files.BookID, 5)
+	assertEqual(t, env.Files.Format, teal.FormatEPUB)
+	assertEqual(t, len(created.Author), 1)
+	assertEqual(t, created.Author[0], "James S.A. Corey")
+}
+
+func TestAddFileExistingBook(t *testing.T) {
+	var files []*teal.File
+	cover := "existing"
+	books := testCoverBookStore(&cover)
+	books.GetBookByISBNFn = func(userID int64, isbn string) (*teal.Book, error) {
+		return books.Get(userID, 1)
+	}
+	books.UpdateBookFn = func(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+		return b, nil
+	}
+	books.CreateBookFn = func(userID int64, b *teal.Book) (*teal.Book, error) {
+		t.Fatalf("book was created")
+		return nil, nil
+	}
+	testServer.Books = books
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = testEbookStore(t)
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/files/",
+		data:   testEPUBFile(t, "9780316129084"),
+		fn:     testServer.AddFile,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusCreated)
+	assertEqual(t, len(files), 1)
+	assertEqual(t, files[0].BookID, 1)
+	assertEqual(t, files[0].Name, testBook1.Title+".epub")
+	// the book's cover is kept
+	assertEqual(t, cover, "existing")
+}
+
+func TestAddFileNotEPUB(t *testing.T) {
+	var files []*teal.File
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = testEbookStore(t)
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/files/",
+		data:   testPDF,
+		fn:     testServer.AddFile,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "file", "must be an EPUB, attach other formats to an existing book")
+	assertEqual(t, ebookExists(testServer.Ebooks, testPDF, teal.FormatPDF), false)
+}
+
+func TestGetBookFile(t *testing.T) {
+	store := testEbookStore(t)
+	stored, err := store.Save(bytes.NewReader(testPDF))
+	checkErr(t, err)
+	files := []*teal.File{{
+		ID:       1,
+		BookID:   1,
+		Name:     "foo bar.pdf",
+		Format:   stored.Format,
+		Size:     stored.Size,
+		Checksum: stored.Checksum,
+	}}
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = store
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		body    []byte
+	}{
+		{"full", nil, http.StatusOK, testPDF},
+		{"range", map[string]string{"Range": "bytes=0-3"}, http.StatusPartialContent, testPDF[:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method:  http.MethodGet,
+				url:     "/api/books/1/files/1/",
+				headers: tt.headers,
+				params:  map[string]string{"id": "1", "fid": "1"},
+				fn:      testServer.GetBookFile,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertEqual(t, w.Code, tt.status)
+			assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/pdf")
+			assertEqual(t, w.HeaderMap.Get("Content-Disposition"), `attachment; filename="foo bar.pdf"`)
+			assertEqual(t, w.HeaderMap.Get("ETag"), `"`+stored.Checksum+`"`)
+
+			got, err := io.ReadAll(w.Body)
+			checkErr(t, err)
+			assertEqual(t, string(got), string(tt.body))
+		})
+	}
+
+	t.Run("does not exist", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodGet,
+			url:    "/api/books/1/files/2/",
+			params: map[string]string{"id": "1", "fid": "2"},
+			fn:     testServer.GetBookFile,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+	})
+}
+
+func TestDeleteBookFile(t *testing.T) {
+	store := testEbookStore(t)
+	stored, err := store.Save(bytes.NewReader(testPDF))
+	checkErr(t, err)
+	files := []*teal.File{
+		{ID: 1, BookID: 1, Format: stored.Format, Checksum: stored.Checksum},
+		{ID: 2, BookID: 2, Format: stored.Format, Checksum: stored.Checksum},
+	}
+	testServer.Files = testFileStore(&files)
+	testServer.Ebooks = store
+
+	deleteFile := func(bookID, id string) int {
+		tc := &testCase{
+			method: http.MethodDelete,
+			url:    "/api/books/" + bookID + "/files/" + id + "/",
+			params: map[string]string{"id": bookID, "fid": id},
+			fn:     testServer.DeleteBookFile,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		return w.Code
+	}
+
+	// the file is kept while another book has it
+	assertEqual(t, deleteFile("1", "1"), http.StatusOK)
+	assertEqual(t, ebookExists(store, testPDF, teal.FormatPDF), true)
+
+	assertEqual(t, deleteFile("2", "2"), http.StatusOK)
+	assertEqual(t, ebookExists(store, testPDF, teal.FormatPDF), false)
+
+	assertEqual(t, deleteFile("2", "2"), http.StatusNotFound)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
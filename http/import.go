@@ -0,0 +1,102 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/importer"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+// maximum size of uploaded exports. Calibre databases of large libraries are
+// tens of MB
+const maxImportBytes = 64 << 20
+
+// uploaded files larger than this are buffered on disk
+const maxUploadMemory = 32 << 20
+
+// Import a Goodreads library export into the user's library. With dry_run,
+// the report of what would be imported is returned and no books are created
+func (s *Server) ImportGoodreads(rw http.ResponseWriter, r *http.Request) {
+	s.importBooks(rw, r, "Goodreads", importer.ReadGoodreads)
+}
+
+// Import the metadata.db of a Calibre library into the user's library. With
+// dry_run, the report of what would be imported is returned and no books are
+// created
+func (s *Server) ImportCalibre(rw http.ResponseWriter, r *http.Request) {
+	s.importBooks(rw, r, "Calibre", importer.ReadCalibreDB)
+}
+
+func (s *Server) importBooks(rw http.ResponseWriter, r *http.Request, format string, read func(io.Reader) ([]*importer.Record, error)) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	dryRun := readBool(r, "dry_run", v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	file, _, err := readUpload(rw, r, maxImportBytes)
+	if err != nil {
+		s.InfoLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+	defer file.Close()
+
+	records, err := read(file)
+	if err != nil {
+		s.InfoLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	report, err := importer.Import(s.Books, userID, records, dryRun)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"import": report})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%s import (dry run: %t): %d new, %d duplicates, %d invalid, %d failed",
+		format, dryRun, report.New, report.Duplicates, report.Invalid, report.Failed)
+	response.OK(rw, r, res)
+}
+
+// Read an uploaded file of at most maxBytes, either as the file field of a
+// multipart form or as the request body. The file name is taken from the form
+// or the Content-Disposition header, if given
+func readUpload(rw http.ResponseWriter, r *http.Request, maxBytes int64) (io.ReadCloser, string, error) {
+	r.Body = http.MaxBytesReader(rw, r.Body, maxBytes)
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt != "multipart/form-data" {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		return r.Body, params["filename"], nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, "", fmt.Errorf("unable to read form: %v", err)
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read file: %v", err)
+	}
+	return file, header.Filename, nil
+}
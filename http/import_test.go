@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/importer"
+	"github.com/kencx/teal/mock"
+)
+
+const testGoodreadsCSV = `Book Id,Title,Author,Additional Authors,ISBN,ISBN13,My Rating,Number of Pages,Date Read,Date Added,Exclusive Shelf
+1,Leviathan Wakes,James S.A. Corey,,"=""0316129089""","=""9780316129084""",5,561,2019/05/24,2019/01/02,read
+2,Red Rising,Pierce Brown,,"=""""","=""9780345539786""",0,382,,2020/03/04,to-read
+3,No ISBN,John Doe,,"=""""","=""""",3,100,,2021/07/08,read
+`
+
+func testImportStore(created *int) *mock.BookStore {
+	return &mock.BookStore{
+		GetBookByISBNFn: func(userID int64, isbn string) (*teal.Book, error) {
+			if isbn == "9780345539786" {
+				return &teal.Book{ID: 2, ISBN: isbn}, nil
+			}
+			return nil, teal.ErrDoesNotExist
+		},
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
+			*created++
+			b.ID = 10
+			return b, nil
+		},
+	}
+}
+
+func decodeImportReport(t *testing.T, body *bytes.Buffer) *importer.Report {
+	t.Helper()
+
+	var env map[string]*importer.Report
+	err := json.NewDecoder(body).Decode(&env)
+	checkErr(t, err)
+	return env["import"]
+}
+
+func TestImportGoodreads(t *testing.T) {
+	var created int
+	testServer.Books = testImportStore(&created)
+
+	tc := &testCase{
+		method:  http.MethodPost,
+		url:     "/api/import/goodreads/",
+		headers: map[string]string{"Content-Type": "text/csv"},
+		data:    []byte(testGoodreadsCSV),
+		fn:      testServer.ImportGoodreads,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+
+	got := decodeImportReport(t, w.Body)
+	assertEqual(t, got.DryRun, false)
+	assertEqual(t, got.New, 1)
+	assertEqual(t, got.Duplicates, 1)
+	assertEqual(t, got.Invalid, 1)
+	assertEqual(t, created, 1)
+	assertEqual(t, got.Results[0].ID, 10)
+	assertEqual(t, got.Results[2].Errors["isbn"], "value is missing")
+}
+
+func TestImportGoodreadsDryRun(t *testing.T) {
+	var created int
+	testServer.Books = testImportStore(&created)
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/import/goodreads/?dry_run=true",
+		data:   []byte(testGoodreadsCSV),
+		fn:     testServer.ImportGoodreads,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+
+	got := decodeImportReport(t, w.Body)
+	assertEqual(t, got.DryRun, true)
+	assertEqual(t, got.New, 1)
+	assertEqual(t, created, 0)
+}
+
+func TestImportGoodreadsMultipart(t *testing.T) {
+	var created int
+	testServer.Books = testImportStore(&created)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "goodreads_library_export.csv")
+	checkErr(t, err)
+	_, err = fw.Write([]byte(testGoodreadsCSV))
+	checkErr(t, err)
+	checkErr(t, mw.Close())
+
+	tc := &testCase{
+		method:  http.MethodPost,
+		url:     "/api/import/goodreads/",
+		headers: map[string]string{"Content-Type": mw.FormDataContentType()},
+		data:    body.Bytes(),
+		fn:      testServer.ImportGoodreads,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+
+	got := decodeImportReport(t, w.Body)
+	assertEqual(t, got.New, 1)
+	assertEqual(t, created, 1)
+}
+
+func TestImportGoodreadsInvalid(t *testing.T) {
+	testServer.Books = &mock.BookStore{}
+
+	tests := []struct {
+		name string
+		url  string
+		data string
+		code int
+	}{
+		{name: "dry run", url: "/api/import/goodreads/?dry_run=foo", data: testGoodreadsCSV, code: http.StatusUnprocessableEntity},
+		{name: "empty", url: "/api/import/goodreads/", data: "", code: http.StatusBadRequest},
+		{name: "missing columns", url: "/api/import/goodreads/", data: "Title\nfoo\n", code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodPost,
+				url:    tt.url,
+				data:   []byte(tt.data),
+				fn:     testServer.ImportGoodreads,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertEqual(t, w.Code, tt.code)
+		})
+	}
+}
+
+func TestImportCalibreInvalid(t *testing.T) {
+	testServer.Books = &mock.BookStore{}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/import/calibre/",
+		data:   []byte("not a database"),
+		fn:     testServer.ImportCalibre,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusBadRequest)
+}
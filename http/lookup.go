@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+var errLookupFailed = errors.New("metadata providers are unavailable")
+
+type MetadataLookup interface {
+	teal.MetadataService
+}
+
+// Look up the metadata of an ISBN. The response is a book prefilled with the
+// metadata, its cover URL and the provider
+func (s *Server) Lookup(rw http.ResponseWriter, r *http.Request) {
+
+	isbn := r.URL.Query().Get("isbn")
+	v := validator.New()
+	v.Check(isbn != "", "isbn", "value is missing")
+	if _, err := teal.ParseISBN(isbn); err != nil {
+		v.AddError("isbn", err.Error())
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	m, err := s.Metadata.Lookup(isbn)
+	if err == teal.ErrDoesNotExist {
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadGateway(rw, r, errLookupFailed)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{
+		"books":     m.Book(),
+		"cover_url": m.CoverURL,
+		"provider":  m.Provider,
+	})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	response.OK(rw, r, res)
+}
+
+// Fill the fields of b that are not set with its metadata. Books are created
+// without metadata if the lookup fails, and invalid ISBNs are left to
+// validation
+func (s *Server) enrichBook(b *teal.Book) {
+	if _, err := teal.ParseISBN(b.ISBN); s.Metadata == nil || err != nil {
+		return
+	}
+
+	m, err := s.Metadata.Lookup(b.ISBN)
+	if err != nil {
+		if err != teal.ErrDoesNotExist {
+			s.ErrLog.Printf("err: %v", err)
+		}
+		return
+	}
+	m.Enrich(b)
+}
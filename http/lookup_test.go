@@ -0,0 +1,178 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+)
+
+var testMetadata = &teal.Metadata{
+	ISBN:        "9780316129084",
+	Provider:    "openlibrary",
+	Title:       "Leviathan Wakes",
+	Author:      []string{"James S.A. Corey"},
+	Description: "Humanity has colonized the solar system.",
+	NumOfPages:  561,
+	CoverURL:    "https://covers.openlibrary.org/b/id/6621086-L.jpg",
+}
+
+func testMetadataLookup(lookups *int) *mock.MetadataLookup {
+	return &mock.MetadataLookup{
+		LookupFn: func(isbn string) (*teal.Metadata, error) {
+			*lookups++
+			switch teal.NormalizeISBN(isbn) {
+			case testMetadata.ISBN:
+				return testMetadata, nil
+			case "9780441013593":
+				return nil, errors.New("openlibrary: unexpected status 503")
+			default:
+				return nil, teal.ErrDoesNotExist
+			}
+		},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var lookups int
+	testServer.Metadata = testMetadataLookup(&lookups)
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/lookup/?isbn=0-316-12908-9",
+		fn:     testServer.Lookup,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+
+	var env struct {
+		Book     *teal.Book `json:"books"`
+		CoverURL string     `json:"cover_url"`
+		Provider string     `json:"provider"`
+	}
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	assertEqual(t, env.Book.Title, testMetadata.Title)
+	assertEqual(t, env.Book.ISBN, testMetadata.ISBN)
+	assertEqual(t, env.Book.Author[0], testMetadata.Author[0])
+	assertEqual(t, env.Book.Description.String, testMetadata.Description)
+	assertEqual(t, env.Book.NumOfPages, testMetadata.NumOfPages)
+	assertEqual(t, env.CoverURL, testMetadata.CoverURL)
+	assertEqual(t, env.Provider, "openlibrary")
+}
+
+func TestLookupFail(t *testing.T) {
+	var lookups int
+	testServer.Metadata = testMetadataLookup(&lookups)
+
+	tests := []struct {
+		name string
+		url  string
+		code int
+	}{
+		{name: "no isbn", url: "/api/lookup/", code: http.StatusUnprocessableEntity},
+		{name: "invalid isbn", url: "/api/lookup/?isbn=9780316129085", code: http.StatusUnprocessableEntity},
+		{name: "not found", url: "/api/lookup/?isbn=9780345539786", code: http.StatusNotFound},
+		{name: "provider error", url: "/api/lookup/?isbn=9780441013593", code: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodGet,
+				url:    tt.url,
+				fn:     testServer.Lookup,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertEqual(t, w.Code, tt.code)
+		})
+	}
+	assertEqual(t, lookups, 2)
+}
+
+func TestAddBookEnrich(t *testing.T) {
+	var lookups int
+	var created *teal.Book
+	testServer.Metadata = testMetadataLookup(&lookups)
+	testServer.Books = &mock.BookStore{
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
+			created = b
+			return b, nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		data    string
+		code    int
+		lookups int
+		title   string
+		pages   int
+	}{
+		{
+			name:    "enrich",
+			url:     "/api/books/?enrich=true",
+			data:    `{"isbn": "9780316129084", "title": "The Expanse 1"}`,
+			code:    http.StatusCreated,
+			lookups: 1,
+			title:   "The Expanse 1",
+			pages:   561,
+		}, {
+			name:    "without enrich",
+			url:     "/api/books/",
+			data:    `{"isbn": "9780316129084"}`,
+			code:    http.StatusUnprocessableEntity,
+			lookups: 0,
+		}, {
+			name:    "provider error",
+			url:     "/api/books/?enrich=true",
+			data:    `{"isbn": "9780441013593", "title": "Dune", "author": ["Frank Herbert"]}`,
+			code:    http.StatusCreated,
+			lookups: 1,
+			title:   "Dune",
+		}, {
+			name:    "invalid isbn",
+			url:     "/api/books/?enrich=true",
+			data:    `{"isbn": "123"}`,
+			code:    http.StatusUnprocessableEntity,
+			lookups: 0,
+		}, {
+			name:    "invalid enrich",
+			url:     "/api/books/?enrich=foo",
+			data:    `{"isbn": "9780316129084"}`,
+			code:    http.StatusUnprocessableEntity,
+			lookups: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups, created = 0, nil
+
+			tc := &testCase{
+				method: http.MethodPost,
+				url:    tt.url,
+				data:   []byte(tt.data),
+				fn:     testServer.AddBook,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertEqual(t, w.Code, tt.code)
+			assertEqual(t, lookups, tt.lookups)
+
+			if tt.code == http.StatusCreated {
+				// fields in the payload are kept
+				assertEqual(t, created.Title, tt.title)
+				assertEqual(t, created.NumOfPages, tt.pages)
+				assertEqual(t, len(created.Author), 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/covers"
+	"github.com/kencx/teal/ebook"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/opds"
+	"github.com/kencx/teal/validator"
+)
+
+const (
+	opdsRoot       = "/opds/"
+	opdsOpenSearch = "/opds/search.xml"
+)
+
+// The root of the OPDS catalog, which links to the other feeds
+func (s *Server) OPDSRoot(rw http.ResponseWriter, r *http.Request) {
+	f := s.newOPDSFeed(r, "urn:teal:opds", "Teal", opds.NavigationType)
+	f.Entries = []opds.Entry{
+		opdsNavEntry("urn:teal:opds:recent", "Recently added", "/opds/recent/", opds.RelNew, opds.AcquisitionType),
+		opdsNavEntry("urn:teal:opds:authors", "By author", "/opds/authors/", opds.RelSubsection, opds.NavigationType),
+		opdsNavEntry("urn:teal:opds:states", "By state", "/opds/states/", opds.RelSubsection, opds.NavigationType),
+	}
+	s.writeOPDS(rw, r, f, opds.NavigationType)
+}
+
+// Books in the order they were added, newest first
+func (s *Server) OPDSRecent(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	f := s.newOPDSFeed(r, "urn:teal:opds:recent", "Recently added", opds.AcquisitionType)
+	s.opdsBooks(rw, r, userID, f, &teal.BookFilter{}, "-dateAdded")
+}
+
+func (s *Server) OPDSAuthors(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	p := &teal.AuthorFilter{Page: readPage(r, v)}
+	p.Sort = "name"
+	p.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	f := s.newOPDSFeed(r, "urn:teal:opds:authors", "By author", opds.NavigationType)
+	authors, info, err := s.Authors.GetAll(userID, p)
+	if err != nil && err != teal.ErrNoRows {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	for _, a := range authors {
+		f.Entries = append(f.Entries, opdsNavEntry(
+			fmt.Sprintf("urn:teal:opds:authors:%d", a.ID),
+			a.Name,
+			fmt.Sprintf("/opds/authors/%d/", a.ID),
+			opds.RelSubsection,
+			opds.AcquisitionType,
+		))
+	}
+	opdsPage(f, r, p.Page, info, opds.NavigationType)
+	s.writeOPDS(rw, r, f, opds.NavigationType)
+}
+
+// Books of an author, by title
+func (s *Server) OPDSAuthorBooks(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	a, err := s.Authors.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Author %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	f := s.newOPDSFeed(r, fmt.Sprintf("urn:teal:opds:authors:%d", id), a.Name, opds.AcquisitionType)
+	s.opdsBooks(rw, r, userID, f, &teal.BookFilter{Author: a.Name}, "title")
+}
+
+func (s *Server) OPDSStates(rw http.ResponseWriter, r *http.Request) {
+	f := s.newOPDSFeed(r, "urn:teal:opds:states", "By state", opds.NavigationType)
+	for _, state := range teal.States {
+		f.Entries = append(f.Entries, opdsNavEntry(
+			"urn:teal:opds:states:"+state,
+			stateTitle(state),
+			"/opds/states/"+state+"/",
+			opds.RelSubsection,
+			opds.AcquisitionType,
+		))
+	}
+	s.writeOPDS(rw, r, f, opds.NavigationType)
+}
+
+// Books in a state, by title
+func (s *Server) OPDSStateBooks(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	state := mux.Vars(r)["state"]
+	if !teal.IsValidState(state) {
+		response.NotFound(rw, r, teal.ErrDoesNotExist)
+		return
+	}
+
+	f := s.newOPDSFeed(r, "urn:teal:opds:states:"+state, stateTitle(state), opds.AcquisitionType)
+	s.opdsBooks(rw, r, userID, f, &teal.BookFilter{State: state}, "title")
+}
+
+// Full-text search of books, see SearchBooks
+func (s *Server) OPDSSearch(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	v := validator.New()
+	v.Check(q != "", "q", "value is missing")
+	filter := &teal.BookFilter{Page: readPage(r, v), Query: q}
+	if v.Valid() {
+		filter.Validate(v)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	f := s.newOPDSFeed(r, "urn:teal:opds:search", fmt.Sprintf("Search: %s", q), opds.AcquisitionType)
+	results, info, err := s.Books.Search(userID, filter)
+	if err != nil && err != teal.ErrNoRows {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	books := make([]*teal.Book, len(results))
+	for i, res := range results {
+		books[i] = res.Book
+	}
+	if !s.opdsEntries(rw, r, userID, f, books) {
+		return
+	}
+
+	opdsPage(f, r, filter.Page, info, opds.AcquisitionType)
+	s.writeOPDS(rw, r, f, opds.AcquisitionType)
+}
+
+// The OpenSearch description of OPDSSearch
+func (s *Server) OPDSOpenSearch(rw http.ResponseWriter, r *http.Request) {
+	d := opds.NewOpenSearchDescription("Teal", "Search books by title, description and author", "/opds/search/?q={searchTerms}")
+	body, err := d.Marshal()
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	rw.Header().Set("Content-Type", opds.OpenSearchType)
+	rw.Write(body)
+}
+
+// a feed linking to itself, the catalog's root and search
+func (s *Server) newOPDSFeed(r *http.Request, id, title, kind string) *opds.Feed {
+	f := opds.NewFeed(id, title)
+	f.Links = []opds.Link{
+		{Rel: opds.RelSelf, Href: r.URL.RequestURI(), Type: kind},
+		{Rel: opds.RelStart, Href: opdsRoot, Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: opdsOpenSearch, Type: opds.OpenSearchType},
+	}
+	if r.URL.Path != opdsRoot {
+		f.Links = append(f.Links, opds.Link{Rel: opds.RelUp, Href: opdsRoot, Type: opds.NavigationType})
+	}
+	return f
+}
+
+// respond with a page of the user's books, sorted by the given field unless the
+// request has a sort
+func (s *Server) opdsBooks(rw http.ResponseWriter, r *http.Request, userID int64, f *opds.Feed, filter *teal.BookFilter, sort string) {
+	v := validator.New()
+	filter.Page = readPage(r, v)
+	if filter.Sort == "" {
+		filter.Sort = sort
+	}
+	if v.Valid() {
+		filter.Validate(v)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	books, info, err := s.Books.GetAll(userID, filter)
+	if err != nil && err != teal.ErrNoRows {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	if !s.opdsEntries(rw, r, userID, f, books) {
+		return
+	}
+
+	opdsPage(f, r, filter.Page, info, opds.AcquisitionType)
+	s.writeOPDS(rw, r, f, opds.AcquisitionType)
+}
+
+// add an entry of each book to the feed, with acquisition links of its files.
+// Responds with an error and returns false if the files cannot be retrieved
+func (s *Server) opdsEntries(rw http.ResponseWriter, r *http.Request, userID int64, f *opds.Feed, books []*teal.Book) bool {
+	ids := make([]int64, len(books))
+	for i, b := range books {
+		ids[i] = b.ID
+	}
+	files, err := s.Files.GetByBooks(userID, ids)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return false
+	}
+
+	for _, b := range books {
+		f.Entries = append(f.Entries, opdsBookEntry(b, files[b.ID]))
+	}
+	return true
+}
+
+func opdsBookEntry(b *teal.Book, files []*teal.File) opds.Entry {
+	e := opds.Entry{
+		ID:      fmt.Sprintf("urn:teal:book:%d", b.ID),
+		Title:   b.Title,
+		Updated: bookUpdated(b),
+	}
+	for _, a := range b.Author {
+		e.Authors = append(e.Authors, opds.Author{Name: a})
+	}
+	if b.ISBN != "" {
+		e.Identifier = "urn:isbn:" + b.ISBN
+	}
+	for _, c := range b.Categories {
+		e.Categories = append(e.Categories, opds.Category{Term: c, Label: c})
+	}
+	if b.Description.Valid && b.Description.String != "" {
+		e.Content = &opds.Content{Type: "text", Value: b.Description.String}
+	}
+
+	if b.Cover != "" {
+		cover := fmt.Sprintf("/api/books/%d/cover/", b.ID)
+		e.Links = append(e.Links,
+			opds.Link{Rel: opds.RelImage, Href: fmt.Sprintf("%s?v=%s", cover, b.Cover)},
+			opds.Link{Rel: opds.RelThumbnail, Href: fmt.Sprintf("%s?size=%s&v=%s", cover, covers.Medium, b.Cover), Type: "image/jpeg"},
+		)
+	}
+	for _, file := range files {
+		e.Links = append(e.Links, opds.Link{
+			Rel:   opds.RelAcquisition,
+			Href:  fmt.Sprintf("/api/books/%d/files/%d/", b.ID, file.ID),
+			Type:  ebook.ContentTypes[file.Format],
+			Title: file.Name,
+		})
+	}
+	return e
+}
+
+func opdsNavEntry(id, title, href, rel, kind string) opds.Entry {
+	return opds.Entry{
+		ID:      id,
+		Title:   title,
+		Updated: time.Now().UTC(),
+		Links:   []opds.Link{{Rel: rel, Href: href, Type: kind}},
+	}
+}
+
+// add the OpenSearch counts and the link to the page after p
+func opdsPage(f *opds.Feed, r *http.Request, p teal.Page, info teal.PageInfo, kind string) {
+	f.TotalResults = info.Total
+	f.ItemsPerPage = p.Limit
+
+	l := pageLinks(r, info)
+	if l.Next != "" {
+		f.Links = append(f.Links, opds.Link{Rel: opds.RelNext, Href: l.Next, Type: kind})
+	}
+}
+
+func (s *Server) writeOPDS(rw http.ResponseWriter, r *http.Request, f *opds.Feed, kind string) {
+	body, err := f.Marshal()
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	rw.Header().Set("Content-Type", kind)
+	rw.Write(body)
+}
+
+func bookUpdated(b *teal.Book) time.Time {
+	if b.DateUpdated.Valid {
+		return b.DateUpdated.Time.UTC()
+	}
+	if b.DateAdded.Valid {
+		return b.DateAdded.Time.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// want-to-read is shown as Want to read
+func stateTitle(state string) string {
+	title := strings.ReplaceAll(state, "-", " ")
+	return strings.ToUpper(title[:1]) + title[1:]
+}
@@ -0,0 +1,299 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+	"github.com/kencx/teal/opds"
+)
+
+// the parts of a feed that tests check
+type testFeed struct {
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	TotalResults int         `xml:"totalResults"`
+	Links        []opds.Link `xml:"link"`
+	Entries      []struct {
+		ID      string      `xml:"id"`
+		Title   string      `xml:"title"`
+		Authors []string    `xml:"author>name"`
+		Content string      `xml:"content"`
+		Links   []opds.Link `xml:"link"`
+	} `xml:"entry"`
+}
+
+func readTestFeed(t *testing.T, tc *testCase, kind string) *testFeed {
+	t.Helper()
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), kind)
+
+	var f testFeed
+	checkErr(t, xml.NewDecoder(w.Body).Decode(&f))
+	return &f
+}
+
+func findLink(links []opds.Link, rel string) *opds.Link {
+	for _, l := range links {
+		if l.Rel == rel {
+			return &l
+		}
+	}
+	return nil
+}
+
+// a book store of testBook1 as book 1 with a cover, which records the filter
+// of listings
+func testOPDSBookStore(got **teal.BookFilter) *mock.BookStore {
+	return &mock.BookStore{
+		GetAllBooksFn: func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+			*got = f
+			b := *testBook1
+			b.ID = 1
+			b.Cover = "abc"
+			b.Description = teal.NullString{NullString: sql.NullString{String: "A description", Valid: true}}
+			return []*teal.Book{&b}, teal.PageInfo{Total: 3, Next: &teal.Cursor{Sort: f.Sort, ID: 1}}, nil
+		},
+	}
+}
+
+func testOPDSFileStore() *mock.FileStore {
+	return &mock.FileStore{
+		GetFilesByBooksFn: func(userID int64, bookIDs []int64) (map[int64][]*teal.File, error) {
+			return map[int64][]*teal.File{
+				1: {{ID: 2, BookID: 1, Name: "foobar.epub", Format: teal.FormatEPUB}},
+			}, nil
+		},
+	}
+}
+
+func TestOPDSRoutes(t *testing.T) {
+	s := NewServer()
+	for _, path := range []string{
+		"/opds/",
+		"/opds/recent/",
+		"/opds/authors/",
+		"/opds/authors/1/",
+		"/opds/states/",
+		"/opds/states/read/",
+		"/opds/search/",
+		"/opds/search.xml",
+	} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		checkErr(t, err)
+		var match mux.RouteMatch
+		if !s.Router.Match(req, &match) || match.MatchErr != nil {
+			t.Errorf("no route matches %s", path)
+		}
+	}
+}
+
+func TestOPDSRoot(t *testing.T) {
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/",
+		fn:     testServer.OPDSRoot,
+	}
+	f := readTestFeed(t, tc, opds.NavigationType)
+	assertEqual(t, len(f.Entries), 3)
+	assertEqual(t, f.Entries[0].Links[0].Href, "/opds/recent/")
+	assertEqual(t, f.Entries[0].Links[0].Type, opds.AcquisitionType)
+	assertEqual(t, findLink(f.Links, opds.RelSearch).Href, "/opds/search.xml")
+	// the root has no parent
+	if findLink(f.Links, opds.RelUp) != nil {
+		t.Errorf("got up link")
+	}
+}
+
+func TestOPDSRecent(t *testing.T) {
+	var got *teal.BookFilter
+	testServer.Books = testOPDSBookStore(&got)
+	testServer.Files = testOPDSFileStore()
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/recent/?limit=1",
+		fn:     testServer.OPDSRecent,
+	}
+	f := readTestFeed(t, tc, opds.AcquisitionType)
+	assertEqual(t, got.Sort, "-dateAdded")
+	assertEqual(t, got.Limit, 1)
+	assertEqual(t, f.TotalResults, 3)
+	assertEqual(t, findLink(f.Links, opds.RelSelf).Href, "/opds/recent/?limit=1")
+	next := findLink(f.Links, opds.RelNext)
+	if next == nil {
+		t.Fatalf("got no next link")
+	}
+
+	assertEqual(t, len(f.Entries), 1)
+	e := f.Entries[0]
+	assertEqual(t, e.ID, "urn:teal:book:1")
+	assertEqual(t, e.Title, testBook1.Title)
+	assertEqual(t, e.Authors[0], "John Doe")
+	assertEqual(t, e.Content, "A description")
+
+	acq := findLink(e.Links, opds.RelAcquisition)
+	if acq == nil {
+		t.Fatalf("got no acquisition link")
+	}
+	assertEqual(t, acq.Href, "/api/books/1/files/2/")
+	assertEqual(t, acq.Type, "application/epub+zip")
+	assertEqual(t, findLink(e.Links, opds.RelThumbnail).Href, "/api/books/1/cover/?size=medium&v=abc")
+
+	// the next page continues after the last book in the same sort
+	tc.url = next.Href
+	readTestFeed(t, tc, opds.AcquisitionType)
+	assertEqual(t, got.Sort, "-dateAdded")
+	assertEqual(t, got.After.ID, int64(1))
+}
+
+func TestOPDSAuthors(t *testing.T) {
+	testServer.Authors = &mock.AuthorStore{
+		GetAllAuthorsFn: func(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error) {
+			assertEqual(t, f.Sort, "name")
+			return []*teal.Author{{ID: 4, Name: "John Doe"}}, teal.PageInfo{Total: 1}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/authors/",
+		fn:     testServer.OPDSAuthors,
+	}
+	f := readTestFeed(t, tc, opds.NavigationType)
+	assertEqual(t, len(f.Entries), 1)
+	assertEqual(t, f.Entries[0].Title, "John Doe")
+	assertEqual(t, f.Entries[0].Links[0].Href, "/opds/authors/4/")
+}
+
+func TestOPDSAuthorBooks(t *testing.T) {
+	var got *teal.BookFilter
+	testServer.Books = testOPDSBookStore(&got)
+	testServer.Files = testOPDSFileStore()
+	testServer.Authors = &mock.AuthorStore{
+		GetAuthorFn: func(userID, id int64) (*teal.Author, error) {
+			if id != 4 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return &teal.Author{ID: 4, Name: "John Doe"}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/authors/4/",
+		params: map[string]string{"id": "4"},
+		fn:     testServer.OPDSAuthorBooks,
+	}
+	f := readTestFeed(t, tc, opds.AcquisitionType)
+	assertEqual(t, f.Title, "John Doe")
+	assertEqual(t, got.Author, "John Doe")
+	assertEqual(t, got.Sort, "title")
+	assertEqual(t, len(f.Entries), 1)
+
+	tc = &testCase{
+		method: http.MethodGet,
+		url:    "/opds/authors/5/",
+		params: map[string]string{"id": "5"},
+		fn:     testServer.OPDSAuthorBooks,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestOPDSStates(t *testing.T) {
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/states/",
+		fn:     testServer.OPDSStates,
+	}
+	f := readTestFeed(t, tc, opds.NavigationType)
+	assertEqual(t, len(f.Entries), len(teal.States))
+	assertEqual(t, f.Entries[0].Title, "Want to read")
+	assertEqual(t, f.Entries[0].Links[0].Href, "/opds/states/want-to-read/")
+}
+
+func TestOPDSStateBooks(t *testing.T) {
+	var got *teal.BookFilter
+	testServer.Books = testOPDSBookStore(&got)
+	testServer.Files = testOPDSFileStore()
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/states/reading/",
+		params: map[string]string{"state": "reading"},
+		fn:     testServer.OPDSStateBooks,
+	}
+	f := readTestFeed(t, tc, opds.AcquisitionType)
+	assertEqual(t, f.Title, "Reading")
+	assertEqual(t, got.State, teal.StateReading)
+
+	tc = &testCase{
+		method: http.MethodGet,
+		url:    "/opds/states/unknown/",
+		params: map[string]string{"state": "unknown"},
+		fn:     testServer.OPDSStateBooks,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestOPDSSearch(t *testing.T) {
+	var gotFilter *teal.BookFilter
+	testServer.Books = &mock.BookStore{
+		SearchFn: func(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error) {
+			gotFilter = f
+			if f.Query == "nothing" {
+				return nil, teal.PageInfo{}, teal.ErrNoRows
+			}
+			b := *testBook1
+			b.ID = 1
+			return []*teal.SearchResult{{Book: &b}}, teal.PageInfo{Total: 1}, nil
+		},
+	}
+	testServer.Files = testOPDSFileStore()
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/search/?q=foo",
+		fn:     testServer.OPDSSearch,
+	}
+	f := readTestFeed(t, tc, opds.AcquisitionType)
+	assertEqual(t, gotFilter.Query, "foo")
+	assertEqual(t, len(f.Entries), 1)
+	assertEqual(t, findLink(f.Entries[0].Links, opds.RelAcquisition).Href, "/api/books/1/files/2/")
+
+	// no matches is an empty feed
+	tc.url = "/opds/search/?q=nothing"
+	f = readTestFeed(t, tc, opds.AcquisitionType)
+	assertEqual(t, len(f.Entries), 0)
+
+	tc.url = "/opds/search/"
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "q", "value is missing")
+}
+
+func TestOPDSOpenSearch(t *testing.T) {
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/opds/search.xml",
+		fn:     testServer.OPDSOpenSearch,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), opds.OpenSearchType)
+
+	var d opds.OpenSearchDescription
+	checkErr(t, xml.NewDecoder(w.Body).Decode(&d))
+	assertEqual(t, d.URL.Template, "/opds/search/?q={searchTerms}")
+}
@@ -6,9 +6,22 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/kencx/teal"
+	tcontext "github.com/kencx/teal/context"
 	"github.com/kencx/teal/http/response"
 )
 
+// Retrieve the id of the authenticated user. Books and their reading history
+// are scoped to this user
+func HandleUserID(rw http.ResponseWriter, r *http.Request) int64 {
+	user, err := tcontext.GetUser(r.Context())
+	if err != nil || user == nil {
+		response.Unauthorized(rw, r, teal.ErrNoAuthHeader)
+		return -1
+	}
+	return user.ID
+}
+
 func HandleInt64(key string, rw http.ResponseWriter, r *http.Request) int64 {
 	vars := mux.Vars(r)
 
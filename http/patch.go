@@ -0,0 +1,23 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kencx/teal/http/request"
+	"github.com/kencx/teal/http/response"
+)
+
+// Respond to a patch that could not be applied
+func (s *Server) patchError(rw http.ResponseWriter, r *http.Request, err error) {
+	s.InfoLog.Printf("patch failed: %v", err)
+
+	switch {
+	case errors.Is(err, request.ErrUnsupportedPatch):
+		response.UnsupportedMediaType(rw, r, err)
+	case errors.Is(err, request.ErrPatchTestFailed):
+		response.Conflict(rw, r, err)
+	default:
+		response.BadRequest(rw, r, err)
+	}
+}
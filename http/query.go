@@ -0,0 +1,169 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/validator"
+)
+
+const dateFormat = "2006-01-02"
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Links to the next page of a listing
+type links struct {
+	Next string `json:"next,omitempty"`
+}
+
+// Read limit, cursor and sort query parameters. Parameters that fail to parse
+// are added to v
+func readPage(r *http.Request, v *validator.Validator) teal.Page {
+	q := r.URL.Query()
+	p := teal.Page{
+		Limit: teal.DefaultPageSize,
+		Sort:  q.Get("sort"),
+	}
+
+	if limit := readInt(r, "limit", v); limit != nil {
+		p.Limit = *limit
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			v.AddError("cursor", err.Error())
+		}
+		p.After = c
+	}
+	return p
+}
+
+// Read the query parameters of a book listing
+func readBookFilter(r *http.Request, v *validator.Validator) *teal.BookFilter {
+	q := r.URL.Query()
+	return &teal.BookFilter{
+		Page:     readPage(r, v),
+		Query:    q.Get("q"),
+		Author:   q.Get("author"),
+		Role:     q.Get("role"),
+		Category: q.Get("category"),
+		Tags:     q["tag"],
+		MatchAll: q.Get("match") == "all",
+		State:    q.Get("state"),
+
+		MinRating: readInt(r, "min_rating", v),
+		MaxRating: readInt(r, "max_rating", v),
+		MinPages:  readInt(r, "min_pages", v),
+		MaxPages:  readInt(r, "max_pages", v),
+
+		AddedAfter:      readDate(r, "added_after", v),
+		AddedBefore:     readDate(r, "added_before", v),
+		CompletedAfter:  readDate(r, "completed_after", v),
+		CompletedBefore: readDate(r, "completed_before", v),
+	}
+}
+
+// Read an integer query parameter. Returns nil if it is not given
+func readInt(r *http.Request, key string, v *validator.Validator) *int {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer")
+		return nil
+	}
+	return &i
+}
+
+// Read a number query parameter. Returns nil if it is not given
+func readFloat(r *http.Request, key string, v *validator.Validator) *float64 {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return nil
+	}
+	return &f
+}
+
+// Read a boolean query parameter. Returns false if it is not given
+func readBool(r *http.Request, key string, v *validator.Validator) bool {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be true or false")
+		return false
+	}
+	return b
+}
+
+// Read a YYYY-MM-DD query parameter. Returns the zero time if it is not given
+func readDate(r *http.Request, key string, v *validator.Validator) time.Time {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+
+	d, err := time.Parse(dateFormat, s)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY-MM-DD format")
+		return time.Time{}
+	}
+	return d
+}
+
+// Link to the page after the listing's current page, keeping all other query
+// parameters
+func pageLinks(r *http.Request, info teal.PageInfo) links {
+	var l links
+	if info.Next != nil {
+		q := r.URL.Query()
+		q.Set("cursor", encodeCursor(info.Next))
+		next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+		l.Next = next.String()
+	}
+	return l
+}
+
+// cursor in a URL. Cursors are opaque to clients and may change format
+type cursor struct {
+	Sort  string  `json:"s,omitempty"`
+	Value *string `json:"v"`
+	ID    int64   `json:"id"`
+}
+
+func encodeCursor(c *teal.Cursor) string {
+	b, _ := json.Marshal(cursor{Sort: c.Sort, Value: c.Value, ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*teal.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	return &teal.Cursor{Sort: c.Sort, Value: c.Value, ID: c.ID}, nil
+}
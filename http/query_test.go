@@ -0,0 +1,61 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/kencx/teal"
+)
+
+func TestPageLinks(t *testing.T) {
+	title := "leviathan wakes"
+	tests := []struct {
+		name string
+		info teal.PageInfo
+		want *teal.Cursor
+	}{{
+		name: "last page",
+		info: teal.PageInfo{Total: 5},
+		want: nil,
+	}, {
+		name: "next page",
+		info: teal.PageInfo{Total: 25, Next: &teal.Cursor{Sort: "title", Value: &title, ID: 12}},
+		want: &teal.Cursor{Sort: "title", Value: &title, ID: 12},
+	}, {
+		name: "null sort value",
+		info: teal.PageInfo{Total: 25, Next: &teal.Cursor{Sort: "-rating", ID: 3}},
+		want: &teal.Cursor{Sort: "-rating", ID: 3},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/api/books/?limit=10&cursor=foo&state=read", nil)
+			checkErr(t, err)
+
+			got := pageLinks(r, tt.info)
+			if tt.want == nil {
+				assertEqual(t, got.Next, "")
+				return
+			}
+
+			next, err := url.Parse(got.Next)
+			checkErr(t, err)
+			assertEqual(t, next.Path, "/api/books/")
+			assertEqual(t, next.Query().Get("limit"), "10")
+			assertEqual(t, next.Query().Get("state"), "read")
+
+			c, err := decodeCursor(next.Query().Get("cursor"))
+			checkErr(t, err)
+			assertObjectEqual(t, c, tt.want)
+		})
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, s := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := decodeCursor(s); err != errInvalidCursor {
+			t.Errorf("decode %q: got %v, want %v", s, err, errInvalidCursor)
+		}
+	}
+}
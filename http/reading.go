@@ -0,0 +1,260 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/request"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type ReadingStore interface {
+	teal.ReadingService
+}
+
+func (s *Server) GetReadingSessions(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	sessions, err := s.Reading.GetSessions(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err == teal.ErrNoRows {
+		s.InfoLog.Printf("No reading sessions retrieved for book %d", id)
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"reads": sessions})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d reading sessions retrieved for book %d", len(sessions), id)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) AddReadingSession(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var session teal.ReadingSession
+	err := request.Read(rw, r, &session)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	// validate payload
+	v := validator.New()
+	session.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Reading.CreateSession(userID, id, &session)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	body, err := util.ToJSON(response.Envelope{"reads": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	s.InfoLog.Printf("New reading session created: %v", result)
+	response.Created(rw, r, body)
+}
+
+func (s *Server) DeleteReadingSession(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	rid := HandleInt64("rid", rw, r)
+	if rid == -1 {
+		return
+	}
+
+	err := s.Reading.DeleteSession(userID, id, rid)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Reading session %d of book %d does not exist", rid, id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Reading session %d of book %d deleted", rid, id)
+	response.OK(rw, r, nil)
+}
+
+func (s *Server) GetProgress(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	progress, err := s.Reading.GetProgress(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err == teal.ErrNoRows {
+		s.InfoLog.Printf("No progress retrieved for book %d", id)
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"progress": progress})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d progress updates retrieved for book %d", len(progress), id)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) AddProgress(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var progress teal.Progress
+	err := request.Read(rw, r, &progress)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	// validate payload
+	v := validator.New()
+	progress.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Reading.AddProgress(userID, id, &progress)
+	if err != nil {
+		switch {
+		case err == teal.ErrDoesNotExist:
+			s.InfoLog.Printf("Book %d does not exist", id)
+			response.NotFound(rw, r, err)
+			return
+		case err == teal.ErrNotReading:
+			v.AddError("state", err.Error())
+			response.ValidationError(rw, r, v.Errors)
+			return
+		case errors.Is(err, teal.ErrPageOutOfRange):
+			v.AddError("page", err.Error())
+			response.ValidationError(rw, r, v.Errors)
+			return
+		default:
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	body, err := util.ToJSON(response.Envelope{"progress": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	s.InfoLog.Printf("New progress added: %v", result)
+	response.Created(rw, r, body)
+}
+
+func (s *Server) DeleteProgress(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	pid := HandleInt64("pid", rw, r)
+	if pid == -1 {
+		return
+	}
+
+	err := s.Reading.DeleteProgress(userID, id, pid)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Progress %d of book %d does not exist", pid, id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Progress %d of book %d deleted", pid, id)
+	response.OK(rw, r, nil)
+}
@@ -0,0 +1,318 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+)
+
+var testSession = &teal.ReadingSession{
+	ID:           1,
+	BookID:       1,
+	DateStarted:  time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+	DateFinished: teal.NullTime{NullTime: sql.NullTime{Time: time.Date(2022, 1, 20, 0, 0, 0, 0, time.UTC), Valid: true}},
+	Outcome:      teal.StateRead,
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestGetReadingSessions(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		GetSessionsFn: func(userID, bookID int64) ([]*teal.ReadingSession, error) {
+			return []*teal.ReadingSession{testSession}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/1/reads/",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetReadingSessions,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.ReadingSession
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["reads"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, len(got), 1)
+	assertEqual(t, got[0].Outcome, testSession.Outcome)
+	assertEqual(t, got[0].DateFinished, testSession.DateFinished)
+}
+
+func TestGetReadingSessionsNil(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		GetSessionsFn: func(userID, bookID int64) ([]*teal.ReadingSession, error) {
+			if bookID == 10 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return nil, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/2/reads/",
+		params: map[string]string{"id": "2"},
+		fn:     testServer.GetReadingSessions,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+
+	tc = &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/10/reads/",
+		params: map[string]string{"id": "10"},
+		fn:     testServer.GetReadingSessions,
+	}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestAddReadingSession(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		CreateSessionFn: func(userID, bookID int64, r *teal.ReadingSession) (*teal.ReadingSession, error) {
+			r.ID = 2
+			r.BookID = bookID
+			return r, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/books/1/reads/",
+		data:   []byte(`{"date_started": "2021-03-01T00:00:00Z", "date_finished": "2021-03-09T00:00:00Z", "outcome": "did-not-finish"}`),
+		params: map[string]string{"id": "1"},
+		fn:     testServer.AddReadingSession,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.ReadingSession
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["reads"]
+	assertEqual(t, w.Code, http.StatusCreated)
+	assertEqual(t, got.BookID, 1)
+	assertEqual(t, got.Outcome, teal.StateDidNotFinish)
+	assertEqual(t, got.DateFinished.Time, time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC))
+}
+
+func TestAddReadingSessionInvalid(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		CreateSessionFn: func(userID, bookID int64, r *teal.ReadingSession) (*teal.ReadingSession, error) {
+			return r, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/books/1/reads/",
+		data:   []byte(`{"date_started": "2021-03-01T00:00:00Z", "outcome": "read"}`),
+		params: map[string]string{"id": "1"},
+		fn:     testServer.AddReadingSession,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "date_finished", "value is missing")
+}
+
+func TestDeleteReadingSession(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		DeleteSessionFn: func(userID, bookID, id int64) error {
+			if id == 10 {
+				return teal.ErrDoesNotExist
+			}
+			return nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/books/1/reads/1/",
+		params: map[string]string{"id": "1", "rid": "1"},
+		fn:     testServer.DeleteReadingSession,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+
+	tc = &testCase{
+		method: http.MethodDelete,
+		url:    "/api/books/1/reads/10/",
+		params: map[string]string{"id": "1", "rid": "10"},
+		fn:     testServer.DeleteReadingSession,
+	}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestGetProgress(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		GetProgressFn: func(userID, bookID int64) ([]*teal.Progress, error) {
+			return []*teal.Progress{{ID: 1, BookID: bookID, Page: intPtr(120)}}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/1/progress/",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetProgress,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.Progress
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["progress"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, len(got), 1)
+	assertEqual(t, *got[0].Page, 120)
+}
+
+func TestAddProgress(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		AddProgressFn: func(userID, bookID int64, p *teal.Progress) (*teal.Progress, error) {
+			p.ID = 1
+			p.BookID = bookID
+			if err := p.Complete(300); err != nil {
+				return nil, err
+			}
+			return p, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/books/1/progress/",
+		data:   []byte(`{"page": 120}`),
+		params: map[string]string{"id": "1"},
+		fn:     testServer.AddProgress,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Progress
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["progress"]
+	assertEqual(t, w.Code, http.StatusCreated)
+	assertEqual(t, *got.Page, 120)
+	assertEqual(t, *got.Percent, 40.0)
+}
+
+func TestAddProgressFail(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		AddProgressFn: func(userID, bookID int64, p *teal.Progress) (*teal.Progress, error) {
+			switch bookID {
+			case 2:
+				return nil, teal.ErrNotReading
+			case 3:
+				return nil, fmt.Errorf("%w: page 400 of 300", teal.ErrPageOutOfRange)
+			}
+			return nil, teal.ErrDoesNotExist
+		},
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		data    string
+		key     string
+		message string
+	}{{
+		name:    "no page or percent",
+		id:      "1",
+		data:    `{}`,
+		key:     "page",
+		message: "page or percent is missing",
+	}, {
+		name:    "not reading",
+		id:      "2",
+		data:    `{"page": 10}`,
+		key:     "state",
+		message: "book is not being read",
+	}, {
+		name:    "page out of range",
+		id:      "3",
+		data:    `{"page": 400}`,
+		key:     "page",
+		message: "page is greater than the number of pages: page 400 of 300",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodPost,
+				url:    "/api/books/" + tt.id + "/progress/",
+				data:   []byte(tt.data),
+				params: map[string]string{"id": tt.id},
+				fn:     testServer.AddProgress,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertValidationError(t, w, tt.key, tt.message)
+		})
+	}
+
+	t.Run("not exists", func(t *testing.T) {
+		tc := &testCase{
+			method: http.MethodPost,
+			url:    "/api/books/10/progress/",
+			data:   []byte(`{"page": 10}`),
+			params: map[string]string{"id": "10"},
+			fn:     testServer.AddProgress,
+		}
+		w, err := testResponse(t, tc)
+		checkErr(t, err)
+		assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+	})
+}
+
+func TestDeleteProgress(t *testing.T) {
+	testServer.Reading = &mock.ReadingStore{
+		DeleteProgressFn: func(userID, bookID, id int64) error {
+			return teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/books/1/progress/1/",
+		params: map[string]string{"id": "1", "pid": "1"},
+		fn:     testServer.DeleteProgress,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestReadingRoutes(t *testing.T) {
+	for _, path := range []string{"/api/books/1/reads", "/api/books/1/reads/"} {
+		assertRoute(t, http.MethodGet, path, "/api/books/{id:[0-9]+}/reads")
+		assertRoute(t, http.MethodPost, path, "/api/books/{id:[0-9]+}/reads")
+	}
+	for _, path := range []string{"/api/books/1/progress", "/api/books/1/progress/"} {
+		assertRoute(t, http.MethodGet, path, "/api/books/{id:[0-9]+}/progress")
+		assertRoute(t, http.MethodPost, path, "/api/books/{id:[0-9]+}/progress")
+	}
+}
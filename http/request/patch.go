@@ -0,0 +1,125 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+)
+
+// Media types of patch documents
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+	ErrPatchTestFailed  = errors.New("patch test operation failed")
+)
+
+// Apply the patch in the body to dest. The patch is a JSON Merge Patch
+// (RFC 7396) or a JSON Patch (RFC 6902), chosen by the Content-Type header.
+// Requests without a Content-Type, or with application/json, are merge
+// patches.
+//
+// dest is patched as it is encoded in JSON, and replaced by the result. Keys
+// that are not fields of dest are not allowed
+func Patch(rw http.ResponseWriter, r *http.Request, dest interface{}) error {
+
+	mediaType := MergePatchType
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return ErrUnsupportedPatch
+		}
+		mediaType = mt
+	}
+
+	// limit request body
+	r.Body = http.MaxBytesReader(rw, r.Body, int64(maxBytes))
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return decodeError(err)
+	}
+
+	var doc interface{}
+	current, err := json.Marshal(dest)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(current, &doc); err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case MergePatchType, "application/json":
+		var patch interface{}
+		if err := unmarshal(body, &patch); err != nil {
+			return err
+		}
+		doc = MergePatch(doc, patch)
+
+	case JSONPatchType:
+		var ops []Operation
+		if err := unmarshal(body, &ops); err != nil {
+			return err
+		}
+		doc, err = ApplyJSONPatch(doc, ops)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return ErrUnsupportedPatch
+	}
+
+	patched, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	// fields removed by the patch are zeroed
+	v := reflect.ValueOf(dest).Elem()
+	v.Set(reflect.Zero(v.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dest); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+func unmarshal(body []byte, v interface{}) error {
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+// Apply a JSON Merge Patch (RFC 7396) to doc. Objects in patch are merged
+// recursively, null values remove keys and all other values replace the
+// target
+func MergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	target, ok := doc.(map[string]interface{})
+	if !ok {
+		target = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(target, k)
+		} else {
+			target[k] = MergePatch(target[k], v)
+		}
+	}
+	return target
+}
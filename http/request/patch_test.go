@@ -0,0 +1,268 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	return v
+}
+
+func TestMergePatch(t *testing.T) {
+	// examples from RFC 7396, Appendix A
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			got := MergePatch(decode(t, tt.doc), decode(t, tt.patch))
+			want := decode(t, tt.want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	// examples from RFC 6902, Appendix A
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   bool
+	}{{
+		name:  "add object member",
+		doc:   `{"foo":"bar"}`,
+		patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+		want:  `{"baz":"qux","foo":"bar"}`,
+	}, {
+		name:  "add array element",
+		doc:   `{"foo":["bar","baz"]}`,
+		patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+		want:  `{"foo":["bar","qux","baz"]}`,
+	}, {
+		name:  "append array element",
+		doc:   `{"foo":["bar"]}`,
+		patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+		want:  `{"foo":["bar",["abc","def"]]}`,
+	}, {
+		name:  "remove object member",
+		doc:   `{"baz":"qux","foo":"bar"}`,
+		patch: `[{"op":"remove","path":"/baz"}]`,
+		want:  `{"foo":"bar"}`,
+	}, {
+		name:  "remove array element",
+		doc:   `{"foo":["bar","qux","baz"]}`,
+		patch: `[{"op":"remove","path":"/foo/1"}]`,
+		want:  `{"foo":["bar","baz"]}`,
+	}, {
+		name:  "replace value",
+		doc:   `{"baz":"qux","foo":"bar"}`,
+		patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+		want:  `{"baz":"boo","foo":"bar"}`,
+	}, {
+		name:  "move value",
+		doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+		patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+		want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+	}, {
+		name:  "move array element",
+		doc:   `{"foo":["all","grass","cows","eat"]}`,
+		patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+		want:  `{"foo":["all","cows","eat","grass"]}`,
+	}, {
+		name:  "copy value",
+		doc:   `{"foo":{"bar":1}}`,
+		patch: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+		want:  `{"foo":{"bar":1},"baz":{"bar":2}}`,
+	}, {
+		name:  "test value",
+		doc:   `{"baz":"qux","foo":["a",2,"c"]}`,
+		patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+		want:  `{"baz":"qux","foo":["a",2,"c"]}`,
+	}, {
+		name:  "escaped paths",
+		doc:   `{"/":9,"~1":10}`,
+		patch: `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`,
+		want:  `{"~1":10}`,
+	}, {
+		name:  "add null value",
+		doc:   `{"foo":"bar"}`,
+		patch: `[{"op":"add","path":"/baz","value":null}]`,
+		want:  `{"baz":null,"foo":"bar"}`,
+	}, {
+		name:  "test fails",
+		doc:   `{"baz":"qux"}`,
+		patch: `[{"op":"test","path":"/baz","value":"bar"}]`,
+		err:   true,
+	}, {
+		name:  "add to nonexistent target",
+		doc:   `{"foo":"bar"}`,
+		patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+		err:   true,
+	}, {
+		name:  "remove nonexistent member",
+		doc:   `{"foo":"bar"}`,
+		patch: `[{"op":"remove","path":"/baz"}]`,
+		err:   true,
+	}, {
+		name:  "out of range index",
+		doc:   `{"foo":["bar"]}`,
+		patch: `[{"op":"add","path":"/foo/2","value":"qux"}]`,
+		err:   true,
+	}, {
+		name:  "leading zero index",
+		doc:   `{"foo":["bar","baz"]}`,
+		patch: `[{"op":"remove","path":"/foo/01"}]`,
+		err:   true,
+	}, {
+		name:  "missing value",
+		doc:   `{"foo":"bar"}`,
+		patch: `[{"op":"add","path":"/baz"}]`,
+		err:   true,
+	}, {
+		name:  "unknown operation",
+		doc:   `{"foo":"bar"}`,
+		patch: `[{"op":"foo","path":"/baz"}]`,
+		err:   true,
+	}, {
+		name:  "move into child",
+		doc:   `{"foo":{"bar":1}}`,
+		patch: `[{"op":"move","from":"/foo","path":"/foo/baz"}]`,
+		err:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(tt.patch), &ops); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			doc := decode(t, tt.doc)
+			got, err := ApplyJSONPatch(doc, ops)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				// the document is unchanged
+				if !reflect.DeepEqual(doc, decode(t, tt.doc)) {
+					t.Errorf("document changed by failed patch: %v", doc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			want := decode(t, tt.want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+type patchItem struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+	Note string   `json:"note,omitempty"`
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        patchItem
+		err         error
+	}{{
+		name: "merge patch without content type",
+		body: `{"name":"bar","note":null}`,
+		want: patchItem{Name: "bar", Tags: []string{"a"}},
+	}, {
+		name:        "merge patch",
+		contentType: "application/merge-patch+json; charset=utf-8",
+		body:        `{"tags":["a","b"]}`,
+		want:        patchItem{Name: "foo", Tags: []string{"a", "b"}, Note: "baz"},
+	}, {
+		name:        "json patch",
+		contentType: JSONPatchType,
+		body:        `[{"op":"add","path":"/tags/-","value":"b"},{"op":"remove","path":"/note"}]`,
+		want:        patchItem{Name: "foo", Tags: []string{"a", "b"}},
+	}, {
+		name:        "json patch test failed",
+		contentType: JSONPatchType,
+		body:        `[{"op":"test","path":"/name","value":"bar"}]`,
+		err:         ErrPatchTestFailed,
+	}, {
+		name:        "unsupported media type",
+		contentType: "text/plain",
+		body:        `{"name":"bar"}`,
+		err:         ErrUnsupportedPatch,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			got := patchItem{Name: "foo", Tags: []string{"a"}, Note: "baz"}
+			err := Patch(httptest.NewRecorder(), req, &got)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchUnknownField(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"foo":"bar"}`))
+	got := patchItem{Name: "foo"}
+
+	err := Patch(httptest.NewRecorder(), req, &got)
+	if err == nil || err.Error() != `body contains unknown key "foo"` {
+		t.Errorf("got %v, want unknown key error", err)
+	}
+}
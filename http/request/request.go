@@ -26,36 +26,41 @@ func Read(rw http.ResponseWriter, r *http.Request, dest interface{}) error {
 	err := decoder.Decode(dest)
 
 	if err != nil {
-		switch {
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contains badly-formed JSON at character %d", syntaxError.Offset)
+		return decodeError(err)
+	}
+	return nil
+}
 
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains badly-formed JSON")
+// map JSON decoding errors to messages for clients
+func decodeError(err error) error {
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("body contains badly-formed JSON at character %d", syntaxError.Offset)
 
-		case errors.As(err, &unmarshalTypeError):
-			if unmarshalTypeError.Field != "" {
-				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
-			}
-			return fmt.Errorf("body contains incorrect JSON type at character %d", unmarshalTypeError.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains badly-formed JSON")
 
-		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
+	case errors.As(err, &unmarshalTypeError):
+		if unmarshalTypeError.Field != "" {
+			return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+		}
+		return fmt.Errorf("body contains incorrect JSON type at character %d", unmarshalTypeError.Offset)
 
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
 
-		case err.Error() == "http: request body too large":
-			return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Errorf("body contains unknown key %s", fieldName)
 
-		// panic when decoding to non-nil pointer
-		case errors.As(err, &invalidUnmarshalError):
-			panic(err)
+	case err.Error() == "http: request body too large":
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytes)
 
-		default:
-			return err
-		}
+	// panic when decoding to non-nil pointer
+	case errors.As(err, &invalidUnmarshalError):
+		panic(err)
+
+	default:
+		return err
 	}
-	return nil
 }
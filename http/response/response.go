@@ -47,6 +47,15 @@ func Created(rw http.ResponseWriter, r *http.Request, body []byte) {
 	res.Write()
 }
 
+// The request's precondition failed. body should contain the current
+// representation of the resource
+func PreconditionFailed(rw http.ResponseWriter, r *http.Request, body []byte) {
+	res := New(rw, r)
+	res.statusCode = http.StatusPreconditionFailed
+	res.body = body
+	res.Write()
+}
+
 func NewError(rw http.ResponseWriter, r *http.Request, err interface{}) *response {
 	res := New(rw, r)
 	res.statusCode = http.StatusBadRequest
@@ -85,6 +94,24 @@ func NotFound(rw http.ResponseWriter, r *http.Request, err error) {
 	res.Write()
 }
 
+func Conflict(rw http.ResponseWriter, r *http.Request, err error) {
+	res := NewError(rw, r, err)
+	res.statusCode = http.StatusConflict
+	res.Write()
+}
+
+func UnsupportedMediaType(rw http.ResponseWriter, r *http.Request, err error) {
+	res := NewError(rw, r, err)
+	res.statusCode = http.StatusUnsupportedMediaType
+	res.Write()
+}
+
+func BadGateway(rw http.ResponseWriter, r *http.Request, err error) {
+	res := NewError(rw, r, err)
+	res.statusCode = http.StatusBadGateway
+	res.Write()
+}
+
 func Unauthorized(rw http.ResponseWriter, r *http.Request, err error) {
 	res := NewError(rw, r, err)
 	res.statusCode = http.StatusUnauthorized
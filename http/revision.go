@@ -0,0 +1,118 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+)
+
+type RevisionStore interface {
+	teal.RevisionService
+}
+
+func (s *Server) GetBookHistory(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	revisions, err := s.Revisions.GetBookHistory(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err == teal.ErrNoRows {
+		s.InfoLog.Printf("No history of book %d", id)
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"history": revisions})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d revisions of book %d retrieved", len(revisions), id)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) GetAuthorHistory(rw http.ResponseWriter, r *http.Request) {
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	revisions, err := s.Revisions.GetAuthorHistory(id)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Printf("No history of author %d", id)
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"history": revisions})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d revisions of author %d retrieved", len(revisions), id)
+	response.OK(rw, r, res)
+}
+
+// Revert a book to its details after the given revision
+func (s *Server) RevertBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+	rid := HandleInt64("rid", rw, r)
+	if rid == -1 {
+		return
+	}
+
+	book, err := s.Revisions.RevertBook(userID, id, rid)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Revision %d of book %d does not exist", rid, id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"books": book})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Book %d reverted to revision %d", id, rid)
+	response.OK(rw, r, res)
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+)
+
+func TestGetBookHistory(t *testing.T) {
+	var gotUser int64
+	testServer.Revisions = &mock.RevisionStore{
+		GetBookHistoryFn: func(userID, bookID int64) ([]*teal.Revision, error) {
+			gotUser = userID
+			if bookID == 10 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return []*teal.Revision{{
+				ID:      2,
+				Action:  teal.ActionUpdate,
+				Changes: teal.Changes{"title": {From: "foo", To: testBook1.Title}},
+			}, {
+				ID:     1,
+				Action: teal.ActionCreate,
+			}}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/1/history/",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetBookHistory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.Revision
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["history"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, gotUser, testAuthUser.ID)
+	assertEqual(t, len(got), 2)
+	assertEqual(t, got[0].Action, teal.ActionUpdate)
+	assertEqual(t, got[0].Changes["title"].To.(string), testBook1.Title)
+
+	tc = &testCase{
+		method: http.MethodGet,
+		url:    "/api/books/10/history/",
+		params: map[string]string{"id": "10"},
+		fn:     testServer.GetBookHistory,
+	}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestGetAuthorHistoryNil(t *testing.T) {
+	testServer.Revisions = &mock.RevisionStore{
+		GetAuthorHistoryFn: func(authorID int64) ([]*teal.Revision, error) {
+			return nil, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/authors/1/history/",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetAuthorHistory,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+}
+
+func TestRevertBook(t *testing.T) {
+	testServer.Revisions = &mock.RevisionStore{
+		RevertBookFn: func(userID, bookID, revisionID int64) (*teal.Book, error) {
+			if revisionID == 10 {
+				return nil, teal.ErrDoesNotExist
+			}
+			return testBook1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/books/1/history/2/revert/",
+		params: map[string]string{"id": "1", "rid": "2"},
+		fn:     testServer.RevertBook,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Book
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, env["books"].Title, testBook1.Title)
+
+	tc = &testCase{
+		method: http.MethodPost,
+		url:    "/api/books/1/history/10/revert/",
+		params: map[string]string{"id": "1", "rid": "10"},
+		fn:     testServer.RevertBook,
+	}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestHistoryRoutes(t *testing.T) {
+	for _, path := range []string{"/api/books/1/history", "/api/books/1/history/"} {
+		assertRoute(t, http.MethodGet, path, "/api/books/{id:[0-9]+}/history")
+	}
+	for _, path := range []string{"/api/authors/1/history", "/api/authors/1/history/"} {
+		assertRoute(t, http.MethodGet, path, "/api/authors/{id:[0-9]+}/history")
+	}
+	for _, path := range []string{"/api/books/1/history/2/revert", "/api/books/1/history/2/revert/"} {
+		assertRoute(t, http.MethodPost, path, "/api/books/{id:[0-9]+}/history/{rid:[0-9]+}/revert")
+	}
+}
@@ -0,0 +1,238 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/request"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type SeriesStore interface {
+	teal.SeriesService
+}
+
+func (s *Server) GetSeries(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	series, err := s.Series.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Series %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"series": series})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Series %d retrieved: %v", id, series)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) GetAllSeries(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	series, err := s.Series.GetAll(userID)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Println("No series retrieved")
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"series": series})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d series retrieved: %v", len(series), series)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) GetNextUnreadInSeries(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	next, err := s.Series.GetNextUnread(userID)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Println("No unread books in series")
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"next": next})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Next unread books retrieved for %d series", len(next))
+	response.OK(rw, r, res)
+}
+
+func (s *Server) AddSeries(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var series teal.Series
+	err := request.Read(rw, r, &series)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	// validate payload
+	v := validator.New()
+	series.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Series.Create(userID, &series)
+	if err != nil {
+		switch {
+		case errors.Is(err, teal.ErrDuplicateSeries):
+			v.AddError("name", "this series already exists")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		default:
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	body, err := util.ToJSON(response.Envelope{"series": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+	s.InfoLog.Printf("New series created: %v", result)
+	response.Created(rw, r, body)
+}
+
+func (s *Server) UpdateSeries(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var series teal.Series
+	err := request.Read(rw, r, &series)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	// validate payload
+	// PUT should require all fields
+	v := validator.New()
+	series.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Series.Update(userID, id, &series)
+	if err != nil {
+		switch {
+		case errors.Is(err, teal.ErrDoesNotExist):
+			s.InfoLog.Printf("Series %d does not exist", id)
+			response.NotFound(rw, r, err)
+			return
+		case errors.Is(err, teal.ErrDuplicateSeries):
+			v.AddError("name", "this series already exists")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		default:
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	body, err := util.ToJSON(response.Envelope{"series": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Series %d updated: %v", id, result)
+	response.OK(rw, r, body)
+}
+
+func (s *Server) DeleteSeries(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	err := s.Series.Delete(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Series %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Series %d deleted", id)
+	response.OK(rw, r, nil)
+}
@@ -0,0 +1,272 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+	"github.com/kencx/teal/util"
+)
+
+var (
+	testSeries1 = &teal.Series{
+		Name:  "The Expanse",
+		Books: []*teal.Book{testBook1, testBook2},
+	}
+	testSeries2 = &teal.Series{
+		Name: "Red Rising Saga",
+	}
+	testAllSeries = []*teal.Series{testSeries1, testSeries2}
+)
+
+func TestGetSeries(t *testing.T) {
+	testServer.Series = &mock.SeriesStore{
+		GetSeriesFn: func(userID, id int64) (*teal.Series, error) {
+			return testSeries1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/series/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Series
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["series"]
+	assertEqual(t, got.Name, testSeries1.Name)
+	assertEqual(t, len(got.Books), len(testSeries1.Books))
+	for i, b := range got.Books {
+		assertEqual(t, b.ISBN, testSeries1.Books[i].ISBN)
+	}
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestGetSeriesNil(t *testing.T) {
+	testServer.Series = &mock.SeriesStore{
+		GetSeriesFn: func(userID, id int64) (*teal.Series, error) {
+			return nil, teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/series/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestGetAllSeries(t *testing.T) {
+	testServer.Series = &mock.SeriesStore{
+		GetAllSeriesFn: func(userID int64) ([]*teal.Series, error) {
+			return testAllSeries, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/series/",
+		fn:     testServer.GetAllSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.Series
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["series"]
+	for i, v := range got {
+		assertEqual(t, v.Name, testAllSeries[i].Name)
+	}
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestGetNextUnreadInSeries(t *testing.T) {
+	want := []*teal.NextInSeries{{
+		SeriesID: 1,
+		Series:   testSeries1.Name,
+		Position: 2.5,
+		Book:     testBook2,
+	}}
+
+	testServer.Series = &mock.SeriesStore{
+		GetNextUnreadFn: func(userID int64) ([]*teal.NextInSeries, error) {
+			return want, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/series/next/",
+		fn:     testServer.GetNextUnreadInSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.NextInSeries
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["next"]
+	assertEqual(t, len(got), len(want))
+	assertEqual(t, got[0].Series, want[0].Series)
+	assertEqual(t, got[0].Position, want[0].Position)
+	assertEqual(t, got[0].Book.ISBN, want[0].Book.ISBN)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestGetNextUnreadInSeriesNil(t *testing.T) {
+	testServer.Series = &mock.SeriesStore{
+		GetNextUnreadFn: func(userID int64) ([]*teal.NextInSeries, error) {
+			return nil, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/series/next/",
+		fn:     testServer.GetNextUnreadInSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+}
+
+func TestGetNextUnreadInSeriesRoute(t *testing.T) {
+	for _, path := range []string{"/api/series/next", "/api/series/next/"} {
+		assertRoute(t, http.MethodGet, path, "/api/series/next")
+	}
+}
+
+func TestAddSeries(t *testing.T) {
+	want, err := util.ToJSON(testSeries2)
+	checkErr(t, err)
+
+	testServer.Series = &mock.SeriesStore{
+		CreateSeriesFn: func(userID int64, s *teal.Series) (*teal.Series, error) {
+			return testSeries2, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/series/",
+		data:   want,
+		fn:     testServer.AddSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Series
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["series"]
+	assertEqual(t, got.Name, testSeries2.Name)
+	assertEqual(t, w.Code, http.StatusCreated)
+}
+
+func TestAddSeriesFailValidation(t *testing.T) {
+	want, err := util.ToJSON(&teal.Series{Name: ""})
+	checkErr(t, err)
+
+	testServer.Series = &mock.SeriesStore{
+		CreateSeriesFn: func(userID int64, s *teal.Series) (*teal.Series, error) {
+			return testSeries2, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/series/",
+		data:   want,
+		fn:     testServer.AddSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "name", "value is missing")
+}
+
+func TestUpdateSeries(t *testing.T) {
+	want, err := util.ToJSON(testSeries2)
+	checkErr(t, err)
+
+	testServer.Series = &mock.SeriesStore{
+		UpdateSeriesFn: func(userID, id int64, s *teal.Series) (*teal.Series, error) {
+			return testSeries2, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/series/2",
+		data:   want,
+		params: map[string]string{"id": "2"},
+		fn:     testServer.UpdateSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Series
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["series"]
+	assertEqual(t, got.Name, testSeries2.Name)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestUpdateSeriesNil(t *testing.T) {
+	want, err := util.ToJSON(testSeries2)
+	checkErr(t, err)
+
+	testServer.Series = &mock.SeriesStore{
+		UpdateSeriesFn: func(userID, id int64, s *teal.Series) (*teal.Series, error) {
+			return nil, teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/series/10",
+		data:   want,
+		params: map[string]string{"id": "10"},
+		fn:     testServer.UpdateSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestDeleteSeries(t *testing.T) {
+	testServer.Series = &mock.SeriesStore{
+		DeleteSeriesFn: func(userID, id int64) error {
+			return nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/series/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.DeleteSeries,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+}
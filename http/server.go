@@ -8,12 +8,17 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/kencx/teal/covers"
+	"github.com/kencx/teal/ebook"
 )
 
 var (
-	idleTimeout      = 60 * time.Second
-	readWriteTimeout = 3 * time.Second
-	closeTimeout     = 5 * time.Second
+	idleTimeout       = 60 * time.Second
+	readHeaderTimeout = 3 * time.Second
+	// ebook uploads and downloads may be large
+	readTimeout  = 5 * time.Minute
+	writeTimeout = 10 * time.Minute
+	closeTimeout = 5 * time.Second
 )
 
 type Server struct {
@@ -22,9 +27,20 @@ type Server struct {
 	InfoLog *log.Logger
 	ErrLog  *log.Logger
 
-	Books   BookStore
-	Authors AuthorStore
-	Users   UserStore
+	Books      BookStore
+	Authors    AuthorStore
+	Categories CategoryStore
+	Series     SeriesStore
+	Tags       TagStore
+	Reading    ReadingStore
+	Stats      StatsStore
+	Trash      TrashStore
+	Revisions  RevisionStore
+	Metadata   MetadataLookup
+	Covers     *covers.Store
+	Files      FileStore
+	Ebooks     *ebook.Store
+	Users      UserStore
 }
 
 func NewServer() *Server {
@@ -35,11 +51,12 @@ func NewServer() *Server {
 	}
 
 	s.Server = &http.Server{
-		Handler:      s.Router,
-		ErrorLog:     s.ErrLog,
-		IdleTimeout:  idleTimeout,
-		ReadTimeout:  readWriteTimeout,
-		WriteTimeout: readWriteTimeout,
+		Handler:           s.Router,
+		ErrorLog:          s.ErrLog,
+		IdleTimeout:       idleTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
 	}
 
 	s.RegisterRoutes()
@@ -74,6 +91,18 @@ func (s *Server) RegisterRoutes() {
 	router.HandleFunc("/api/users/", s.Register).Methods(http.MethodPost)
 	// r.HandleFunc("/api/tokens/", s.NewToken).Methods(http.MethodPost)
 
+	// OPDS catalog for e-reader apps
+	or := router.PathPrefix("/opds/").Subrouter()
+	or.Use(s.basicAuth)
+	or.HandleFunc("/", s.OPDSRoot).Methods(http.MethodGet)
+	or.HandleFunc("/recent/", s.OPDSRecent).Methods(http.MethodGet)
+	or.HandleFunc("/authors/", s.OPDSAuthors).Methods(http.MethodGet)
+	or.HandleFunc("/authors/{id:[0-9]+}/", s.OPDSAuthorBooks).Methods(http.MethodGet)
+	or.HandleFunc("/states/", s.OPDSStates).Methods(http.MethodGet)
+	or.HandleFunc("/states/{state}/", s.OPDSStateBooks).Methods(http.MethodGet)
+	or.HandleFunc("/search/", s.OPDSSearch).Methods(http.MethodGet)
+	or.HandleFunc("/search.xml", s.OPDSOpenSearch).Methods(http.MethodGet)
+
 	api := router.PathPrefix("/api").Subrouter()
 	// api.Use(s.apiKeyAuth)
 	api.Use(s.basicAuth)
@@ -88,13 +117,94 @@ func (s *Server) RegisterRoutes() {
 	br.HandleFunc("/", s.GetAllBooks).Methods(http.MethodGet)
 	br.HandleFunc("/", s.AddBook).Methods(http.MethodPost)
 	br.HandleFunc("/{id:[0-9]+}/", s.UpdateBook).Methods(http.MethodPut)
+	br.HandleFunc("/{id:[0-9]+}/", s.PatchBook).Methods(http.MethodPatch)
+	br.HandleFunc("/{id:[0-9]+}/state", s.UpdateBookState).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/state/", s.UpdateBookState).Methods(http.MethodPost)
 	br.HandleFunc("/{id:[0-9]+}/", s.DeleteBook).Methods(http.MethodDelete)
+	br.HandleFunc("/{id:[0-9]+}/cover", s.GetBookCover).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/cover/", s.GetBookCover).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/cover", s.PutBookCover).Methods(http.MethodPut)
+	br.HandleFunc("/{id:[0-9]+}/cover/", s.PutBookCover).Methods(http.MethodPut)
+	br.HandleFunc("/{id:[0-9]+}/cover", s.DeleteBookCover).Methods(http.MethodDelete)
+	br.HandleFunc("/{id:[0-9]+}/cover/", s.DeleteBookCover).Methods(http.MethodDelete)
+	br.HandleFunc("/{id:[0-9]+}/files", s.GetBookFiles).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/files/", s.GetBookFiles).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/files", s.AddBookFile).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/files/", s.AddBookFile).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/files/{fid:[0-9]+}/", s.GetBookFile).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/files/{fid:[0-9]+}/", s.DeleteBookFile).Methods(http.MethodDelete)
+	br.HandleFunc("/{id:[0-9]+}/reads", s.GetReadingSessions).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/reads/", s.GetReadingSessions).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/reads", s.AddReadingSession).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/reads/", s.AddReadingSession).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/reads/{rid:[0-9]+}/", s.DeleteReadingSession).Methods(http.MethodDelete)
+	br.HandleFunc("/{id:[0-9]+}/progress", s.GetProgress).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/progress/", s.GetProgress).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/progress", s.AddProgress).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/progress/", s.AddProgress).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/progress/{pid:[0-9]+}/", s.DeleteProgress).Methods(http.MethodDelete)
+	br.HandleFunc("/{id:[0-9]+}/history", s.GetBookHistory).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/history/", s.GetBookHistory).Methods(http.MethodGet)
+	br.HandleFunc("/{id:[0-9]+}/history/{rid:[0-9]+}/revert", s.RevertBook).Methods(http.MethodPost)
+	br.HandleFunc("/{id:[0-9]+}/history/{rid:[0-9]+}/revert/", s.RevertBook).Methods(http.MethodPost)
 
 	ar := api.PathPrefix("/authors/").Subrouter()
+	ar.HandleFunc("/duplicates", s.GetDuplicateAuthors).Methods(http.MethodGet)
+	ar.HandleFunc("/duplicates/", s.GetDuplicateAuthors).Methods(http.MethodGet)
 	ar.HandleFunc("/{id:[0-9]+}/", s.GetAuthor).Methods(http.MethodGet)
 	ar.HandleFunc("/{name}/", s.GetAuthorByName).Methods(http.MethodGet)
 	ar.HandleFunc("/", s.GetAllAuthors).Methods(http.MethodGet)
 	ar.HandleFunc("/", s.AddAuthor).Methods(http.MethodPost)
 	ar.HandleFunc("/{id:[0-9]+}/", s.UpdateAuthor).Methods(http.MethodPut)
+	ar.HandleFunc("/{id:[0-9]+}/", s.PatchAuthor).Methods(http.MethodPatch)
 	ar.HandleFunc("/{id:[0-9]+}/", s.DeleteAuthor).Methods(http.MethodDelete)
+	ar.HandleFunc("/{id:[0-9]+}/history", s.GetAuthorHistory).Methods(http.MethodGet)
+	ar.HandleFunc("/{id:[0-9]+}/history/", s.GetAuthorHistory).Methods(http.MethodGet)
+	ar.HandleFunc("/{id:[0-9]+}/merge", s.MergeAuthor).Methods(http.MethodPost)
+	ar.HandleFunc("/{id:[0-9]+}/merge/", s.MergeAuthor).Methods(http.MethodPost)
+
+	cr := api.PathPrefix("/categories/").Subrouter()
+	cr.HandleFunc("/{id:[0-9]+}/", s.GetCategory).Methods(http.MethodGet)
+	cr.HandleFunc("/", s.GetAllCategories).Methods(http.MethodGet)
+	cr.HandleFunc("/", s.AddCategory).Methods(http.MethodPost)
+	cr.HandleFunc("/{id:[0-9]+}/", s.UpdateCategory).Methods(http.MethodPut)
+	cr.HandleFunc("/{id:[0-9]+}/", s.DeleteCategory).Methods(http.MethodDelete)
+
+	sr := api.PathPrefix("/series/").Subrouter()
+	sr.HandleFunc("/next", s.GetNextUnreadInSeries).Methods(http.MethodGet)
+	sr.HandleFunc("/next/", s.GetNextUnreadInSeries).Methods(http.MethodGet)
+	sr.HandleFunc("/{id:[0-9]+}/", s.GetSeries).Methods(http.MethodGet)
+	sr.HandleFunc("/", s.GetAllSeries).Methods(http.MethodGet)
+	sr.HandleFunc("/", s.AddSeries).Methods(http.MethodPost)
+	sr.HandleFunc("/{id:[0-9]+}/", s.UpdateSeries).Methods(http.MethodPut)
+	sr.HandleFunc("/{id:[0-9]+}/", s.DeleteSeries).Methods(http.MethodDelete)
+
+	api.HandleFunc("/stats", s.GetStats).Methods(http.MethodGet)
+	api.HandleFunc("/stats/", s.GetStats).Methods(http.MethodGet)
+	api.HandleFunc("/lookup", s.Lookup).Methods(http.MethodGet)
+	api.HandleFunc("/lookup/", s.Lookup).Methods(http.MethodGet)
+	api.HandleFunc("/export", s.Export).Methods(http.MethodGet)
+	api.HandleFunc("/export/", s.Export).Methods(http.MethodGet)
+	api.HandleFunc("/files", s.AddFile).Methods(http.MethodPost)
+	api.HandleFunc("/files/", s.AddFile).Methods(http.MethodPost)
+
+	trr := api.PathPrefix("/trash/").Subrouter()
+	trr.HandleFunc("/", s.GetTrash).Methods(http.MethodGet)
+	trr.HandleFunc("/", s.EmptyTrash).Methods(http.MethodDelete)
+	trr.HandleFunc("/{id:[0-9]+}/restore", s.RestoreBook).Methods(http.MethodPost)
+	trr.HandleFunc("/{id:[0-9]+}/restore/", s.RestoreBook).Methods(http.MethodPost)
+
+	ir := api.PathPrefix("/import/").Subrouter()
+	ir.HandleFunc("/goodreads", s.ImportGoodreads).Methods(http.MethodPost)
+	ir.HandleFunc("/goodreads/", s.ImportGoodreads).Methods(http.MethodPost)
+	ir.HandleFunc("/calibre", s.ImportCalibre).Methods(http.MethodPost)
+	ir.HandleFunc("/calibre/", s.ImportCalibre).Methods(http.MethodPost)
+
+	tr := api.PathPrefix("/tags/").Subrouter()
+	tr.HandleFunc("/{id:[0-9]+}/", s.GetTag).Methods(http.MethodGet)
+	tr.HandleFunc("/", s.GetAllTags).Methods(http.MethodGet)
+	tr.HandleFunc("/{id:[0-9]+}/", s.UpdateTag).Methods(http.MethodPut)
+	tr.HandleFunc("/{id:[0-9]+}/merge", s.MergeTag).Methods(http.MethodPost)
+	tr.HandleFunc("/{id:[0-9]+}/merge/", s.MergeTag).Methods(http.MethodPost)
+	tr.HandleFunc("/{id:[0-9]+}/", s.DeleteTag).Methods(http.MethodDelete)
 }
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type StatsStore interface {
+	teal.StatsService
+}
+
+func (s *Server) GetStats(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	f := &teal.StatsFilter{
+		From: readDate(r, "from", v),
+		To:   readDate(r, "to", v),
+	}
+	if v.Valid() {
+		f.Validate(v)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	stats, err := s.Stats.Get(userID, f)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"stats": stats})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Stats retrieved for %d books", stats.Books)
+	response.OK(rw, r, res)
+}
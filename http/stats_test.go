@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+)
+
+func TestGetStats(t *testing.T) {
+	var gotFilter *teal.StatsFilter
+	testServer.Stats = &mock.StatsStore{
+		GetStatsFn: func(userID int64, f *teal.StatsFilter) (*teal.Stats, error) {
+			gotFilter = f
+			return &teal.Stats{
+				Books:  2,
+				States: map[string]int{teal.StateRead: 1, teal.StateReading: 1},
+				Read:   1,
+			}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/stats?from=2022-01-01&to=2022-12-31",
+		fn:     testServer.GetStats,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Stats
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["stats"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, got.Books, 2)
+	assertEqual(t, got.States[teal.StateRead], 1)
+	assertEqual(t, gotFilter.From, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	assertEqual(t, gotFilter.To, time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC))
+}
+
+func TestGetStatsInvalid(t *testing.T) {
+	testServer.Stats = &mock.StatsStore{
+		GetStatsFn: func(userID int64, f *teal.StatsFilter) (*teal.Stats, error) {
+			return &teal.Stats{}, nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		key     string
+		message string
+	}{{
+		name:    "invalid date",
+		url:     "/api/stats?from=01-01-2022",
+		key:     "from",
+		message: "must be a date in YYYY-MM-DD format",
+	}, {
+		name:    "from after to",
+		url:     "/api/stats?from=2022-02-01&to=2022-01-01",
+		key:     "from",
+		message: "must not be after to",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &testCase{
+				method: http.MethodGet,
+				url:    tt.url,
+				fn:     testServer.GetStats,
+			}
+			w, err := testResponse(t, tc)
+			checkErr(t, err)
+			assertValidationError(t, w, tt.key, tt.message)
+		})
+	}
+}
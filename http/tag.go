@@ -0,0 +1,226 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/request"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type TagStore interface {
+	teal.TagService
+}
+
+func (s *Server) GetTag(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	t, err := s.Tags.Get(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Tag %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"tags": t})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Tag %d retrieved: %v", id, t)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) GetAllTags(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	t, err := s.Tags.GetAll(userID)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Println("No tags retrieved")
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"tags": t})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d tags retrieved: %v", len(t), t)
+	response.OK(rw, r, res)
+}
+
+func (s *Server) UpdateTag(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	// marshal payload to struct
+	var tag teal.Tag
+	err := request.Read(rw, r, &tag)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	// validate payload
+	v := validator.New()
+	tag.Validate(v)
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Tags.Update(userID, id, &tag)
+	if err != nil {
+		switch {
+		case errors.Is(err, teal.ErrDoesNotExist):
+			s.InfoLog.Printf("Tag %d does not exist", id)
+			response.NotFound(rw, r, err)
+			return
+		case errors.Is(err, teal.ErrSharedItem):
+			s.InfoLog.Printf("Tag %d has books of other users", id)
+			response.Conflict(rw, r, err)
+			return
+		case errors.Is(err, teal.ErrDuplicateTag):
+			v.AddError("name", "this tag already exists, merge the tags instead")
+			response.ValidationError(rw, r, v.Errors)
+			return
+		default:
+			s.ErrLog.Printf("err: %v", err)
+			response.InternalServerError(rw, r, err)
+			return
+		}
+	}
+
+	body, err := util.ToJSON(response.Envelope{"tags": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Tag %d updated: %v", id, result)
+	response.OK(rw, r, body)
+}
+
+func (s *Server) MergeTag(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	var input struct {
+		Into int64 `json:"into"`
+	}
+	err := request.Read(rw, r, &input)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.BadRequest(rw, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Into > 0, "into", "value is missing")
+	v.Check(input.Into != id, "into", "cannot merge tag into itself")
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	result, err := s.Tags.Merge(userID, id, input.Into)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Tag %d or %d does not exist", id, input.Into)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err == teal.ErrSharedItem {
+		s.InfoLog.Printf("Tag %d has books of other users", id)
+		response.Conflict(rw, r, err)
+		return
+	}
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	body, err := util.ToJSON(response.Envelope{"tags": result})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Tag %d merged into %v", id, result)
+	response.OK(rw, r, body)
+}
+
+func (s *Server) DeleteTag(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	err := s.Tags.Delete(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Tag %d does not exist", id)
+		response.NotFound(rw, r, err)
+		return
+	}
+	if err == teal.ErrSharedItem {
+		s.InfoLog.Printf("Tag %d has books of other users", id)
+		response.Conflict(rw, r, err)
+		return
+	}
+
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Tag %d deleted", id)
+	response.OK(rw, r, nil)
+}
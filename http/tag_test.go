@@ -0,0 +1,318 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+	"github.com/kencx/teal/util"
+)
+
+var (
+	testTag1 = &teal.Tag{
+		ID:    1,
+		Name:  "space",
+		Count: 2,
+	}
+	testTag2 = &teal.Tag{
+		ID:    2,
+		Name:  "favourite",
+		Count: 1,
+	}
+	testTags = []*teal.Tag{testTag2, testTag1}
+)
+
+func TestGetTag(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		GetTagFn: func(userID, id int64) (*teal.Tag, error) {
+			return testTag1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/tags/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Tag
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["tags"]
+	assertEqual(t, got.Name, testTag1.Name)
+	assertEqual(t, got.Count, testTag1.Count)
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, w.HeaderMap.Get("Content-Type"), "application/json")
+}
+
+func TestGetTagNil(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		GetTagFn: func(userID, id int64) (*teal.Tag, error) {
+			return nil, teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/tags/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.GetTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestGetAllTags(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		GetAllTagsFn: func(userID int64) ([]*teal.Tag, error) {
+			return testTags, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/tags/",
+		fn:     testServer.GetAllTags,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.Tag
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["tags"]
+	assertObjectEqual(t, got, testTags)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestGetAllTagsNil(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		GetAllTagsFn: func(userID int64) ([]*teal.Tag, error) {
+			return nil, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/tags/",
+		fn:     testServer.GetAllTags,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+}
+
+func TestUpdateTag(t *testing.T) {
+	want, err := util.ToJSON(&teal.Tag{Name: "outer space"})
+	checkErr(t, err)
+
+	testServer.Tags = &mock.TagStore{
+		UpdateTagFn: func(userID, id int64, tag *teal.Tag) (*teal.Tag, error) {
+			return &teal.Tag{ID: id, Name: tag.Name, Count: 2}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/tags/1",
+		data:   want,
+		params: map[string]string{"id": "1"},
+		fn:     testServer.UpdateTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Tag
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["tags"]
+	assertEqual(t, got.Name, "outer space")
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestUpdateTagFailValidation(t *testing.T) {
+	want, err := util.ToJSON(&teal.Tag{Name: ""})
+	checkErr(t, err)
+
+	testServer.Tags = &mock.TagStore{
+		UpdateTagFn: func(userID, id int64, tag *teal.Tag) (*teal.Tag, error) {
+			return testTag1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/tags/1",
+		data:   want,
+		params: map[string]string{"id": "1"},
+		fn:     testServer.UpdateTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "name", "value is missing")
+}
+
+func TestUpdateTagDuplicate(t *testing.T) {
+	want, err := util.ToJSON(&teal.Tag{Name: "favourite"})
+	checkErr(t, err)
+
+	testServer.Tags = &mock.TagStore{
+		UpdateTagFn: func(userID, id int64, tag *teal.Tag) (*teal.Tag, error) {
+			return nil, teal.ErrDuplicateTag
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPut,
+		url:    "/api/tags/1",
+		data:   want,
+		params: map[string]string{"id": "1"},
+		fn:     testServer.UpdateTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "name", "this tag already exists, merge the tags instead")
+}
+
+func TestMergeTag(t *testing.T) {
+	want, err := util.ToJSON(map[string]int64{"into": 2})
+	checkErr(t, err)
+
+	testServer.Tags = &mock.TagStore{
+		MergeTagFn: func(userID, id, into int64) (*teal.Tag, error) {
+			return &teal.Tag{ID: into, Name: testTag2.Name, Count: 2}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/tags/1/merge/",
+		data:   want,
+		params: map[string]string{"id": "1"},
+		fn:     testServer.MergeTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Tag
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["tags"]
+	assertEqual(t, got.ID, 2)
+	assertEqual(t, got.Count, 2)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestMergeTagIntoItself(t *testing.T) {
+	want, err := util.ToJSON(map[string]int64{"into": 1})
+	checkErr(t, err)
+
+	testServer.Tags = &mock.TagStore{
+		MergeTagFn: func(userID, id, into int64) (*teal.Tag, error) {
+			return testTag1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/tags/1/merge/",
+		data:   want,
+		params: map[string]string{"id": "1"},
+		fn:     testServer.MergeTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "into", "cannot merge tag into itself")
+}
+
+func TestMergeTagNil(t *testing.T) {
+	want, err := util.ToJSON(map[string]int64{"into": 10})
+	checkErr(t, err)
+
+	testServer.Tags = &mock.TagStore{
+		MergeTagFn: func(userID, id, into int64) (*teal.Tag, error) {
+			return nil, teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/tags/1/merge/",
+		data:   want,
+		params: map[string]string{"id": "1"},
+		fn:     testServer.MergeTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestMergeTagRoute(t *testing.T) {
+	for _, path := range []string{"/api/tags/1/merge", "/api/tags/1/merge/"} {
+		assertRoute(t, http.MethodPost, path, "/api/tags/{id:[0-9]+}/merge")
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		DeleteTagFn: func(userID, id int64) error {
+			return nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/tags/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.DeleteTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusOK)
+}
+
+func TestDeleteTagNil(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		DeleteTagFn: func(userID, id int64) error {
+			return teal.ErrDoesNotExist
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/tags/10",
+		params: map[string]string{"id": "10"},
+		fn:     testServer.DeleteTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+}
+
+func TestDeleteTagShared(t *testing.T) {
+	testServer.Tags = &mock.TagStore{
+		DeleteTagFn: func(userID, id int64) error {
+			return teal.ErrSharedItem
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/tags/1",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.DeleteTag,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusConflict, "the item is used by books of other users")
+}
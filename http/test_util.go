@@ -8,9 +8,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/kencx/teal"
+	tcontext "github.com/kencx/teal/context"
 )
 
 var testServer = Server{
@@ -18,6 +21,29 @@ var testServer = Server{
 	ErrLog:  log.New(io.Discard, "", log.LstdFlags),
 }
 
+// authenticated user of requests made with testResponse
+var testAuthUser = &teal.User{ID: 1, Username: "johndoe"}
+
+// fail the test unless the route of path, with or without its trailing slash,
+// matches a request to path with the given method
+func assertRoute(t *testing.T, method, path, template string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, path, nil)
+	checkErr(t, err)
+
+	var match mux.RouteMatch
+	if !NewServer().Router.Match(req, &match) || match.MatchErr != nil {
+		t.Errorf("no route matches %s %s", method, path)
+		return
+	}
+	got, err := match.Route.GetPathTemplate()
+	checkErr(t, err)
+	if strings.TrimSuffix(got, "/") != strings.TrimSuffix(template, "/") {
+		t.Errorf("%s %s matched %s, want %s", method, path, got, template)
+	}
+}
+
 type testCase struct {
 	url     string
 	method  string
@@ -25,6 +51,8 @@ type testCase struct {
 	data    []byte
 	params  map[string]string
 	fn      func(http.ResponseWriter, *http.Request)
+	// authenticated user, defaults to testAuthUser
+	user *teal.User
 }
 
 func testResponse(t *testing.T, tc *testCase) (*httptest.ResponseRecorder, error) {
@@ -35,6 +63,15 @@ func testResponse(t *testing.T, tc *testCase) (*httptest.ResponseRecorder, error
 		return nil, err
 	}
 
+	user := tc.user
+	if user == nil {
+		user = testAuthUser
+	}
+	req = req.WithContext(tcontext.WithUser(req.Context(), user))
+	for k, v := range tc.headers {
+		req.Header.Add(k, v)
+	}
+
 	rw := httptest.NewRecorder()
 	if tc.params != nil {
 		req = mux.SetURLVars(req, tc.params)
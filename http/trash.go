@@ -0,0 +1,129 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/http/response"
+	"github.com/kencx/teal/util"
+	"github.com/kencx/teal/validator"
+)
+
+type TrashStore interface {
+	teal.TrashService
+}
+
+func (s *Server) GetTrash(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	books, err := s.Trash.GetAll(userID)
+	if err == teal.ErrNoRows {
+		s.InfoLog.Println("No books in trash")
+		response.NoContent(rw, r)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"books": books})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d books retrieved from trash", len(books))
+	response.OK(rw, r, res)
+}
+
+func (s *Server) RestoreBook(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+	id := HandleInt64("id", rw, r)
+	if id == -1 {
+		return
+	}
+
+	book, err := s.Trash.Restore(userID, id)
+	if err == teal.ErrDoesNotExist {
+		s.InfoLog.Printf("Book %d is not in trash", id)
+		response.NotFound(rw, r, err)
+		return
+
+	} else if err == teal.ErrDuplicateBook {
+		s.InfoLog.Printf("Book %d has the ISBN of another book", id)
+		response.Conflict(rw, r, err)
+		return
+
+	} else if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	res, err := util.ToJSON(response.Envelope{"books": book})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("Book %d restored from trash", id)
+	response.OK(rw, r, res)
+}
+
+// Permanently delete books in the trash. With older_than, only books deleted
+// more than the given number of days ago are purged
+func (s *Server) EmptyTrash(rw http.ResponseWriter, r *http.Request) {
+	userID := HandleUserID(rw, r)
+	if userID == -1 {
+		return
+	}
+
+	v := validator.New()
+	before := time.Now()
+	if days := readInt(r, "older_than", v); days != nil {
+		v.Check(*days >= 0, "older_than", "must be >= 0")
+		before = before.AddDate(0, 0, -*days)
+	}
+	if !v.Valid() {
+		response.ValidationError(rw, r, v.Errors)
+		return
+	}
+
+	count, err := s.Trash.Empty(userID, before)
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	// purged books may have been the last with their cover or files
+	if count > 0 {
+		if _, err := s.PruneCovers(); err != nil {
+			s.ErrLog.Printf("err: %v", err)
+		}
+		if _, err := s.PruneEbooks(); err != nil {
+			s.ErrLog.Printf("err: %v", err)
+		}
+	}
+
+	res, err := util.ToJSON(response.Envelope{"purged": count})
+	if err != nil {
+		s.ErrLog.Printf("err: %v", err)
+		response.InternalServerError(rw, r, err)
+		return
+	}
+
+	s.InfoLog.Printf("%d books purged from trash", count)
+	response.OK(rw, r, res)
+}
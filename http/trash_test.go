@@ -0,0 +1,173 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/covers"
+	"github.com/kencx/teal/ebook"
+	"github.com/kencx/teal/mock"
+)
+
+func TestGetTrash(t *testing.T) {
+	deleted := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	testServer.Trash = &mock.TrashStore{
+		GetTrashFn: func(userID int64) ([]*teal.Book, error) {
+			return []*teal.Book{{ID: 1, Title: testBook1.Title, DateDeleted: &deleted}}, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/trash/",
+		fn:     testServer.GetTrash,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string][]*teal.Book
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["books"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, len(got), 1)
+	assertEqual(t, got[0].Title, testBook1.Title)
+	assertEqual(t, *got[0].DateDeleted, deleted)
+}
+
+func TestGetTrashNil(t *testing.T) {
+	testServer.Trash = &mock.TrashStore{
+		GetTrashFn: func(userID int64) ([]*teal.Book, error) {
+			return nil, teal.ErrNoRows
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodGet,
+		url:    "/api/trash/",
+		fn:     testServer.GetTrash,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertEqual(t, w.Code, http.StatusNoContent)
+}
+
+func TestRestoreBook(t *testing.T) {
+	testServer.Trash = &mock.TrashStore{
+		RestoreFn: func(userID, id int64) (*teal.Book, error) {
+			if id == 10 {
+				return nil, teal.ErrDoesNotExist
+			}
+			if id == 11 {
+				return nil, teal.ErrDuplicateBook
+			}
+			return testBook1, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodPost,
+		url:    "/api/trash/1/restore",
+		params: map[string]string{"id": "1"},
+		fn:     testServer.RestoreBook,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]*teal.Book
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	got := env["books"]
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, got.Title, testBook1.Title)
+
+	tc = &testCase{
+		method: http.MethodPost,
+		url:    "/api/trash/10/restore",
+		params: map[string]string{"id": "10"},
+		fn:     testServer.RestoreBook,
+	}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusNotFound, "the item does not exist")
+
+	tc = &testCase{
+		method: http.MethodPost,
+		url:    "/api/trash/11/restore",
+		params: map[string]string{"id": "11"},
+		fn:     testServer.RestoreBook,
+	}
+	w, err = testResponse(t, tc)
+	checkErr(t, err)
+	assertResponseError(t, w, http.StatusConflict, "book with the same ISBN already exists")
+}
+
+func TestEmptyTrash(t *testing.T) {
+	var gotBefore time.Time
+	testServer.Trash = &mock.TrashStore{
+		EmptyTrashFn: func(userID int64, before time.Time) (int, error) {
+			gotBefore = before
+			return 2, nil
+		},
+	}
+	var pruned bool
+	testServer.Books = &mock.BookStore{
+		CoversFn: func() ([]string, error) {
+			pruned = true
+			return nil, nil
+		},
+	}
+	testServer.Covers = &covers.Store{Dir: t.TempDir()}
+	var prunedFiles bool
+	testServer.Files = &mock.FileStore{
+		ChecksumsFn: func() ([]string, error) {
+			prunedFiles = true
+			return nil, nil
+		},
+	}
+	testServer.Ebooks = &ebook.Store{Dir: t.TempDir()}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/trash/?older_than=7",
+		fn:     testServer.EmptyTrash,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+
+	var env map[string]int
+	err = json.NewDecoder(w.Body).Decode(&env)
+	checkErr(t, err)
+
+	assertEqual(t, w.Code, http.StatusOK)
+	assertEqual(t, env["purged"], 2)
+	assertEqual(t, pruned, true)
+	assertEqual(t, prunedFiles, true)
+
+	want := time.Now().AddDate(0, 0, -7)
+	if d := want.Sub(gotBefore); d < 0 || d > time.Minute {
+		t.Errorf("got before %v, want %v", gotBefore, want)
+	}
+}
+
+func TestEmptyTrashInvalid(t *testing.T) {
+	testServer.Trash = &mock.TrashStore{
+		EmptyTrashFn: func(userID int64, before time.Time) (int, error) {
+			return 0, nil
+		},
+	}
+
+	tc := &testCase{
+		method: http.MethodDelete,
+		url:    "/api/trash/?older_than=-1",
+		fn:     testServer.EmptyTrash,
+	}
+	w, err := testResponse(t, tc)
+	checkErr(t, err)
+	assertValidationError(t, w, "older_than", "must be >= 0")
+}
@@ -45,6 +45,7 @@ func (s *Server) GetUser(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("User %d retrieved: %v", id, u)
+	setETag(rw, u.Version)
 	response.OK(rw, r, res)
 }
 
@@ -71,6 +72,7 @@ func (s *Server) GetUserByUsername(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	s.InfoLog.Printf("User %q retrieved: %v", username, u)
+	setETag(rw, u.Version)
 	response.OK(rw, r, res)
 }
 
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/util"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The database file of a Calibre library
+const calibreDB = "metadata.db"
+
+type calibreBook struct {
+	ID          int64          `db:"id"`
+	Title       string         `db:"title"`
+	ISBN        sql.NullString `db:"isbn"`
+	SeriesIndex float64        `db:"series_index"`
+	Timestamp   sql.NullTime   `db:"timestamp"`
+}
+
+// a value of a table linked to books
+type calibreLink struct {
+	Book  int64  `db:"book"`
+	Value string `db:"value"`
+}
+
+// Read the books of a Calibre library. path is the metadata.db file or the
+// library directory that contains it. Books are numbered by their Calibre ID.
+//
+// Calibre's ratings are already out of 10. Comments are the description, with
+// HTML removed. The ISBN identifier is preferred over the ISBN field. Books have
+// no place for other identifiers, like goodreads or amazon, so they are listed
+// in the record's Skipped as type:value
+func ReadCalibre(path string) ([]*Record, error) {
+	if fi, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("calibre: %v", err)
+	} else if fi.IsDir() {
+		path = filepath.Join(path, calibreDB)
+	}
+
+	// the library is opened read-only, as a URI
+	uri := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+	db, err := sqlx.Open("sqlite3", "file:"+uri+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("calibre: failed to open: %v", err)
+	}
+	defer db.Close()
+
+	var books []calibreBook
+	if err := db.Select(&books, "SELECT id, title, isbn, series_index, timestamp FROM books ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("calibre: %v", err)
+	}
+
+	links := map[string]string{
+		"authors": `SELECT l.book, a.name AS value FROM books_authors_link l
+			JOIN authors a ON a.id=l.author ORDER BY l.id`,
+		"tags": `SELECT l.book, t.name AS value FROM books_tags_link l
+			JOIN tags t ON t.id=l.tag ORDER BY t.name`,
+		"series": `SELECT l.book, s.name AS value FROM books_series_link l
+			JOIN series s ON s.id=l.series`,
+		"ratings": `SELECT l.book, CAST(r.rating AS TEXT) AS value FROM books_ratings_link l
+			JOIN ratings r ON r.id=l.rating`,
+		"isbn": `SELECT book, val AS value FROM identifiers WHERE lower(type)='isbn'`,
+		"identifiers": `SELECT book, lower(type) || ':' || val AS value FROM identifiers
+			WHERE lower(type)<>'isbn' ORDER BY lower(type)`,
+		"comments": `SELECT book, text AS value FROM comments`,
+	}
+	values := make(map[string]map[int64][]string)
+	for name, query := range links {
+		var rows []calibreLink
+		if err := db.Select(&rows, query); err != nil {
+			return nil, fmt.Errorf("calibre: %s: %v", name, err)
+		}
+
+		values[name] = make(map[int64][]string)
+		for _, r := range rows {
+			values[name][r.Book] = append(values[name][r.Book], r.Value)
+		}
+	}
+
+	first := func(name string, id int64) string {
+		if v := values[name][id]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+
+	var records []*Record
+	for _, cb := range books {
+		rec := &Record{Row: int(cb.ID), Errors: make(map[string]string), Skipped: values["identifiers"][cb.ID]}
+		b := &teal.Book{
+			Title: strings.TrimSpace(cb.Title),
+			Tags:  values["tags"][cb.ID],
+			ISBN:  calibreISBN(first("isbn", cb.ID)),
+			State: teal.StateWantToRead,
+		}
+		if b.ISBN == "" {
+			b.ISBN = calibreISBN(cb.ISBN.String)
+		}
+
+		// commas in author names are stored as |
+		for _, a := range values["authors"][cb.ID] {
+			b.Author = append(b.Author, strings.ReplaceAll(a, "|", ","))
+		}
+
+		// unparseable timestamps are read as the zero time
+		if cb.Timestamp.Valid && !cb.Timestamp.Time.IsZero() {
+			b.DateAdded = sql.NullTime{Time: cb.Timestamp.Time.UTC(), Valid: true}
+		}
+
+		if s := first("series", cb.ID); s != "" {
+			b.Series = []teal.SeriesEntry{{Name: s, Position: cb.SeriesIndex}}
+		}
+		if s := first("ratings", cb.ID); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				rec.Errors["rating"] = "must be a number"
+			}
+			b.Rating = n
+		}
+		if s := util.HTMLText(first("comments", cb.ID)); s != "" {
+			b.Description = teal.NullString{NullString: sql.NullString{String: s, Valid: true}}
+		}
+
+		rec.Book = b
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// ISBNs may be formatted with hyphens or spaces
+func calibreISBN(s string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s))
+}
+
+// Read the books of an uploaded Calibre metadata.db. SQLite databases can only
+// be opened from a file, so r is copied to a temporary file first
+func ReadCalibreDB(r io.Reader) ([]*Record, error) {
+	f, err := os.CreateTemp("", "teal-calibre-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("calibre: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return nil, fmt.Errorf("calibre: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("calibre: %v", err)
+	}
+	return ReadCalibre(f.Name())
+}
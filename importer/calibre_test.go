@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// the tables of a Calibre library that are imported
+const calibreSchema = `
+CREATE TABLE books (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL DEFAULT 'Unknown' COLLATE NOCASE,
+	sort TEXT COLLATE NOCASE,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	pubdate TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	series_index REAL NOT NULL DEFAULT 1.0,
+	author_sort TEXT COLLATE NOCASE,
+	isbn TEXT DEFAULT "" COLLATE NOCASE,
+	lccn TEXT DEFAULT "" COLLATE NOCASE,
+	path TEXT NOT NULL DEFAULT "",
+	flags INTEGER NOT NULL DEFAULT 1,
+	uuid TEXT,
+	has_cover BOOL DEFAULT 0,
+	last_modified TIMESTAMP NOT NULL DEFAULT "2000-01-01 00:00:00+00:00"
+);
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL COLLATE NOCASE, sort TEXT COLLATE NOCASE, link TEXT NOT NULL DEFAULT "");
+CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, author INTEGER NOT NULL);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL COLLATE NOCASE);
+CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, tag INTEGER NOT NULL);
+CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT NOT NULL COLLATE NOCASE, sort TEXT COLLATE NOCASE);
+CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, series INTEGER NOT NULL);
+CREATE TABLE ratings (id INTEGER PRIMARY KEY, rating INTEGER CHECK(rating > -1 AND rating < 11));
+CREATE TABLE books_ratings_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, rating INTEGER NOT NULL);
+CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, type TEXT NOT NULL DEFAULT "isbn" COLLATE NOCASE, val TEXT NOT NULL COLLATE NOCASE);
+CREATE TABLE comments (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, text TEXT NOT NULL COLLATE NOCASE);
+
+INSERT INTO books (id, title, timestamp, series_index, isbn) VALUES
+	(1, 'Leviathan Wakes', '2019-01-02 10:11:12.123456+00:00', 1.0, ''),
+	(2, 'Caliban''s War', '2019-02-03 00:00:00+00:00', 2.0, '978-0-316-12906-0'),
+	(3, 'Untitled', '2020-01-01 00:00:00+00:00', 1.0, '');
+INSERT INTO authors (id, name) VALUES (1, 'James S.A. Corey'), (2, 'Abraham| Daniel'), (3, 'Ty Franck');
+INSERT INTO books_authors_link (book, author) VALUES (1, 1), (2, 3), (2, 2), (3, 1);
+INSERT INTO tags (id, name) VALUES (1, 'space'), (2, 'fiction');
+INSERT INTO books_tags_link (book, tag) VALUES (1, 1), (1, 2);
+INSERT INTO series (id, name) VALUES (1, 'The Expanse');
+INSERT INTO books_series_link (book, series) VALUES (1, 1), (2, 1);
+INSERT INTO ratings (id, rating) VALUES (1, 9), (2, 0);
+INSERT INTO books_ratings_link (book, rating) VALUES (1, 1), (2, 2);
+INSERT INTO identifiers (book, type, val) VALUES
+	(1, 'goodreads', '8855321'),
+	(1, 'ISBN', '9780316129084'),
+	(1, 'amazon', 'B0047Y171G');
+INSERT INTO comments (book, text) VALUES
+	(1, '<div><p>Humanity has colonized the solar system.</p><p>Ships &amp; stations.</p></div>');
+`
+
+func testCalibreLibrary(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	db, err := sqlx.Open("sqlite3", filepath.Join(dir, "metadata.db"))
+	checkErr(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(calibreSchema)
+	checkErr(t, err)
+	return dir
+}
+
+func TestReadCalibre(t *testing.T) {
+	dir := testCalibreLibrary(t)
+
+	for _, path := range []string{dir, filepath.Join(dir, "metadata.db")} {
+		got, err := ReadCalibre(path)
+		checkErr(t, err)
+		if len(got) != 3 {
+			t.Fatalf("got %d records, want 3", len(got))
+		}
+
+		b := got[0].Book
+		assertEqual(t, got[0].Row, 1)
+		assertEqual(t, b.Title, "Leviathan Wakes")
+		assertEqual(t, b.ISBN, "9780316129084")
+		assertEqual(t, b.Rating, 9)
+		assertEqual(t, b.State, teal.StateWantToRead)
+		assertEqual(t, b.Description.String, "Humanity has colonized the solar system.\nShips & stations.")
+		assertEqual(t, b.DateAdded.Time.Equal(time.Date(2019, 1, 2, 10, 11, 12, 123456000, time.UTC)), true)
+		assertSlice(t, b.Author, []string{"James S.A. Corey"})
+		assertSlice(t, b.Tags, []string{"fiction", "space"})
+		assertSlice(t, b.Series, []teal.SeriesEntry{{Name: "The Expanse", Position: 1}})
+		assertSlice(t, got[0].Skipped, []string{"amazon:B0047Y171G", "goodreads:8855321"})
+
+		// authors are in Calibre's order
+		b = got[1].Book
+		assertEqual(t, b.ISBN, "9780316129060")
+		assertEqual(t, b.Rating, 0)
+		assertEqual(t, b.Description.Valid, false)
+		assertSlice(t, b.Author, []string{"Ty Franck", "Abraham, Daniel"})
+		assertSlice(t, b.Series, []teal.SeriesEntry{{Name: "The Expanse", Position: 2}})
+		assertEqual(t, len(got[1].Skipped), 0)
+
+		b = got[2].Book
+		assertEqual(t, b.ISBN, "")
+		assertEqual(t, len(b.Tags), 0)
+		assertEqual(t, len(b.Series), 0)
+	}
+}
+
+func TestReadCalibreInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ReadCalibre(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Errorf("expected error for missing library")
+	}
+
+	// not a Calibre library
+	_, err = ReadCalibre(dir)
+	if err == nil {
+		t.Errorf("expected error for empty directory")
+	}
+}
+
+func TestReadCalibreDB(t *testing.T) {
+	dir := testCalibreLibrary(t)
+
+	f, err := os.Open(filepath.Join(dir, "metadata.db"))
+	checkErr(t, err)
+	defer f.Close()
+
+	got, err := ReadCalibreDB(f)
+	checkErr(t, err)
+	assertEqual(t, len(got), 3)
+}
+
+func TestImportCalibre(t *testing.T) {
+	records, err := ReadCalibre(testCalibreLibrary(t))
+	checkErr(t, err)
+
+	var created []*teal.Book
+	store := testStore(t, &created)
+	store.GetBookByISBNFn = func(userID int64, isbn string) (*teal.Book, error) {
+		if isbn == "9780316129060" {
+			return &teal.Book{ID: 5, ISBN: isbn}, nil
+		}
+		return nil, teal.ErrDoesNotExist
+	}
+
+	report, err := Import(store, 1, records, false)
+	checkErr(t, err)
+	assertEqual(t, report.New, 1)
+	assertEqual(t, report.Duplicates, 1)
+	assertEqual(t, report.Invalid, 1)
+	assertEqual(t, report.Results[1].ID, 5)
+	assertSlice(t, report.Results[0].Skipped, []string{"amazon:B0047Y171G", "goodreads:8855321"})
+	assertEqual(t, created[0].Description.Valid, true)
+}
+
+func assertSlice[T any](t *testing.T, got, want []T) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
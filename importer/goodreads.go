@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+// Goodreads dates are formatted as 2006/01/02
+const goodreadsDate = "2006/01/02"
+
+// Goodreads columns that are imported
+var goodreadsColumns = []string{
+	"Title",
+	"Author",
+	"Additional Authors",
+	"ISBN",
+	"ISBN13",
+	"My Rating",
+	"Number of Pages",
+	"Date Read",
+	"Date Added",
+	"Exclusive Shelf",
+}
+
+// Goodreads exclusive shelves and their reading state. Other shelves are
+// want-to-read
+var goodreadsShelves = map[string]string{
+	"read":              teal.StateRead,
+	"currently-reading": teal.StateReading,
+	"to-read":           teal.StateWantToRead,
+	"did-not-finish":    teal.StateDidNotFinish,
+	"dnf":               teal.StateDidNotFinish,
+	"on-hold":           teal.StateOnHold,
+}
+
+// Read the books of a Goodreads library export. Rows are numbered as in a
+// spreadsheet, the header being row 1.
+//
+// ISBN13 is preferred over ISBN, ratings out of 5 are doubled, and the date
+// read is the completion date of read books
+func ReadGoodreads(r io.Reader) ([]*Record, error) {
+	cr := csv.NewReader(r)
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("goodreads: file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("goodreads: %v", err)
+	}
+
+	cols := make(map[string]int)
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, name := range goodreadsColumns {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("goodreads: missing column %q", name)
+		}
+	}
+
+	var records []*Record
+	for row := 2; ; row++ {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("goodreads: %v", err)
+		}
+
+		get := func(name string) string {
+			return strings.TrimSpace(fields[cols[name]])
+		}
+		records = append(records, readGoodreadsRow(row, get))
+	}
+	return records, nil
+}
+
+func readGoodreadsRow(row int, get func(string) string) *Record {
+	rec := &Record{Row: row, Errors: make(map[string]string)}
+	b := &teal.Book{
+		Title: get("Title"),
+		ISBN:  goodreadsISBN(get("ISBN13")),
+	}
+	if b.ISBN == "" {
+		b.ISBN = goodreadsISBN(get("ISBN"))
+	}
+
+	if a := get("Author"); a != "" {
+		b.Author = append(b.Author, a)
+	}
+	for _, a := range strings.Split(get("Additional Authors"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			b.Author = append(b.Author, a)
+		}
+	}
+
+	if s := get("Number of Pages"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			rec.Errors["numOfPages"] = "must be a number"
+		}
+		b.NumOfPages = n
+	}
+	if s := get("My Rating"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 5 {
+			rec.Errors["rating"] = "must be a number from 0 to 5"
+		}
+		b.Rating = n * 2
+	}
+
+	b.State = teal.StateWantToRead
+	if state, ok := goodreadsShelves[get("Exclusive Shelf")]; ok {
+		b.State = state
+	}
+
+	added, err := goodreadsTime(get("Date Added"))
+	if err != nil {
+		rec.Errors["dateAdded"] = "incorrect format"
+	}
+	b.DateAdded = added
+
+	if b.State == teal.StateRead {
+		read, err := goodreadsTime(get("Date Read"))
+		if err != nil {
+			rec.Errors["dateCompleted"] = "incorrect format"
+		}
+		b.DateCompleted = read
+	}
+
+	rec.Book = b
+	return rec
+}
+
+// ISBNs are exported as formulas, e.g. ="0345391802"
+func goodreadsISBN(s string) string {
+	return strings.Trim(s, `="`)
+}
+
+func goodreadsTime(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.ParseInLocation(goodreadsDate, s, time.UTC)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
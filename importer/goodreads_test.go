@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func TestReadGoodreads(t *testing.T) {
+	f, err := os.Open("testdata/goodreads_library_export.csv")
+	checkErr(t, err)
+	defer f.Close()
+
+	got, err := ReadGoodreads(f)
+	checkErr(t, err)
+	if len(got) != 5 {
+		t.Fatalf("got %d records, want 5", len(got))
+	}
+
+	// rows are numbered from the header, multi-line fields are one row
+	assertEqual(t, got[0].Row, 2)
+	assertEqual(t, got[1].Row, 3)
+
+	b := got[0].Book
+	assertEqual(t, b.Title, "Leviathan Wakes")
+	assertEqual(t, b.ISBN, "9780316129084")
+	assertEqual(t, b.NumOfPages, 561)
+	assertEqual(t, b.Rating, 10)
+	assertEqual(t, b.State, teal.StateRead)
+	assertEqual(t, b.DateCompleted.Time, time.Date(2019, 5, 24, 0, 0, 0, 0, time.UTC))
+	assertEqual(t, b.DateAdded.Time, time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC))
+	if !reflect.DeepEqual(b.Author, []string{"James S.A. Corey"}) {
+		t.Errorf("got %v, want %v", b.Author, []string{"James S.A. Corey"})
+	}
+
+	b = got[1].Book
+	assertEqual(t, b.ISBN, "9780345539786")
+	assertEqual(t, b.Rating, 0)
+	assertEqual(t, b.State, teal.StateWantToRead)
+	assertEqual(t, b.DateCompleted.Valid, false)
+
+	// ISBN is used without ISBN13
+	b = got[2].Book
+	assertEqual(t, b.ISBN, "1484200772")
+	assertEqual(t, b.State, teal.StateReading)
+	want := []string{"Scott Chacon", "Ben Straub", "Someone Else"}
+	if !reflect.DeepEqual(b.Author, want) {
+		t.Errorf("got %v, want %v", b.Author, want)
+	}
+
+	assertEqual(t, got[3].Book.ISBN, "")
+	assertEqual(t, got[3].Book.State, teal.StateDidNotFinish)
+	assertEqual(t, got[3].Errors["numOfPages"], "must be a number")
+}
+
+func TestReadGoodreadsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "empty", data: "", err: "goodreads: file is empty"},
+		{name: "missing column", data: "Title,Author\nfoo,bar\n", err: `goodreads: missing column "Additional Authors"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadGoodreads(strings.NewReader(tt.data))
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package importer adds books from the exports of other applications to a
+// user's library
+package importer
+
+import (
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/validator"
+)
+
+// Books of a user's library that imported books are added to
+type BookStore interface {
+	GetByISBN(userID int64, isbn string) (*teal.Book, error)
+	Create(userID int64, b *teal.Book) (*teal.Book, error)
+}
+
+// Status of an imported book
+const (
+	StatusNew       = "new"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+	StatusFailed    = "failed"
+)
+
+// A book read from an export. Errors holds the fields that could not be read,
+// Skipped the values that were read but cannot be stored, like identifiers of
+// other sites
+type Record struct {
+	Row     int
+	Book    *teal.Book
+	Errors  map[string]string
+	Skipped []string
+}
+
+// The outcome of importing a single book
+type Result struct {
+	Row     int               `json:"row"`
+	Title   string            `json:"title"`
+	ISBN    string            `json:"isbn"`
+	Status  string            `json:"status"`
+	ID      int64             `json:"id,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+	Skipped []string          `json:"skipped,omitempty"`
+}
+
+// The outcome of an import. In a dry run, new books are reported but not
+// created
+type Report struct {
+	DryRun     bool      `json:"dry_run"`
+	New        int       `json:"new"`
+	Duplicates int       `json:"duplicates"`
+	Invalid    int       `json:"invalid"`
+	Failed     int       `json:"failed"`
+	Results    []*Result `json:"books"`
+}
+
+// Add the books of records to a user's library. Books with the ISBN of an
+// existing book, or of an earlier record, in either form, are skipped as
+// duplicates. Books that fail Book.Validate are rejected. Books that cannot be
+// created are reported as failed, and the import continues
+func Import(store BookStore, userID int64, records []*Record, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun, Results: []*Result{}}
+	seen := make(map[string]bool)
+
+	for _, rec := range records {
+		b := rec.Book
+		res := &Result{Row: rec.Row, Title: b.Title, ISBN: b.ISBN, Skipped: rec.Skipped}
+		report.Results = append(report.Results, res)
+
+		v := validator.New()
+		for k, msg := range rec.Errors {
+			v.AddError(k, msg)
+		}
+		b.Validate(v)
+		if !v.Valid() {
+			res.Status, res.Errors = StatusInvalid, v.Errors
+			report.Invalid++
+			continue
+		}
+
+		// the same book may be listed by its ISBN-10 and ISBN-13
+		isbn := teal.NormalizeISBN(b.ISBN)
+		if seen[isbn] {
+			res.Status = StatusDuplicate
+			report.Duplicates++
+			continue
+		}
+		seen[isbn] = true
+
+		existing, err := store.GetByISBN(userID, b.ISBN)
+		if err == nil {
+			res.Status, res.ID = StatusDuplicate, existing.ID
+			report.Duplicates++
+			continue
+		}
+		if err != teal.ErrDoesNotExist {
+			return nil, err
+		}
+
+		if !dryRun {
+			created, err := store.Create(userID, b)
+			if err != nil {
+				res.Status, res.Errors = StatusFailed, map[string]string{"error": err.Error()}
+				report.Failed++
+				continue
+			}
+			res.ID = created.ID
+		}
+		res.Status = StatusNew
+		report.New++
+	}
+	return report, nil
+}
@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/mock"
+)
+
+func testStore(t *testing.T, created *[]*teal.Book) *mock.BookStore {
+	return &mock.BookStore{
+		GetBookByISBNFn: func(userID int64, isbn string) (*teal.Book, error) {
+			if isbn == "9780345539786" {
+				return &teal.Book{ID: 2, ISBN: isbn}, nil
+			}
+			return nil, teal.ErrDoesNotExist
+		},
+		CreateBookFn: func(userID int64, b *teal.Book) (*teal.Book, error) {
+			if b.ISBN == "1484200772" {
+				return nil, errors.New("db: insert to books table failed")
+			}
+			*created = append(*created, b)
+			b.ID = int64(len(*created) + 10)
+			return b, nil
+		},
+	}
+}
+
+func readTestRecords(t *testing.T) []*Record {
+	f, err := os.Open("testdata/goodreads_library_export.csv")
+	checkErr(t, err)
+	defer f.Close()
+
+	records, err := ReadGoodreads(f)
+	checkErr(t, err)
+	return records
+}
+
+func TestImport(t *testing.T) {
+	var created []*teal.Book
+	report, err := Import(testStore(t, &created), 1, readTestRecords(t), false)
+	checkErr(t, err)
+
+	assertEqual(t, report.DryRun, false)
+	assertEqual(t, report.New, 1)
+	assertEqual(t, report.Duplicates, 2)
+	assertEqual(t, report.Invalid, 1)
+	assertEqual(t, report.Failed, 1)
+	assertEqual(t, len(created), 1)
+
+	want := []struct {
+		status string
+		id     int64
+	}{
+		{StatusNew, 11},
+		{StatusDuplicate, 2},
+		{StatusFailed, 0},
+		{StatusInvalid, 0},
+		{StatusDuplicate, 0},
+	}
+	for i, w := range want {
+		assertEqual(t, report.Results[i].Status, w.status)
+		assertEqual(t, report.Results[i].ID, w.id)
+	}
+
+	invalid := report.Results[3].Errors
+	assertEqual(t, invalid["isbn"], "value is missing")
+	assertEqual(t, invalid["numOfPages"], "must be a number")
+}
+
+func TestImportDryRun(t *testing.T) {
+	var created []*teal.Book
+	report, err := Import(testStore(t, &created), 1, readTestRecords(t), true)
+	checkErr(t, err)
+
+	assertEqual(t, report.DryRun, true)
+	// books that would fail to be created are only found on import
+	assertEqual(t, report.New, 2)
+	assertEqual(t, report.Failed, 0)
+	assertEqual(t, len(created), 0)
+}
+
+func TestImportStoreError(t *testing.T) {
+	store := &mock.BookStore{
+		GetBookByISBNFn: func(userID int64, isbn string) (*teal.Book, error) {
+			return nil, errors.New("db: failed")
+		},
+	}
+	_, err := Import(store, 1, readTestRecords(t), false)
+	if err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestImportISBNForms(t *testing.T) {
+	var created []*teal.Book
+	records := []*Record{
+		{Row: 2, Book: &teal.Book{Title: "Leviathan Wakes", Author: []string{"James S.A. Corey"}, ISBN: "0-316-12908-9"}},
+		{Row: 3, Book: &teal.Book{Title: "Leviathan Wakes", Author: []string{"James S.A. Corey"}, ISBN: "9780316129084"}},
+		{Row: 4, Book: &teal.Book{Title: "Leviathan Wakes", Author: []string{"James S.A. Corey"}, ISBN: "9780316129085"}},
+	}
+
+	report, err := Import(testStore(t, &created), 1, records, false)
+	checkErr(t, err)
+	assertEqual(t, report.New, 1)
+	assertEqual(t, report.Duplicates, 1)
+	assertEqual(t, report.Invalid, 1)
+	assertEqual(t, report.Results[2].Errors["isbn"], "incorrect check digit")
+}
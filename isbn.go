@@ -0,0 +1,95 @@
+package teal
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrISBNFormat   = errors.New("incorrect format")
+	ErrISBNChecksum = errors.New("incorrect check digit")
+)
+
+// Parse an ISBN-10 or ISBN-13, with or without hyphens and spaces, and return
+// it as an ISBN-13. The check digit of either form must be correct
+func ParseISBN(s string) (string, error) {
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+
+	switch len(isbn) {
+	case 10:
+		if !isDigits(isbn[:9]) || !(isDigits(isbn[9:]) || isbn[9] == 'X') {
+			return "", ErrISBNFormat
+		}
+		if isbn10CheckDigit(isbn[:9]) != isbn[9] {
+			return "", ErrISBNChecksum
+		}
+		isbn13 := "978" + isbn[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+
+	case 13:
+		if !isDigits(isbn) || !(strings.HasPrefix(isbn, "978") || strings.HasPrefix(isbn, "979")) {
+			return "", ErrISBNFormat
+		}
+		if isbn13CheckDigit(isbn[:12]) != isbn[12] {
+			return "", ErrISBNChecksum
+		}
+		return isbn, nil
+
+	default:
+		return "", ErrISBNFormat
+	}
+}
+
+// Normalize an ISBN to ISBN-13. Invalid ISBNs are returned unchanged
+func NormalizeISBN(s string) string {
+	isbn, err := ParseISBN(s)
+	if err != nil {
+		return s
+	}
+	return isbn
+}
+
+// The ISBN-10 form of an ISBN, or an empty string if it has none. Only
+// ISBN-13s with the 978 prefix have an ISBN-10
+func ISBN10(s string) string {
+	isbn, err := ParseISBN(s)
+	if err != nil || !strings.HasPrefix(isbn, "978") {
+		return ""
+	}
+	return isbn[3:12] + string(isbn10CheckDigit(isbn[3:12]))
+}
+
+// check digit of the first 9 digits of an ISBN-10
+func isbn10CheckDigit(s string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(s[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// check digit of the first 12 digits of an ISBN-13
+func isbn13CheckDigit(s string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package teal
+
+import "testing"
+
+func TestParseISBN(t *testing.T) {
+	tests := []struct {
+		name   string
+		isbn   string
+		want   string
+		isbn10 string
+		err    error
+	}{
+		{name: "isbn13", isbn: "9780316129084", want: "9780316129084", isbn10: "0316129089"},
+		{name: "isbn13 hyphens", isbn: "978-0-316-12908-4", want: "9780316129084", isbn10: "0316129089"},
+		{name: "isbn10", isbn: "0316129089", want: "9780316129084", isbn10: "0316129089"},
+		{name: "isbn10 spaces", isbn: " 0 316 12908 9 ", want: "9780316129084", isbn10: "0316129089"},
+		{name: "isbn10 check digit X", isbn: "080442957X", want: "9780804429573", isbn10: "080442957X"},
+		{name: "isbn10 lower case x", isbn: "0-8044-2957-x", want: "9780804429573", isbn10: "080442957X"},
+		{name: "979 prefix", isbn: "979-10-90636-07-1", want: "9791090636071", isbn10: ""},
+		{name: "isbn13 checksum", isbn: "9780316129085", err: ErrISBNChecksum},
+		{name: "isbn10 checksum", isbn: "0316129088", err: ErrISBNChecksum},
+		{name: "letters", isbn: "abc1", err: ErrISBNFormat},
+		{name: "X in isbn13", isbn: "978031612908X", err: ErrISBNFormat},
+		{name: "X not last", isbn: "03161X9089", err: ErrISBNFormat},
+		{name: "short", isbn: "100", err: ErrISBNFormat},
+		{name: "prefix", isbn: "9770316129084", err: ErrISBNFormat},
+		{name: "empty", isbn: "", err: ErrISBNFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseISBN(tt.isbn)
+			if err != tt.err {
+				t.Fatalf("got err %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if got := ISBN10(tt.isbn); got != tt.isbn10 {
+				t.Errorf("got isbn10 %q, want %q", got, tt.isbn10)
+			}
+		})
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	if got := NormalizeISBN("0-316-12908-9"); got != "9780316129084" {
+		t.Errorf("got %q, want %q", got, "9780316129084")
+	}
+	// invalid ISBNs are unchanged
+	if got := NormalizeISBN("12-3"); got != "12-3" {
+		t.Errorf("got %q, want %q", got, "12-3")
+	}
+}
@@ -0,0 +1,53 @@
+package teal
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Details of a book from a metadata provider, by ISBN-13
+type Metadata struct {
+	ISBN        string    `json:"isbn"`
+	Provider    string    `json:"provider"`
+	Title       string    `json:"title"`
+	Author      []string  `json:"author"`
+	Description string    `json:"description,omitempty"`
+	NumOfPages  int       `json:"num_of_pages,omitempty"`
+	CoverURL    string    `json:"cover_url,omitempty"`
+	DateAdded   time.Time `json:"-"`
+}
+
+// A book prefilled with the metadata
+func (m *Metadata) Book() *Book {
+	b := &Book{ISBN: m.ISBN}
+	m.Enrich(b)
+	return b
+}
+
+// Fill the fields of b that are not set with the metadata
+func (m *Metadata) Enrich(b *Book) {
+	if b.Title == "" {
+		b.Title = m.Title
+	}
+	if len(b.Author) == 0 && len(m.Author) > 0 {
+		b.Author = append([]string{}, m.Author...)
+	}
+	if !b.Description.Valid && m.Description != "" {
+		b.Description = NullString{NullString: sql.NullString{String: m.Description, Valid: true}}
+	}
+	if b.NumOfPages == 0 {
+		b.NumOfPages = m.NumOfPages
+	}
+}
+
+// A source of book metadata, such as Open Library. Lookup returns
+// ErrDoesNotExist if the provider has no book with the ISBN
+type MetadataProvider interface {
+	Name() string
+	Lookup(isbn string) (*Metadata, error)
+}
+
+// Looks up book metadata from providers in order of priority
+type MetadataService interface {
+	Lookup(isbn string) (*Metadata, error)
+}
@@ -0,0 +1,79 @@
+package metadata
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kencx/teal"
+)
+
+const (
+	GoogleBooksName = "googlebooks"
+	googleBooksURL  = "https://www.googleapis.com"
+)
+
+// Looks up books with the Google Books API. The API key is optional, requests
+// without one have a lower quota
+type GoogleBooks struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+func NewGoogleBooks(apiKey string) *GoogleBooks {
+	return &GoogleBooks{BaseURL: googleBooksURL, APIKey: apiKey, Client: newClient()}
+}
+
+func (g *GoogleBooks) Name() string {
+	return GoogleBooksName
+}
+
+type googleBooksVolumes struct {
+	TotalItems int `json:"totalItems"`
+	Items      []struct {
+		VolumeInfo struct {
+			Title       string   `json:"title"`
+			Subtitle    string   `json:"subtitle"`
+			Authors     []string `json:"authors"`
+			Description string   `json:"description"`
+			PageCount   int      `json:"pageCount"`
+			ImageLinks  struct {
+				SmallThumbnail string `json:"smallThumbnail"`
+				Thumbnail      string `json:"thumbnail"`
+			} `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+func (g *GoogleBooks) Lookup(isbn string) (*teal.Metadata, error) {
+	q := url.Values{"q": {"isbn:" + isbn}}
+	if g.APIKey != "" {
+		q.Set("key", g.APIKey)
+	}
+
+	var res googleBooksVolumes
+	if err := getJSON(g.Client, g.BaseURL+"/books/v1/volumes?"+q.Encode(), &res); err != nil {
+		return nil, fmt.Errorf("googlebooks: %v", err)
+	}
+	if res.TotalItems == 0 || len(res.Items) == 0 {
+		return nil, teal.ErrDoesNotExist
+	}
+
+	v := res.Items[0].VolumeInfo
+	cover := v.ImageLinks.Thumbnail
+	if cover == "" {
+		cover = v.ImageLinks.SmallThumbnail
+	}
+	return &teal.Metadata{
+		ISBN:        isbn,
+		Provider:    GoogleBooksName,
+		Title:       joinTitle(v.Title, v.Subtitle),
+		Author:      v.Authors,
+		Description: strings.TrimSpace(v.Description),
+		NumOfPages:  v.PageCount,
+		// image links are returned with http
+		CoverURL: strings.Replace(cover, "http://", "https://", 1),
+	}, nil
+}
@@ -0,0 +1,144 @@
+// Package metadata looks up book details by ISBN from external providers such
+// as Open Library and Google Books
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+const (
+	// timeout of requests to providers
+	requestTimeout = 10 * time.Second
+	// maximum size of provider responses
+	maxResponseBytes = 2 << 20
+
+	// DefaultMaxAge is how long cached metadata is used before it is looked
+	// up again
+	DefaultMaxAge = 30 * 24 * time.Hour
+)
+
+// Providers in their default order of priority
+var DefaultProviders = []string{OpenLibraryName, GoogleBooksName}
+
+// Metadata that was looked up before
+type Cache interface {
+	Get(isbn string) (*teal.Metadata, error)
+	Put(m *teal.Metadata) error
+}
+
+// Looks up metadata from providers in order of priority. The first provider
+// that has the book is used. Results are cached for MaxAge
+type Lookup struct {
+	Providers []teal.MetadataProvider
+	Cache     Cache
+	MaxAge    time.Duration
+}
+
+// Options of the providers that need them
+type Config struct {
+	GoogleBooksKey string
+}
+
+// Create the providers with the given names, in order of priority
+func NewProviders(names []string, c Config) ([]teal.MetadataProvider, error) {
+	var providers []teal.MetadataProvider
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case OpenLibraryName:
+			providers = append(providers, NewOpenLibrary())
+		case GoogleBooksName:
+			providers = append(providers, NewGoogleBooks(c.GoogleBooksKey))
+		case "":
+		default:
+			return nil, fmt.Errorf("metadata: unknown provider %q, must be one of %s", name, strings.Join(DefaultProviders, ", "))
+		}
+	}
+	return providers, nil
+}
+
+// Look up the metadata of an ISBN-10 or ISBN-13. Returns teal.ErrDoesNotExist
+// if no provider has the book. If a provider fails, the next provider is
+// tried, and the error is only returned if none of them has the book
+func (l *Lookup) Lookup(isbn string) (*teal.Metadata, error) {
+	isbn, err := teal.ParseISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Cache != nil {
+		m, err := l.Cache.Get(isbn)
+		if err == nil && time.Since(m.DateAdded) < l.MaxAge {
+			return m, nil
+		}
+		if err != nil && err != teal.ErrDoesNotExist {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, p := range l.Providers {
+		m, err := p.Lookup(isbn)
+		if err == teal.ErrDoesNotExist {
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if l.Cache != nil {
+			if err := l.Cache.Put(m); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, teal.ErrDoesNotExist
+}
+
+func newClient() *http.Client {
+	return &http.Client{Timeout: requestTimeout}
+}
+
+// GET url and decode the JSON response into dest
+func getJSON(client *http.Client, url string, dest interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "teal")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseBytes)).Decode(dest); err != nil {
+		return fmt.Errorf("invalid response: %v", err)
+	}
+	return nil
+}
+
+func joinTitle(title, subtitle string) string {
+	title, subtitle = strings.TrimSpace(title), strings.TrimSpace(subtitle)
+	if subtitle == "" {
+		return title
+	}
+	return title + ": " + subtitle
+}
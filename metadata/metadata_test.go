@@ -0,0 +1,243 @@
+package metadata
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+const testISBN = "9780316129084"
+
+// a fake provider API that serves the file at path for requests for testISBN
+func testProviderServer(t *testing.T, path, param, value, notFound string) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	checkErr(t, err)
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+		rw.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get(param) != value {
+			rw.Write([]byte(notFound))
+			return
+		}
+		rw.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func testOpenLibrary(t *testing.T) (*OpenLibrary, *[]string) {
+	srv, requests := testProviderServer(t, "testdata/openlibrary.json", "bibkeys", "ISBN:"+testISBN, "{}")
+	return &OpenLibrary{BaseURL: srv.URL, Client: srv.Client()}, requests
+}
+
+func testGoogleBooks(t *testing.T) (*GoogleBooks, *[]string) {
+	srv, requests := testProviderServer(t, "testdata/googlebooks.json", "q", "isbn:"+testISBN, `{"kind": "books#volumes", "totalItems": 0}`)
+	return &GoogleBooks{BaseURL: srv.URL, APIKey: "secret", Client: srv.Client()}, requests
+}
+
+func TestOpenLibrary(t *testing.T) {
+	p, requests := testOpenLibrary(t)
+
+	got, err := p.Lookup(testISBN)
+	checkErr(t, err)
+
+	want := &teal.Metadata{
+		ISBN:        testISBN,
+		Provider:    OpenLibraryName,
+		Title:       "Leviathan Wakes: The Expanse",
+		Author:      []string{"James S.A. Corey"},
+		Description: "Humanity has colonized the solar system.",
+		NumOfPages:  561,
+		CoverURL:    "https://covers.openlibrary.org/b/id/6621086-L.jpg",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	assertEqual(t, (*requests)[0], "/api/books?bibkeys=ISBN%3A9780316129084&format=json&jscmd=details")
+
+	_, err = p.Lookup("9780441013593")
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestOpenLibraryText(t *testing.T) {
+	assertEqual(t, openLibraryText([]byte(`"foo "`)), "foo")
+	assertEqual(t, openLibraryText([]byte(`{"type": "/type/text", "value": "bar"}`)), "bar")
+	assertEqual(t, openLibraryText(nil), "")
+}
+
+func TestGoogleBooks(t *testing.T) {
+	p, requests := testGoogleBooks(t)
+
+	got, err := p.Lookup(testISBN)
+	checkErr(t, err)
+
+	want := &teal.Metadata{
+		ISBN:        testISBN,
+		Provider:    GoogleBooksName,
+		Title:       "Leviathan Wakes",
+		Author:      []string{"James S. A. Corey"},
+		Description: "Humanity has colonized the solar system.",
+		NumOfPages:  592,
+		CoverURL:    "https://books.google.com/books/content?id=yud-foLDmmIC&printsec=frontcover&img=1&zoom=1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	assertEqual(t, (*requests)[0], "/books/v1/volumes?key=secret&q=isbn%3A9780316129084")
+
+	_, err = p.Lookup("9780441013593")
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.Error(rw, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p := &OpenLibrary{BaseURL: srv.URL, Client: srv.Client()}
+	_, err := p.Lookup(testISBN)
+	if err == nil || err == teal.ErrDoesNotExist {
+		t.Errorf("got %v, want provider error", err)
+	}
+}
+
+// a provider that records lookups
+type fakeProvider struct {
+	name    string
+	result  *teal.Metadata
+	err     error
+	lookups int
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Lookup(isbn string) (*teal.Metadata, error) {
+	p.lookups++
+	if p.err != nil {
+		return nil, p.err
+	}
+	m := *p.result
+	m.ISBN = isbn
+	return &m, nil
+}
+
+type fakeCache map[string]*teal.Metadata
+
+func (c fakeCache) Get(isbn string) (*teal.Metadata, error) {
+	m, ok := c[isbn]
+	if !ok {
+		return nil, teal.ErrDoesNotExist
+	}
+	return m, nil
+}
+
+func (c fakeCache) Put(m *teal.Metadata) error {
+	m.DateAdded = time.Now()
+	c[m.ISBN] = m
+	return nil
+}
+
+func TestLookup(t *testing.T) {
+	failing := &fakeProvider{name: "failing", err: errors.New("unavailable")}
+	missing := &fakeProvider{name: "missing", err: teal.ErrDoesNotExist}
+	found := &fakeProvider{name: "found", result: &teal.Metadata{Provider: "found", Title: "Leviathan Wakes"}}
+	last := &fakeProvider{name: "last", result: &teal.Metadata{Provider: "last"}}
+	cache := fakeCache{}
+
+	l := &Lookup{
+		Providers: []teal.MetadataProvider{failing, missing, found, last},
+		Cache:     cache,
+		MaxAge:    time.Hour,
+	}
+
+	// ISBN-10s are looked up as ISBN-13
+	got, err := l.Lookup("0-316-12908-9")
+	checkErr(t, err)
+	assertEqual(t, got.Provider, "found")
+	assertEqual(t, got.ISBN, testISBN)
+	assertEqual(t, found.lookups, 1)
+	assertEqual(t, last.lookups, 0)
+	assertEqual(t, cache[testISBN].Title, "Leviathan Wakes")
+
+	// cached metadata is used until it is older than MaxAge
+	_, err = l.Lookup(testISBN)
+	checkErr(t, err)
+	assertEqual(t, found.lookups, 1)
+
+	cache[testISBN].DateAdded = time.Now().Add(-2 * time.Hour)
+	_, err = l.Lookup(testISBN)
+	checkErr(t, err)
+	assertEqual(t, found.lookups, 2)
+
+	_, err = l.Lookup("abc")
+	if err != teal.ErrISBNFormat {
+		t.Errorf("got %v, want %v", err, teal.ErrISBNFormat)
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	missing := &fakeProvider{name: "missing", err: teal.ErrDoesNotExist}
+	failing := &fakeProvider{name: "failing", err: errors.New("unavailable")}
+
+	l := &Lookup{Providers: []teal.MetadataProvider{missing}}
+	_, err := l.Lookup(testISBN)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	// provider errors are returned if no provider has the book
+	l.Providers = append(l.Providers, failing)
+	_, err = l.Lookup(testISBN)
+	if err == nil || err == teal.ErrDoesNotExist {
+		t.Errorf("got %v, want provider error", err)
+	}
+}
+
+func TestNewProviders(t *testing.T) {
+	got, err := NewProviders([]string{"googlebooks", " openlibrary"}, Config{GoogleBooksKey: "secret"})
+	checkErr(t, err)
+	assertEqual(t, len(got), 2)
+	assertEqual(t, got[0].Name(), GoogleBooksName)
+	assertEqual(t, got[0].(*GoogleBooks).APIKey, "secret")
+	assertEqual(t, got[1].Name(), OpenLibraryName)
+
+	got, err = NewProviders([]string{""}, Config{})
+	checkErr(t, err)
+	assertEqual(t, len(got), 0)
+
+	_, err = NewProviders([]string{"amazon"}, Config{})
+	if err == nil {
+		t.Errorf("expected error for unknown provider")
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kencx/teal"
+)
+
+const (
+	OpenLibraryName  = "openlibrary"
+	openLibraryURL   = "https://openlibrary.org"
+	openLibraryCover = "https://covers.openlibrary.org/b/id/%d-L.jpg"
+)
+
+// Looks up books with the Open Library Books API
+type OpenLibrary struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewOpenLibrary() *OpenLibrary {
+	return &OpenLibrary{BaseURL: openLibraryURL, Client: newClient()}
+}
+
+func (o *OpenLibrary) Name() string {
+	return OpenLibraryName
+}
+
+// an edition in the response of the Books API with jscmd=details
+type openLibraryBook struct {
+	Details struct {
+		Title    string `json:"title"`
+		Subtitle string `json:"subtitle"`
+		Authors  []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		// a string or a text object
+		Description   json.RawMessage `json:"description"`
+		NumberOfPages int             `json:"number_of_pages"`
+		Covers        []int           `json:"covers"`
+	} `json:"details"`
+}
+
+func (o *OpenLibrary) Lookup(isbn string) (*teal.Metadata, error) {
+	key := "ISBN:" + isbn
+	q := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"details"}}
+
+	var res map[string]openLibraryBook
+	if err := getJSON(o.Client, o.BaseURL+"/api/books?"+q.Encode(), &res); err != nil {
+		return nil, fmt.Errorf("openlibrary: %v", err)
+	}
+	book, ok := res[key]
+	if !ok {
+		return nil, teal.ErrDoesNotExist
+	}
+
+	d := book.Details
+	m := &teal.Metadata{
+		ISBN:        isbn,
+		Provider:    OpenLibraryName,
+		Title:       joinTitle(d.Title, d.Subtitle),
+		Description: openLibraryText(d.Description),
+		NumOfPages:  d.NumberOfPages,
+	}
+	for _, a := range d.Authors {
+		m.Author = append(m.Author, a.Name)
+	}
+	// negative IDs are placeholders
+	if len(d.Covers) > 0 && d.Covers[0] > 0 {
+		m.CoverURL = fmt.Sprintf(openLibraryCover, d.Covers[0])
+	}
+	return m, nil
+}
+
+// Open Library text fields are strings or {"type": "/type/text", "value": ""}
+func openLibraryText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	var text struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strings.TrimSpace(text.Value)
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package teal
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataEnrich(t *testing.T) {
+	m := &Metadata{
+		ISBN:        "9780316129084",
+		Title:       "Leviathan Wakes",
+		Author:      []string{"James S.A. Corey"},
+		Description: "Humanity has colonized the solar system.",
+		NumOfPages:  561,
+	}
+
+	got := m.Book()
+	want := &Book{
+		ISBN:        "9780316129084",
+		Title:       "Leviathan Wakes",
+		Author:      []string{"James S.A. Corey"},
+		Description: NullString{sql.NullString{String: "Humanity has colonized the solar system.", Valid: true}},
+		NumOfPages:  561,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// fields that are set are kept
+	b := &Book{
+		Title:       "The Expanse 1",
+		Description: NullString{sql.NullString{String: "", Valid: true}},
+		NumOfPages:  600,
+	}
+	m.Enrich(b)
+	if b.Title != "The Expanse 1" || b.NumOfPages != 600 || b.Description.String != "" {
+		t.Errorf("set fields were changed: %v", b)
+	}
+	if !reflect.DeepEqual(b.Author, m.Author) {
+		t.Errorf("got %v, want %v", b.Author, m.Author)
+	}
+}
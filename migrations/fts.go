@@ -0,0 +1,6 @@
+//go:build sqlite_fts5 || fts5
+
+package migrations
+
+// SQLiteFTS5 is true when go-sqlite3 is built with FTS5
+const SQLiteFTS5 = true
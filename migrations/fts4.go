@@ -0,0 +1,7 @@
+//go:build !sqlite_fts5 && !fts5
+
+package migrations
+
+// SQLiteFTS5 is false when go-sqlite3 is built without FTS5. The SQLite
+// migrations and search fall back to FTS4
+const SQLiteFTS5 = false
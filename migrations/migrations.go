@@ -0,0 +1,54 @@
+// Package migrations embeds the versioned database migrations.
+//
+// Each supported driver has its own directory of migrations. Migrations are
+// named NNNN_name.up.sql and NNNN_name.down.sql, and are applied in order of
+// version. Append new migrations to every driver, do not edit applied ones.
+//
+// SQLite is only built with FTS5 when the sqlite_fts5 build tag is set. Without
+// it, the migrations in sqlite3/fts4 replace those of the same name.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed sqlite3/*.sql sqlite3/fts4/*.sql postgres/*.sql
+var files embed.FS
+
+// For returns the migrations of the given database driver
+func For(driver string) (fs.FS, error) {
+	switch driver {
+	case "sqlite3":
+		fsys, err := fs.Sub(files, driver)
+		if err != nil || SQLiteFTS5 {
+			return fsys, err
+		}
+		fts4, err := fs.Sub(fsys, "fts4")
+		if err != nil {
+			return nil, err
+		}
+		return overlayFS{base: fsys, override: fts4}, nil
+	case "postgres":
+		return fs.Sub(files, driver)
+	default:
+		return nil, fmt.Errorf("migrations: unsupported driver %q", driver)
+	}
+}
+
+// overlayFS serves files in override in place of files of the same name in
+// base. Directories are always read from base
+type overlayFS struct {
+	base     fs.FS
+	override fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if name != "." {
+		if f, err := o.override.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return o.base.Open(name)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS books_categories;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS categories (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	parent_id BIGINT REFERENCES categories(id),
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS books_categories (
+	book_id BIGINT REFERENCES books(id),
+	category_id BIGINT REFERENCES categories(id),
+	PRIMARY KEY(book_id, category_id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS series (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS books_series (
+	book_id BIGINT REFERENCES books(id),
+	series_id BIGINT REFERENCES series(id),
+	position DOUBLE PRECISION NOT NULL DEFAULT 0,
+	PRIMARY KEY(book_id, series_id)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS books_tags (
+	book_id BIGINT REFERENCES books(id),
+	tag_id BIGINT REFERENCES tags(id),
+	PRIMARY KEY(book_id, tag_id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS books_search_document_idx;
+DROP TABLE IF EXISTS books_search;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS books_search (
+	book_id BIGINT PRIMARY KEY REFERENCES books(id),
+	title TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	author TEXT NOT NULL DEFAULT '',
+	document TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS books_search_document_idx ON books_search USING GIN (document);
+
+INSERT INTO books_search (book_id, title, description, author, document)
+	SELECT id, title, description, author,
+		setweight(to_tsvector('simple', title), 'A') ||
+		setweight(to_tsvector('simple', author), 'B') ||
+		setweight(to_tsvector('simple', description), 'C')
+	FROM (
+		SELECT b.id, b.title, COALESCE(b.description, '') AS description,
+			COALESCE((SELECT STRING_AGG(a.name, ' ')
+				FROM books_authors ba
+				JOIN authors a ON a.id=ba.author_id
+				WHERE ba.book_id=b.id), '') AS author
+		FROM books b
+	) AS books_text;
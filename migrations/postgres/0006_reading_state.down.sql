@@ -0,0 +1,6 @@
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_state_check;
+ALTER TABLE books ALTER COLUMN state SET DEFAULT 'unread';
+
+UPDATE books SET state='unread' WHERE state='want-to-read';
+
+ALTER TABLE books DROP COLUMN IF EXISTS dateStarted;
@@ -0,0 +1,9 @@
+-- Unknown states, including the old default 'unread', become 'want-to-read'
+ALTER TABLE books ADD COLUMN IF NOT EXISTS dateStarted TIMESTAMP;
+
+UPDATE books SET state='want-to-read'
+	WHERE state NOT IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold');
+
+ALTER TABLE books ALTER COLUMN state SET DEFAULT 'want-to-read';
+ALTER TABLE books ADD CONSTRAINT books_state_check
+	CHECK (state IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold'));
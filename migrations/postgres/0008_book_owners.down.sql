@@ -0,0 +1,7 @@
+-- Fails if two users own books with the same ISBN
+DROP INDEX IF EXISTS books_user_id;
+
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_user_id_isbn_key;
+ALTER TABLE books ADD CONSTRAINT books_isbn_key UNIQUE (isbn);
+
+ALTER TABLE books DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,11 @@
+-- Books are owned by a user and ISBNs are unique per user. Existing books are
+-- given to the first registered user. Without users, they are left without an
+-- owner until 0019_book_owners_required
+ALTER TABLE books ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id);
+
+UPDATE books SET user_id=(SELECT MIN(id) FROM users);
+
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_isbn_key;
+ALTER TABLE books ADD CONSTRAINT books_user_id_isbn_key UNIQUE (user_id, isbn);
+
+CREATE INDEX IF NOT EXISTS books_user_id ON books(user_id);
@@ -0,0 +1,15 @@
+-- Revisions of books and authors. changes holds the field-level diff and
+-- snapshot the entity after the change, both as JSON. user_id is the user who
+-- made the change, if known
+CREATE TABLE IF NOT EXISTS revisions (
+	id        BIGSERIAL PRIMARY KEY,
+	entity    TEXT NOT NULL CHECK (entity IN ('book', 'author')),
+	entity_id BIGINT NOT NULL,
+	user_id   BIGINT,
+	action    TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert')),
+	changes   TEXT NOT NULL DEFAULT '{}',
+	snapshot  TEXT,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS revisions_entity ON revisions(entity, entity_id);
@@ -0,0 +1,8 @@
+-- Book metadata looked up from providers, by ISBN-13. data holds the metadata
+-- as JSON
+CREATE TABLE IF NOT EXISTS metadata (
+	isbn      TEXT NOT NULL PRIMARY KEY,
+	provider  TEXT NOT NULL,
+	data      TEXT NOT NULL,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
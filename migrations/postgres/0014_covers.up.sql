@@ -0,0 +1,2 @@
+-- hash of the book's cover image, which names its files in the cover store
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover TEXT NOT NULL DEFAULT '';
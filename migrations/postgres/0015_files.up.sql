@@ -0,0 +1,15 @@
+-- ebook files attached to books. The files are stored on disk by checksum
+CREATE TABLE IF NOT EXISTS files (
+	id        BIGSERIAL PRIMARY KEY,
+	book_id   BIGINT NOT NULL REFERENCES books(id),
+	user_id   BIGINT NOT NULL REFERENCES users(id),
+	name      TEXT NOT NULL,
+	format    TEXT NOT NULL CHECK (format IN ('epub', 'pdf', 'mobi')),
+	size      BIGINT NOT NULL,
+	checksum  TEXT NOT NULL,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, checksum)
+);
+
+CREATE INDEX IF NOT EXISTS files_book_id ON files(book_id);
+CREATE INDEX IF NOT EXISTS files_checksum ON files(checksum);
@@ -0,0 +1,9 @@
+-- only authors are kept
+DELETE FROM books_authors WHERE role<>'author';
+
+ALTER TABLE books_authors DROP CONSTRAINT IF EXISTS books_authors_pkey;
+ALTER TABLE books_authors DROP COLUMN IF EXISTS position;
+ALTER TABLE books_authors DROP COLUMN IF EXISTS role;
+ALTER TABLE books_authors ADD PRIMARY KEY (book_id, author_id);
+
+DELETE FROM authors WHERE id NOT IN (SELECT author_id FROM books_authors);
@@ -0,0 +1,11 @@
+-- Contributors of a book are ordered and have a role. Existing authors are kept
+-- in the order they were created
+ALTER TABLE books_authors ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'author'
+	CHECK (role IN ('author', 'translator', 'editor', 'illustrator', 'narrator'));
+ALTER TABLE books_authors ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE books_authors ba SET position=(SELECT COUNT(*) FROM books_authors p
+	WHERE p.book_id=ba.book_id AND p.author_id<ba.author_id);
+
+ALTER TABLE books_authors DROP CONSTRAINT IF EXISTS books_authors_pkey;
+ALTER TABLE books_authors ADD PRIMARY KEY (book_id, author_id, role);
@@ -0,0 +1,7 @@
+-- other names of authors, added by merging authors
+CREATE TABLE IF NOT EXISTS author_aliases (
+	name      TEXT NOT NULL PRIMARY KEY,
+	author_id BIGINT NOT NULL REFERENCES authors(id)
+);
+
+CREATE INDEX IF NOT EXISTS author_aliases_author_id ON author_aliases(author_id);
@@ -0,0 +1,4 @@
+-- Fails if a user has a book in the trash with the ISBN of another book
+DROP INDEX IF EXISTS books_user_id_isbn;
+
+ALTER TABLE books ADD CONSTRAINT books_user_id_isbn_key UNIQUE (user_id, isbn);
@@ -0,0 +1,6 @@
+-- ISBNs are unique among a user's books that are not in the trash, so a deleted
+-- book can be added again
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_user_id_isbn_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS books_user_id_isbn ON books(user_id, isbn)
+	WHERE dateDeleted IS NULL;
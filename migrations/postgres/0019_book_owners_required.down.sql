@@ -0,0 +1 @@
+ALTER TABLE books ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,4 @@
+-- Every book has an owner. Books left without one by 0008_book_owners are
+-- assigned to the user given with 'teal migrate -owner USERNAME up' before this
+-- runs
+ALTER TABLE books ALTER COLUMN user_id SET NOT NULL;
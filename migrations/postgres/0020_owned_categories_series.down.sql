@@ -0,0 +1,35 @@
+-- Categories and series of the same name are merged into the one with the
+-- lowest id, and their books are linked to it
+CREATE TABLE categories_map AS
+	SELECT c.id AS old_id, (SELECT MIN(c2.id) FROM categories c2 WHERE c2.name=c.name) AS new_id
+	FROM categories c;
+
+UPDATE books_categories SET category_id=m.new_id
+	FROM categories_map m
+	WHERE m.old_id=books_categories.category_id;
+
+UPDATE categories SET parent_id=m.new_id
+	FROM categories_map m
+	WHERE m.old_id=categories.parent_id;
+
+DELETE FROM categories WHERE id NOT IN (SELECT new_id FROM categories_map);
+DROP TABLE categories_map;
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_user_id_name_key;
+ALTER TABLE categories DROP COLUMN IF EXISTS user_id;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+
+CREATE TABLE series_map AS
+	SELECT s.id AS old_id, (SELECT MIN(s2.id) FROM series s2 WHERE s2.name=s.name) AS new_id
+	FROM series s;
+
+UPDATE books_series SET series_id=m.new_id
+	FROM series_map m
+	WHERE m.old_id=books_series.series_id;
+
+DELETE FROM series WHERE id NOT IN (SELECT new_id FROM series_map);
+DROP TABLE series_map;
+
+ALTER TABLE series DROP CONSTRAINT IF EXISTS series_user_id_name_key;
+ALTER TABLE series DROP COLUMN IF EXISTS user_id;
+ALTER TABLE series ADD CONSTRAINT series_name_key UNIQUE (name);
@@ -0,0 +1,90 @@
+-- Categories and series are owned by a user, and names are unique per user.
+-- Existing categories and series were shared by all users, so every user is
+-- given a copy and their books are linked to it. The first user keeps the
+-- existing ids
+CREATE TABLE categories_map (
+	old_id  BIGINT NOT NULL,
+	user_id BIGINT NOT NULL,
+	new_id  BIGINT NOT NULL,
+	PRIMARY KEY(old_id, user_id)
+);
+
+INSERT INTO categories_map (old_id, user_id, new_id)
+	SELECT c.id, u.id, c.id
+	FROM categories c, users u
+	WHERE u.id=(SELECT MIN(id) FROM users);
+
+INSERT INTO categories_map (old_id, user_id, new_id)
+	SELECT c.id, u.id, (SELECT MAX(id) FROM categories) + ROW_NUMBER() OVER (ORDER BY u.id, c.id)
+	FROM categories c, users u
+	WHERE u.id>(SELECT MIN(id) FROM users);
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+ALTER TABLE categories ADD COLUMN user_id BIGINT REFERENCES users(id);
+UPDATE categories SET user_id=(SELECT MIN(id) FROM users);
+
+INSERT INTO categories (id, user_id, name, dateAdded, dateUpdated)
+	SELECT m.new_id, m.user_id, c.name, c.dateAdded, c.dateUpdated
+	FROM categories_map m
+	JOIN categories c ON c.id=m.old_id
+	WHERE m.new_id<>m.old_id;
+
+UPDATE categories SET parent_id=p.new_id
+	FROM categories_map m, categories c, categories_map p
+	WHERE m.new_id=categories.id AND m.new_id<>m.old_id
+		AND c.id=m.old_id AND p.old_id=c.parent_id AND p.user_id=m.user_id;
+
+UPDATE books_categories SET category_id=m.new_id
+	FROM categories_map m, books b
+	WHERE m.old_id=books_categories.category_id AND b.id=books_categories.book_id
+		AND m.user_id=b.user_id;
+
+-- without users, no one can own the existing categories
+DELETE FROM books_categories WHERE category_id IN (SELECT id FROM categories WHERE user_id IS NULL);
+DELETE FROM categories WHERE user_id IS NULL;
+
+ALTER TABLE categories ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE categories ADD CONSTRAINT categories_user_id_name_key UNIQUE (user_id, name);
+SELECT setval(pg_get_serial_sequence('categories', 'id'), (SELECT COALESCE(MAX(id), 0) + 1 FROM categories), false);
+DROP TABLE categories_map;
+
+CREATE TABLE series_map (
+	old_id  BIGINT NOT NULL,
+	user_id BIGINT NOT NULL,
+	new_id  BIGINT NOT NULL,
+	PRIMARY KEY(old_id, user_id)
+);
+
+INSERT INTO series_map (old_id, user_id, new_id)
+	SELECT s.id, u.id, s.id
+	FROM series s, users u
+	WHERE u.id=(SELECT MIN(id) FROM users);
+
+INSERT INTO series_map (old_id, user_id, new_id)
+	SELECT s.id, u.id, (SELECT MAX(id) FROM series) + ROW_NUMBER() OVER (ORDER BY u.id, s.id)
+	FROM series s, users u
+	WHERE u.id>(SELECT MIN(id) FROM users);
+
+ALTER TABLE series DROP CONSTRAINT IF EXISTS series_name_key;
+ALTER TABLE series ADD COLUMN user_id BIGINT REFERENCES users(id);
+UPDATE series SET user_id=(SELECT MIN(id) FROM users);
+
+INSERT INTO series (id, user_id, name, dateAdded, dateUpdated)
+	SELECT m.new_id, m.user_id, s.name, s.dateAdded, s.dateUpdated
+	FROM series_map m
+	JOIN series s ON s.id=m.old_id
+	WHERE m.new_id<>m.old_id;
+
+UPDATE books_series SET series_id=m.new_id
+	FROM series_map m, books b
+	WHERE m.old_id=books_series.series_id AND b.id=books_series.book_id
+		AND m.user_id=b.user_id;
+
+-- without users, no one can own the existing series
+DELETE FROM books_series WHERE series_id IN (SELECT id FROM series WHERE user_id IS NULL);
+DELETE FROM series WHERE user_id IS NULL;
+
+ALTER TABLE series ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE series ADD CONSTRAINT series_user_id_name_key UNIQUE (user_id, name);
+SELECT setval(pg_get_serial_sequence('series', 'id'), (SELECT COALESCE(MAX(id), 0) + 1 FROM series), false);
+DROP TABLE series_map;
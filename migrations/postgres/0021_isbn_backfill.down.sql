@@ -0,0 +1,3 @@
+-- The original form of converted ISBNs is not kept, and books moved to the
+-- trash stay there
+SELECT 1;
@@ -0,0 +1,10 @@
+-- aliases of the same name are merged, keeping the oldest author
+ALTER TABLE author_aliases DROP CONSTRAINT IF EXISTS author_aliases_pkey;
+
+DELETE FROM author_aliases a
+	USING author_aliases b
+	WHERE a.name=b.name
+	AND (a.author_id > b.author_id OR (a.author_id=b.author_id AND a.user_id > b.user_id));
+
+ALTER TABLE author_aliases DROP COLUMN IF EXISTS user_id;
+ALTER TABLE author_aliases ADD PRIMARY KEY (name);
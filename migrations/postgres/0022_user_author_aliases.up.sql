@@ -0,0 +1,15 @@
+-- Aliases are owned by the user that merged the authors, and names are unique
+-- per user. Existing aliases are given to every user with books of their author
+ALTER TABLE author_aliases DROP CONSTRAINT IF EXISTS author_aliases_pkey;
+ALTER TABLE author_aliases ADD COLUMN user_id BIGINT REFERENCES users(id);
+
+INSERT INTO author_aliases (user_id, name, author_id)
+	SELECT DISTINCT b.user_id, a.name, a.author_id
+	FROM author_aliases a
+	JOIN books_authors ba ON ba.author_id=a.author_id
+	JOIN books b ON b.id=ba.book_id
+	WHERE a.user_id IS NULL;
+
+DELETE FROM author_aliases WHERE user_id IS NULL;
+ALTER TABLE author_aliases ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE author_aliases ADD PRIMARY KEY (user_id, name);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS apikeys;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS books_authors;
+DROP TABLE IF EXISTS authors;
+DROP TABLE IF EXISTS books;
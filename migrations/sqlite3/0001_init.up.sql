@@ -5,7 +5,7 @@ CREATE TABLE IF NOT EXISTS books (
 	isbn          TEXT NOT NULL UNIQUE,
 	numOfPages    INTEGER DEFAULT 0,
 	rating        INTEGER DEFAULT 0,
-	state         TEXT NOT NULL DEFAULT "unread",
+	state         TEXT NOT NULL DEFAULT 'unread',
 	dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	dateCompleted TIMESTAMP
@@ -29,7 +29,7 @@ CREATE TABLE IF NOT EXISTS users (
 	name TEXT NOT NULL,
 	username VARCHAR(255) NOT NULL UNIQUE,
 	hashed_password CHAR(60) NOT NULL,
-	role TEXT NOT NULL DEFAULT "user",
+	role TEXT NOT NULL DEFAULT 'user',
 	dateAdded TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -42,4 +42,3 @@ CREATE TABLE IF NOT EXISTS apikeys (
 	dateAdded TIMESTAMP NOT NULL,
 	dateExpired TIMESTAMP NOT NULL
 );
-
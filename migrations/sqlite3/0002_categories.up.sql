@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS categories (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	parent_id INTEGER REFERENCES categories(id),
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS books_categories (
+	book_id INTEGER REFERENCES books(id),
+	category_id INTEGER REFERENCES categories(id),
+	PRIMARY KEY(book_id, category_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS books_series;
+DROP TABLE IF EXISTS series;
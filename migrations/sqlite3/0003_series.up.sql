@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS series (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS books_series (
+	book_id INTEGER REFERENCES books(id),
+	series_id INTEGER REFERENCES series(id),
+	position REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY(book_id, series_id)
+);
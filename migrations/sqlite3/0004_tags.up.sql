@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS books_tags (
+	book_id INTEGER REFERENCES books(id),
+	tag_id INTEGER REFERENCES tags(id),
+	PRIMARY KEY(book_id, tag_id)
+);
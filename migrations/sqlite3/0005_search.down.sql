@@ -0,0 +1 @@
+DROP TABLE IF EXISTS books_fts;
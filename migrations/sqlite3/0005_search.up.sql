@@ -0,0 +1,14 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts5 (
+	title,
+	description,
+	author,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO books_fts (rowid, title, description, author)
+	SELECT b.id, b.title, COALESCE(b.description, ''),
+		COALESCE((SELECT GROUP_CONCAT(a.name, ' ')
+			FROM books_authors ba
+			JOIN authors a ON a.id=ba.author_id
+			WHERE ba.book_id=b.id), '')
+	FROM books b;
@@ -0,0 +1,23 @@
+CREATE TABLE books_old (
+	id            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	title         TEXT NOT NULL,
+	description   TEXT,
+	isbn          TEXT NOT NULL UNIQUE,
+	numOfPages    INTEGER DEFAULT 0,
+	rating        INTEGER DEFAULT 0,
+	state         TEXT NOT NULL DEFAULT 'unread',
+	dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateCompleted TIMESTAMP
+);
+
+INSERT INTO books_old (
+	id, title, description, isbn, numOfPages, rating, state, dateAdded, dateUpdated, dateCompleted
+) SELECT
+	id, title, description, isbn, numOfPages, rating,
+	CASE WHEN state='want-to-read' THEN 'unread' ELSE state END,
+	dateAdded, dateUpdated, dateCompleted
+FROM books;
+
+DROP TABLE books;
+ALTER TABLE books_old RENAME TO books;
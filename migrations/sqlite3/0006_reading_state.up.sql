@@ -0,0 +1,29 @@
+-- SQLite cannot alter a column's default or add a constraint, so the books
+-- table is rebuilt. Unknown states, including the old default 'unread', become
+-- 'want-to-read'
+CREATE TABLE books_new (
+	id            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	title         TEXT NOT NULL,
+	description   TEXT,
+	isbn          TEXT NOT NULL UNIQUE,
+	numOfPages    INTEGER DEFAULT 0,
+	rating        INTEGER DEFAULT 0,
+	state         TEXT NOT NULL DEFAULT 'want-to-read'
+		CHECK (state IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold')),
+	dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateStarted   TIMESTAMP,
+	dateCompleted TIMESTAMP
+);
+
+INSERT INTO books_new (
+	id, title, description, isbn, numOfPages, rating, state, dateAdded, dateUpdated, dateCompleted
+) SELECT
+	id, title, description, isbn, numOfPages, rating,
+	CASE WHEN state IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold')
+		THEN state ELSE 'want-to-read' END,
+	dateAdded, dateUpdated, dateCompleted
+FROM books;
+
+DROP TABLE books;
+ALTER TABLE books_new RENAME TO books;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS reading_sessions (
+	id           INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	book_id      INTEGER NOT NULL REFERENCES books(id),
+	dateStarted  TIMESTAMP NOT NULL,
+	dateFinished TIMESTAMP,
+	outcome      TEXT CHECK (outcome IN ('read', 'did-not-finish')),
+	dateAdded    TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reading_progress (
+	id         INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	book_id    INTEGER NOT NULL REFERENCES books(id),
+	session_id INTEGER NOT NULL REFERENCES reading_sessions(id),
+	page       INTEGER,
+	percent    REAL,
+	dateAdded  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS reading_sessions_book_id ON reading_sessions(book_id);
+CREATE INDEX IF NOT EXISTS reading_progress_book_id ON reading_progress(book_id);
+
+-- books that are being read, or have been read, start with one session
+INSERT INTO reading_sessions (book_id, dateStarted, dateFinished, outcome)
+SELECT id,
+	COALESCE(dateStarted, dateCompleted, dateUpdated, CURRENT_TIMESTAMP),
+	CASE WHEN state IN ('read', 'did-not-finish')
+		THEN COALESCE(dateCompleted, dateUpdated, CURRENT_TIMESTAMP) END,
+	CASE WHEN state IN ('read', 'did-not-finish') THEN state END
+FROM books
+WHERE state IN ('reading', 'on-hold', 'read', 'did-not-finish');
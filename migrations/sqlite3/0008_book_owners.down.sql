@@ -0,0 +1,26 @@
+-- Fails if two users own books with the same ISBN
+CREATE TABLE books_old (
+	id            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	title         TEXT NOT NULL,
+	description   TEXT,
+	isbn          TEXT NOT NULL UNIQUE,
+	numOfPages    INTEGER DEFAULT 0,
+	rating        INTEGER DEFAULT 0,
+	state         TEXT NOT NULL DEFAULT 'want-to-read'
+		CHECK (state IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold')),
+	dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateStarted   TIMESTAMP,
+	dateCompleted TIMESTAMP
+);
+
+INSERT INTO books_old (
+	id, title, description, isbn, numOfPages, rating, state,
+	dateAdded, dateUpdated, dateStarted, dateCompleted
+) SELECT
+	id, title, description, isbn, numOfPages, rating, state,
+	dateAdded, dateUpdated, dateStarted, dateCompleted
+FROM books;
+
+DROP TABLE books;
+ALTER TABLE books_old RENAME TO books;
@@ -0,0 +1,33 @@
+-- Books are owned by a user and ISBNs are unique per user. SQLite cannot drop
+-- a UNIQUE constraint, so the books table is rebuilt. Existing books are given
+-- to the first registered user. Without users, they are left without an owner
+-- until 0019_book_owners_required
+CREATE TABLE books_new (
+	id            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id       INTEGER REFERENCES users(id),
+	title         TEXT NOT NULL,
+	description   TEXT,
+	isbn          TEXT NOT NULL,
+	numOfPages    INTEGER DEFAULT 0,
+	rating        INTEGER DEFAULT 0,
+	state         TEXT NOT NULL DEFAULT 'want-to-read'
+		CHECK (state IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold')),
+	dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateStarted   TIMESTAMP,
+	dateCompleted TIMESTAMP,
+	UNIQUE(user_id, isbn)
+);
+
+INSERT INTO books_new (
+	id, user_id, title, description, isbn, numOfPages, rating, state,
+	dateAdded, dateUpdated, dateStarted, dateCompleted
+) SELECT
+	id, (SELECT MIN(id) FROM users), title, description, isbn, numOfPages, rating, state,
+	dateAdded, dateUpdated, dateStarted, dateCompleted
+FROM books;
+
+DROP TABLE books;
+ALTER TABLE books_new RENAME TO books;
+
+CREATE INDEX IF NOT EXISTS books_user_id ON books(user_id);
@@ -0,0 +1,4 @@
+-- Books in the trash are restored
+DROP INDEX IF EXISTS books_dateDeleted;
+
+ALTER TABLE books DROP COLUMN dateDeleted;
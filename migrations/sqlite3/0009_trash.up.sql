@@ -0,0 +1,5 @@
+-- Deleted books are moved to the trash by setting dateDeleted, and purged
+-- later
+ALTER TABLE books ADD COLUMN dateDeleted TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS books_dateDeleted ON books(dateDeleted);
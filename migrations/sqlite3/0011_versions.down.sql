@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE authors DROP COLUMN version;
+ALTER TABLE books DROP COLUMN version;
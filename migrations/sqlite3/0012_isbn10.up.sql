@@ -0,0 +1,13 @@
+-- ISBNs are stored as ISBN-13, together with the ISBN-10 of ISBNs with the 978
+-- prefix. Hyphens and spaces are removed from existing ISBNs, unless the user
+-- already has a book with the stripped ISBN. Existing ISBN-10s are converted
+-- when their book is next updated
+ALTER TABLE books ADD COLUMN isbn10 TEXT NOT NULL DEFAULT '';
+
+UPDATE books SET isbn=UPPER(REPLACE(REPLACE(isbn, '-', ''), ' ', ''))
+WHERE isbn<>UPPER(REPLACE(REPLACE(isbn, '-', ''), ' ', ''))
+AND NOT EXISTS (
+	SELECT 1 FROM books b
+	WHERE b.user_id=books.user_id
+	AND b.isbn=UPPER(REPLACE(REPLACE(books.isbn, '-', ''), ' ', ''))
+);
@@ -0,0 +1,15 @@
+-- ebook files attached to books. The files are stored on disk by checksum
+CREATE TABLE IF NOT EXISTS files (
+	id        INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	book_id   INTEGER NOT NULL REFERENCES books(id),
+	user_id   INTEGER NOT NULL REFERENCES users(id),
+	name      TEXT NOT NULL,
+	format    TEXT NOT NULL CHECK (format IN ('epub', 'pdf', 'mobi')),
+	size      INTEGER NOT NULL,
+	checksum  TEXT NOT NULL,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, checksum)
+);
+
+CREATE INDEX IF NOT EXISTS files_book_id ON files(book_id);
+CREATE INDEX IF NOT EXISTS files_checksum ON files(checksum);
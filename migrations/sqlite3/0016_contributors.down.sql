@@ -0,0 +1,14 @@
+-- only authors are kept
+CREATE TABLE books_authors_new (
+	book_id   INTEGER REFERENCES books(id),
+	author_id INTEGER REFERENCES authors(id),
+	PRIMARY KEY(book_id, author_id)
+);
+
+INSERT INTO books_authors_new (book_id, author_id)
+SELECT book_id, author_id FROM books_authors WHERE role='author';
+
+DROP TABLE books_authors;
+ALTER TABLE books_authors_new RENAME TO books_authors;
+
+DELETE FROM authors WHERE id NOT IN (SELECT author_id FROM books_authors);
@@ -0,0 +1,20 @@
+-- Contributors of a book are ordered and have a role. SQLite cannot change a
+-- primary key, so books_authors is rebuilt. Existing authors are kept in the
+-- order they were created
+CREATE TABLE books_authors_new (
+	book_id   INTEGER REFERENCES books(id),
+	author_id INTEGER REFERENCES authors(id),
+	role      TEXT NOT NULL DEFAULT 'author'
+		CHECK (role IN ('author', 'translator', 'editor', 'illustrator', 'narrator')),
+	position  INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY(book_id, author_id, role)
+);
+
+INSERT INTO books_authors_new (book_id, author_id, role, position)
+SELECT ba.book_id, ba.author_id, 'author',
+	(SELECT COUNT(*) FROM books_authors p
+		WHERE p.book_id=ba.book_id AND p.author_id<ba.author_id)
+FROM books_authors ba;
+
+DROP TABLE books_authors;
+ALTER TABLE books_authors_new RENAME TO books_authors;
@@ -0,0 +1,37 @@
+CREATE TABLE books_new (
+	id            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id       INTEGER REFERENCES users(id),
+	title         TEXT NOT NULL,
+	description   TEXT,
+	isbn          TEXT NOT NULL,
+	numOfPages    INTEGER DEFAULT 0,
+	rating        INTEGER DEFAULT 0,
+	state         TEXT NOT NULL DEFAULT 'want-to-read'
+		CHECK (state IN ('want-to-read', 'reading', 'read', 'did-not-finish', 'on-hold')),
+	dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateStarted   TIMESTAMP,
+	dateCompleted TIMESTAMP,
+	dateDeleted   TIMESTAMP,
+	version       INTEGER NOT NULL DEFAULT 1,
+	isbn10        TEXT NOT NULL DEFAULT '',
+	cover         TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO books_new (
+	id, user_id, title, description, isbn, numOfPages, rating, state,
+	dateAdded, dateUpdated, dateStarted, dateCompleted, dateDeleted, version,
+	isbn10, cover
+) SELECT
+	id, user_id, title, description, isbn, numOfPages, rating, state,
+	dateAdded, dateUpdated, dateStarted, dateCompleted, dateDeleted, version,
+	isbn10, cover
+FROM books;
+
+DROP TABLE books;
+ALTER TABLE books_new RENAME TO books;
+
+CREATE INDEX IF NOT EXISTS books_user_id ON books(user_id);
+CREATE INDEX IF NOT EXISTS books_dateDeleted ON books(dateDeleted);
+CREATE UNIQUE INDEX IF NOT EXISTS books_user_id_isbn ON books(user_id, isbn)
+	WHERE dateDeleted IS NULL;
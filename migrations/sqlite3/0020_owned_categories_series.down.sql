@@ -0,0 +1,48 @@
+-- Categories and series of the same name are merged into the one with the
+-- lowest id, and their books are linked to it
+CREATE TABLE categories_old (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	parent_id INTEGER REFERENCES categories(id),
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO categories_old (id, name, parent_id, dateAdded, dateUpdated)
+	SELECT c.id, c.name,
+		(SELECT MIN(p2.id) FROM categories p JOIN categories p2 ON p2.name=p.name WHERE p.id=c.parent_id),
+		c.dateAdded, c.dateUpdated
+	FROM categories c
+	WHERE c.id=(SELECT MIN(id) FROM categories WHERE name=c.name);
+
+UPDATE OR IGNORE books_categories SET category_id=(
+	SELECT MIN(c2.id) FROM categories c
+	JOIN categories c2 ON c2.name=c.name
+	WHERE c.id=books_categories.category_id
+);
+DELETE FROM books_categories WHERE category_id NOT IN (SELECT id FROM categories_old);
+
+DROP TABLE categories;
+ALTER TABLE categories_old RENAME TO categories;
+
+CREATE TABLE series_old (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	dateAdded TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO series_old (id, name, dateAdded, dateUpdated)
+	SELECT s.id, s.name, s.dateAdded, s.dateUpdated
+	FROM series s
+	WHERE s.id=(SELECT MIN(id) FROM series WHERE name=s.name);
+
+UPDATE OR IGNORE books_series SET series_id=(
+	SELECT MIN(s2.id) FROM series s
+	JOIN series s2 ON s2.name=s.name
+	WHERE s.id=books_series.series_id
+);
+DELETE FROM books_series WHERE series_id NOT IN (SELECT id FROM series_old);
+
+DROP TABLE series;
+ALTER TABLE series_old RENAME TO series;
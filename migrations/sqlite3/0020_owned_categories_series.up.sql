@@ -0,0 +1,89 @@
+-- Categories and series are owned by a user, and names are unique per user.
+-- Existing categories and series were shared by all users, so every user is
+-- given a copy and their books are linked to it. The first user keeps the
+-- existing ids. SQLite cannot drop a UNIQUE constraint, so the tables are rebuilt
+CREATE TABLE categories_map (
+	old_id  INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	new_id  INTEGER NOT NULL,
+	PRIMARY KEY(old_id, user_id)
+);
+
+INSERT INTO categories_map (old_id, user_id, new_id)
+	SELECT c.id, u.id, c.id
+	FROM categories c, users u
+	WHERE u.id=(SELECT MIN(id) FROM users);
+
+INSERT INTO categories_map (old_id, user_id, new_id)
+	SELECT c.id, u.id, (SELECT MAX(id) FROM categories) + ROW_NUMBER() OVER (ORDER BY u.id, c.id)
+	FROM categories c, users u
+	WHERE u.id>(SELECT MIN(id) FROM users);
+
+CREATE TABLE categories_new (
+	id          INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id     INTEGER NOT NULL REFERENCES users(id),
+	name        TEXT NOT NULL,
+	parent_id   INTEGER REFERENCES categories(id),
+	dateAdded   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, name)
+);
+
+INSERT INTO categories_new (id, user_id, name, parent_id, dateAdded, dateUpdated)
+	SELECT m.new_id, m.user_id, c.name, p.new_id, c.dateAdded, c.dateUpdated
+	FROM categories_map m
+	JOIN categories c ON c.id=m.old_id
+	LEFT JOIN categories_map p ON p.old_id=c.parent_id AND p.user_id=m.user_id;
+
+UPDATE books_categories SET category_id=(
+	SELECT m.new_id FROM categories_map m
+	JOIN books b ON b.user_id=m.user_id
+	WHERE m.old_id=books_categories.category_id AND b.id=books_categories.book_id
+);
+DELETE FROM books_categories WHERE category_id IS NULL;
+
+DROP TABLE categories;
+ALTER TABLE categories_new RENAME TO categories;
+DROP TABLE categories_map;
+
+CREATE TABLE series_map (
+	old_id  INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	new_id  INTEGER NOT NULL,
+	PRIMARY KEY(old_id, user_id)
+);
+
+INSERT INTO series_map (old_id, user_id, new_id)
+	SELECT s.id, u.id, s.id
+	FROM series s, users u
+	WHERE u.id=(SELECT MIN(id) FROM users);
+
+INSERT INTO series_map (old_id, user_id, new_id)
+	SELECT s.id, u.id, (SELECT MAX(id) FROM series) + ROW_NUMBER() OVER (ORDER BY u.id, s.id)
+	FROM series s, users u
+	WHERE u.id>(SELECT MIN(id) FROM users);
+
+CREATE TABLE series_new (
+	id          INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id     INTEGER NOT NULL REFERENCES users(id),
+	name        TEXT NOT NULL,
+	dateAdded   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	dateUpdated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, name)
+);
+
+INSERT INTO series_new (id, user_id, name, dateAdded, dateUpdated)
+	SELECT m.new_id, m.user_id, s.name, s.dateAdded, s.dateUpdated
+	FROM series_map m
+	JOIN series s ON s.id=m.old_id;
+
+UPDATE books_series SET series_id=(
+	SELECT m.new_id FROM series_map m
+	JOIN books b ON b.user_id=m.user_id
+	WHERE m.old_id=books_series.series_id AND b.id=books_series.book_id
+);
+DELETE FROM books_series WHERE series_id IS NULL;
+
+DROP TABLE series;
+ALTER TABLE series_new RENAME TO series;
+DROP TABLE series_map;
@@ -0,0 +1,13 @@
+-- aliases of the same name are merged, keeping the oldest author
+CREATE TABLE author_aliases_old (
+	name      TEXT NOT NULL PRIMARY KEY,
+	author_id INTEGER NOT NULL REFERENCES authors(id)
+);
+
+INSERT INTO author_aliases_old (name, author_id)
+	SELECT name, MIN(author_id) FROM author_aliases GROUP BY name;
+
+DROP TABLE author_aliases;
+ALTER TABLE author_aliases_old RENAME TO author_aliases;
+
+CREATE INDEX IF NOT EXISTS author_aliases_author_id ON author_aliases(author_id);
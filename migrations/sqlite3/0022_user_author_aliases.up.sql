@@ -0,0 +1,19 @@
+-- Aliases are owned by the user that merged the authors, and names are unique
+-- per user. Existing aliases are given to every user with books of their author
+CREATE TABLE author_aliases_new (
+	user_id   INTEGER NOT NULL REFERENCES users(id),
+	name      TEXT NOT NULL,
+	author_id INTEGER NOT NULL REFERENCES authors(id),
+	PRIMARY KEY (user_id, name)
+);
+
+INSERT INTO author_aliases_new (user_id, name, author_id)
+	SELECT DISTINCT b.user_id, a.name, a.author_id
+	FROM author_aliases a
+	JOIN books_authors ba ON ba.author_id=a.author_id
+	JOIN books b ON b.id=ba.book_id;
+
+DROP TABLE author_aliases;
+ALTER TABLE author_aliases_new RENAME TO author_aliases;
+
+CREATE INDEX IF NOT EXISTS author_aliases_author_id ON author_aliases(author_id);
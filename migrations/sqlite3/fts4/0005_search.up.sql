@@ -0,0 +1,16 @@
+-- FTS4 fallback for SQLite built without FTS5, see migrations/fts4.go
+
+CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts4 (
+	title,
+	description,
+	author,
+	tokenize=unicode61 "remove_diacritics=2"
+);
+
+INSERT INTO books_fts (rowid, title, description, author)
+	SELECT b.id, b.title, COALESCE(b.description, ''),
+		COALESCE((SELECT GROUP_CONCAT(a.name, ' ')
+			FROM books_authors ba
+			JOIN authors a ON a.id=ba.author_id
+			WHERE ba.book_id=b.id), '')
+	FROM books b;
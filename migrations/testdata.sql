@@ -1,71 +1,124 @@
 -- ORDER MATTERS, APPEND NEW DATA TO END
 -- remember to add structs to testdata.go as well
+-- loaded after all migrations are applied
+
+-- user 1, 2
+INSERT INTO users (
+	name, username, hashed_password
+) VALUES
+	('John Doe', 'johndoe', 'abc123456789'),
+	('Ben Adams', 'benadams', 'abc123456789');
 
 -- book 1
 INSERT INTO books (
-	title, isbn, numOfPages, rating, state
-) VALUES ("Leviathan Wakes", "1", 250, 5, "read");
+	user_id, title, isbn, numOfPages, rating, state
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'Leviathan Wakes', '1', 250, 5, 'read');
 
 INSERT INTO authors (
 	name
-) VALUES ("S.A. Corey");
+) VALUES ('S.A. Corey');
 
 INSERT INTO books_authors (
 	book_id, author_id
 	) VALUES (
-	(SELECT id FROM books WHERE title = "Leviathan Wakes"),
-	(SELECT id FROM authors WHERE name = "S.A. Corey")
+	(SELECT id FROM books WHERE title = 'Leviathan Wakes'),
+	(SELECT id FROM authors WHERE name = 'S.A. Corey')
 );
 
 -- book 2
 INSERT INTO books (
-	title, isbn, numOfPages, rating, state
-) VALUES ("Red Rising", "2", 900, 4, "unread");
+	user_id, title, isbn, numOfPages, rating, state
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'Red Rising', '2', 900, 4, 'want-to-read');
 
 INSERT INTO authors (
 	name
-) VALUES ("Pierce Brown");
+) VALUES ('Pierce Brown');
 
 INSERT INTO books_authors (
 	book_id, author_id
 	) VALUES (
-	(SELECT id FROM books WHERE title = "Red Rising"),
-	(SELECT id FROM authors WHERE name = "Pierce Brown")
+	(SELECT id FROM books WHERE title = 'Red Rising'),
+	(SELECT id FROM authors WHERE name = 'Pierce Brown')
 );
 
 -- book 3 multiple authors
 INSERT INTO books (
-	title, isbn
-) VALUES ("Many Authors", "3");
+	user_id, title, isbn
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'Many Authors', '3');
 
 INSERT INTO authors (
 	name
 ) VALUES
-	("John Doe"),
-	("Regina Phallange"),
-	("Ken Adams");
+	('John Doe'),
+	('Regina Phallange'),
+	('Ken Adams');
 
 INSERT INTO books_authors (
 	book_id, author_id
 	) VALUES
-	((SELECT id FROM books WHERE title = "Many Authors"), (SELECT id FROM authors WHERE name = "John Doe")),
-	((SELECT id FROM books WHERE title = "Many Authors"), (SELECT id FROM authors WHERE name = "Regina Phallange")),
-	((SELECT id FROM books WHERE title = "Many Authors"), (SELECT id FROM authors WHERE name = "Ken Adams"));
+	((SELECT id FROM books WHERE title = 'Many Authors'), (SELECT id FROM authors WHERE name = 'John Doe')),
+	((SELECT id FROM books WHERE title = 'Many Authors'), (SELECT id FROM authors WHERE name = 'Regina Phallange')),
+	((SELECT id FROM books WHERE title = 'Many Authors'), (SELECT id FROM authors WHERE name = 'Ken Adams'));
 
 -- book 4 existing author
 INSERT INTO books (
-	title, isbn
-) VALUES ("New Book", "4");
+	user_id, title, isbn
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'New Book', '4');
 
 INSERT INTO books_authors (
 	book_id, author_id
 	) VALUES
-	((SELECT id FROM books WHERE title = "New Book"), (SELECT id FROM authors WHERE name = "John Doe"));
+	((SELECT id FROM books WHERE title = 'New Book'), (SELECT id FROM authors WHERE name = 'John Doe'));
 
--- user 1, 2
-INSERT INTO users (
-	name, username, hashed_password
+-- categories
+INSERT INTO categories (
+	user_id, name
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'Fiction');
+
+INSERT INTO categories (
+	user_id, name, parent_id
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'Sci-Fi', (SELECT id FROM categories WHERE name = 'Fiction'));
+
+INSERT INTO categories (
+	user_id, name
+) VALUES ((SELECT id FROM users WHERE username = 'johndoe'), 'Non-Fiction');
+
+INSERT INTO books_categories (
+	book_id, category_id
+	) VALUES
+	((SELECT id FROM books WHERE title = 'Leviathan Wakes'), (SELECT id FROM categories WHERE name = 'Sci-Fi')),
+	((SELECT id FROM books WHERE title = 'Red Rising'), (SELECT id FROM categories WHERE name = 'Sci-Fi'));
+
+-- series
+INSERT INTO series (
+	user_id, name
+) VALUES
+	((SELECT id FROM users WHERE username = 'johndoe'), 'The Expanse'),
+	((SELECT id FROM users WHERE username = 'johndoe'), 'Red Rising Saga');
+
+INSERT INTO books_series (
+	book_id, series_id, position
+	) VALUES
+	((SELECT id FROM books WHERE title = 'Leviathan Wakes'), (SELECT id FROM series WHERE name = 'The Expanse'), 1),
+	((SELECT id FROM books WHERE title = 'Red Rising'), (SELECT id FROM series WHERE name = 'Red Rising Saga'), 1);
+
+-- tags
+INSERT INTO tags (
+	name
 ) VALUES
-	("John Doe", "johndoe", "abc123456789"),
-	("Ben Adams", "benadams", "abc123456789");
+	('space'),
+	('favourite'),
+	('to-lend');
 
+INSERT INTO books_tags (
+	book_id, tag_id
+	) VALUES
+	((SELECT id FROM books WHERE title = 'Leviathan Wakes'), (SELECT id FROM tags WHERE name = 'space')),
+	((SELECT id FROM books WHERE title = 'Leviathan Wakes'), (SELECT id FROM tags WHERE name = 'favourite')),
+	((SELECT id FROM books WHERE title = 'Red Rising'), (SELECT id FROM tags WHERE name = 'space'));
+
+-- reading sessions
+INSERT INTO reading_sessions (
+	book_id, dateStarted, dateFinished, outcome
+) VALUES
+	((SELECT id FROM books WHERE title = 'Leviathan Wakes'), '2022-01-02 00:00:00', '2022-01-20 00:00:00', 'read');
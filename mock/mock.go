@@ -1,27 +1,102 @@
 package mock
 
 import (
+	"time"
+
 	"github.com/kencx/teal"
 )
 
 type BookStore struct {
-	GetAllBooksFn    func() ([]*teal.Book, error)
-	GetBookFn        func(id int64) (*teal.Book, error)
-	GetBookByISBNFn  func(isbn string) (*teal.Book, error)
-	GetBookByTitleFn func(title string) (*teal.Book, error)
-	CreateBookFn     func(b *teal.Book) (*teal.Book, error)
-	UpdateBookFn     func(id int64, b *teal.Book) (*teal.Book, error)
-	DeleteBookFn     func(id int64) error
-	GetByAuthorFn    func(name string) ([]*teal.Book, error)
+	GetAllBooksFn    func(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error)
+	GetBookFn        func(userID, id int64) (*teal.Book, error)
+	GetBookByISBNFn  func(userID int64, isbn string) (*teal.Book, error)
+	GetBookByTitleFn func(userID int64, title string) (*teal.Book, error)
+	CreateBookFn     func(userID int64, b *teal.Book) (*teal.Book, error)
+	UpdateBookFn     func(userID, id, version int64, b *teal.Book) (*teal.Book, error)
+	UpdateStateFn    func(userID, id, version int64, state string) (*teal.Book, error)
+	DeleteBookFn     func(userID, id, version int64) error
+	SetCoverFn       func(userID, id, version int64, cover string) (*teal.Book, error)
+	CoverInUseFn     func(cover string) (bool, error)
+	CoversFn         func() ([]string, error)
+	SearchFn         func(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error)
 }
 
 type AuthorStore struct {
-	GetAuthorFn       func(id int64) (*teal.Author, error)
-	GetAuthorByNameFn func(name string) (*teal.Author, error)
-	GetAllAuthorsFn   func() ([]*teal.Author, error)
-	CreateAuthorFn    func(a *teal.Author) (*teal.Author, error)
-	UpdateAuthorFn    func(id int64, a *teal.Author) (*teal.Author, error)
-	DeleteAuthorFn    func(id int64) error
+	GetAuthorFn           func(userID, id int64) (*teal.Author, error)
+	GetAuthorByNameFn     func(userID int64, name string) (*teal.Author, error)
+	GetAllAuthorsFn       func(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error)
+	CreateAuthorFn        func(userID int64, a *teal.Author) (*teal.Author, error)
+	UpdateAuthorFn        func(userID, id, version int64, a *teal.Author) (*teal.Author, error)
+	DeleteAuthorFn        func(userID, id int64) error
+	MergeAuthorFn         func(userID, id, into int64) (*teal.Author, error)
+	GetDuplicateAuthorsFn func(userID int64, minSimilarity float64) ([]*teal.AuthorDuplicate, error)
+}
+
+type CategoryStore struct {
+	GetCategoryFn       func(userID, id int64) (*teal.Category, error)
+	GetCategoryByNameFn func(userID int64, name string) (*teal.Category, error)
+	GetAllCategoriesFn  func(userID int64) ([]*teal.Category, error)
+	CreateCategoryFn    func(userID int64, c *teal.Category) (*teal.Category, error)
+	UpdateCategoryFn    func(userID, id int64, c *teal.Category) (*teal.Category, error)
+	DeleteCategoryFn    func(userID, id int64) error
+}
+
+type SeriesStore struct {
+	GetSeriesFn       func(userID, id int64) (*teal.Series, error)
+	GetSeriesByNameFn func(userID int64, name string) (*teal.Series, error)
+	GetAllSeriesFn    func(userID int64) ([]*teal.Series, error)
+	GetNextUnreadFn   func(userID int64) ([]*teal.NextInSeries, error)
+	CreateSeriesFn    func(userID int64, s *teal.Series) (*teal.Series, error)
+	UpdateSeriesFn    func(userID, id int64, s *teal.Series) (*teal.Series, error)
+	DeleteSeriesFn    func(userID, id int64) error
+}
+
+type TagStore struct {
+	GetTagFn     func(userID, id int64) (*teal.Tag, error)
+	GetAllTagsFn func(userID int64) ([]*teal.Tag, error)
+	UpdateTagFn  func(userID, id int64, t *teal.Tag) (*teal.Tag, error)
+	MergeTagFn   func(userID, id, into int64) (*teal.Tag, error)
+	DeleteTagFn  func(userID, id int64) error
+}
+
+type ReadingStore struct {
+	GetSessionsFn    func(userID, bookID int64) ([]*teal.ReadingSession, error)
+	CreateSessionFn  func(userID, bookID int64, r *teal.ReadingSession) (*teal.ReadingSession, error)
+	DeleteSessionFn  func(userID, bookID, id int64) error
+	GetProgressFn    func(userID, bookID int64) ([]*teal.Progress, error)
+	AddProgressFn    func(userID, bookID int64, p *teal.Progress) (*teal.Progress, error)
+	DeleteProgressFn func(userID, bookID, id int64) error
+}
+
+type StatsStore struct {
+	GetStatsFn func(userID int64, f *teal.StatsFilter) (*teal.Stats, error)
+}
+
+type TrashStore struct {
+	GetTrashFn   func(userID int64) ([]*teal.Book, error)
+	RestoreFn    func(userID, id int64) (*teal.Book, error)
+	EmptyTrashFn func(userID int64, before time.Time) (int, error)
+}
+
+type RevisionStore struct {
+	GetBookHistoryFn   func(userID, bookID int64) ([]*teal.Revision, error)
+	GetAuthorHistoryFn func(authorID int64) ([]*teal.Revision, error)
+	RevertBookFn       func(userID, bookID, revisionID int64) (*teal.Book, error)
+}
+
+type FileStore struct {
+	GetFileFn           func(userID, bookID, id int64) (*teal.File, error)
+	GetAllFilesFn       func(userID, bookID int64) ([]*teal.File, error)
+	GetFilesByBooksFn   func(userID int64, bookIDs []int64) (map[int64][]*teal.File, error)
+	GetFileByChecksumFn func(userID int64, checksum string) (*teal.File, error)
+	CreateFileFn        func(userID int64, f *teal.File) (*teal.File, error)
+	DeleteFileFn        func(userID, bookID, id int64) error
+	InUseFn             func(checksum string) (bool, error)
+	ChecksumsFn         func() ([]string, error)
+}
+
+type MetadataLookup struct {
+	LookupFn func(isbn string) (*teal.Metadata, error)
 }
 
 type UserStore struct {
@@ -33,60 +108,208 @@ type UserStore struct {
 	DeleteUserFn        func(id int64) error
 }
 
-func (s *BookStore) Get(id int64) (*teal.Book, error) {
-	return s.GetBookFn(id)
+func (s *BookStore) Get(userID, id int64) (*teal.Book, error) {
+	return s.GetBookFn(userID, id)
+}
+
+func (s *BookStore) GetByISBN(userID int64, isbn string) (*teal.Book, error) {
+	return s.GetBookByISBNFn(userID, isbn)
+}
+
+func (s *BookStore) GetByTitle(userID int64, title string) (*teal.Book, error) {
+	return s.GetBookByTitleFn(userID, title)
+}
+
+func (s *BookStore) GetAll(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
+	return s.GetAllBooksFn(userID, f)
+}
+
+func (s *BookStore) Create(userID int64, b *teal.Book) (*teal.Book, error) {
+	return s.CreateBookFn(userID, b)
+}
+
+func (s *BookStore) Update(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+	return s.UpdateBookFn(userID, id, version, b)
+}
+
+func (s *BookStore) UpdateState(userID, id, version int64, state string) (*teal.Book, error) {
+	return s.UpdateStateFn(userID, id, version, state)
+}
+
+func (s *BookStore) Delete(userID, id, version int64) error {
+	return s.DeleteBookFn(userID, id, version)
+}
+
+func (s *BookStore) SetCover(userID, id, version int64, cover string) (*teal.Book, error) {
+	return s.SetCoverFn(userID, id, version, cover)
+}
+
+func (s *BookStore) CoverInUse(cover string) (bool, error) {
+	return s.CoverInUseFn(cover)
+}
+
+func (s *BookStore) Covers() ([]string, error) {
+	return s.CoversFn()
+}
+
+func (s *BookStore) Search(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error) {
+	return s.SearchFn(userID, f)
+}
+
+func (s *AuthorStore) Get(userID, id int64) (*teal.Author, error) {
+	return s.GetAuthorFn(userID, id)
+}
+
+func (s *AuthorStore) GetByName(userID int64, name string) (*teal.Author, error) {
+	return s.GetAuthorByNameFn(userID, name)
+}
+
+func (s *AuthorStore) GetAll(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error) {
+	return s.GetAllAuthorsFn(userID, f)
+}
+
+func (s *AuthorStore) Create(userID int64, a *teal.Author) (*teal.Author, error) {
+	return s.CreateAuthorFn(userID, a)
 }
 
-func (s *BookStore) GetByISBN(isbn string) (*teal.Book, error) {
-	return s.GetBookByISBNFn(isbn)
+func (s *AuthorStore) Update(userID, id, version int64, a *teal.Author) (*teal.Author, error) {
+	return s.UpdateAuthorFn(userID, id, version, a)
 }
 
-func (s *BookStore) GetByTitle(title string) (*teal.Book, error) {
-	return s.GetBookByTitleFn(title)
+func (s *AuthorStore) Delete(userID, id int64) error {
+	return s.DeleteAuthorFn(userID, id)
 }
 
-func (s *BookStore) GetAll() ([]*teal.Book, error) {
-	return s.GetAllBooksFn()
+func (s *AuthorStore) Merge(userID, id, into int64) (*teal.Author, error) {
+	return s.MergeAuthorFn(userID, id, into)
 }
 
-func (s *BookStore) Create(b *teal.Book) (*teal.Book, error) {
-	return s.CreateBookFn(b)
+func (s *AuthorStore) GetDuplicates(userID int64, minSimilarity float64) ([]*teal.AuthorDuplicate, error) {
+	return s.GetDuplicateAuthorsFn(userID, minSimilarity)
 }
 
-func (s *BookStore) Update(id int64, b *teal.Book) (*teal.Book, error) {
-	return s.UpdateBookFn(id, b)
+func (s *CategoryStore) Get(userID, id int64) (*teal.Category, error) {
+	return s.GetCategoryFn(userID, id)
 }
 
-func (s *BookStore) Delete(id int64) error {
-	return s.DeleteBookFn(id)
+func (s *CategoryStore) GetByName(userID int64, name string) (*teal.Category, error) {
+	return s.GetCategoryByNameFn(userID, name)
 }
 
-func (s *BookStore) GetByAuthor(name string) ([]*teal.Book, error) {
-	return s.GetByAuthorFn(name)
+func (s *CategoryStore) GetAll(userID int64) ([]*teal.Category, error) {
+	return s.GetAllCategoriesFn(userID)
 }
 
-func (s *AuthorStore) Get(id int64) (*teal.Author, error) {
-	return s.GetAuthorFn(id)
+func (s *CategoryStore) Create(userID int64, c *teal.Category) (*teal.Category, error) {
+	return s.CreateCategoryFn(userID, c)
 }
 
-func (s *AuthorStore) GetByName(name string) (*teal.Author, error) {
-	return s.GetAuthorByNameFn(name)
+func (s *CategoryStore) Update(userID, id int64, c *teal.Category) (*teal.Category, error) {
+	return s.UpdateCategoryFn(userID, id, c)
 }
 
-func (s *AuthorStore) GetAll() ([]*teal.Author, error) {
-	return s.GetAllAuthorsFn()
+func (s *CategoryStore) Delete(userID, id int64) error {
+	return s.DeleteCategoryFn(userID, id)
 }
 
-func (s *AuthorStore) Create(a *teal.Author) (*teal.Author, error) {
-	return s.CreateAuthorFn(a)
+func (s *SeriesStore) Get(userID, id int64) (*teal.Series, error) {
+	return s.GetSeriesFn(userID, id)
 }
 
-func (s *AuthorStore) Update(id int64, a *teal.Author) (*teal.Author, error) {
-	return s.UpdateAuthorFn(id, a)
+func (s *SeriesStore) GetByName(userID int64, name string) (*teal.Series, error) {
+	return s.GetSeriesByNameFn(userID, name)
 }
 
-func (s *AuthorStore) Delete(id int64) error {
-	return s.DeleteAuthorFn(id)
+func (s *SeriesStore) GetAll(userID int64) ([]*teal.Series, error) {
+	return s.GetAllSeriesFn(userID)
+}
+
+func (s *SeriesStore) GetNextUnread(userID int64) ([]*teal.NextInSeries, error) {
+	return s.GetNextUnreadFn(userID)
+}
+
+func (s *SeriesStore) Create(userID int64, series *teal.Series) (*teal.Series, error) {
+	return s.CreateSeriesFn(userID, series)
+}
+
+func (s *SeriesStore) Update(userID, id int64, series *teal.Series) (*teal.Series, error) {
+	return s.UpdateSeriesFn(userID, id, series)
+}
+
+func (s *SeriesStore) Delete(userID, id int64) error {
+	return s.DeleteSeriesFn(userID, id)
+}
+
+func (s *TagStore) Get(userID, id int64) (*teal.Tag, error) {
+	return s.GetTagFn(userID, id)
+}
+
+func (s *TagStore) GetAll(userID int64) ([]*teal.Tag, error) {
+	return s.GetAllTagsFn(userID)
+}
+
+func (s *TagStore) Update(userID, id int64, t *teal.Tag) (*teal.Tag, error) {
+	return s.UpdateTagFn(userID, id, t)
+}
+
+func (s *TagStore) Merge(userID, id, into int64) (*teal.Tag, error) {
+	return s.MergeTagFn(userID, id, into)
+}
+
+func (s *TagStore) Delete(userID, id int64) error {
+	return s.DeleteTagFn(userID, id)
+}
+
+func (s *ReadingStore) GetSessions(userID, bookID int64) ([]*teal.ReadingSession, error) {
+	return s.GetSessionsFn(userID, bookID)
+}
+
+func (s *ReadingStore) CreateSession(userID, bookID int64, r *teal.ReadingSession) (*teal.ReadingSession, error) {
+	return s.CreateSessionFn(userID, bookID, r)
+}
+
+func (s *ReadingStore) DeleteSession(userID, bookID, id int64) error {
+	return s.DeleteSessionFn(userID, bookID, id)
+}
+
+func (s *ReadingStore) GetProgress(userID, bookID int64) ([]*teal.Progress, error) {
+	return s.GetProgressFn(userID, bookID)
+}
+
+func (s *ReadingStore) AddProgress(userID, bookID int64, p *teal.Progress) (*teal.Progress, error) {
+	return s.AddProgressFn(userID, bookID, p)
+}
+
+func (s *ReadingStore) DeleteProgress(userID, bookID, id int64) error {
+	return s.DeleteProgressFn(userID, bookID, id)
+}
+
+func (s *StatsStore) Get(userID int64, f *teal.StatsFilter) (*teal.Stats, error) {
+	return s.GetStatsFn(userID, f)
+}
+
+func (s *TrashStore) GetAll(userID int64) ([]*teal.Book, error) {
+	return s.GetTrashFn(userID)
+}
+
+func (s *TrashStore) Restore(userID, id int64) (*teal.Book, error) {
+	return s.RestoreFn(userID, id)
+}
+
+func (s *TrashStore) Empty(userID int64, before time.Time) (int, error) {
+	return s.EmptyTrashFn(userID, before)
+}
+
+func (s *RevisionStore) GetBookHistory(userID, bookID int64) ([]*teal.Revision, error) {
+	return s.GetBookHistoryFn(userID, bookID)
+}
+
+func (s *RevisionStore) GetAuthorHistory(authorID int64) ([]*teal.Revision, error) {
+	return s.GetAuthorHistoryFn(authorID)
+}
+
+func (s *RevisionStore) RevertBook(userID, bookID, revisionID int64) (*teal.Book, error) {
+	return s.RevertBookFn(userID, bookID, revisionID)
 }
 
 func (s *UserStore) Get(id int64) (*teal.User, error) {
@@ -110,3 +333,39 @@ func (s *UserStore) Update(id int64, a *teal.User) (*teal.User, error) {
 func (s *UserStore) Delete(id int64) error {
 	return s.DeleteUserFn(id)
 }
+
+func (s *FileStore) Get(userID, bookID, id int64) (*teal.File, error) {
+	return s.GetFileFn(userID, bookID, id)
+}
+
+func (s *FileStore) GetAll(userID, bookID int64) ([]*teal.File, error) {
+	return s.GetAllFilesFn(userID, bookID)
+}
+
+func (s *FileStore) GetByBooks(userID int64, bookIDs []int64) (map[int64][]*teal.File, error) {
+	return s.GetFilesByBooksFn(userID, bookIDs)
+}
+
+func (s *FileStore) GetByChecksum(userID int64, checksum string) (*teal.File, error) {
+	return s.GetFileByChecksumFn(userID, checksum)
+}
+
+func (s *FileStore) Create(userID int64, f *teal.File) (*teal.File, error) {
+	return s.CreateFileFn(userID, f)
+}
+
+func (s *FileStore) Delete(userID, bookID, id int64) error {
+	return s.DeleteFileFn(userID, bookID, id)
+}
+
+func (s *FileStore) InUse(checksum string) (bool, error) {
+	return s.InUseFn(checksum)
+}
+
+func (s *FileStore) Checksums() ([]string, error) {
+	return s.ChecksumsFn()
+}
+
+func (s *MetadataLookup) Lookup(isbn string) (*teal.Metadata, error) {
+	return s.LookupFn(isbn)
+}
@@ -0,0 +1,142 @@
+// Package opds builds OPDS 1.2 catalogs, Atom feeds that e-reader apps browse
+// to find and download books
+package opds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+const (
+	// Media types of navigation and acquisition feeds
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	// Media type of OpenSearch descriptions
+	OpenSearchType = "application/opensearchdescription+xml"
+
+	RelStart       = "start"
+	RelSelf        = "self"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelPrevious    = "previous"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelImage       = "http://opds-spec.org/image"
+	RelThumbnail   = "http://opds-spec.org/image/thumbnail"
+	// sorted by the date books were added
+	RelNew = "http://opds-spec.org/sort/new"
+
+	atomNS       = "http://www.w3.org/2005/Atom"
+	dcNS         = "http://purl.org/dc/terms/"
+	openSearchNS = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+// An Atom feed. Navigation feeds link to other feeds, and acquisition feeds list
+// books
+type Feed struct {
+	XMLName      xml.Name  `xml:"feed"`
+	Xmlns        string    `xml:"xmlns,attr"`
+	XmlnsDC      string    `xml:"xmlns:dc,attr"`
+	XmlnsSearch  string    `xml:"xmlns:opensearch,attr"`
+	ID           string    `xml:"id"`
+	Title        string    `xml:"title"`
+	Updated      time.Time `xml:"updated"`
+	TotalResults int       `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int       `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int       `xml:"opensearch:startIndex,omitempty"`
+	Links        []Link    `xml:"link"`
+	Entries      []Entry   `xml:"entry"`
+}
+
+// An empty feed, updated now
+func NewFeed(id, title string) *Feed {
+	return &Feed{
+		Xmlns:       atomNS,
+		XmlnsDC:     dcNS,
+		XmlnsSearch: openSearchNS,
+		ID:          id,
+		Title:       title,
+		Updated:     time.Now().UTC(),
+	}
+}
+
+// An entry of a feed, either a link to another feed or a book
+type Entry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    time.Time  `xml:"updated"`
+	Authors    []Author   `xml:"author,omitempty"`
+	Identifier string     `xml:"dc:identifier,omitempty"`
+	Categories []Category `xml:"category,omitempty"`
+	Content    *Content   `xml:"content,omitempty"`
+	Links      []Link     `xml:"link"`
+}
+
+type Author struct {
+	Name string `xml:"name"`
+}
+
+type Category struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type Content struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+// The feed as an XML document
+func (f *Feed) Marshal() ([]byte, error) {
+	return marshal(f)
+}
+
+// An OpenSearch description, which tells clients how to search a catalog
+type OpenSearchDescription struct {
+	XMLName     xml.Name `xml:"OpenSearchDescription"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	ShortName   string   `xml:"ShortName"`
+	Description string   `xml:"Description"`
+	URL         struct {
+		Type     string `xml:"type,attr"`
+		Template string `xml:"template,attr"`
+	} `xml:"Url"`
+}
+
+// A description of searches with the URL template, in which {searchTerms} is
+// replaced by the query
+func NewOpenSearchDescription(name, description, template string) *OpenSearchDescription {
+	d := &OpenSearchDescription{
+		Xmlns:       openSearchNS,
+		ShortName:   name,
+		Description: description,
+	}
+	d.URL.Type = AcquisitionType
+	d.URL.Template = template
+	return d
+}
+
+// The description as an XML document
+func (d *OpenSearchDescription) Marshal() ([]byte, error) {
+	return marshal(d)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,78 @@
+package opds
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestFeedMarshal(t *testing.T) {
+	f := NewFeed("urn:teal:opds:recent", "Recently added")
+	f.TotalResults = 1
+	f.Links = []Link{{Rel: RelSelf, Href: "/opds/recent/", Type: AcquisitionType}}
+	f.Entries = []Entry{{
+		ID:         "urn:teal:book:1",
+		Title:      "Leviathan Wakes",
+		Authors:    []Author{{Name: "James S.A. Corey"}},
+		Identifier: "urn:isbn:9780316129084",
+		Links: []Link{{
+			Rel:  RelAcquisition,
+			Href: "/api/books/1/files/1/",
+			Type: "application/epub+zip",
+		}},
+	}}
+
+	data, err := f.Marshal()
+	checkErr(t, err)
+	doc := string(data)
+
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<feed xmlns="http://www.w3.org/2005/Atom"`,
+		`xmlns:dc="http://purl.org/dc/terms/"`,
+		`<opensearch:totalResults>1</opensearch:totalResults>`,
+		`<dc:identifier>urn:isbn:9780316129084</dc:identifier>`,
+		`<link rel="http://opds-spec.org/acquisition" href="/api/books/1/files/1/" type="application/epub+zip"></link>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("feed does not contain %s:\n%s", want, doc)
+		}
+	}
+	// unset counts are left out
+	if strings.Contains(doc, "itemsPerPage") {
+		t.Errorf("feed contains itemsPerPage:\n%s", doc)
+	}
+
+	// the feed is a valid Atom document
+	var got struct {
+		XMLName xml.Name
+		Entries []struct {
+			Title string `xml:"title"`
+		} `xml:"entry"`
+	}
+	checkErr(t, xml.Unmarshal(data, &got))
+	if got.XMLName.Space != "http://www.w3.org/2005/Atom" || got.XMLName.Local != "feed" {
+		t.Errorf("got root %v", got.XMLName)
+	}
+	if len(got.Entries) != 1 || got.Entries[0].Title != "Leviathan Wakes" {
+		t.Errorf("got entries %+v", got.Entries)
+	}
+}
+
+func TestOpenSearchDescriptionMarshal(t *testing.T) {
+	d := NewOpenSearchDescription("Teal", "Search books", "/opds/search/?q={searchTerms}")
+	data, err := d.Marshal()
+	checkErr(t, err)
+
+	want := `<Url type="` + AcquisitionType + `" template="/opds/search/?q={searchTerms}"></Url>`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("description does not contain %s:\n%s", want, data)
+	}
+}
+
+func checkErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
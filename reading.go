@@ -0,0 +1,136 @@
+package teal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kencx/teal/validator"
+)
+
+// Outcomes of a finished reading session
+var Outcomes = []string{StateRead, StateDidNotFinish}
+
+// A single read of a book. Each reread is a new session. A session without a
+// finish date is still in progress
+type ReadingSession struct {
+	ID           int64     `json:"id" db:"id"`
+	BookID       int64     `json:"book_id" db:"book_id"`
+	DateStarted  time.Time `json:"date_started" db:"dateStarted"`
+	DateFinished NullTime  `json:"date_finished" db:"dateFinished"`
+	Outcome      string    `json:"outcome,omitempty" db:"outcome"`
+	DateAdded    time.Time `json:"-" db:"dateAdded"`
+}
+
+// Progress through the current reading session of a book, as a page, a
+// percentage or both. The missing value is computed from the book's number of
+// pages, if known
+type Progress struct {
+	ID        int64     `json:"id" db:"id"`
+	BookID    int64     `json:"book_id" db:"book_id"`
+	SessionID int64     `json:"session_id" db:"session_id"`
+	Page      *int      `json:"page,omitempty" db:"page"`
+	Percent   *float64  `json:"percent,omitempty" db:"percent"`
+	DateAdded time.Time `json:"date_added" db:"dateAdded"`
+}
+
+// Reading sessions and progress of a user's books
+type ReadingService interface {
+	GetSessions(userID, bookID int64) ([]*ReadingSession, error)
+	CreateSession(userID, bookID int64, r *ReadingSession) (*ReadingSession, error)
+	DeleteSession(userID, bookID, id int64) error
+	GetProgress(userID, bookID int64) ([]*Progress, error)
+	AddProgress(userID, bookID int64, p *Progress) (*Progress, error)
+	DeleteProgress(userID, bookID, id int64) error
+}
+
+func (r ReadingSession) String() string {
+	return fmt.Sprintf(`[book=%d started=%s finished=%v outcome=%s]`,
+		r.BookID, r.DateStarted.Format(time.RFC3339), r.DateFinished.Time, r.Outcome)
+}
+
+// Validate a past reading session. Sessions in progress are only created by
+// changing the state of a book
+func (r *ReadingSession) Validate(v *validator.Validator) {
+	v.Check(!r.DateStarted.IsZero(), "date_started", "value is missing")
+	v.Check(r.DateFinished.Valid, "date_finished", "value is missing")
+	if !r.DateStarted.IsZero() && r.DateFinished.Valid {
+		v.Check(!r.DateFinished.Time.Before(r.DateStarted), "date_finished", "must not be before date_started")
+	}
+	v.Check(contains(Outcomes, r.Outcome), "outcome", fmt.Sprintf("must be one of %s", strings.Join(Outcomes, ", ")))
+}
+
+func (p Progress) String() string {
+	var page, percent string
+	if p.Page != nil {
+		page = fmt.Sprint(*p.Page)
+	}
+	if p.Percent != nil {
+		percent = fmt.Sprint(*p.Percent)
+	}
+	return fmt.Sprintf(`[book=%d page=%s percent=%s]`, p.BookID, page, percent)
+}
+
+func (p *Progress) Validate(v *validator.Validator) {
+	v.Check(p.Page != nil || p.Percent != nil, "page", "page or percent is missing")
+
+	if p.Page != nil {
+		v.Check(*p.Page >= 0, "page", "must be >= 0")
+	}
+	if p.Percent != nil {
+		v.Check(*p.Percent >= 0, "percent", "must be >= 0")
+		v.Check(*p.Percent <= 100, "percent", "must be <= 100")
+	}
+}
+
+// Fill in the page or percentage from the number of pages of the book.
+// Percentages are rounded to one decimal place
+func (p *Progress) Complete(numOfPages int) error {
+	if numOfPages <= 0 {
+		return nil
+	}
+	if p.Page != nil && *p.Page > numOfPages {
+		return fmt.Errorf("%w: page %d of %d", ErrPageOutOfRange, *p.Page, numOfPages)
+	}
+
+	switch {
+	case p.Page != nil && p.Percent == nil:
+		percent := math.Round(float64(*p.Page)/float64(numOfPages)*1000) / 10
+		p.Percent = &percent
+	case p.Percent != nil && p.Page == nil:
+		page := int(math.Round(*p.Percent * float64(numOfPages) / 100))
+		p.Page = &page
+	}
+	return nil
+}
+
+// sql.NullTime that is marshalled to null when not valid
+type NullTime struct {
+	sql.NullTime
+}
+
+func (n NullTime) MarshalJSON() ([]byte, error) {
+	if n.Valid {
+		return json.Marshal(n.Time)
+	}
+	return json.Marshal(nil)
+}
+
+func (n *NullTime) UnmarshalJSON(data []byte) error {
+	var t *time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	if t != nil {
+		n.Valid = true
+		n.Time = *t
+	} else {
+		n.Valid = false
+		n.Time = time.Time{}
+	}
+	return nil
+}
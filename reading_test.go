@@ -0,0 +1,197 @@
+package teal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal/validator"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestValidateReadingSession(t *testing.T) {
+	started := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+	finished := NullTime{sql.NullTime{Time: started.AddDate(0, 0, 7), Valid: true}}
+
+	tests := []struct {
+		name    string
+		session *ReadingSession
+		err     map[string]string
+	}{{
+		name:    "success",
+		session: &ReadingSession{DateStarted: started, DateFinished: finished, Outcome: StateRead},
+		err:     nil,
+	}, {
+		name:    "no start date",
+		session: &ReadingSession{DateFinished: finished, Outcome: StateRead},
+		err:     map[string]string{"date_started": "value is missing"},
+	}, {
+		name:    "in progress",
+		session: &ReadingSession{DateStarted: started, Outcome: StateRead},
+		err:     map[string]string{"date_finished": "value is missing"},
+	}, {
+		name: "finished before started",
+		session: &ReadingSession{
+			DateStarted:  started,
+			DateFinished: NullTime{sql.NullTime{Time: started.AddDate(0, 0, -1), Valid: true}},
+			Outcome:      StateDidNotFinish,
+		},
+		err: map[string]string{"date_finished": "must not be before date_started"},
+	}, {
+		name:    "invalid outcome",
+		session: &ReadingSession{DateStarted: started, DateFinished: finished, Outcome: StateOnHold},
+		err:     map[string]string{"outcome": "must be one of read, did-not-finish"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.session.Validate(v)
+
+			if tt.err == nil && !v.Valid() {
+				t.Fatalf("expected no err, got %v", v.Errors)
+			}
+			if tt.err != nil && !reflect.DeepEqual(v.Errors, tt.err) {
+				t.Errorf("got %v, want %v", v.Errors, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress *Progress
+		err      map[string]string
+	}{{
+		name:     "page",
+		progress: &Progress{Page: intPtr(10)},
+		err:      nil,
+	}, {
+		name:     "percent",
+		progress: &Progress{Percent: floatPtr(12.5)},
+		err:      nil,
+	}, {
+		name:     "missing",
+		progress: &Progress{},
+		err:      map[string]string{"page": "page or percent is missing"},
+	}, {
+		name:     "negative page",
+		progress: &Progress{Page: intPtr(-1)},
+		err:      map[string]string{"page": "must be >= 0"},
+	}, {
+		name:     "percent over 100",
+		progress: &Progress{Percent: floatPtr(100.5)},
+		err:      map[string]string{"percent": "must be <= 100"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.progress.Validate(v)
+
+			if tt.err == nil && !v.Valid() {
+				t.Fatalf("expected no err, got %v", v.Errors)
+			}
+			if tt.err != nil && !reflect.DeepEqual(v.Errors, tt.err) {
+				t.Errorf("got %v, want %v", v.Errors, tt.err)
+			}
+		})
+	}
+}
+
+func TestCompleteProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		progress   *Progress
+		numOfPages int
+		page       *int
+		percent    *float64
+	}{{
+		name:       "page",
+		progress:   &Progress{Page: intPtr(100)},
+		numOfPages: 300,
+		page:       intPtr(100),
+		percent:    floatPtr(33.3),
+	}, {
+		name:       "percent",
+		progress:   &Progress{Percent: floatPtr(25)},
+		numOfPages: 250,
+		page:       intPtr(63),
+		percent:    floatPtr(25),
+	}, {
+		name:       "both",
+		progress:   &Progress{Page: intPtr(10), Percent: floatPtr(50)},
+		numOfPages: 250,
+		page:       intPtr(10),
+		percent:    floatPtr(50),
+	}, {
+		name:       "unknown number of pages",
+		progress:   &Progress{Page: intPtr(100)},
+		numOfPages: 0,
+		page:       intPtr(100),
+		percent:    nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.progress.Complete(tt.numOfPages); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(tt.progress.Page, tt.page) {
+				t.Errorf("got page %v, want %v", tt.progress.Page, tt.page)
+			}
+			if !reflect.DeepEqual(tt.progress.Percent, tt.percent) {
+				t.Errorf("got percent %v, want %v", tt.progress.Percent, tt.percent)
+			}
+		})
+	}
+}
+
+func TestCompleteProgressOutOfRange(t *testing.T) {
+	p := &Progress{Page: intPtr(301)}
+	if err := p.Complete(300); !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("got %v, want %v", err, ErrPageOutOfRange)
+	}
+}
+
+func TestNullTimeJSON(t *testing.T) {
+	want := NullTime{sql.NullTime{Time: time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true}}
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if string(b) != `"2022-01-02T00:00:00Z"` {
+		t.Errorf("got %s, want %q", b, "2022-01-02T00:00:00Z")
+	}
+
+	var got NullTime
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	b, err = json.Marshal(NullTime{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if string(b) != "null" {
+		t.Errorf("got %s, want null", b)
+	}
+
+	if err := json.Unmarshal([]byte("null"), &got); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got.Valid {
+		t.Errorf("got %v, want invalid", got)
+	}
+}
@@ -0,0 +1,121 @@
+package teal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Actions recorded in revisions
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRevert  = "revert"
+)
+
+// A recorded change of a book or author, made by the given user
+type Revision struct {
+	ID        int64     `json:"id" db:"id"`
+	Entity    string    `json:"-" db:"entity"`
+	EntityID  int64     `json:"-" db:"entity_id"`
+	UserID    *int64    `json:"user_id" db:"user_id"`
+	Action    string    `json:"action" db:"action"`
+	Changes   Changes   `json:"changes" db:"changes"`
+	Snapshot  []byte    `json:"-" db:"snapshot"`
+	DateAdded time.Time `json:"date_added" db:"dateAdded"`
+}
+
+// A change of a single field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Field-level changes, keyed by JSON field name. Stored as JSON
+type Changes map[string]Change
+
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (c *Changes) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case nil:
+		*c = Changes{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Changes", src)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// Compute the field-level changes from before to after, as they are encoded in
+// JSON. A nil before or after has no fields. Fields in ignore are skipped
+func Diff(before, after interface{}, ignore ...string) (Changes, error) {
+	from, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(Changes)
+	for k, v := range to {
+		if !reflect.DeepEqual(from[k], v) {
+			changes[k] = Change{From: from[k], To: v}
+		}
+	}
+	for k, v := range from {
+		if _, ok := to[k]; !ok {
+			changes[k] = Change{From: v}
+		}
+	}
+	for _, k := range ignore {
+		delete(changes, k)
+	}
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if v == nil {
+		return fields, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return fields, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Retrieve and revert the revisions of a user's books, and of authors
+type RevisionService interface {
+	GetBookHistory(userID, bookID int64) ([]*Revision, error)
+	GetAuthorHistory(authorID int64) ([]*Revision, error)
+	RevertBook(userID, bookID, revisionID int64) (*Book, error)
+}
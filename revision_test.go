@@ -0,0 +1,95 @@
+package teal
+
+import (
+	"reflect"
+	"testing"
+)
+
+type diffItem struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		ignore []string
+		want   Changes
+	}{{
+		name:  "create",
+		after: &diffItem{ID: 1, Name: "foo"},
+		want: Changes{
+			"id":   {To: float64(1)},
+			"name": {To: "foo"},
+		},
+	}, {
+		name:   "update",
+		before: &diffItem{ID: 1, Name: "foo"},
+		after:  &diffItem{ID: 1, Name: "bar"},
+		want:   Changes{"name": {From: "foo", To: "bar"}},
+	}, {
+		name:   "no changes",
+		before: &diffItem{ID: 1, Name: "foo"},
+		after:  &diffItem{ID: 1, Name: "foo"},
+		want:   Changes{},
+	}, {
+		name:   "delete",
+		before: &diffItem{ID: 1, Name: "foo"},
+		after:  (*diffItem)(nil),
+		want: Changes{
+			"id":   {From: float64(1)},
+			"name": {From: "foo"},
+		},
+	}, {
+		name:   "ignored fields",
+		after:  &diffItem{ID: 1, Name: "foo"},
+		ignore: []string{"id"},
+		want:   Changes{"name": {To: "foo"}},
+	}, {
+		name:   "slices",
+		before: &Book{Title: "foo", Author: []string{"John Doe"}},
+		after:  &Book{Title: "foo", Author: []string{"John Doe", "Jane Doe"}},
+		ignore: []string{"id"},
+		want: Changes{"author": {
+			From: []interface{}{"John Doe"},
+			To:   []interface{}{"John Doe", "Jane Doe"},
+		}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.before, tt.after, tt.ignore...)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangesScan(t *testing.T) {
+	want := Changes{"name": {From: "foo", To: "bar"}}
+	v, err := want.Value()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	var got Changes
+	if err := got.Scan(v); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := got.Scan(nil); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %v, want empty changes", got)
+	}
+}
@@ -0,0 +1,15 @@
+package teal
+
+// SearchResult is a book matched by a full-text search. Matching terms in
+// Highlight are wrapped in <mark></mark>
+type SearchResult struct {
+	*Book
+	Rank      float64   `json:"rank"`
+	Highlight Highlight `json:"highlight"`
+}
+
+type Highlight struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+}
@@ -1,17 +1,56 @@
 package teal
 
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/kencx/teal/validator"
+)
+
 type Series struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Books       []Book `json:"books,omitempty"`
-	DateAdded   string `json:"-"`
-	DateUpdated string `json:"-"`
+	ID          int64        `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Books       []*Book      `json:"books,omitempty"`
+	DateAdded   sql.NullTime `json:"-" db:"dateAdded"`
+	DateUpdated sql.NullTime `json:"-" db:"dateUpdated"`
+}
+
+// A book's position in a series. Positions can be fractional, such as 2.5 for
+// a novella set between the second and third books
+type SeriesEntry struct {
+	Name     string  `json:"name" db:"name"`
+	Position float64 `json:"position" db:"position"`
+}
+
+// The next unread book in a series
+type NextInSeries struct {
+	SeriesID int64   `json:"series_id"`
+	Series   string  `json:"series"`
+	Position float64 `json:"position"`
+	Book     *Book   `json:"book"`
 }
 
+// Series are shared by all users. Books in a series are those of the given
+// user
 type SeriesService interface {
-	GetAllSeries() ([]*Series, error)
-	GetSeries(id int) (*Series, error)
-	CreateSeries(b *Series) error
-	UpdateSeries(id int) error
-	DeleteSeries(id int) error
+	Get(userID, id int64) (*Series, error)
+	GetByName(userID int64, name string) (*Series, error)
+	GetAll(userID int64) ([]*Series, error)
+	GetNextUnread(userID int64) ([]*NextInSeries, error)
+	Create(userID int64, s *Series) (*Series, error)
+	Update(userID, id int64, s *Series) (*Series, error)
+	Delete(userID, id int64) error
+}
+
+func (s Series) String() string {
+	return fmt.Sprintf(`[name=%s]`, s.Name)
+}
+
+func (s *Series) Validate(v *validator.Validator) {
+	v.Check(s.Name != "", "name", "value is missing")
+}
+
+func (e *SeriesEntry) Validate(v *validator.Validator) {
+	v.Check(e.Name != "", "series", "name is missing")
+	v.Check(e.Position >= 0, "series", "position must be >= 0")
 }
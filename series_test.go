@@ -0,0 +1,51 @@
+package teal
+
+import (
+	"testing"
+
+	"github.com/kencx/teal/validator"
+)
+
+func TestValidateSeries(t *testing.T) {
+	tests := []struct {
+		name   string
+		series *Series
+		err    map[string]string
+	}{{
+		name:   "success",
+		series: &Series{Name: "The Expanse"},
+		err:    nil,
+	}, {
+		name:   "no name",
+		series: &Series{Name: ""},
+		err:    map[string]string{"name": "value is missing"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.series.Validate(v)
+
+			if !v.Valid() && tt.err == nil {
+				t.Fatalf("expected no err, got %v", v.Errors)
+			}
+
+			if v.Valid() && tt.err != nil {
+				t.Fatalf("expected err with %q, got nil", tt.err)
+			}
+
+			if !v.Valid() && tt.err != nil {
+				for k, v := range v.Errors {
+					s, ok := tt.err[k]
+					if !ok {
+						t.Fatalf("err field missing %q", k)
+					}
+
+					if v != s {
+						t.Fatalf("got %v, want %v error", v, s)
+					}
+				}
+			}
+		})
+	}
+}
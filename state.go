@@ -0,0 +1,85 @@
+package teal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Reading states of a book
+const (
+	StateWantToRead   = "want-to-read"
+	StateReading      = "reading"
+	StateRead         = "read"
+	StateDidNotFinish = "did-not-finish"
+	StateOnHold       = "on-hold"
+
+	DefaultState = StateWantToRead
+)
+
+var States = []string{StateWantToRead, StateReading, StateRead, StateDidNotFinish, StateOnHold}
+
+// allowed transitions from each state
+var stateTransitions = map[string][]string{
+	StateWantToRead:   {StateReading, StateRead},
+	StateReading:      {StateRead, StateDidNotFinish, StateOnHold, StateWantToRead},
+	StateOnHold:       {StateReading, StateDidNotFinish, StateWantToRead},
+	StateDidNotFinish: {StateReading, StateWantToRead},
+	StateRead:         {StateReading},
+}
+
+func IsValidState(state string) bool {
+	return contains(States, state)
+}
+
+// Check if a book can move from one state to another
+func CanTransition(from, to string) bool {
+	return contains(stateTransitions[from], to)
+}
+
+// Set the initial state of a new book. Books start as want-to-read by default.
+// The start or completion date is set to now if the book is created as reading
+// or read and the date is not given
+func (b *Book) InitState(now time.Time) {
+	if b.State == "" {
+		b.State = DefaultState
+	}
+
+	switch b.State {
+	case StateReading:
+		if !b.DateStarted.Valid {
+			b.DateStarted = sql.NullTime{Time: now, Valid: true}
+		}
+	case StateRead:
+		if !b.DateCompleted.Valid {
+			b.DateCompleted = sql.NullTime{Time: now, Valid: true}
+		}
+	}
+}
+
+// Move a book to the given state and update its dates:
+//   - reading sets the start date, unless resuming from on-hold, and clears the
+//     completion date
+//   - read sets the completion date
+//   - want-to-read clears both dates
+func (b *Book) Transition(to string, now time.Time) error {
+	if !CanTransition(b.State, to) {
+		return fmt.Errorf("%w: cannot change from %s to %s", ErrInvalidTransition, b.State, to)
+	}
+
+	switch to {
+	case StateReading:
+		if b.State != StateOnHold || !b.DateStarted.Valid {
+			b.DateStarted = sql.NullTime{Time: now, Valid: true}
+		}
+		b.DateCompleted = sql.NullTime{}
+	case StateRead:
+		b.DateCompleted = sql.NullTime{Time: now, Valid: true}
+	case StateWantToRead:
+		b.DateStarted = sql.NullTime{}
+		b.DateCompleted = sql.NullTime{}
+	}
+
+	b.State = to
+	return nil
+}
@@ -0,0 +1,145 @@
+package teal
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{from: StateWantToRead, to: StateReading, want: true},
+		{from: StateWantToRead, to: StateRead, want: true},
+		{from: StateWantToRead, to: StateOnHold, want: false},
+		{from: StateReading, to: StateOnHold, want: true},
+		{from: StateReading, to: StateDidNotFinish, want: true},
+		{from: StateOnHold, to: StateRead, want: false},
+		{from: StateDidNotFinish, to: StateReading, want: true},
+		{from: StateRead, to: StateReading, want: true},
+		{from: StateRead, to: StateWantToRead, want: false},
+		{from: StateReading, to: StateReading, want: false},
+		{from: "unread", to: StateReading, want: false},
+		{from: StateReading, to: "foo", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitState(t *testing.T) {
+	now := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	completed := sql.NullTime{Time: now.AddDate(0, -1, 0), Valid: true}
+
+	tests := []struct {
+		name      string
+		book      *Book
+		state     string
+		started   bool
+		completed sql.NullTime
+	}{{
+		name:  "default",
+		book:  &Book{},
+		state: StateWantToRead,
+	}, {
+		name:    "reading",
+		book:    &Book{State: StateReading},
+		state:   StateReading,
+		started: true,
+	}, {
+		name:      "read",
+		book:      &Book{State: StateRead},
+		state:     StateRead,
+		completed: sql.NullTime{Time: now, Valid: true},
+	}, {
+		name:      "read with completion date",
+		book:      &Book{State: StateRead, DateCompleted: completed},
+		state:     StateRead,
+		completed: completed,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.book.InitState(now)
+
+			if tt.book.State != tt.state {
+				t.Errorf("got state %q, want %q", tt.book.State, tt.state)
+			}
+			if tt.book.DateStarted.Valid != tt.started {
+				t.Errorf("got start date %v, want set %v", tt.book.DateStarted, tt.started)
+			}
+			if tt.book.DateCompleted != tt.completed {
+				t.Errorf("got completion date %v, want %v", tt.book.DateCompleted, tt.completed)
+			}
+		})
+	}
+}
+
+func TestTransition(t *testing.T) {
+	day1 := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	b := &Book{State: StateWantToRead}
+
+	if err := b.Transition(StateReading, day1); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !b.DateStarted.Time.Equal(day1) {
+		t.Errorf("got start date %v, want %v", b.DateStarted.Time, day1)
+	}
+
+	// resuming from on-hold keeps the start date
+	if err := b.Transition(StateOnHold, day2); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := b.Transition(StateReading, day2); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !b.DateStarted.Time.Equal(day1) {
+		t.Errorf("got start date %v, want %v", b.DateStarted.Time, day1)
+	}
+
+	if err := b.Transition(StateRead, day3); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if b.State != StateRead || !b.DateCompleted.Time.Equal(day3) {
+		t.Errorf("got %q completed %v, want %q completed %v", b.State, b.DateCompleted.Time, StateRead, day3)
+	}
+
+	// rereading restarts the book
+	if err := b.Transition(StateReading, day3); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !b.DateStarted.Time.Equal(day3) || b.DateCompleted.Valid {
+		t.Errorf("got started %v completed %v, want started %v and not completed", b.DateStarted, b.DateCompleted, day3)
+	}
+
+	if err := b.Transition(StateWantToRead, day3); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if b.DateStarted.Valid || b.DateCompleted.Valid {
+		t.Errorf("got started %v completed %v, want no dates", b.DateStarted, b.DateCompleted)
+	}
+}
+
+func TestTransitionNotAllowed(t *testing.T) {
+	b := &Book{State: StateRead}
+
+	err := b.Transition(StateOnHold, time.Now())
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidTransition)
+	}
+	if b.State != StateRead {
+		t.Errorf("got state %q, want %q", b.State, StateRead)
+	}
+}
@@ -0,0 +1,61 @@
+package teal
+
+import (
+	"time"
+
+	"github.com/kencx/teal/validator"
+)
+
+// Statistics of a library. Reading statistics cover reads of books, including
+// rereads, by their completion date. State counts and ratings cover all books,
+// by the date they were added
+type Stats struct {
+	Books         int            `json:"books"`
+	States        map[string]int `json:"states"`
+	AverageRating float64        `json:"average_rating"`
+	Ratings       []RatingCount  `json:"ratings"`
+
+	Read                  int            `json:"read"`
+	PagesRead             int            `json:"pages_read"`
+	ReadByMonth           []PeriodCount  `json:"read_by_month"`
+	ReadByYear            []PeriodCount  `json:"read_by_year"`
+	TopAuthors            []*AuthorCount `json:"top_authors"`
+	AverageDaysToComplete float64        `json:"average_days_to_complete"`
+	PagesPerDay           float64        `json:"pages_per_day"`
+}
+
+// Books and pages read in a month (YYYY-MM) or year (YYYY)
+type PeriodCount struct {
+	Period string `json:"period"`
+	Books  int    `json:"books"`
+	Pages  int    `json:"pages"`
+}
+
+// Number of books with a rating
+type RatingCount struct {
+	Rating int `json:"rating"`
+	Books  int `json:"books"`
+}
+
+// Number of books read by an author
+type AuthorCount struct {
+	ID    int64  `json:"id" db:"id"`
+	Name  string `json:"name" db:"name"`
+	Books int    `json:"books" db:"books"`
+}
+
+// Date range of statistics, inclusive of both days. Unset dates do not limit
+// the range
+type StatsFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// Statistics of a user's library
+type StatsService interface {
+	Get(userID int64, f *StatsFilter) (*Stats, error)
+}
+
+func (f *StatsFilter) Validate(v *validator.Validator) {
+	checkDates(v, f.From, f.To, "from", "to")
+}
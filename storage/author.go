@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -16,17 +18,8 @@ type AuthorStore struct {
 	db *sqlx.DB
 }
 
-func parseAuthors(authors []string) []*teal.Author {
-	var result []*teal.Author
-	for _, a := range authors {
-		result = append(result, &teal.Author{
-			Name: a,
-		})
-	}
-	return result
-}
-
-func (s *AuthorStore) Get(id int64) (*teal.Author, error) {
+// Retrieve an author with the given user's aliases of the author
+func (s *AuthorStore) Get(userID, id int64) (*teal.Author, error) {
 	tx, err := s.db.Beginx()
 	if err != nil {
 		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
@@ -42,45 +35,75 @@ func (s *AuthorStore) Get(id int64) (*teal.Author, error) {
 	if err != nil {
 		return nil, fmt.Errorf("db: retrieve author %d failed: %v", id, err)
 	}
+	if err := populateAliases(tx, userID, []*teal.Author{&author}); err != nil {
+		return nil, err
+	}
 	return &author, nil
 }
 
-func (s *AuthorStore) GetByName(name string) (*teal.Author, error) {
+// Retrieve an author by name or by one of the given user's aliases
+func (s *AuthorStore) GetByName(userID int64, name string) (*teal.Author, error) {
 	tx, err := s.db.Beginx()
 	if err != nil {
 		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
 	}
 	defer endTx(tx, err)
 
+	id, err := getAuthorIDByName(tx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+
 	var author teal.Author
-	stmt := `SELECT * FROM authors WHERE name=$1;`
-	err = tx.QueryRowx(stmt, name).StructScan(&author)
+	stmt := `SELECT * FROM authors WHERE id=$1;`
+	err = tx.QueryRowx(stmt, id).StructScan(&author)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrDoesNotExist
 	}
 	if err != nil {
 		return nil, fmt.Errorf("db: retrieve author %q failed: %v", name, err)
 	}
+	if err := populateAliases(tx, userID, []*teal.Author{&author}); err != nil {
+		return nil, err
+	}
 	return &author, nil
 }
 
-func (s *AuthorStore) GetAll() ([]*teal.Author, error) {
+// Retrieve a page of authors with the given user's aliases, the total number of
+// authors and the cursor of the next page. A nil filter retrieves all authors
+func (s *AuthorStore) GetAll(userID int64, f *teal.AuthorFilter) ([]*teal.Author, teal.PageInfo, error) {
+	if f == nil {
+		f = &teal.AuthorFilter{}
+	}
+
 	tx, err := s.db.Beginx()
 	if err != nil {
-		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+		return nil, teal.PageInfo{}, fmt.Errorf("db: failed to start transaction: %v", err)
 	}
 	defer endTx(tx, err)
 
 	var authors []*teal.Author
-	stmt := `SELECT * FROM authors;`
-	err = tx.Select(&authors, stmt)
-	if err == sql.ErrNoRows {
-		return nil, teal.ErrNoRows
-	}
+	total, err := selectPage(tx, &authors, `a.*`, `FROM authors a`, &where{}, f.Page, authorSortColumns, "a.id")
 	if err != nil {
-		return nil, fmt.Errorf("db: retrieve all authors failed: %v", err)
+		return nil, teal.PageInfo{}, fmt.Errorf("db: retrieve all authors failed: %v", err)
+	}
+	info := teal.PageInfo{Total: total}
+	if len(authors) == 0 {
+		return nil, info, teal.ErrNoRows
+	}
+
+	if f.Limit > 0 && len(authors) > f.Limit {
+		authors = authors[:f.Limit]
+		info.Next, err = nextCursor(tx, f.Page, authorSortColumns, `FROM authors a`, "a.id", authors[f.Limit-1].ID)
+		if err != nil {
+			return nil, teal.PageInfo{}, fmt.Errorf("db: retrieve all authors failed: %v", err)
+		}
 	}
-	return authors, nil
+
+	if err := populateAliases(tx, userID, authors); err != nil {
+		return nil, teal.PageInfo{}, err
+	}
+	return authors, info, nil
 }
 
 func (s *AuthorStore) GetAllNames() ([]string, error) {
@@ -91,7 +114,7 @@ func (s *AuthorStore) GetAllNames() ([]string, error) {
 	defer endTx(tx, err)
 
 	var author []string
-	stmt := `SELECT name FROM authors;`
+	stmt := `SELECT name FROM authors ORDER BY id;`
 	err = tx.Select(&author, stmt)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrNoRows
@@ -102,19 +125,35 @@ func (s *AuthorStore) GetAllNames() ([]string, error) {
 	return author, nil
 }
 
-func (s *AuthorStore) Create(a *teal.Author) (*teal.Author, error) {
+// Create an author, made by the given user. An existing author of the same
+// name or alias is returned unchanged
+func (s *AuthorStore) Create(userID int64, a *teal.Author) (*teal.Author, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
 
-		id, err := insertOrGetAuthor(tx, a)
+		_, err := getAuthorIDByName(tx, userID, a.Name)
+		if err != nil && err != teal.ErrDoesNotExist {
+			return err
+		}
+		exists := err == nil
+
+		id, err := insertOrGetAuthor(tx, userID, a)
 		if err != nil {
 			return err
 		}
 		// save id to context for querying later
 		ctx = tcontext.WithAuthorID(ctx, id)
-		return nil
+
+		if exists {
+			return nil
+		}
+		after, err := getAuthor(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		return recordAuthorRevision(tx, id, userID, teal.ActionCreate, nil, after)
 
 	}); err != nil {
 		return nil, err
@@ -126,14 +165,16 @@ func (s *AuthorStore) Create(a *teal.Author) (*teal.Author, error) {
 	}
 
 	// query author after transaction committed
-	author, err := s.Get(id)
+	author, err := s.Get(userID, id)
 	if err != nil {
 		return nil, err
 	}
 	return author, nil
 }
 
-func (s *AuthorStore) Update(id int64, a *teal.Author) (*teal.Author, error) {
+// Update an author, made by the given user. A non-zero version must match the
+// author's current version, or teal.ErrVersionConflict is returned
+func (s *AuthorStore) Update(userID, id, version int64, a *teal.Author) (*teal.Author, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -143,8 +184,22 @@ func (s *AuthorStore) Update(id int64, a *teal.Author) (*teal.Author, error) {
 	}
 	defer endTx(tx, err)
 
-	stmt := `UPDATE authors SET name=$1 WHERE id=$2`
-	res, err := tx.Exec(stmt, a.Name, id)
+	before, err := getAuthor(tx, userID, id)
+	if err == teal.ErrDoesNotExist {
+		return nil, errors.New("db: no authors updated")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != before.Version {
+		return nil, teal.ErrVersionConflict
+	}
+	if a.Name == before.Name {
+		return before, nil
+	}
+
+	stmt := `UPDATE authors SET name=$1, version=version+1 WHERE id=$2 AND version=$3`
+	res, err := tx.Exec(stmt, a.Name, id, before.Version)
 
 	if err != nil {
 		return nil, fmt.Errorf("db: update author %d failed: %v", id, err)
@@ -156,21 +211,52 @@ func (s *AuthorStore) Update(id int64, a *teal.Author) (*teal.Author, error) {
 	if count == 0 {
 		return nil, errors.New("db: no authors updated")
 	}
+
+	after, err := getAuthor(tx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	a.Version = after.Version
+	if err = recordAuthorRevision(tx, id, userID, teal.ActionUpdate, before, after); err != nil {
+		return nil, err
+	}
+
+	ids, err := getBookIDsFromAuthor(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = bumpBookVersions(tx, ids); err != nil {
+		return nil, err
+	}
+	if err = indexBooks(tx, ids); err != nil {
+		return nil, err
+	}
 	return a, nil
 }
 
-func (s *AuthorStore) Delete(id int64) error {
+// Delete an author, made by the given user. The author's revisions are kept.
+// Returns teal.ErrSharedItem if books of other users have the author
+func (s *AuthorStore) Delete(userID, id int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
 
-		err := deleteAuthor(tx, id)
+		before, err := getAuthor(tx, userID, id)
 		if err != nil {
 			return err
 		}
+		if err := checkSharedAuthor(tx, userID, id); err != nil {
+			return err
+		}
 
-		// delete all author entries from booksAuthors table
+		ids, err := getBookIDsFromAuthor(tx, id)
+		if err != nil {
+			return err
+		}
+
+		// delete all author entries from booksAuthors table before the author
+		// to satisfy foreign keys
 		stmt := `DELETE FROM books_authors WHERE author_id=$1;`
 		res, err := tx.Exec(stmt, id)
 		if err != nil {
@@ -185,7 +271,17 @@ func (s *AuthorStore) Delete(id int64) error {
 		if count == 0 {
 			return errors.New("no rows deleted from books_authors table")
 		}
-		return nil
+
+		if err := deleteAuthor(tx, id); err != nil {
+			return err
+		}
+		if err := recordAuthorRevision(tx, id, userID, teal.ActionDelete, before, nil); err != nil {
+			return err
+		}
+		if err := bumpBookVersions(tx, ids); err != nil {
+			return err
+		}
+		return indexBooks(tx, ids)
 
 	}); err != nil {
 		return err
@@ -193,56 +289,272 @@ func (s *AuthorStore) Delete(id int64) error {
 	return nil
 }
 
-// insert author. If already exists, return author id
-func insertOrGetAuthor(tx *sqlx.Tx, a *teal.Author) (int64, error) {
+// Merge author id into author into for the books of the given user, made by
+// the user. Author into takes the place of author id in the user's books, and
+// the name and the user's aliases of author id become the user's aliases of
+// author into, so books the user adds with them are given author into instead.
+// Books of other users keep author id, which is deleted once it has no books
+func (s *AuthorStore) Merge(userID, id, into int64) (*teal.Author, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	stmt := `INSERT OR IGNORE INTO authors (name) VALUES ($1);`
-	res, err := tx.Exec(stmt, a.Name)
-	if err != nil {
-		return -1, fmt.Errorf("db: insert to authors table failed: %v", err)
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		source, err := getAuthor(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		target, err := getAuthor(tx, userID, into)
+		if err != nil {
+			return err
+		}
+
+		ids, err := getUserBookIDsFromAuthor(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		// books with both authors in the same role keep the target
+		stmt := `DELETE FROM books_authors WHERE author_id=$1
+			AND book_id IN (SELECT id FROM books WHERE user_id=$2)
+			AND EXISTS (SELECT 1 FROM books_authors t
+				WHERE t.book_id=books_authors.book_id AND t.role=books_authors.role
+				AND t.author_id=$3);`
+		if _, err := tx.Exec(stmt, id, userID, into); err != nil {
+			return fmt.Errorf("db: merge author %d into %d failed: %v", id, into, err)
+		}
+		stmt = `UPDATE books_authors SET author_id=$1
+			WHERE author_id=$2 AND book_id IN (SELECT id FROM books WHERE user_id=$3);`
+		if _, err := tx.Exec(stmt, into, id, userID); err != nil {
+			return fmt.Errorf("db: merge author %d into %d failed: %v", id, into, err)
+		}
+
+		stmt = `UPDATE author_aliases SET author_id=$1 WHERE author_id=$2 AND user_id=$3;`
+		if _, err := tx.Exec(stmt, into, id, userID); err != nil {
+			return fmt.Errorf("db: move aliases of author %d to %d failed: %v", id, into, err)
+		}
+		stmt = `INSERT INTO author_aliases (user_id, name, author_id) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, name) DO UPDATE SET author_id=excluded.author_id;`
+		if _, err := tx.Exec(stmt, userID, source.Name, into); err != nil {
+			return fmt.Errorf("db: insert alias of author %d failed: %v", into, err)
+		}
+
+		remaining, err := getBookIDsFromAuthor(tx, id)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			if err := deleteAuthor(tx, id); err != nil {
+				return err
+			}
+			if err := recordAuthorRevision(tx, id, userID, teal.ActionDelete, source, nil); err != nil {
+				return err
+			}
+		}
+
+		stmt = `UPDATE authors SET version=version+1 WHERE id=$1;`
+		if _, err := tx.Exec(stmt, into); err != nil {
+			return fmt.Errorf("db: update author %d failed: %v", into, err)
+		}
+		after, err := getAuthor(tx, userID, into)
+		if err != nil {
+			return err
+		}
+		if err := recordAuthorRevision(tx, into, userID, teal.ActionUpdate, target, after); err != nil {
+			return err
+		}
+		if err := bumpBookVersions(tx, ids); err != nil {
+			return err
+		}
+		return indexBooks(tx, ids)
+
+	}); err != nil {
+		return nil, err
 	}
 
-	n, err := res.RowsAffected()
+	// query author after transaction committed
+	return s.Get(userID, into)
+}
+
+// Retrieve pairs of authors of the given user's books that may be the same
+// person, with names at least minSimilarity similar, most similar first. Only
+// authors whose first or last names start with the same letter are compared.
+// See teal.AuthorSimilarity
+func (s *AuthorStore) GetDuplicates(userID int64, minSimilarity float64) ([]*teal.AuthorDuplicate, error) {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return -1, fmt.Errorf("db: insert to authors table failed: %v", err)
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var authors []*teal.Author
+	stmt := `SELECT * FROM authors WHERE id IN (
+		SELECT ba.author_id FROM books_authors ba
+		JOIN books b ON b.id=ba.book_id
+		WHERE b.user_id=$1)
+		ORDER BY id;`
+	if err := tx.Select(&authors, stmt, userID); err != nil {
+		return nil, fmt.Errorf("db: retrieve authors of user %d failed: %v", userID, err)
+	}
+	if err := populateAliases(tx, userID, authors); err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[rune][]int)
+	for i, a := range authors {
+		for _, key := range authorBlockKeys(a.Name) {
+			blocks[key] = append(blocks[key], i)
+		}
 	}
 
+	// authors in both blocks of a pair are compared once
+	var duplicates []*teal.AuthorDuplicate
+	compared := make(map[[2]int]bool)
+	for _, block := range blocks {
+		for j, a := range block {
+			for _, b := range block[j+1:] {
+				if compared[[2]int{a, b}] {
+					continue
+				}
+				compared[[2]int{a, b}] = true
+
+				similarity := teal.AuthorSimilarity(authors[a].Name, authors[b].Name)
+				if similarity >= minSimilarity {
+					duplicates = append(duplicates, &teal.AuthorDuplicate{
+						Author:     authors[a],
+						Duplicate:  authors[b],
+						Similarity: similarity,
+					})
+				}
+			}
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil, teal.ErrNoRows
+	}
+
+	// pairs of the same similarity are in order of id
+	sort.Slice(duplicates, func(i, j int) bool {
+		a, b := duplicates[i], duplicates[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		if a.Author.ID != b.Author.ID {
+			return a.Author.ID < b.Author.ID
+		}
+		return a.Duplicate.ID < b.Duplicate.ID
+	})
+	return duplicates, nil
+}
+
+// the first letters of the first and last words of a normalized author name
+func authorBlockKeys(name string) []rune {
+	words := strings.Fields(teal.NormalizeAuthorName(name))
+	if len(words) == 0 {
+		return nil
+	}
+	first := []rune(words[0])[0]
+	last := []rune(words[len(words)-1])[0]
+	if first == last {
+		return []rune{first}
+	}
+	return []rune{first, last}
+}
+
+// insert author. If an author or alias of the same name already exists,
+// return the author's id
+func insertOrGetAuthor(tx *sqlx.Tx, userID int64, a *teal.Author) (int64, error) {
+
+	id, err := getAuthorIDByName(tx, userID, a.Name)
+	if err == nil {
+		return id, nil
+	}
+	if err != teal.ErrDoesNotExist {
+		return -1, err
+	}
+
+	stmt := `INSERT INTO authors (name) VALUES ($1) ON CONFLICT DO NOTHING RETURNING id;`
+	err = tx.Get(&id, stmt, a.Name)
+
 	// no rows inserted, query to get existing id
-	if n == 0 {
+	if err == sql.ErrNoRows {
 		// authors.name is unique
-		var id int64
 		stmt := `SELECT id FROM authors WHERE name=$1;`
-		err := tx.Get(&id, stmt, a.Name)
-		if err != nil {
+		if err := tx.Get(&id, stmt, a.Name); err != nil {
 			return -1, fmt.Errorf("db: query existing author failed: %v", err)
 		}
 		return id, nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("db: insert to authors table failed: %v", err)
+	}
+	return id, nil
+}
 
-	} else {
-		id, err := res.LastInsertId()
-		if err != nil {
-			return -1, fmt.Errorf("db: query existing author failed: %v", err)
-		}
-		return id, nil
+// retrieve the id of the author with the given user's alias or, if there is
+// none, the author with the given name. A merged author keeps its name while
+// books of other users have it, so the alias is looked up first
+func getAuthorIDByName(tx *sqlx.Tx, userID int64, name string) (int64, error) {
+
+	var id int64
+	stmt := `SELECT author_id FROM author_aliases WHERE user_id=$1 AND name=$2;`
+	err := tx.Get(&id, stmt, userID, name)
+	if err == sql.ErrNoRows {
+		stmt = `SELECT id FROM authors WHERE name=$1;`
+		err = tx.Get(&id, stmt, name)
+	}
+	if err == sql.ErrNoRows {
+		return -1, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return -1, fmt.Errorf("db: query existing author failed: %v", err)
 	}
+	return id, nil
 }
 
-func insertOrGetAuthors(tx *sqlx.Tx, a []*teal.Author) ([]int64, error) {
+// fill in the given user's aliases of each given author, by name
+func populateAliases(tx *sqlx.Tx, userID int64, authors []*teal.Author) error {
+	if len(authors) == 0 {
+		return nil
+	}
 
 	var ids []int64
-	for _, author := range a {
-		id, err := insertOrGetAuthor(tx, author)
-		if err != nil {
-			return nil, err
+	index := make(map[int64]*teal.Author)
+	for _, a := range authors {
+		a.Aliases = nil
+		ids = append(ids, a.ID)
+		index[a.ID] = a
+	}
+
+	var dest []struct {
+		Name      string
+		Author_id int64
+	}
+	query, args, err := sqlx.In(`SELECT name, author_id FROM author_aliases
+		WHERE user_id=? AND author_id IN (?) ORDER BY name;`, userID, ids)
+	if err != nil {
+		return fmt.Errorf("db: retrieve aliases of authors %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&dest, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: retrieve aliases of authors %v failed: %v", ids, err)
+	}
+
+	for _, v := range dest {
+		if a, ok := index[v.Author_id]; ok {
+			a.Aliases = append(a.Aliases, v.Name)
 		}
-		ids = append(ids, id)
 	}
-	return ids, nil
+	return nil
 }
 
 func deleteAuthor(tx *sqlx.Tx, id int64) error {
 
-	stmt := `DELETE FROM authors WHERE id=$1;`
+	stmt := `DELETE FROM author_aliases WHERE author_id=$1;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: unable to delete aliases of author %d: %w", id, err)
+	}
+
+	stmt = `DELETE FROM authors WHERE id=$1;`
 	res, err := tx.Exec(stmt, id)
 	if err != nil {
 		return fmt.Errorf("db: unable to delete author %d: %w", id, err)
@@ -261,7 +573,13 @@ func deleteAuthor(tx *sqlx.Tx, id int64) error {
 
 func deleteAuthorsWithNoBooks(tx *sqlx.Tx) error {
 
-	stmt := `DELETE FROM authors WHERE id NOT IN
+	stmt := `DELETE FROM author_aliases WHERE author_id NOT IN
+				(SELECT author_id FROM books_authors);`
+	if _, err := tx.Exec(stmt); err != nil {
+		return fmt.Errorf("db: delete aliases from author_aliases table failed: %v", err)
+	}
+
+	stmt = `DELETE FROM authors WHERE id NOT IN
 				(SELECT author_id FROM books_authors);`
 	res, err := tx.Exec(stmt)
 	if err != nil {
@@ -279,3 +597,43 @@ func deleteAuthorsWithNoBooks(tx *sqlx.Tx) error {
 	}
 	return nil
 }
+
+// Returns teal.ErrSharedItem if books of users other than userID, including
+// books in their trash, have the author
+func checkSharedAuthor(tx *sqlx.Tx, userID, id int64) error {
+
+	var count int
+	stmt := `SELECT COUNT(*) FROM books_authors ba
+		JOIN books b ON b.id=ba.book_id
+		WHERE ba.author_id=$1 AND b.user_id<>$2;`
+	if err := tx.Get(&count, stmt, id, userID); err != nil {
+		return fmt.Errorf("db: retrieve books of author %d failed: %v", id, err)
+	}
+	if count > 0 {
+		return teal.ErrSharedItem
+	}
+	return nil
+}
+
+// ids of the given user's books with the author, including books in the trash
+func getUserBookIDsFromAuthor(tx *sqlx.Tx, userID, id int64) ([]int64, error) {
+
+	var ids []int64
+	stmt := `SELECT DISTINCT ba.book_id FROM books_authors ba
+		JOIN books b ON b.id=ba.book_id
+		WHERE ba.author_id=$1 AND b.user_id=$2 ORDER BY ba.book_id;`
+	if err := tx.Select(&ids, stmt, id, userID); err != nil {
+		return nil, fmt.Errorf("db: retrieve books of author %d failed: %v", id, err)
+	}
+	return ids, nil
+}
+
+func getBookIDsFromAuthor(tx *sqlx.Tx, id int64) ([]int64, error) {
+
+	var ids []int64
+	stmt := `SELECT DISTINCT book_id FROM books_authors WHERE author_id=$1 ORDER BY book_id;`
+	if err := tx.Select(&ids, stmt, id); err != nil {
+		return nil, fmt.Errorf("db: retrieve books of author %d failed: %v", id, err)
+	}
+	return ids, nil
+}
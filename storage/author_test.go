@@ -8,7 +8,7 @@ import (
 )
 
 func TestGetAuthor(t *testing.T) {
-	got, err := ts.Authors.Get(testAuthor1.ID)
+	got, err := ts.Authors.Get(testUser1.ID, testAuthor1.ID)
 	checkErr(t, err)
 
 	want := testAuthor1
@@ -18,7 +18,7 @@ func TestGetAuthor(t *testing.T) {
 }
 
 func TestGetAuthorWithName(t *testing.T) {
-	got, err := ts.Authors.GetByName(testAuthor2.Name)
+	got, err := ts.Authors.GetByName(testUser1.ID, testAuthor2.Name)
 	checkErr(t, err)
 
 	want := testAuthor2
@@ -28,7 +28,7 @@ func TestGetAuthorWithName(t *testing.T) {
 }
 
 func TestGetAuthorNotExists(t *testing.T) {
-	result, err := ts.Authors.Get(-1)
+	result, err := ts.Authors.Get(testUser1.ID, -1)
 	if err == nil {
 		t.Fatalf("expected error: ErrDoesNotExist")
 	}
@@ -43,10 +43,11 @@ func TestGetAuthorNotExists(t *testing.T) {
 }
 
 func TestGetAllAuthors(t *testing.T) {
-	got, err := ts.Authors.GetAll()
+	got, info, err := ts.Authors.GetAll(testUser1.ID, nil)
 	checkErr(t, err)
 
 	want := []*teal.Author{testAuthor1, testAuthor2, testAuthor3, testAuthor4, testAuthor5}
+	assertEqual(t, info.Total, len(want))
 
 	if len(got) != len(want) {
 		t.Fatalf("got %d books, want %d books", len(got), len(want))
@@ -60,7 +61,7 @@ func TestGetAllAuthors(t *testing.T) {
 // TODO
 // func TestGetAllAuthorEmpty(t *testing.T) {
 // 	// delete all entries
-// 	got, err := ts.Authors.GetAll()
+// 	got, err := ts.Authors.GetAll(testUser1.ID, )
 //
 // 	if err == nil {
 // 		t.Fatalf("expected error: ErrNoRows")
@@ -78,7 +79,7 @@ func TestGetAllAuthors(t *testing.T) {
 func TestCreateAuthor(t *testing.T) {
 	want := &teal.Author{Name: "FooBar"}
 
-	got, err := ts.Authors.Create(want)
+	got, err := ts.Authors.Create(testUser1.ID, want)
 	checkErr(t, err)
 
 	if got.Name != want.Name {
@@ -95,7 +96,7 @@ func TestCreateAuthorDuplicates(t *testing.T) {
 
 	want := testAuthor3
 
-	_, err = insertOrGetAuthor(tx, want)
+	_, err = insertOrGetAuthor(tx, testUser1.ID, want)
 	checkErr(t, err)
 
 	// check for number of entries in authors
@@ -117,7 +118,7 @@ func TestUpdateAuthor(t *testing.T) {
 	want := testAuthor1
 	want.Name = "John Watson"
 
-	got, err := ts.Authors.Update(want.ID, want)
+	got, err := ts.Authors.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if got.Name != want.Name {
@@ -129,17 +130,204 @@ func TestUpdateAuthorExisting(t *testing.T) {
 	want := testAuthor1
 	want.Name = "John Doe"
 
-	_, err := ts.Authors.Update(want.ID, want)
+	_, err := ts.Authors.Update(testUser1.ID, want.ID, 0, want)
 	if err == nil {
 		t.Errorf("expected error: unique constraint Name")
 	}
 }
 
+func TestUpdateAuthorVersion(t *testing.T) {
+	defer resetDB(testdb)
+
+	got, err := ts.Authors.Update(testUser1.ID, testAuthor2.ID, 1, &teal.Author{Name: "P. Brown"})
+	checkErr(t, err)
+	assertEqual(t, got.Version, 2)
+
+	// stale version
+	_, err = ts.Authors.Update(testUser1.ID, testAuthor2.ID, 1, &teal.Author{Name: "Brown"})
+	if err != teal.ErrVersionConflict {
+		t.Errorf("got %v, want %v", err, teal.ErrVersionConflict)
+	}
+
+	current, err := ts.Authors.Get(testUser1.ID, testAuthor2.ID)
+	checkErr(t, err)
+	assertEqual(t, current.Name, "P. Brown")
+	assertEqual(t, current.Version, 2)
+}
+
+func TestMergeAuthor(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Leviathan Falls",
+		ISBN:   "1040",
+		Author: []string{"James S.A. Corey"},
+	})
+	checkErr(t, err)
+	target, err := ts.Authors.GetByName(testUser1.ID, "James S.A. Corey")
+	checkErr(t, err)
+
+	got, err := ts.Authors.Merge(testUser1.ID, 1, target.ID)
+	checkErr(t, err)
+	assertEqual(t, got.ID, target.ID)
+	assertEqual(t, got.Version, target.Version+1)
+	if !reflect.DeepEqual(got.Aliases, []string{"S.A. Corey"}) {
+		t.Errorf("got aliases %v, want %v", got.Aliases, []string{"S.A. Corey"})
+	}
+
+	_, err = ts.Authors.Get(testUser1.ID, 1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("expected error: author 1 not deleted")
+	}
+
+	// books of the merged author are given the target
+	book, err := ts.Books.Get(testUser1.ID, 1)
+	checkErr(t, err)
+	assertEqual(t, book.Author[0], target.Name)
+
+	// the alias resolves to the target
+	alias, err := ts.Authors.GetByName(testUser1.ID, "S.A. Corey")
+	checkErr(t, err)
+	assertEqual(t, alias.ID, target.ID)
+
+	books, err := ts.Books.GetByAuthor(testUser1.ID, "S.A. Corey", "")
+	checkErr(t, err)
+	assertEqual(t, len(books), 2)
+
+	// books added with the alias are given the target
+	added, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Abaddon's Gate",
+		ISBN:   "1041",
+		Author: []string{"S.A. Corey"},
+	})
+	checkErr(t, err)
+	assertEqual(t, added.Author[0], target.Name)
+}
+
+func TestMergeAuthorSameBook(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, 3)
+	checkErr(t, err)
+
+	// book 3 is by both authors
+	_, err = ts.Authors.Merge(testUser1.ID, 3, 5)
+	checkErr(t, err)
+
+	book, err := ts.Books.Get(testUser1.ID, 3)
+	checkErr(t, err)
+	assertEqual(t, book.Version, before.Version+1)
+	want := []string{"Regina Phallange", "Ken Adams"}
+	if !reflect.DeepEqual(book.Author, want) {
+		t.Errorf("got %v, want %v", book.Author, want)
+	}
+
+	book, err = ts.Books.Get(testUser1.ID, 4)
+	checkErr(t, err)
+	assertEqual(t, book.Author[0], "Ken Adams")
+}
+
+func TestMergeAuthorNotExists(t *testing.T) {
+	_, err := ts.Authors.Merge(testUser1.ID, -1, 1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want ErrDoesNotExist", err)
+	}
+}
+
+func TestAuthorsOfOtherUsers(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Books.Create(testUser2.ID, &teal.Book{
+		Title:  "Caliban's War",
+		ISBN:   "1043",
+		Author: []string{"S.A. Corey"},
+	})
+	checkErr(t, err)
+
+	// only the books of the user are merged, and the author is kept for the
+	// books of other users
+	got, err := ts.Authors.Merge(testUser1.ID, 1, 2)
+	checkErr(t, err)
+	if !reflect.DeepEqual(got.Aliases, []string{"S.A. Corey"}) {
+		t.Errorf("got aliases %v, want %v", got.Aliases, []string{"S.A. Corey"})
+	}
+
+	book, err := ts.Books.Get(testUser1.ID, 1)
+	checkErr(t, err)
+	assertEqual(t, book.Author[0], got.Name)
+	book, err = ts.Books.Get(testUser2.ID, 5)
+	checkErr(t, err)
+	assertEqual(t, book.Author[0], "S.A. Corey")
+
+	// aliases are only used for the user that merged the authors
+	a, err := ts.Authors.GetByName(testUser1.ID, "S.A. Corey")
+	checkErr(t, err)
+	assertEqual(t, a.ID, int64(2))
+	a, err = ts.Authors.GetByName(testUser2.ID, "S.A. Corey")
+	checkErr(t, err)
+	assertEqual(t, a.ID, int64(1))
+	assertEqual(t, len(a.Aliases), 0)
+
+	added, err := ts.Books.Create(testUser2.ID, &teal.Book{
+		Title:  "Abaddon's Gate",
+		ISBN:   "1044",
+		Author: []string{"S.A. Corey"},
+	})
+	checkErr(t, err)
+	assertEqual(t, added.Author[0], "S.A. Corey")
+
+	// authors of books of other users cannot be deleted
+	err = ts.Authors.Delete(testUser1.ID, 1)
+	if err != teal.ErrSharedItem {
+		t.Errorf("got %v, want %v", err, teal.ErrSharedItem)
+	}
+}
+
+func TestGetDuplicateAuthors(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Authors.GetDuplicates(testUser1.ID, teal.DuplicateAuthorSimilarity)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want ErrNoRows", err)
+	}
+
+	_, err = ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Caliban's War",
+		ISBN:   "1042",
+		Author: []string{"S. A. Corey", "Pierce Browne"},
+	})
+	checkErr(t, err)
+
+	got, err := ts.Authors.GetDuplicates(testUser1.ID, teal.DuplicateAuthorSimilarity)
+	checkErr(t, err)
+	assertEqual(t, len(got), 2)
+	assertEqual(t, got[0].Author.Name, "S.A. Corey")
+	assertEqual(t, got[0].Duplicate.Name, "S. A. Corey")
+	assertEqual(t, got[0].Similarity, 1.0)
+	assertEqual(t, got[1].Author.Name, "Pierce Brown")
+	assertEqual(t, got[1].Duplicate.Name, "Pierce Browne")
+
+	// authors of other users are not compared
+	_, err = ts.Books.Create(testUser2.ID, &teal.Book{
+		Title:  "Golden Son",
+		ISBN:   "1045",
+		Author: []string{"Pierce Browning"},
+	})
+	checkErr(t, err)
+	got, err = ts.Authors.GetDuplicates(testUser1.ID, 0.5)
+	checkErr(t, err)
+	for _, d := range got {
+		if d.Duplicate.Name == "Pierce Browning" {
+			t.Errorf("got duplicate %q of another user", d.Duplicate.Name)
+		}
+	}
+}
+
 func TestDeleteAuthor(t *testing.T) {
-	err := ts.Authors.Delete(testAuthor1.ID)
+	err := ts.Authors.Delete(testUser1.ID, testAuthor1.ID)
 	checkErr(t, err)
 
-	_, err = ts.Authors.Get(testAuthor1.ID)
+	_, err = ts.Authors.Get(testUser1.ID, testAuthor1.ID)
 	if err == nil {
 		t.Errorf("expected error, author %d not deleted", testAuthor1.ID)
 	}
@@ -163,7 +351,7 @@ func TestDeleteAuthor(t *testing.T) {
 }
 
 func TestDeleteAuthorNotExists(t *testing.T) {
-	err := ts.Authors.Delete(testAuthor1.ID)
+	err := ts.Authors.Delete(testUser1.ID, testAuthor1.ID)
 	if err == nil {
 		t.Errorf("expected error: author not exists")
 	}
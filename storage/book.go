@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"reflect"
 	"strings"
 	"time"
 
@@ -18,12 +17,8 @@ type BookStore struct {
 	db *sqlx.DB
 }
 
-type BookModel struct {
-	*teal.Book
-	AuthorString string `db:"author_string"`
-}
-
-func (bs *BookStore) Get(id int64) (*teal.Book, error) {
+// Retrieve a book of a user
+func (bs *BookStore) Get(userID, id int64) (*teal.Book, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -33,15 +28,10 @@ func (bs *BookStore) Get(id int64) (*teal.Book, error) {
 	}
 	defer endTx(tx, err)
 
-	var dest BookModel
-	stmt := `SELECT b.*, GROUP_CONCAT(a.name) AS author_string
-		FROM books b
-		INNER JOIN books_authors ba ON ba.book_id=b.id
-		INNER JOIN authors a ON ba.author_id=a.id
-		WHERE b.id=$1
-		GROUP BY b.id;`
+	var book teal.Book
+	stmt := `SELECT * FROM books WHERE id=$1 AND user_id=$2 AND dateDeleted IS NULL;`
 
-	err = tx.QueryRowxContext(ctx, stmt, id).StructScan(&dest)
+	err = tx.QueryRowxContext(ctx, stmt, id, userID).StructScan(&book)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrDoesNotExist
 	}
@@ -49,11 +39,14 @@ func (bs *BookStore) Get(id int64) (*teal.Book, error) {
 		return nil, fmt.Errorf("db: retrieve book id %d failed: %v", id, err)
 	}
 
-	dest.Author = strings.Split(dest.AuthorString, ",")
-	return dest.Book, nil
+	if err := populateBooks(tx, []*teal.Book{&book}); err != nil {
+		return nil, err
+	}
+	return &book, nil
 }
 
-func (bs *BookStore) GetByISBN(isbn string) (*teal.Book, error) {
+// Retrieve a book of a user by its ISBN-10 or ISBN-13, with or without hyphens
+func (bs *BookStore) GetByISBN(userID int64, isbn string) (*teal.Book, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -63,15 +56,10 @@ func (bs *BookStore) GetByISBN(isbn string) (*teal.Book, error) {
 	}
 	defer endTx(tx, err)
 
-	var dest BookModel
-	stmt := `SELECT b.*, GROUP_CONCAT(a.name) AS author_string
-		FROM books b
-		INNER JOIN books_authors ba ON ba.book_id=b.id
-		INNER JOIN authors a ON ba.author_id=a.id
-		WHERE b.isbn=$1
-		GROUP BY b.isbn;`
+	var book teal.Book
+	stmt := `SELECT * FROM books WHERE isbn=$1 AND user_id=$2 AND dateDeleted IS NULL;`
 
-	err = tx.QueryRowxContext(ctx, stmt, isbn).StructScan(&dest)
+	err = tx.QueryRowxContext(ctx, stmt, teal.NormalizeISBN(isbn), userID).StructScan(&book)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrDoesNotExist
 	}
@@ -79,11 +67,13 @@ func (bs *BookStore) GetByISBN(isbn string) (*teal.Book, error) {
 		return nil, fmt.Errorf("db: retrieve book isbn %q failed: %v", isbn, err)
 	}
 
-	dest.Author = strings.Split(dest.AuthorString, ",")
-	return dest.Book, nil
+	if err := populateBooks(tx, []*teal.Book{&book}); err != nil {
+		return nil, err
+	}
+	return &book, nil
 }
 
-func (bs *BookStore) GetByTitle(title string) (*teal.Book, error) {
+func (bs *BookStore) GetByTitle(userID int64, title string) (*teal.Book, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -93,15 +83,10 @@ func (bs *BookStore) GetByTitle(title string) (*teal.Book, error) {
 	}
 	defer endTx(tx, err)
 
-	var dest BookModel
-	stmt := `SELECT b.*, GROUP_CONCAT(a.name) AS author_string
-		FROM books b
-		INNER JOIN books_authors ba ON ba.book_id=b.id
-		INNER JOIN authors a ON ba.author_id=a.id
-		WHERE b.title=$1
-		GROUP BY b.title;`
+	var book teal.Book
+	stmt := `SELECT * FROM books WHERE title=$1 AND user_id=$2 AND dateDeleted IS NULL;`
 
-	err = tx.QueryRowxContext(ctx, stmt, title).StructScan(&dest)
+	err = tx.QueryRowxContext(ctx, stmt, title, userID).StructScan(&book)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrDoesNotExist
 	}
@@ -109,57 +94,76 @@ func (bs *BookStore) GetByTitle(title string) (*teal.Book, error) {
 		return nil, fmt.Errorf("db: retrieve book title %q failed: %v", title, err)
 	}
 
-	dest.Author = strings.Split(dest.AuthorString, ",")
-	return dest.Book, nil
+	if err := populateBooks(tx, []*teal.Book{&book}); err != nil {
+		return nil, err
+	}
+	return &book, nil
 }
 
-func (bs *BookStore) GetAll() ([]*teal.Book, error) {
+// Retrieve a page of a user's books matching the filter, the total number of
+// matching books and the cursor of the next page. A nil filter retrieves all
+// books
+func (bs *BookStore) GetAll(userID int64, f *teal.BookFilter) ([]*teal.Book, teal.PageInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	if f == nil {
+		f = &teal.BookFilter{}
+	}
+
 	tx, err := bs.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+		return nil, teal.PageInfo{}, fmt.Errorf("db: failed to start transaction: %v", err)
 	}
 	defer endTx(tx, err)
 
-	var dest []BookModel
-	stmt := `SELECT b.*, GROUP_CONCAT(a.name) AS author_string
-		FROM books b
-		INNER JOIN books_authors ba ON ba.book_id=b.id
-		INNER JOIN authors a ON ba.author_id=a.id
-		GROUP BY b.id
-		ORDER BY b.id;`
-
-	err = tx.SelectContext(ctx, &dest, stmt)
+	var books []*teal.Book
+	total, err := selectPage(tx, &books, `b.*`, `FROM books b`, bookWhere(userID, f), f.Page, bookSortColumns, "b.id")
 	// sqlx Select does not seem to return sql.ErrNoRows
 	// related issue: https://github.com/jmoiron/sqlx/issues/762#issuecomment-1062649063
-	// if err == sql.ErrNoRows {
-	// 	return nil, err
-	// }
 	if err != nil {
-		return nil, fmt.Errorf("db: retrieve all books failed: %v", err)
+		return nil, teal.PageInfo{}, fmt.Errorf("db: retrieve all books failed: %v", err)
 	}
-	if len(dest) == 0 {
-		return nil, teal.ErrNoRows
+	info := teal.PageInfo{Total: total}
+	if len(books) == 0 {
+		return nil, info, teal.ErrNoRows
 	}
 
-	var books []*teal.Book
-	for _, row := range dest {
-		row.Author = strings.Split(row.AuthorString, ",")
-		books = append(books, row.Book)
+	if f.Limit > 0 && len(books) > f.Limit {
+		books = books[:f.Limit]
+		info.Next, err = nextCursor(tx, f.Page, bookSortColumns, `FROM books b`, "b.id", books[f.Limit-1].ID)
+		if err != nil {
+			return nil, teal.PageInfo{}, fmt.Errorf("db: retrieve all books failed: %v", err)
+		}
+	}
+
+	if err := populateBooks(tx, books); err != nil {
+		return nil, teal.PageInfo{}, err
 	}
-	return books, nil
+	return books, info, nil
 }
 
-// Create a book entry in books, author entries in authors and establishes the necessary
-// book author relationships
-func (bs *BookStore) Create(b *teal.Book) (*teal.Book, error) {
+// Create a book entry in books, owned by the given user, author entries in
+// authors and establishes the necessary book author relationships. Returns
+// teal.ErrDuplicateBook if the user has a book with the ISBN in either form
+func (bs *BookStore) Create(userID int64, b *teal.Book) (*teal.Book, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	now := time.Now().UTC()
+	b.UserID = userID
+	b.InitState(now)
+	normalizeISBN(b)
+	b.NormalizeContributors(nil)
+	// covers are only set with SetCover
+	b.Cover = ""
+
 	if err := Tx(bs.db, ctx, func(tx *sqlx.Tx) error {
 
+		if err := checkDuplicateISBN(tx, userID, 0, b.ISBN); err != nil {
+			return err
+		}
+
 		book, err := insertBook(tx, b)
 		if err != nil {
 			return err
@@ -167,19 +171,53 @@ func (bs *BookStore) Create(b *teal.Book) (*teal.Book, error) {
 		// save created entity to context to extract after transaction
 		ctx = tcontext.WithBook(ctx, book)
 
-		// create authors
-		authors := parseAuthors(b.Author)
-		a_ids, err := insertOrGetAuthors(tx, authors)
+		// create authors and establish book contributor relationship
+		err = linkBookToContributors(tx, userID, book.ID, b.Contributors)
 		if err != nil {
 			return err
 		}
 
-		// establish new book author relationship
-		err = linkBookToAuthors(tx, book.ID, a_ids)
+		// create categories and establish book category relationship
+		c_ids, err := insertOrGetCategories(tx, userID, b.Categories)
+		if err != nil {
+			return err
+		}
+		err = linkBookToCategories(tx, book.ID, c_ids)
+		if err != nil {
+			return err
+		}
+
+		// create series and establish book series relationship
+		err = linkBookToSeries(tx, userID, book.ID, b.Series)
 		if err != nil {
 			return err
 		}
-		return nil
+
+		// create tags and establish book tag relationship
+		t_ids, err := insertOrGetTags(tx, b.Tags)
+		if err != nil {
+			return err
+		}
+		err = linkBookToTags(tx, book.ID, t_ids)
+		if err != nil {
+			return err
+		}
+
+		// start or finish a reading session for books created as reading or read
+		if err := recordTransition(tx, book.ID, "", book, now); err != nil {
+			return err
+		}
+
+		after, err := getBook(tx, book.ID)
+		if err != nil {
+			return err
+		}
+		// authors added by alias are returned as the author
+		ctx = tcontext.WithBook(ctx, after)
+		if err := recordBookRevision(tx, book.ID, userID, teal.ActionCreate, nil, after); err != nil {
+			return err
+		}
+		return indexBook(tx, book.ID)
 
 	}); err != nil {
 		return nil, err
@@ -195,47 +233,128 @@ func (bs *BookStore) Create(b *teal.Book) (*teal.Book, error) {
 // Update book details.
 // For authors, a new author row is created for each new author
 // No authors are deleted, unless it has no relationship with any books
-func (bs *BookStore) Update(id int64, b *teal.Book) (*teal.Book, error) {
+// A change of state must be an allowed transition, see UpdateState. An empty
+// state keeps the current state
+// A non-zero version must match the book's current version, or
+// teal.ErrVersionConflict is returned
+func (bs *BookStore) Update(userID, id, version int64, b *teal.Book) (*teal.Book, error) {
+	return bs.update(userID, id, version, b, teal.ActionUpdate)
+}
+
+// update a book, recording the change as a revision with the given action
+func (bs *BookStore) update(userID, id, version int64, b *teal.Book, action string) (*teal.Book, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	if err := Tx(bs.db, ctx, func(tx *sqlx.Tx) error {
 
-		err := updateBook(tx, id, b)
+		current, err := getBookState(tx, userID, id)
 		if err != nil {
 			return err
 		}
-
-		current_authors, err := bs.GetAuthorsFromBook(id)
+		if version != 0 && version != current.Version {
+			return teal.ErrVersionConflict
+		}
+		before, err := getBook(tx, id)
 		if err != nil {
 			return err
 		}
 
-		// TODO check if order matters
-		if !reflect.DeepEqual(current_authors, b.Author) {
+		// dates are only changed by state transitions
+		normalizeISBN(b)
+		if b.ISBN != before.ISBN {
+			if err := checkDuplicateISBN(tx, userID, id, b.ISBN); err != nil {
+				return err
+			}
+		}
+		b.NormalizeContributors(before)
+		b.UserID = userID
+		b.Version = current.Version
+		b.Cover = before.Cover
+		state := b.State
+		b.State = current.State
+		b.DateStarted = current.DateStarted
+		b.DateCompleted = current.DateCompleted
+		if state != "" && state != current.State {
+			now := time.Now().UTC()
+			if err := b.Transition(state, now); err != nil {
+				return err
+			}
+			if err := recordTransition(tx, id, current.State, b, now); err != nil {
+				return err
+			}
+		}
+
+		// only changed columns and relationships are written
+		contributorsChanged := !sameContributors(before.Contributors, b.Contributors)
+		categoriesChanged := !sameStrings(before.Categories, b.Categories)
+		seriesChanged := !sameSeries(before.Series, b.Series)
+		tagsChanged := !sameStrings(before.Tags, b.Tags)
+
+		cols := changedColumns(before, b)
+		if len(cols) > 0 || contributorsChanged || categoriesChanged || seriesChanged || tagsChanged {
+			if err := updateBook(tx, id, b.Version, cols); err != nil {
+				return err
+			}
+		}
+
+		if contributorsChanged {
 
-			// Add new or get existing authors
 			// Renaming an author should not update the same author row for other books
 			// Always create a new author row, never update the original in this case
-			authors := parseAuthors(b.Author)
-			a_ids, err := insertOrGetAuthors(tx, authors)
+			if err := linkBookToContributors(tx, userID, id, b.Contributors); err != nil {
+				return err
+			}
+
+			// delete authors with no books
+			err = deleteAuthorsWithNoBooks(tx)
 			if err != nil {
 				return err
 			}
+		}
 
-			// establish book author relationships with NEW or EXISTING authors
-			linkBookToAuthors(tx, id, a_ids)
+		// categories are never deleted when they have no books
+		if categoriesChanged {
+			c_ids, err := insertOrGetCategories(tx, userID, b.Categories)
+			if err != nil {
+				return err
+			}
+			if err := linkBookToCategories(tx, id, c_ids); err != nil {
+				return err
+			}
+			if err := unlinkBookFromCategories(tx, id, c_ids); err != nil {
+				return err
+			}
+		}
 
-			// remove broken book author relationships
-			unlinkBookFromAuthors(tx, id, a_ids)
+		if seriesChanged {
+			if err := linkBookToSeries(tx, userID, id, b.Series); err != nil {
+				return err
+			}
+		}
 
-			// delete authors with no books
-			err = deleteAuthorsWithNoBooks(tx)
+		if tagsChanged {
+			t_ids, err := insertOrGetTags(tx, b.Tags)
 			if err != nil {
 				return err
 			}
+			if err := linkBookToTags(tx, id, t_ids); err != nil {
+				return err
+			}
+			if err := unlinkBookFromTags(tx, id, t_ids); err != nil {
+				return err
+			}
 		}
-		return nil
+
+		after, err := getBook(tx, id)
+		if err != nil {
+			return err
+		}
+		b.Version = after.Version
+		if err := recordBookRevision(tx, id, userID, action, before, after); err != nil {
+			return err
+		}
+		return indexBook(tx, id)
 
 	}); err != nil {
 		return nil, err
@@ -243,35 +362,87 @@ func (bs *BookStore) Update(id int64, b *teal.Book) (*teal.Book, error) {
 	return b, nil
 }
 
-func (bs *BookStore) Delete(id int64) error {
+// Move a book to the given state. The transition must be allowed by
+// teal.CanTransition. The book's start and completion dates and its reading
+// sessions are updated. A non-zero version must match the book's current
+// version, or teal.ErrVersionConflict is returned
+func (bs *BookStore) UpdateState(userID, id, version int64, state string) (*teal.Book, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	if err := Tx(bs.db, ctx, func(tx *sqlx.Tx) error {
 
-		err := deleteBook(tx, id)
+		b, err := getBookState(tx, userID, id)
 		if err != nil {
 			return err
 		}
+		if version != 0 && version != b.Version {
+			return teal.ErrVersionConflict
+		}
 
-		// delete all book entries from booksAuthors table
-		stmt := `DELETE FROM books_authors WHERE book_id=$1;`
-		res, err := tx.Exec(stmt, id)
+		before, err := getBook(tx, id)
 		if err != nil {
-			return fmt.Errorf("db: delete book %d from books_authors failed: %v", id, err)
+			return err
 		}
 
-		count, err := res.RowsAffected()
+		from, now := b.State, time.Now().UTC()
+		if err := b.Transition(state, now); err != nil {
+			return err
+		}
+		if err := recordTransition(tx, id, from, b, now); err != nil {
+			return err
+		}
+
+		stmt := `UPDATE books
+			SET state=$1,
+			dateStarted=$2,
+			dateCompleted=$3,
+			dateUpdated=CURRENT_TIMESTAMP,
+			version=version+1
+			WHERE id=$4;`
+		if _, err := tx.Exec(stmt, b.State, b.DateStarted, b.DateCompleted, id); err != nil {
+			return fmt.Errorf("db: update state of book %d failed: %v", id, err)
+		}
+
+		after, err := getBook(tx, id)
 		if err != nil {
-			return fmt.Errorf("db: delete book %d from books_authors failed: %v", id, err)
+			return err
 		}
+		return recordBookRevision(tx, id, userID, teal.ActionUpdate, before, after)
 
-		if count == 0 {
-			return errors.New("no rows deleted from books_authors table")
+	}); err != nil {
+		return nil, err
+	}
+	return bs.Get(userID, id)
+}
+
+// Move a book to the trash. Its relationships and reading history are kept
+// until it is purged, see TrashStore. A non-zero version must match the book's
+// current version, or teal.ErrVersionConflict is returned
+func (bs *BookStore) Delete(userID, id, version int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(bs.db, ctx, func(tx *sqlx.Tx) error {
+
+		current, err := getBookState(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return teal.ErrVersionConflict
 		}
 
-		deleteAuthorsWithNoBooks(tx)
-		return nil
+		before, err := getBook(tx, id)
+		if err != nil {
+			return err
+		}
+
+		stmt := `UPDATE books SET dateDeleted=CURRENT_TIMESTAMP, version=version+1 WHERE id=$1;`
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("db: move book %d to trash failed: %v", id, err)
+		}
+		return recordBookRevision(tx, id, userID, teal.ActionDelete, before, nil)
 
 	}); err != nil {
 		return err
@@ -279,68 +450,302 @@ func (bs *BookStore) Delete(id int64) error {
 	return nil
 }
 
+// Set the cover of a user's book to the hash of a cover image, or remove it with
+// an empty hash. A non-zero version must match the book's current version, or
+// teal.ErrVersionConflict is returned. Cover changes are not recorded as
+// revisions
+func (bs *BookStore) SetCover(userID, id, version int64, cover string) (*teal.Book, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(bs.db, ctx, func(tx *sqlx.Tx) error {
+
+		current, err := getBookState(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return teal.ErrVersionConflict
+		}
+		return updateBook(tx, id, current.Version, []column{{"cover", cover}})
+
+	}); err != nil {
+		return nil, err
+	}
+	return bs.Get(userID, id)
+}
+
+// Whether any book, including books in the trash, has the given cover
+func (bs *BookStore) CoverInUse(cover string) (bool, error) {
+	var count int
+	stmt := `SELECT COUNT(*) FROM books WHERE cover=$1;`
+	if err := bs.db.Get(&count, stmt, cover); err != nil {
+		return false, fmt.Errorf("db: retrieve books with cover failed: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Retrieve the covers of all books, including books in the trash
+func (bs *BookStore) Covers() ([]string, error) {
+	var covers []string
+	stmt := `SELECT DISTINCT cover FROM books WHERE cover<>'';`
+	if err := bs.db.Select(&covers, stmt); err != nil {
+		return nil, fmt.Errorf("db: retrieve covers failed: %v", err)
+	}
+	return covers, nil
+}
+
 // insert book entry to books table
 func insertBook(tx *sqlx.Tx, b *teal.Book) (*teal.Book, error) {
 
 	stmt := `INSERT INTO books
-		(title, description, isbn, numOfPages, rating, state, dateAdded, dateUpdated, dateCompleted)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;`
+		(title, description, isbn, isbn10, numOfPages, rating, state, dateAdded, dateUpdated, dateStarted, dateCompleted, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7,
+			COALESCE($8, CURRENT_TIMESTAMP), COALESCE($9, CURRENT_TIMESTAMP), $10, $11, $12) RETURNING id;`
 	err := tx.QueryRowx(stmt,
 		b.Title,
 		b.Description,
 		b.ISBN,
+		b.ISBN10,
 		b.NumOfPages,
 		b.Rating,
 		b.State,
 		b.DateAdded,
 		b.DateUpdated,
-		b.DateCompleted).StructScan(b)
+		b.DateStarted,
+		b.DateCompleted,
+		b.UserID).StructScan(b)
 
+	if isUniqueViolation(err) {
+		return nil, teal.ErrDuplicateBook
+	}
 	if err != nil {
 		return nil, fmt.Errorf("db: insert to books table failed: %v", err)
 	}
 	return b, nil
 }
 
-func updateBook(tx *sqlx.Tx, id int64, b *teal.Book) error {
-
-	stmt := `UPDATE books
-			SET title=$1,
-			description=$2,
-			isbn=$3,
-			numOfPages=$4,
-			rating=$5,
-			state=$6,
-			dateAdded=$7,
-			dateUpdated=$8,
-			dateCompleted=$9
-			WHERE id=$10;`
-	res, err := tx.Exec(stmt,
-		b.Title,
-		b.Description,
-		b.ISBN,
-		b.NumOfPages,
-		b.Rating,
-		b.State,
-		b.DateAdded,
-		b.DateUpdated,
-		b.DateCompleted,
-		id)
+// ISBNs are stored as ISBN-13, together with their ISBN-10. Books are validated
+// before they are stored, so invalid ISBNs are stored as given
+func normalizeISBN(b *teal.Book) {
+	b.ISBN = teal.NormalizeISBN(b.ISBN)
+	b.ISBN10 = teal.ISBN10(b.ISBN)
+}
+
+// Returns teal.ErrDuplicateBook if the user has a book other than id with the
+// given normalized ISBN
+func checkDuplicateISBN(tx *sqlx.Tx, userID, id int64, isbn string) error {
+	var count int
+	stmt := `SELECT COUNT(*) FROM books
+		WHERE user_id=$1 AND id<>$2 AND isbn=$3 AND dateDeleted IS NULL;`
+	if err := tx.Get(&count, stmt, userID, id, isbn); err != nil {
+		return fmt.Errorf("db: query existing book isbn %q failed: %v", isbn, err)
+	}
+	if count > 0 {
+		return teal.ErrDuplicateBook
+	}
+	return nil
+}
+
+// a column of a book entry and its new value
+type column struct {
+	name  string
+	value interface{}
+}
+
+// the columns of a book entry that changed from before to b
+func changedColumns(before, b *teal.Book) []column {
+	var cols []column
+	set := func(name string, changed bool, value interface{}) {
+		if changed {
+			cols = append(cols, column{name, value})
+		}
+	}
+	set("title", before.Title != b.Title, b.Title)
+	set("description", before.Description != b.Description, b.Description)
+	set("isbn", before.ISBN != b.ISBN, b.ISBN)
+	set("isbn10", before.ISBN10 != b.ISBN10, b.ISBN10)
+	set("numOfPages", before.NumOfPages != b.NumOfPages, b.NumOfPages)
+	set("rating", before.Rating != b.Rating, b.Rating)
+	set("state", before.State != b.State, b.State)
+	set("dateStarted", !sameTime(before.DateStarted, b.DateStarted), b.DateStarted)
+	set("dateCompleted", !sameTime(before.DateCompleted, b.DateCompleted), b.DateCompleted)
+	return cols
+}
+
+// update the given columns of a book entry in books table, if its version is
+// still the given version. The version is incremented
+func updateBook(tx *sqlx.Tx, id, version int64, cols []column) error {
 
+	var set strings.Builder
+	var args []interface{}
+	for _, c := range cols {
+		args = append(args, c.value)
+		fmt.Fprintf(&set, "%s=$%d, ", c.name, len(args))
+	}
+
+	stmt := fmt.Sprintf(`UPDATE books
+			SET %sdateUpdated=CURRENT_TIMESTAMP,
+			version=version+1
+			WHERE id=$%d AND version=$%d;`, set.String(), len(args)+1, len(args)+2)
+	res, err := tx.Exec(stmt, append(args, id, version)...)
+
+	if isUniqueViolation(err) {
+		return teal.ErrDuplicateBook
+	}
 	if err != nil {
 		return fmt.Errorf("db: update book %d failed: %v", id, err)
 	}
 	count, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("db: delete book %d failed: %v", id, err)
+		return fmt.Errorf("db: update book %d failed: %v", id, err)
 	}
+	// the book was changed since the version was read
 	if count == 0 {
-		return errors.New("db: no books removed")
+		return teal.ErrVersionConflict
 	}
 	return nil
 }
 
-// delete book entry from books table
+// Increment the versions of books changed through a related entity, such as an
+// author, tag, category, series or reading session, so their ETags no longer
+// match
+func bumpBookVersions(tx *sqlx.Tx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`UPDATE books SET version=version+1 WHERE id IN (?);`, ids)
+	if err != nil {
+		return fmt.Errorf("db: update versions of books %v failed: %v", ids, err)
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: update versions of books %v failed: %v", ids, err)
+	}
+	return nil
+}
+
+func sameTime(a, b sql.NullTime) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.Time.Equal(b.Time))
+}
+
+// whether a and b contain the same strings, in any order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[string]int)
+	for _, s := range a {
+		count[s]++
+	}
+	for _, s := range b {
+		if count[s] == 0 {
+			return false
+		}
+		count[s]--
+	}
+	return true
+}
+
+// whether a and b contain the same contributors in the same order
+func sameContributors(a, b []teal.Contributor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// whether a and b contain the same series and positions, in any order
+func sameSeries(a, b []teal.SeriesEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// retrieve the state, dates and version of a user's book. Returns
+// teal.ErrDoesNotExist if the book does not exist, is in the trash or is owned
+// by another user
+func getBookState(tx *sqlx.Tx, userID, id int64) (*teal.Book, error) {
+
+	var b teal.Book
+	stmt := `SELECT id, user_id, state, numOfPages, dateStarted, dateCompleted, version
+		FROM books WHERE id=$1 AND user_id=$2 AND dateDeleted IS NULL;`
+	err := tx.Get(&b, stmt, id, userID)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve state of book %d failed: %v", id, err)
+	}
+	return &b, nil
+}
+
+// permanently delete a book with its relationships and reading history.
+// Authors left without books are deleted
+func purgeBook(tx *sqlx.Tx, id int64) error {
+
+	// relationships are deleted before the book to satisfy foreign keys
+	if err := unindexBook(tx, id); err != nil {
+		return err
+	}
+
+	stmt := `DELETE FROM books_authors WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: delete book %d from books_authors failed: %v", id, err)
+	}
+
+	stmt = `DELETE FROM books_categories WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: delete book %d from books_categories failed: %v", id, err)
+	}
+
+	stmt = `DELETE FROM books_series WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: delete book %d from books_series failed: %v", id, err)
+	}
+
+	stmt = `DELETE FROM books_tags WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: delete book %d from books_tags failed: %v", id, err)
+	}
+
+	if err := deleteReadingHistory(tx, id); err != nil {
+		return err
+	}
+
+	if err := deleteRevisions(tx, entityBook, id); err != nil {
+		return err
+	}
+
+	if err := deleteFiles(tx, id); err != nil {
+		return err
+	}
+
+	if err := deleteBook(tx, id); err != nil {
+		return err
+	}
+
+	return deleteAuthorsWithNoBooks(tx)
+}
+
 func deleteBook(tx *sqlx.Tx, id int64) error {
 
 	stmt := `DELETE from books WHERE id=$1;`
@@ -1,16 +1,19 @@
 package storage
 
 import (
+	"errors"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kencx/teal"
 )
 
 func TestGetBook(t *testing.T) {
 	resetDB(testdb)
-	got, err := ts.Books.Get(testBook1.ID)
+	got, err := ts.Books.Get(testUser1.ID, testBook1.ID)
 	checkErr(t, err)
 
 	want := testBook1
@@ -20,7 +23,7 @@ func TestGetBook(t *testing.T) {
 }
 
 func TestGetBookByISBN(t *testing.T) {
-	got, err := ts.Books.GetByISBN(testBook1.ISBN)
+	got, err := ts.Books.GetByISBN(testUser1.ID, testBook1.ISBN)
 	checkErr(t, err)
 
 	want := testBook1
@@ -30,7 +33,7 @@ func TestGetBookByISBN(t *testing.T) {
 }
 
 func TestGetBookByTitle(t *testing.T) {
-	got, err := ts.Books.GetByTitle(testBook2.Title)
+	got, err := ts.Books.GetByTitle(testUser1.ID, testBook2.Title)
 	checkErr(t, err)
 
 	want := testBook2
@@ -40,7 +43,7 @@ func TestGetBookByTitle(t *testing.T) {
 }
 
 func TestGetBookNotExists(t *testing.T) {
-	got, err := ts.Books.Get(-1)
+	got, err := ts.Books.Get(testUser1.ID, -1)
 	if err == nil {
 		t.Fatalf("expected error: ErrDoesNotExist")
 	}
@@ -55,8 +58,9 @@ func TestGetBookNotExists(t *testing.T) {
 }
 
 func TestGetAllBooks(t *testing.T) {
-	got, err := ts.Books.GetAll()
+	got, info, err := ts.Books.GetAll(testUser1.ID, nil)
 	checkErr(t, err)
+	assertEqual(t, info.Total, len(allBooks))
 
 	sort.Slice(got, func(i, j int) bool {
 		return got[i].ID < got[j].ID
@@ -128,7 +132,7 @@ func TestCreateBook(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ts.Books.Create(tt.want)
+			got, err := ts.Books.Create(testUser1.ID, tt.want)
 			checkErr(t, err)
 
 			if !assertBooksEqual(got, tt.want) {
@@ -144,7 +148,7 @@ func TestCreateBook(t *testing.T) {
 }
 
 func TestCreateBookExistingISBN(t *testing.T) {
-	_, err := ts.Books.Create(testBook2)
+	_, err := ts.Books.Create(testUser1.ID, testBook2)
 	if err == nil {
 		t.Errorf("expected error")
 	}
@@ -157,10 +161,10 @@ func TestCreateBookExistingAuthor(t *testing.T) {
 		Author:     []string{"Pierce Brown"},
 		NumOfPages: 100,
 		Rating:     10,
-		State:      "unread",
+		State:      "want-to-read",
 	}
 
-	_, err := ts.Books.Create(want)
+	_, err := ts.Books.Create(testUser1.ID, want)
 	checkErr(t, err)
 
 	assertAuthorsExist(t, want)
@@ -172,7 +176,7 @@ func TestCreateBookExistingAuthor(t *testing.T) {
 	defer endTx(tx, err)
 
 	// check books authors table should have two entries for john doe
-	books, err := ts.Books.GetByAuthor(want.Author[0])
+	books, err := ts.Books.GetByAuthor(testUser1.ID, want.Author[0], "")
 	checkErr(t, err)
 
 	if len(books) != 2 {
@@ -187,10 +191,10 @@ func TestCreateBookNewAndExistingAuthor(t *testing.T) {
 		Author:     []string{"S.A. Corey", "Daniel Abrahams"},
 		NumOfPages: 100,
 		Rating:     10,
-		State:      "unread",
+		State:      "want-to-read",
 	}
 
-	_, err := ts.Books.Create(want)
+	_, err := ts.Books.Create(testUser1.ID, want)
 	checkErr(t, err)
 
 	assertAuthorsExist(t, want)
@@ -203,7 +207,7 @@ func TestCreateBookNewAndExistingAuthor(t *testing.T) {
 
 	num := []int{2, 1}
 	for i, v := range want.Author {
-		books, err := ts.Books.GetByAuthor(v)
+		books, err := ts.Books.GetByAuthor(testUser1.ID, v, "")
 		checkErr(t, err)
 
 		if len(books) != num[i] {
@@ -218,9 +222,9 @@ func TestUpdateBookNoAuthorChange(t *testing.T) {
 	want := testBook1
 	want.NumOfPages = 999
 	want.Rating = 1
-	want.State = "unread"
+	want.State = "reading"
 
-	got, err := ts.Books.Update(want.ID, want)
+	got, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if !assertBooksEqual(got, want) {
@@ -234,7 +238,7 @@ func TestUpdateBookAddNewAuthor(t *testing.T) {
 	want := testBook1
 	want.Author = []string{"S.A. Corey", "Ty Franck"}
 
-	got, err := ts.Books.Update(want.ID, want)
+	got, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if !assertBooksEqual(got, want) {
@@ -251,7 +255,7 @@ func TestUpdateBookAddExistingAuthor(t *testing.T) {
 	want := testBook1
 	want.Author = []string{"S.A. Corey", "John Doe"}
 
-	got, err := ts.Books.Update(want.ID, want)
+	got, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if !assertBooksEqual(got, want) {
@@ -267,7 +271,7 @@ func TestUpdateBookRemoveAuthor(t *testing.T) {
 	want := testBook3
 	want.Author = []string{"Regina Phallange", "Ken Adams"}
 
-	got, err := ts.Books.Update(want.ID, want)
+	got, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if !assertBooksEqual(got, want) {
@@ -275,7 +279,7 @@ func TestUpdateBookRemoveAuthor(t *testing.T) {
 	}
 
 	// check john doe still exists in authors table
-	_, err = ts.Authors.GetByName(testAuthor3.Name)
+	_, err = ts.Authors.GetByName(testUser1.ID, testAuthor3.Name)
 	checkErr(t, err)
 
 	// relationship with john doe dropped
@@ -288,7 +292,7 @@ func TestUpdateBookRemoveAuthorCompletely(t *testing.T) {
 	want := testBook3
 	want.Author = []string{"John Doe", "Regina Phallange"}
 
-	got, err := ts.Books.Update(want.ID, want)
+	got, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if !assertBooksEqual(got, want) {
@@ -296,7 +300,7 @@ func TestUpdateBookRemoveAuthorCompletely(t *testing.T) {
 	}
 
 	// check ken adams dropped from authors table completely
-	_, err = ts.Authors.GetByName(testAuthor5.Name)
+	_, err = ts.Authors.GetByName(testUser1.ID, testAuthor5.Name)
 	if err == nil {
 		t.Errorf("expected error: author does not exist")
 	}
@@ -311,7 +315,7 @@ func TestUpdateBookRenameAuthor(t *testing.T) {
 	want := testBook4
 	want.Author = []string{"John Adams"}
 
-	got, err := ts.Books.Update(want.ID, want)
+	got, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	checkErr(t, err)
 
 	if !assertBooksEqual(got, want) {
@@ -321,7 +325,7 @@ func TestUpdateBookRenameAuthor(t *testing.T) {
 	assertAuthorsExist(t, want)
 
 	// check author still exists
-	_, err = ts.Authors.GetByName(testAuthor3.Name)
+	_, err = ts.Authors.GetByName(testUser1.ID, testAuthor3.Name)
 	checkErr(t, err)
 
 	// relationship with john doe dropped
@@ -331,7 +335,7 @@ func TestUpdateBookRenameAuthor(t *testing.T) {
 
 func TestUpdateBookNotExists(t *testing.T) {
 	b := &teal.Book{}
-	_, err := ts.Books.Update(-1, b)
+	_, err := ts.Books.Update(testUser1.ID, -1, 0, b)
 	if err == nil {
 		t.Fatalf("expected error: no books updated")
 	}
@@ -340,21 +344,28 @@ func TestUpdateBookNotExists(t *testing.T) {
 func TestUpdateBookISBNConstraint(t *testing.T) {
 	want := testBook1
 	want.ISBN = testBook2.ISBN
-	_, err := ts.Books.Update(want.ID, want)
+	_, err := ts.Books.Update(testUser1.ID, want.ID, 0, want)
 	if err == nil {
 		t.Errorf("expected error: unique constraint ISBN")
 	}
 }
 
 func TestDeleteBook(t *testing.T) {
-	err := ts.Books.Delete(testBook1.ID)
+	err := ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
 	checkErr(t, err)
 
-	_, err = ts.Books.Get(testBook1.ID)
+	_, err = ts.Books.Get(testUser1.ID, testBook1.ID)
 	if err == nil {
 		t.Errorf("expected error, book %d not deleted", testBook1.ID)
 	}
 
+	// authors are kept while the book is in the trash
+	_, err = ts.Authors.GetByName(testUser1.ID, testBook1.Author[0])
+	checkErr(t, err)
+
+	_, err = ts.Trash.Empty(testUser1.ID, time.Now().Add(time.Minute))
+	checkErr(t, err)
+
 	tx, err := ts.Books.db.Beginx()
 	if err != nil {
 		t.Errorf("db: failed to start transaction: %v", err)
@@ -373,7 +384,7 @@ func TestDeleteBook(t *testing.T) {
 	}
 
 	// check author entry completely deleted from authors
-	_, err = ts.Authors.GetByName(testBook1.Author[0])
+	_, err = ts.Authors.GetByName(testUser1.ID, testBook1.Author[0])
 	if err == nil {
 		t.Errorf("expected error, author %q not deleted", testBook1.Author[0])
 	}
@@ -382,11 +393,11 @@ func TestDeleteBook(t *testing.T) {
 func TestDeleteBookEnsureAuthorRemainsForExistingBooks(t *testing.T) {
 	defer resetDB(testdb)
 
-	err := ts.Books.Delete(testBook3.ID)
+	err := ts.Books.Delete(testUser1.ID, testBook3.ID, 0)
 	checkErr(t, err)
 
 	// check author still exists in authors table
-	got, err := ts.Authors.GetByName(testBook3.Author[0])
+	got, err := ts.Authors.GetByName(testUser1.ID, testBook3.Author[0])
 	checkErr(t, err)
 
 	if got.Name != testBook3.Author[0] {
@@ -420,7 +431,7 @@ func TestDeleteBookEnsureAuthorRemainsForExistingBooks(t *testing.T) {
 }
 
 func TestDeleteBookNotExists(t *testing.T) {
-	err := ts.Books.Delete(-1)
+	err := ts.Books.Delete(testUser1.ID, -1, 0)
 	if err == nil {
 		t.Fatalf("expected error: book not exists")
 	}
@@ -429,7 +440,7 @@ func TestDeleteBookNotExists(t *testing.T) {
 func assertAuthorsExist(t *testing.T, want *teal.Book) {
 	t.Helper()
 	for _, author := range want.Author {
-		got, err := ts.Authors.GetByName(author)
+		got, err := ts.Authors.GetByName(testUser1.ID, author)
 		checkErr(t, err)
 
 		if got.Name != author {
@@ -438,6 +449,78 @@ func assertAuthorsExist(t *testing.T, want *teal.Book) {
 	}
 }
 
+func TestCreateBookContributors(t *testing.T) {
+	defer resetDB(testdb)
+
+	want := []teal.Contributor{
+		{Name: "Zed Author", Role: teal.RoleAuthor},
+		{Name: "Ken Adams", Role: teal.RoleTranslator},
+		{Name: "Anne Author", Role: teal.RoleAuthor},
+	}
+	got, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:        "Translated",
+		ISBN:         "1030",
+		Contributors: want,
+	})
+	checkErr(t, err)
+
+	// contributors keep their order and authors are derived from them
+	if !reflect.DeepEqual(got.Contributors, want) {
+		t.Errorf("got %v, want %v", got.Contributors, want)
+	}
+	assertEqual(t, len(got.Author), 2)
+	assertEqual(t, got.Author[0], "Zed Author")
+	assertEqual(t, got.Author[1], "Anne Author")
+	assertBookAuthorRelationship(t, got)
+
+	// Ken Adams is the author of book 3 and the translator of this book
+	books, err := ts.Books.GetByAuthor(testUser1.ID, "Ken Adams", teal.RoleTranslator)
+	checkErr(t, err)
+	assertEqual(t, len(books), 1)
+	assertEqual(t, books[0].ID, got.ID)
+
+	books, err = ts.Books.GetByAuthor(testUser1.ID, "Ken Adams", "")
+	checkErr(t, err)
+	assertEqual(t, len(books), 1)
+	assertEqual(t, books[0].ID, testBook3.ID)
+}
+
+func TestUpdateBookAuthorsKeepsContributors(t *testing.T) {
+	defer resetDB(testdb)
+
+	b, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title: "Translated",
+		ISBN:  "1031",
+		Contributors: []teal.Contributor{
+			{Name: "Zed Author"},
+			{Name: "Ken Adams", Role: teal.RoleTranslator},
+		},
+	})
+	checkErr(t, err)
+
+	// changing only the authors keeps the translator
+	b.Author = []string{"Anne Author", "Zed Author"}
+	got, err := ts.Books.Update(testUser1.ID, b.ID, 0, b)
+	checkErr(t, err)
+
+	want := []teal.Contributor{
+		{Name: "Anne Author", Role: teal.RoleAuthor},
+		{Name: "Zed Author", Role: teal.RoleAuthor},
+		{Name: "Ken Adams", Role: teal.RoleTranslator},
+	}
+	if !reflect.DeepEqual(got.Contributors, want) {
+		t.Errorf("got %v, want %v", got.Contributors, want)
+	}
+	assertBookAuthorRelationship(t, got)
+
+	// reordering the contributors is a change
+	got.Contributors = []teal.Contributor{want[2], want[1], want[0]}
+	got, err = ts.Books.Update(testUser1.ID, b.ID, 0, got)
+	checkErr(t, err)
+	assertEqual(t, got.Author[0], "Zed Author")
+	assertEqual(t, got.Contributors[0].Name, "Ken Adams")
+}
+
 func assertBookAuthorRelationship(t *testing.T, book *teal.Book) {
 	t.Helper()
 	tx, err := ts.Books.db.Beginx()
@@ -463,9 +546,349 @@ func assertBookAuthorRelationship(t *testing.T, book *teal.Book) {
 
 func assertBooksEqual(a, b *teal.Book) bool {
 	authorEqual := reflect.DeepEqual(a.Author, b.Author)
+	categoriesEqual := reflect.DeepEqual(a.Categories, b.Categories)
+	seriesEqual := reflect.DeepEqual(a.Series, b.Series)
+	tagsEqual := reflect.DeepEqual(a.Tags, b.Tags)
 	return (a.Title == b.Title &&
 		a.ISBN == b.ISBN &&
 		a.NumOfPages == b.NumOfPages &&
 		a.State == b.State &&
-		a.Rating == b.Rating && authorEqual)
+		a.Rating == b.Rating && authorEqual && categoriesEqual && seriesEqual && tagsEqual)
+}
+
+func TestCreateBookState(t *testing.T) {
+	defer resetDB(testdb)
+
+	got, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Dune",
+		ISBN:   "1020",
+		Author: []string{"Frank Herbert"},
+	})
+	checkErr(t, err)
+	assertEqual(t, got.State, teal.StateWantToRead)
+
+	got, err = ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Dune Messiah",
+		ISBN:   "1021",
+		Author: []string{"Frank Herbert"},
+		State:  teal.StateRead,
+	})
+	checkErr(t, err)
+
+	book, err := ts.Books.Get(testUser1.ID, got.ID)
+	checkErr(t, err)
+	assertEqual(t, book.State, teal.StateRead)
+	assertEqual(t, book.DateCompleted.Valid, true)
+	assertEqual(t, book.DateStarted.Valid, false)
+}
+
+func TestUpdateBookState(t *testing.T) {
+	defer resetDB(testdb)
+
+	id := testBook2.ID
+
+	got, err := ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateReading)
+	checkErr(t, err)
+	assertEqual(t, got.State, teal.StateReading)
+	assertEqual(t, got.DateStarted.Valid, true)
+	assertEqual(t, got.DateCompleted.Valid, false)
+	started := got.DateStarted.Time
+
+	got, err = ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateOnHold)
+	checkErr(t, err)
+	assertEqual(t, got.State, teal.StateOnHold)
+
+	// resuming keeps the start date
+	got, err = ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateReading)
+	checkErr(t, err)
+	assertEqual(t, got.DateStarted.Time.Equal(started), true)
+
+	got, err = ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateRead)
+	checkErr(t, err)
+	assertEqual(t, got.State, teal.StateRead)
+	assertEqual(t, got.DateStarted.Valid, true)
+	assertEqual(t, got.DateCompleted.Valid, true)
+
+	// full book is returned
+	if !reflect.DeepEqual(got.Author, testBook2.Author) {
+		t.Errorf("got %v, want %v", got.Author, testBook2.Author)
+	}
+}
+
+func TestUpdateBookStateInvalidTransition(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Books.UpdateState(testUser1.ID, testBook2.ID, 0, teal.StateOnHold)
+	if !errors.Is(err, teal.ErrInvalidTransition) {
+		t.Fatalf("got %v, want %v", err, teal.ErrInvalidTransition)
+	}
+
+	got, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	assertEqual(t, got.State, teal.StateWantToRead)
+}
+
+func TestUpdateBookStateNotExists(t *testing.T) {
+	_, err := ts.Books.UpdateState(testUser1.ID, -1, 0, teal.StateReading)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestUpdateBookKeepsState(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Books.UpdateState(testUser1.ID, testBook2.ID, 0, teal.StateReading)
+	checkErr(t, err)
+
+	// empty state keeps the current state and dates
+	want, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	want.State = ""
+	want.Rating = 2
+
+	_, err = ts.Books.Update(testUser1.ID, want.ID, 0, want)
+	checkErr(t, err)
+
+	got, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	assertEqual(t, got.State, teal.StateReading)
+	assertEqual(t, got.Rating, 2)
+	assertEqual(t, got.DateStarted.Valid, true)
+
+	// invalid transitions are rejected
+	want.State = teal.StateWantToRead
+	_, err = ts.Books.Update(testUser1.ID, want.ID, 0, want)
+	checkErr(t, err)
+
+	want.State = teal.StateOnHold
+	_, err = ts.Books.Update(testUser1.ID, want.ID, 0, want)
+	if !errors.Is(err, teal.ErrInvalidTransition) {
+		t.Errorf("got %v, want %v", err, teal.ErrInvalidTransition)
+	}
+}
+
+func TestBooksScopedToUser(t *testing.T) {
+	defer resetDB(testdb)
+
+	// books of other users do not exist
+	_, err := ts.Books.Get(testUser2.ID, testBook1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	_, _, err = ts.Books.GetAll(testUser2.ID, nil)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+	_, err = ts.Books.Update(testUser2.ID, testBook1.ID, 0, testBook1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	_, err = ts.Books.UpdateState(testUser2.ID, testBook1.ID, 0, teal.StateReading)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	err = ts.Books.Delete(testUser2.ID, testBook1.ID, 0)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, book.UserID, testUser1.ID)
+
+	// ISBNs are unique per user
+	want := &teal.Book{
+		Title:  book.Title,
+		ISBN:   book.ISBN,
+		Author: book.Author,
+	}
+	got, err := ts.Books.Create(testUser2.ID, want)
+	checkErr(t, err)
+	assertEqual(t, got.UserID, testUser2.ID)
+
+	books, info, err := ts.Books.GetAll(testUser2.ID, nil)
+	checkErr(t, err)
+	assertEqual(t, info.Total, 1)
+	assertEqual(t, books[0].ID, got.ID)
+
+	// authors are shared
+	a, err := ts.Authors.GetByName(testUser1.ID, book.Author[0])
+	checkErr(t, err)
+	assertEqual(t, a.Name, book.Author[0])
+
+	// the down migration fails on duplicate ISBNs
+	err = ts.Books.Delete(testUser2.ID, got.ID, 0)
+	checkErr(t, err)
+	_, err = ts.Trash.Empty(testUser2.ID, time.Now().Add(time.Minute))
+	checkErr(t, err)
+}
+
+func TestUpdateBookVersion(t *testing.T) {
+	defer resetDB(testdb)
+
+	b, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	assertEqual(t, b.Version, 1)
+
+	b.Title = "Golden Son"
+	got, err := ts.Books.Update(testUser1.ID, b.ID, b.Version, b)
+	checkErr(t, err)
+	assertEqual(t, got.Version, 2)
+
+	// stale version
+	stale := *b
+	stale.Title = "Morning Star"
+	_, err = ts.Books.Update(testUser1.ID, b.ID, 1, &stale)
+	if err != teal.ErrVersionConflict {
+		t.Errorf("got %v, want %v", err, teal.ErrVersionConflict)
+	}
+
+	// state changes are versioned
+	_, err = ts.Books.UpdateState(testUser1.ID, b.ID, 1, teal.StateReading)
+	if err != teal.ErrVersionConflict {
+		t.Errorf("got %v, want %v", err, teal.ErrVersionConflict)
+	}
+	got, err = ts.Books.UpdateState(testUser1.ID, b.ID, 2, teal.StateReading)
+	checkErr(t, err)
+	assertEqual(t, got.Title, "Golden Son")
+	assertEqual(t, got.Version, 3)
+
+	err = ts.Books.Delete(testUser1.ID, b.ID, 2)
+	if err != teal.ErrVersionConflict {
+		t.Errorf("got %v, want %v", err, teal.ErrVersionConflict)
+	}
+	err = ts.Books.Delete(testUser1.ID, b.ID, 3)
+	checkErr(t, err)
+}
+
+func TestUpdateBookOnlyChanges(t *testing.T) {
+	defer resetDB(testdb)
+
+	b, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	// updates without changes are not written
+	same := *b
+	got, err := ts.Books.Update(testUser1.ID, b.ID, 0, &same)
+	checkErr(t, err)
+	assertEqual(t, got.Version, b.Version)
+
+	history, err := ts.Revisions.GetBookHistory(testUser1.ID, b.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", history, teal.ErrNoRows)
+	}
+
+	// categories and tags in a different order are unchanged
+	reordered := *b
+	reordered.Rating = 2
+	reordered.Tags = append([]string{}, b.Tags...)
+	sort.Sort(sort.Reverse(sort.StringSlice(reordered.Tags)))
+	got, err = ts.Books.Update(testUser1.ID, b.ID, 0, &reordered)
+	checkErr(t, err)
+	assertEqual(t, got.Version, b.Version+1)
+
+	history, err = ts.Revisions.GetBookHistory(testUser1.ID, b.ID)
+	checkErr(t, err)
+	assertEqual(t, len(history[0].Changes), 1)
+	assertEqual(t, history[0].Changes["rating"].To.(float64), 2)
+}
+
+func TestCreateBookNormalizesISBN(t *testing.T) {
+	defer resetDB(testdb)
+
+	b := &teal.Book{
+		Title:  "Leviathan Wakes",
+		ISBN:   "0-316-12908-9",
+		Author: []string{"James S.A. Corey"},
+	}
+	got, err := ts.Books.Create(testUser1.ID, b)
+	checkErr(t, err)
+	assertEqual(t, got.ISBN, "9780316129084")
+	assertEqual(t, got.ISBN10, "0316129089")
+
+	// either form, with or without hyphens, finds the book
+	for _, isbn := range []string{"9780316129084", "978-0-316-12908-4", "0316129089", "0-316-12908-9"} {
+		found, err := ts.Books.GetByISBN(testUser1.ID, isbn)
+		checkErr(t, err)
+		assertEqual(t, found.ID, got.ID)
+		assertEqual(t, found.ISBN10, "0316129089")
+	}
+
+	// the same book by its ISBN-13 is a duplicate
+	_, err = ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Leviathan Wakes",
+		ISBN:   "9780316129084",
+		Author: []string{"James S.A. Corey"},
+	})
+	if err == nil {
+		t.Errorf("expected error for duplicate ISBN")
+	}
+
+	// ISBN-13s with the 979 prefix have no ISBN-10
+	updated := *got
+	updated.ISBN = "979-10-90636-07-1"
+	got, err = ts.Books.Update(testUser1.ID, got.ID, 0, &updated)
+	checkErr(t, err)
+	assertEqual(t, got.ISBN, "9791090636071")
+	assertEqual(t, got.ISBN10, "")
+
+	// the book is purged so its ISBNs can be reused
+	checkErr(t, ts.Books.Delete(testUser1.ID, got.ID, 0))
+	_, err = ts.Trash.Purge(time.Now().Add(time.Hour))
+	checkErr(t, err)
+}
+
+func TestSetBookCover(t *testing.T) {
+	defer resetDB(testdb)
+
+	b, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	cover := strings.Repeat("ab", 32)
+
+	got, err := ts.Books.SetCover(testUser1.ID, b.ID, b.Version, cover)
+	checkErr(t, err)
+	assertEqual(t, got.Cover, cover)
+	assertEqual(t, got.Version, b.Version+1)
+
+	_, err = ts.Books.SetCover(testUser1.ID, b.ID, b.Version, "")
+	if err != teal.ErrVersionConflict {
+		t.Errorf("got %v, want %v", err, teal.ErrVersionConflict)
+	}
+
+	// covers are not changed by updates
+	updated := *got
+	updated.Title = "Golden Son"
+	updated.Cover = ""
+	got, err = ts.Books.Update(testUser1.ID, b.ID, 0, &updated)
+	checkErr(t, err)
+	assertEqual(t, got.Cover, cover)
+
+	// or recorded as revisions
+	history, err := ts.Revisions.GetBookHistory(testUser1.ID, b.ID)
+	checkErr(t, err)
+	assertEqual(t, len(history), 1)
+
+	// covers of books in the trash are in use
+	checkErr(t, ts.Books.Delete(testUser1.ID, b.ID, 0))
+	inUse, err := ts.Books.CoverInUse(cover)
+	checkErr(t, err)
+	assertEqual(t, inUse, true)
+	covers, err := ts.Books.Covers()
+	checkErr(t, err)
+	assertEqual(t, len(covers), 1)
+	assertEqual(t, covers[0], cover)
+
+	_, err = ts.Trash.Purge(time.Now().Add(time.Hour))
+	checkErr(t, err)
+	inUse, err = ts.Books.CoverInUse(cover)
+	checkErr(t, err)
+	assertEqual(t, inUse, false)
+}
+
+func TestSetBookCoverNotExists(t *testing.T) {
+	_, err := ts.Books.SetCover(testUser1.ID, -1, 0, "")
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
 }
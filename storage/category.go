@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+	tcontext "github.com/kencx/teal/context"
+)
+
+type CategoryStore struct {
+	db *sqlx.DB
+}
+
+// parent_id is NULL for top-level categories
+const categoryColumns = `id, name, COALESCE(parent_id, 0) AS parent_id, dateAdded, dateUpdated`
+
+func (s *CategoryStore) Get(userID, id int64) (*teal.Category, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var category teal.Category
+	stmt := `SELECT ` + categoryColumns + ` FROM categories WHERE id=$1 AND user_id=$2;`
+	err = tx.QueryRowx(stmt, id, userID).StructScan(&category)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve category %d failed: %v", id, err)
+	}
+
+	children, err := getChildCategories(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	category.Children = children
+	return &category, nil
+}
+
+func (s *CategoryStore) GetByName(userID int64, name string) (*teal.Category, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var category teal.Category
+	stmt := `SELECT ` + categoryColumns + ` FROM categories WHERE name=$1 AND user_id=$2;`
+	err = tx.QueryRowx(stmt, name, userID).StructScan(&category)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve category %q failed: %v", name, err)
+	}
+
+	children, err := getChildCategories(tx, category.ID)
+	if err != nil {
+		return nil, err
+	}
+	category.Children = children
+	return &category, nil
+}
+
+// Retrieve all of a user's categories as a flat list. Use parent_id to build
+// the hierarchy
+func (s *CategoryStore) GetAll(userID int64) ([]*teal.Category, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var categories []*teal.Category
+	stmt := `SELECT ` + categoryColumns + ` FROM categories WHERE user_id=$1 ORDER BY id;`
+	err = tx.Select(&categories, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve all categories failed: %v", err)
+	}
+	if len(categories) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return categories, nil
+}
+
+func (s *CategoryStore) Create(userID int64, c *teal.Category) (*teal.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if c.ParentID != 0 {
+			if err := checkCategoryExists(tx, userID, c.ParentID); err != nil {
+				return err
+			}
+		}
+
+		var id int64
+		stmt := `INSERT INTO categories (user_id, name, parent_id) VALUES ($1, $2, $3) RETURNING id;`
+		err := tx.QueryRowx(stmt, userID, c.Name, nullCategoryID(c.ParentID)).Scan(&id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return teal.ErrDuplicateCategory
+			}
+			return fmt.Errorf("db: insert to categories table failed: %v", err)
+		}
+		// save id to context for querying later
+		ctx = tcontext.WithCategoryID(ctx, id)
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+
+	id, err := tcontext.GetCategoryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// query category after transaction committed
+	category, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Update category name and parent. A category cannot be moved under itself or
+// any of its descendants
+func (s *CategoryStore) Update(userID, id int64, c *teal.Category) (*teal.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if c.ParentID != 0 {
+			if err := checkCategoryExists(tx, userID, c.ParentID); err != nil {
+				return err
+			}
+
+			isDescendant, err := isDescendantCategory(tx, id, c.ParentID)
+			if err != nil {
+				return err
+			}
+			if isDescendant {
+				return teal.ErrInvalidParent
+			}
+		}
+
+		stmt := `UPDATE categories
+			SET name=$1,
+			parent_id=$2,
+			dateUpdated=CURRENT_TIMESTAMP
+			WHERE id=$3 AND user_id=$4;`
+		res, err := tx.Exec(stmt, c.Name, nullCategoryID(c.ParentID), id, userID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return teal.ErrDuplicateCategory
+			}
+			return fmt.Errorf("db: update category %d failed: %v", id, err)
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: update category %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+
+		ids, err := getBookIDsFromCategory(tx, id)
+		if err != nil {
+			return err
+		}
+		return bumpBookVersions(tx, ids)
+
+	}); err != nil {
+		return nil, err
+	}
+
+	category, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Delete a category and its book relationships. Child categories are moved
+// up to the deleted category's parent
+func (s *CategoryStore) Delete(userID, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		var parentID sql.NullInt64
+		stmt := `SELECT parent_id FROM categories WHERE id=$1 AND user_id=$2;`
+		err := tx.Get(&parentID, stmt, id, userID)
+		if err == sql.ErrNoRows {
+			return teal.ErrDoesNotExist
+		}
+		if err != nil {
+			return fmt.Errorf("db: retrieve category %d failed: %v", id, err)
+		}
+
+		stmt = `UPDATE categories SET parent_id=$1 WHERE parent_id=$2;`
+		if _, err := tx.Exec(stmt, parentID, id); err != nil {
+			return fmt.Errorf("db: move children of category %d failed: %v", id, err)
+		}
+
+		ids, err := getBookIDsFromCategory(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := bumpBookVersions(tx, ids); err != nil {
+			return err
+		}
+
+		stmt = `DELETE FROM books_categories WHERE category_id=$1;`
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("db: delete category %d from books_categories failed: %v", id, err)
+		}
+
+		stmt = `DELETE FROM categories WHERE id=$1;`
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("db: delete category %d failed: %v", id, err)
+		}
+		return nil
+
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getBookIDsFromCategory(tx *sqlx.Tx, id int64) ([]int64, error) {
+
+	var ids []int64
+	stmt := `SELECT book_id FROM books_categories WHERE category_id=$1 ORDER BY book_id;`
+	if err := tx.Select(&ids, stmt, id); err != nil {
+		return nil, fmt.Errorf("db: retrieve books of category %d failed: %v", id, err)
+	}
+	return ids, nil
+}
+
+func getChildCategories(tx *sqlx.Tx, id int64) ([]*teal.Category, error) {
+	var children []*teal.Category
+	stmt := `SELECT ` + categoryColumns + ` FROM categories WHERE parent_id=$1 ORDER BY name;`
+	if err := tx.Select(&children, stmt, id); err != nil {
+		return nil, fmt.Errorf("db: retrieve children of category %d failed: %v", id, err)
+	}
+	return children, nil
+}
+
+// check that a user's category exists
+func checkCategoryExists(tx *sqlx.Tx, userID, id int64) error {
+	var count int
+	stmt := `SELECT COUNT(*) FROM categories WHERE id=$1 AND user_id=$2;`
+	if err := tx.Get(&count, stmt, id, userID); err != nil {
+		return fmt.Errorf("db: retrieve category %d failed: %v", id, err)
+	}
+	if count == 0 {
+		return teal.ErrInvalidParent
+	}
+	return nil
+}
+
+// check if category is the same as, or nested under, ancestor
+func isDescendantCategory(tx *sqlx.Tx, ancestor, category int64) (bool, error) {
+	var count int
+	stmt := `WITH RECURSIVE descendants(id) AS (
+			SELECT id FROM categories WHERE id=$1
+			UNION ALL
+			SELECT c.id FROM categories c
+			JOIN descendants d ON c.parent_id=d.id
+		)
+		SELECT COUNT(*) FROM descendants WHERE id=$2;`
+	if err := tx.Get(&count, stmt, ancestor, category); err != nil {
+		return false, fmt.Errorf("db: retrieve descendants of category %d failed: %v", ancestor, err)
+	}
+	return count > 0, nil
+}
+
+func nullCategoryID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// insert a user's category as top-level category. If already exists, return
+// category id
+func insertOrGetCategory(tx *sqlx.Tx, userID int64, name string) (int64, error) {
+
+	var id int64
+	stmt := `INSERT INTO categories (user_id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING RETURNING id;`
+	err := tx.Get(&id, stmt, userID, name)
+
+	// no rows inserted, query to get existing id
+	if err == sql.ErrNoRows {
+		// categories.name is unique per user
+		stmt := `SELECT id FROM categories WHERE user_id=$1 AND name=$2;`
+		if err := tx.Get(&id, stmt, userID, name); err != nil {
+			return -1, fmt.Errorf("db: query existing category failed: %v", err)
+		}
+		return id, nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("db: insert to categories table failed: %v", err)
+	}
+	return id, nil
+}
+
+func insertOrGetCategories(tx *sqlx.Tx, userID int64, names []string) ([]int64, error) {
+
+	var ids []int64
+	for _, name := range names {
+		id, err := insertOrGetCategory(tx, userID, name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kencx/teal"
+)
+
+func TestGetCategory(t *testing.T) {
+	got, err := ts.Categories.Get(testUser1.ID, testCategory1.ID)
+	checkErr(t, err)
+
+	if !assertCategoriesEqual(got, testCategory1) {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(testCategory1))
+	}
+
+	if len(got.Children) != 1 {
+		t.Fatalf("got %d children, want %d children", len(got.Children), 1)
+	}
+	if !assertCategoriesEqual(got.Children[0], testCategory2) {
+		t.Errorf("got %v, want %v", prettyPrint(got.Children[0]), prettyPrint(testCategory2))
+	}
+}
+
+func TestGetCategoryByName(t *testing.T) {
+	got, err := ts.Categories.GetByName(testUser1.ID, testCategory2.Name)
+	checkErr(t, err)
+
+	if !assertCategoriesEqual(got, testCategory2) {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(testCategory2))
+	}
+}
+
+func TestGetCategoryNotExists(t *testing.T) {
+	got, err := ts.Categories.Get(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
+
+func TestGetAllCategories(t *testing.T) {
+	got, err := ts.Categories.GetAll(testUser1.ID)
+	checkErr(t, err)
+
+	want := []*teal.Category{testCategory1, testCategory2, testCategory3}
+	if len(got) != len(want) {
+		t.Fatalf("got %d categories, want %d categories", len(got), len(want))
+	}
+
+	for i := range got {
+		if !assertCategoriesEqual(got[i], want[i]) {
+			t.Errorf("got %v, want %v", prettyPrint(got[i]), prettyPrint(want[i]))
+		}
+	}
+}
+
+func TestCreateCategory(t *testing.T) {
+	defer resetDB(testdb)
+
+	tests := []struct {
+		name string
+		want *teal.Category
+	}{{
+		name: "top-level category",
+		want: &teal.Category{Name: "Fantasy"},
+	}, {
+		name: "nested category",
+		want: &teal.Category{Name: "Space Opera", ParentID: testCategory2.ID},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.Categories.Create(testUser1.ID, tt.want)
+			checkErr(t, err)
+
+			if got.Name != tt.want.Name || got.ParentID != tt.want.ParentID {
+				t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(tt.want))
+			}
+		})
+	}
+}
+
+func TestCreateCategoryDuplicate(t *testing.T) {
+	_, err := ts.Categories.Create(testUser1.ID, &teal.Category{Name: testCategory1.Name})
+	if err != teal.ErrDuplicateCategory {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCreateCategoryParentNotExists(t *testing.T) {
+	_, err := ts.Categories.Create(testUser1.ID, &teal.Category{Name: "Orphan", ParentID: 100})
+	if err != teal.ErrInvalidParent {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdateCategory(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	want := &teal.Category{Name: "Science Fiction", ParentID: testCategory1.ID}
+	got, err := ts.Categories.Update(testUser1.ID, testCategory2.ID, want)
+	checkErr(t, err)
+
+	if got.Name != want.Name || got.ParentID != want.ParentID {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(want))
+	}
+
+	// book categories reflect the new name
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(book.Categories, []string{want.Name}) {
+		t.Errorf("got %v, want %v", book.Categories, []string{want.Name})
+	}
+	assertEqual(t, book.Version, before.Version+1)
+}
+
+func TestUpdateCategoryCycle(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       int64
+		category *teal.Category
+	}{{
+		name:     "own parent",
+		id:       testCategory1.ID,
+		category: &teal.Category{Name: testCategory1.Name, ParentID: testCategory1.ID},
+	}, {
+		name:     "descendant parent",
+		id:       testCategory1.ID,
+		category: &teal.Category{Name: testCategory1.Name, ParentID: testCategory2.ID},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ts.Categories.Update(testUser1.ID, tt.id, tt.category)
+			if err != teal.ErrInvalidParent {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateCategoryNotExists(t *testing.T) {
+	_, err := ts.Categories.Update(testUser1.ID, -1, &teal.Category{Name: "Foo"})
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDeleteCategory(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Categories.Delete(testUser1.ID, testCategory1.ID)
+	checkErr(t, err)
+
+	_, err = ts.Categories.Get(testUser1.ID, testCategory1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("expected error, category %d not deleted", testCategory1.ID)
+	}
+
+	// child category moved to top-level
+	child, err := ts.Categories.Get(testUser1.ID, testCategory2.ID)
+	checkErr(t, err)
+	if child.ParentID != 0 {
+		t.Errorf("got parent %d, want %d", child.ParentID, 0)
+	}
+}
+
+func TestDeleteCategoryRemovesBookRelationship(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	err = ts.Categories.Delete(testUser1.ID, testCategory2.ID)
+	checkErr(t, err)
+
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	if len(book.Categories) != 0 {
+		t.Errorf("got %v, want no categories", book.Categories)
+	}
+	assertEqual(t, book.Version, before.Version+1)
+}
+
+func TestCategoriesOtherUser(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Categories.GetAll(testUser2.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ts.Categories.Get(testUser2.ID, testCategory1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ts.Categories.Update(testUser2.ID, testCategory2.ID, &teal.Category{Name: "Foo"})
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = ts.Categories.Delete(testUser2.ID, testCategory2.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// another user's category cannot be a parent
+	_, err = ts.Categories.Create(testUser2.ID, &teal.Category{Name: "Space Opera", ParentID: testCategory2.ID})
+	if err != teal.ErrInvalidParent {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// names are unique per user
+	got, err := ts.Categories.Create(testUser2.ID, &teal.Category{Name: testCategory2.Name})
+	checkErr(t, err)
+	if got.ID == testCategory2.ID {
+		t.Errorf("got category %d of another user", got.ID)
+	}
+
+	want, err := ts.Categories.Get(testUser1.ID, testCategory2.ID)
+	checkErr(t, err)
+	assertEqual(t, want.Name, testCategory2.Name)
+	assertEqual(t, want.ParentID, testCategory1.ID)
+}
+
+func TestDeleteCategoryNotExists(t *testing.T) {
+	err := ts.Categories.Delete(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetBooksByCategory(t *testing.T) {
+	resetDB(testdb)
+
+	tests := []struct {
+		name     string
+		category string
+		want     []int64
+	}{{
+		name:     "category",
+		category: testCategory2.Name,
+		want:     []int64{testBook1.ID, testBook2.ID},
+	}, {
+		name:     "parent category includes subcategories",
+		category: testCategory1.Name,
+		want:     []int64{testBook1.ID, testBook2.ID},
+	}, {
+		name:     "category with no books",
+		category: testCategory3.Name,
+		want:     nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.Books.GetByCategory(testUser1.ID, tt.category)
+			checkErr(t, err)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d books, want %d books", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].ID != tt.want[i] {
+					t.Errorf("got book %d, want book %d", got[i].ID, tt.want[i])
+				}
+				if !reflect.DeepEqual(got[i].Categories, []string{testCategory2.Name}) {
+					t.Errorf("got %v, want %v", got[i].Categories, []string{testCategory2.Name})
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBookWithCategories(t *testing.T) {
+	defer resetDB(testdb)
+
+	want := &teal.Book{
+		Title:      "Dune",
+		ISBN:       "1006",
+		Author:     []string{"Frank Herbert"},
+		Categories: []string{"Classics", "Sci-Fi"},
+	}
+
+	_, err := ts.Books.Create(testUser1.ID, want)
+	checkErr(t, err)
+
+	got, err := ts.Books.Get(testUser1.ID, want.ID)
+	checkErr(t, err)
+	if !assertBooksEqual(got, want) {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(want))
+	}
+
+	// new category created as top-level category
+	c, err := ts.Categories.GetByName(testUser1.ID, "Classics")
+	checkErr(t, err)
+	if c.ParentID != 0 {
+		t.Errorf("got parent %d, want %d", c.ParentID, 0)
+	}
+}
+
+func TestUpdateBookCategories(t *testing.T) {
+	defer resetDB(testdb)
+
+	want, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	want.Categories = []string{"Fiction"}
+
+	_, err = ts.Books.Update(testUser1.ID, want.ID, 0, want)
+	checkErr(t, err)
+
+	got, err := ts.Books.Get(testUser1.ID, want.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(got.Categories, want.Categories) {
+		t.Errorf("got %v, want %v", got.Categories, want.Categories)
+	}
+}
+
+func assertCategoriesEqual(a, b *teal.Category) bool {
+	return a.ID == b.ID && a.Name == b.Name && a.ParentID == b.ParentID
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// Ebook files attached to books. The files themselves are stored on disk, see
+// package ebook
+type FileStore struct {
+	db *sqlx.DB
+}
+
+// Retrieve a file attached to a user's book
+func (s *FileStore) Get(userID, bookID, id int64) (*teal.File, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	if _, err := getBookState(tx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	var f teal.File
+	stmt := `SELECT * FROM files WHERE id=$1 AND book_id=$2;`
+	err = tx.Get(&f, stmt, id, bookID)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve file %d failed: %v", id, err)
+	}
+	return &f, nil
+}
+
+// Retrieve all files attached to a user's book, oldest first
+func (s *FileStore) GetAll(userID, bookID int64) ([]*teal.File, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	if _, err := getBookState(tx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	var files []*teal.File
+	stmt := `SELECT * FROM files WHERE book_id=$1 ORDER BY id;`
+	if err := tx.Select(&files, stmt, bookID); err != nil {
+		return nil, fmt.Errorf("db: retrieve files of book %d failed: %v", bookID, err)
+	}
+	if len(files) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return files, nil
+}
+
+// Retrieve the files attached to the user's given books, oldest first, keyed by
+// book ID. Books without files are left out
+func (s *FileStore) GetByBooks(userID int64, bookIDs []int64) (map[int64][]*teal.File, error) {
+	result := make(map[int64][]*teal.File)
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	stmt := `SELECT f.* FROM files f
+		JOIN books b ON b.id=f.book_id
+		WHERE f.user_id=? AND b.dateDeleted IS NULL AND f.book_id IN (?)
+		ORDER BY f.id;`
+	query, args, err := sqlx.In(stmt, userID, bookIDs)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve files of books %v failed: %v", bookIDs, err)
+	}
+
+	var files []*teal.File
+	if err := s.db.Select(&files, s.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("db: retrieve files of books %v failed: %v", bookIDs, err)
+	}
+	for _, f := range files {
+		result[f.BookID] = append(result[f.BookID], f)
+	}
+	return result, nil
+}
+
+// Retrieve a user's file by its checksum, including files of books in the
+// trash
+func (s *FileStore) GetByChecksum(userID int64, checksum string) (*teal.File, error) {
+	var f teal.File
+	stmt := `SELECT * FROM files WHERE user_id=$1 AND checksum=$2;`
+	err := s.db.Get(&f, stmt, userID, checksum)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve file checksum %q failed: %v", checksum, err)
+	}
+	return &f, nil
+}
+
+// Attach a file to a user's book. Returns teal.ErrDuplicateFile if the user
+// already has a file with the same checksum
+func (s *FileStore) Create(userID int64, f *teal.File) (*teal.File, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if _, err := getBookState(tx, userID, f.BookID); err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO files (book_id, user_id, name, format, size, checksum)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;`
+		err := tx.QueryRowx(stmt, f.BookID, userID, f.Name, f.Format, f.Size, f.Checksum).Scan(&id)
+		if isUniqueViolation(err) {
+			return teal.ErrDuplicateFile
+		}
+		if err != nil {
+			return fmt.Errorf("db: insert to files table failed: %v", err)
+		}
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, f.BookID, id)
+}
+
+// Detach a file from a user's book
+func (s *FileStore) Delete(userID, bookID, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if _, err := getBookState(tx, userID, bookID); err != nil {
+			return err
+		}
+
+		stmt := `DELETE FROM files WHERE id=$1 AND book_id=$2;`
+		res, err := tx.Exec(stmt, id, bookID)
+		if err != nil {
+			return fmt.Errorf("db: delete file %d failed: %v", id, err)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: delete file %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+		return nil
+	})
+}
+
+// Whether any user has a file with the given checksum
+func (s *FileStore) InUse(checksum string) (bool, error) {
+	var count int
+	stmt := `SELECT COUNT(*) FROM files WHERE checksum=$1;`
+	if err := s.db.Get(&count, stmt, checksum); err != nil {
+		return false, fmt.Errorf("db: retrieve files with checksum failed: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Retrieve the checksums of all files, including files of books in the trash
+func (s *FileStore) Checksums() ([]string, error) {
+	var checksums []string
+	stmt := `SELECT DISTINCT checksum FROM files;`
+	if err := s.db.Select(&checksums, stmt); err != nil {
+		return nil, fmt.Errorf("db: retrieve checksums failed: %v", err)
+	}
+	return checksums, nil
+}
+
+func deleteFiles(tx *sqlx.Tx, bookID int64) error {
+	stmt := `DELETE FROM files WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, bookID); err != nil {
+		return fmt.Errorf("db: delete files of book %d failed: %v", bookID, err)
+	}
+	return nil
+}
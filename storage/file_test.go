@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func TestFiles(t *testing.T) {
+	defer resetDB(testdb)
+
+	epub := &teal.File{
+		BookID:   testBook1.ID,
+		Name:     "leviathan-wakes.epub",
+		Format:   teal.FormatEPUB,
+		Size:     1024,
+		Checksum: strings.Repeat("ab", 32),
+	}
+	got, err := ts.Files.Create(testUser1.ID, epub)
+	checkErr(t, err)
+	assertEqual(t, got.BookID, testBook1.ID)
+	assertEqual(t, got.UserID, testUser1.ID)
+	assertEqual(t, got.Name, epub.Name)
+	assertEqual(t, got.Size, 1024)
+	if got.DateAdded.IsZero() {
+		t.Errorf("got no date added")
+	}
+
+	pdf := &teal.File{
+		BookID:   testBook1.ID,
+		Name:     "leviathan-wakes.pdf",
+		Format:   teal.FormatPDF,
+		Size:     2048,
+		Checksum: strings.Repeat("cd", 32),
+	}
+	_, err = ts.Files.Create(testUser1.ID, pdf)
+	checkErr(t, err)
+
+	files, err := ts.Files.GetAll(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, len(files), 2)
+	assertEqual(t, files[0].ID, got.ID)
+
+	byBook, err := ts.Files.GetByBooks(testUser1.ID, []int64{testBook1.ID, testBook2.ID})
+	checkErr(t, err)
+	assertEqual(t, len(byBook), 1)
+	assertEqual(t, len(byBook[testBook1.ID]), 2)
+	assertEqual(t, byBook[testBook1.ID][1].Format, teal.FormatPDF)
+
+	// other users' books have no files
+	byBook, err = ts.Files.GetByBooks(testUser2.ID, []int64{testBook1.ID})
+	checkErr(t, err)
+	assertEqual(t, len(byBook), 0)
+
+	found, err := ts.Files.GetByChecksum(testUser1.ID, epub.Checksum)
+	checkErr(t, err)
+	assertEqual(t, found.ID, got.ID)
+
+	// a user can only attach a file once
+	dup := *epub
+	dup.BookID = testBook2.ID
+	_, err = ts.Files.Create(testUser1.ID, &dup)
+	if err != teal.ErrDuplicateFile {
+		t.Errorf("got %v, want %v", err, teal.ErrDuplicateFile)
+	}
+
+	checkErr(t, ts.Files.Delete(testUser1.ID, testBook1.ID, got.ID))
+	_, err = ts.Files.Get(testUser1.ID, testBook1.ID, got.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	inUse, err := ts.Files.InUse(epub.Checksum)
+	checkErr(t, err)
+	assertEqual(t, inUse, false)
+
+	err = ts.Files.Delete(testUser1.ID, testBook1.ID, got.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestFilesScopedToUser(t *testing.T) {
+	defer resetDB(testdb)
+
+	f := &teal.File{
+		BookID:   testBook1.ID,
+		Name:     "leviathan-wakes.epub",
+		Format:   teal.FormatEPUB,
+		Size:     1024,
+		Checksum: strings.Repeat("ab", 32),
+	}
+	_, err := ts.Files.Create(testUser2.ID, f)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	got, err := ts.Files.Create(testUser1.ID, f)
+	checkErr(t, err)
+	_, err = ts.Files.GetAll(testUser2.ID, testBook1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	_, err = ts.Files.GetByChecksum(testUser2.ID, f.Checksum)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	err = ts.Files.Delete(testUser2.ID, testBook1.ID, got.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestPurgeBookDeletesFiles(t *testing.T) {
+	defer resetDB(testdb)
+
+	f := &teal.File{
+		BookID:   testBook1.ID,
+		Name:     "leviathan-wakes.epub",
+		Format:   teal.FormatEPUB,
+		Size:     1024,
+		Checksum: strings.Repeat("ab", 32),
+	}
+	_, err := ts.Files.Create(testUser1.ID, f)
+	checkErr(t, err)
+
+	// files of books in the trash are kept
+	checkErr(t, ts.Books.Delete(testUser1.ID, testBook1.ID, 0))
+	checksums, err := ts.Files.Checksums()
+	checkErr(t, err)
+	assertEqual(t, len(checksums), 1)
+	assertEqual(t, checksums[0], f.Checksum)
+
+	_, err = ts.Trash.Purge(time.Now().Add(time.Hour))
+	checkErr(t, err)
+	inUse, err := ts.Files.InUse(f.Checksum)
+	checkErr(t, err)
+	assertEqual(t, inUse, false)
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// sortable columns of books and authors, keyed by teal.BookSortFields and
+// teal.AuthorSortFields
+var (
+	bookSortColumns = map[string]string{
+		"id":            "b.id",
+		"title":         "LOWER(b.title)",
+		"rating":        "b.rating",
+		"numOfPages":    "b.numOfPages",
+		"dateAdded":     "b.dateAdded",
+		"dateUpdated":   "b.dateUpdated",
+		"dateCompleted": "b.dateCompleted",
+	}
+	authorSortColumns = map[string]string{
+		"id":   "a.id",
+		"name": "LOWER(a.name)",
+	}
+)
+
+// dates are compared as strings in SQLite
+const filterDateFormat = "2006-01-02"
+
+// WHERE clause of a listing, built from its filters. Placeholders are ? and
+// slices are expanded with sqlx.In, so queries must be rebound before use
+type where struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *where) add(cond string, args ...interface{}) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// WHERE clause of a listing of a user's books
+func bookWhere(userID int64, f *teal.BookFilter) *where {
+	w := &where{}
+	w.add(`b.user_id=?`, userID)
+	w.add(`b.dateDeleted IS NULL`)
+
+	if f.Author != "" {
+		role := f.Role
+		if role == "" {
+			role = teal.RoleAuthor
+		}
+		w.add(`b.id IN (SELECT ba.book_id
+			FROM books_authors ba
+			JOIN authors a ON a.id=ba.author_id
+			WHERE (a.name=? OR a.id IN (SELECT author_id FROM author_aliases WHERE name=?))
+				AND ba.role=?)`, f.Author, f.Author, role)
+	}
+
+	if f.Category != "" {
+		w.add(`b.id IN (SELECT bc.book_id
+			FROM books_categories bc
+			WHERE bc.category_id IN (
+				WITH RECURSIVE subcategories(id) AS (
+					SELECT id FROM categories WHERE name=? AND user_id=?
+					UNION ALL
+					SELECT c.id FROM categories c
+					JOIN subcategories s ON c.parent_id=s.id
+				)
+				SELECT id FROM subcategories))`, f.Category, userID)
+	}
+
+	if tags := uniqueStrings(f.Tags); len(tags) > 0 {
+		minMatches := 1
+		if f.MatchAll {
+			minMatches = len(tags)
+		}
+		w.add(`b.id IN (SELECT bt.book_id
+			FROM books_tags bt
+			JOIN tags t ON t.id=bt.tag_id
+			WHERE t.name IN (?)
+			GROUP BY bt.book_id
+			HAVING COUNT(DISTINCT bt.tag_id) >= ?)`, tags, minMatches)
+	}
+
+	if f.State != "" {
+		w.add(`b.state=?`, f.State)
+	}
+
+	if f.MinRating != nil {
+		w.add(`b.rating>=?`, *f.MinRating)
+	}
+	if f.MaxRating != nil {
+		w.add(`b.rating<=?`, *f.MaxRating)
+	}
+	if f.MinPages != nil {
+		w.add(`b.numOfPages>=?`, *f.MinPages)
+	}
+	if f.MaxPages != nil {
+		w.add(`b.numOfPages<=?`, *f.MaxPages)
+	}
+
+	addDateRange(w, "b.dateAdded", f.AddedAfter, f.AddedBefore)
+	addDateRange(w, "b.dateCompleted", f.CompletedAfter, f.CompletedBefore)
+	return w
+}
+
+// filter column to the days between after and before, inclusive
+func addDateRange(w *where, column string, after, before time.Time) {
+	if !after.IsZero() {
+		w.add(column+`>=?`, after.Format(filterDateFormat))
+	}
+	if !before.IsZero() {
+		w.add(column+`<?`, before.AddDate(0, 0, 1).Format(filterDateFormat))
+	}
+}
+
+// column and direction of a page's sort. Pages are sorted by id by default
+func sortColumn(p teal.Page, columns map[string]string) (string, bool, error) {
+	field, desc := p.SortBy()
+	if field == "" {
+		field = "id"
+	}
+
+	col, ok := columns[field]
+	if !ok {
+		return "", false, fmt.Errorf("db: cannot sort by %q", field)
+	}
+	return col, desc, nil
+}
+
+// ORDER BY and LIMIT clauses of a page. NULLs are always sorted last and ties
+// are broken by id. One row more than the limit is selected to tell if there is
+// a next page. A limit of 0 returns all rows
+func pageClause(p teal.Page, columns map[string]string, id string) (string, error) {
+	col, desc, err := sortColumn(p, columns)
+	if err != nil {
+		return "", err
+	}
+
+	order := " ASC"
+	if desc {
+		order = " DESC"
+	}
+
+	clause := fmt.Sprintf(" ORDER BY (%s IS NULL), %s%s", col, col, order)
+	if col != id {
+		clause += ", " + id + order
+	}
+	if p.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", p.Limit+1)
+	}
+	return clause, nil
+}
+
+// filter w to the rows after the page's cursor, in the order of pageClause
+func addCursor(w *where, p teal.Page, columns map[string]string, id string) error {
+	if p.After == nil {
+		return nil
+	}
+
+	col, desc, err := sortColumn(p, columns)
+	if err != nil {
+		return err
+	}
+
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	c := p.After
+	switch {
+	case col == id:
+		w.add(id+op+`?`, c.ID)
+	case c.Value == nil:
+		// NULLs are last, so only NULLs with a later id follow
+		w.add(fmt.Sprintf(`(%s IS NULL AND %s%s?)`, col, id, op), c.ID)
+	default:
+		w.add(fmt.Sprintf(`((%s, %s) %s (?, ?) OR %s IS NULL)`, col, id, op, col), *c.Value, c.ID)
+	}
+	return nil
+}
+
+// select a page of rows into dest and count all rows matching w. from is the
+// FROM clause shared by both queries. Up to p.Limit+1 rows are selected, see
+// nextCursor
+func selectPage(tx *sqlx.Tx, dest interface{}, columns, from string, w *where, p teal.Page, sort map[string]string, id string) (int, error) {
+	page, err := pageClause(p, sort, id)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err := sqlx.In(`SELECT COUNT(*) `+from+w.String(), w.args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	if err := tx.Get(&total, tx.Rebind(query), args...); err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	after := &where{
+		conds: append([]string{}, w.conds...),
+		args:  append([]interface{}{}, w.args...),
+	}
+	if err := addCursor(after, p, sort, id); err != nil {
+		return 0, err
+	}
+
+	query, args, err = sqlx.In(`SELECT `+columns+` `+from+after.String()+page, after.args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Select(dest, tx.Rebind(query), args...); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// cursor of the page after the row with the given id. The sort value is read
+// as text, which both databases convert back when comparing it to the column
+func nextCursor(tx *sqlx.Tx, p teal.Page, sort map[string]string, from, id string, rowID int64) (*teal.Cursor, error) {
+	col, _, err := sortColumn(p, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var value sql.NullString
+	query := `SELECT CAST(` + col + ` AS TEXT) ` + from + ` WHERE ` + id + `=?`
+	if err := tx.Get(&value, tx.Rebind(query), rowID); err != nil {
+		return nil, err
+	}
+
+	c := &teal.Cursor{Sort: p.Sort, ID: rowID}
+	if value.Valid {
+		c.Value = &value.String
+	}
+	return c, nil
+}
+
+func uniqueStrings(values []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestGetAllBooksFilter(t *testing.T) {
+	resetDB(testdb)
+
+	past := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Now().AddDate(1, 0, 0)
+
+	tests := []struct {
+		name   string
+		filter *teal.BookFilter
+		want   []int64
+		total  int
+	}{{
+		name:   "no filter",
+		filter: &teal.BookFilter{},
+		want:   []int64{1, 2, 3, 4},
+		total:  4,
+	}, {
+		name:   "limit",
+		filter: &teal.BookFilter{Page: teal.Page{Limit: 2}},
+		want:   []int64{1, 2},
+		total:  4,
+	}, {
+		name:   "cursor",
+		filter: &teal.BookFilter{Page: teal.Page{Limit: 3, After: &teal.Cursor{ID: 3}}},
+		want:   []int64{4},
+		total:  4,
+	}, {
+		name:   "sort by title",
+		filter: &teal.BookFilter{Page: teal.Page{Sort: "title"}},
+		want:   []int64{1, 3, 4, 2},
+		total:  4,
+	}, {
+		name:   "sort descending, ties broken by id",
+		filter: &teal.BookFilter{Page: teal.Page{Sort: "-rating"}},
+		want:   []int64{1, 2, 4, 3},
+		total:  4,
+	}, {
+		name:   "sort and limit",
+		filter: &teal.BookFilter{Page: teal.Page{Sort: "-numOfPages", Limit: 1}},
+		want:   []int64{2},
+		total:  4,
+	}, {
+		name:   "author",
+		filter: &teal.BookFilter{Author: "John Doe"},
+		want:   []int64{3, 4},
+		total:  2,
+	}, {
+		name:   "category",
+		filter: &teal.BookFilter{Category: "Fiction"},
+		want:   []int64{1, 2},
+		total:  2,
+	}, {
+		name:   "tags",
+		filter: &teal.BookFilter{Tags: []string{"space", "favourite"}, MatchAll: true},
+		want:   []int64{1},
+		total:  1,
+	}, {
+		name:   "state",
+		filter: &teal.BookFilter{State: "want-to-read"},
+		want:   []int64{2, 3, 4},
+		total:  3,
+	}, {
+		name:   "rating range",
+		filter: &teal.BookFilter{MinRating: intPtr(1), MaxRating: intPtr(4)},
+		want:   []int64{2},
+		total:  1,
+	}, {
+		name:   "pages range",
+		filter: &teal.BookFilter{MinPages: intPtr(250)},
+		want:   []int64{1, 2},
+		total:  2,
+	}, {
+		name:   "added range",
+		filter: &teal.BookFilter{AddedAfter: past, AddedBefore: future},
+		want:   []int64{1, 2, 3, 4},
+		total:  4,
+	}, {
+		name:   "added before",
+		filter: &teal.BookFilter{AddedBefore: past},
+		want:   nil,
+		total:  0,
+	}, {
+		name: "combined filters",
+		filter: &teal.BookFilter{
+			Page:      teal.Page{Sort: "-id", Limit: 1},
+			State:     "want-to-read",
+			MaxRating: intPtr(0),
+		},
+		want:  []int64{4},
+		total: 2,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, info, err := ts.Books.GetAll(testUser1.ID, tt.filter)
+			if err != nil && !(err == teal.ErrNoRows && tt.want == nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []int64
+			for _, b := range got {
+				ids = append(ids, b.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+			assertEqual(t, info.Total, tt.total)
+		})
+	}
+}
+
+func TestGetAllBooksCompletedRange(t *testing.T) {
+	defer resetDB(testdb)
+
+	completed := time.Date(2022, 5, 14, 18, 30, 0, 0, time.UTC)
+	book, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:         "Dune",
+		ISBN:          "1012",
+		Author:        []string{"Frank Herbert"},
+		State:         "read",
+		DateCompleted: sql.NullTime{Time: completed, Valid: true},
+	})
+	checkErr(t, err)
+
+	day := time.Date(2022, 5, 14, 0, 0, 0, 0, time.UTC)
+	got, info, err := ts.Books.GetAll(testUser1.ID, &teal.BookFilter{CompletedAfter: day, CompletedBefore: day})
+	checkErr(t, err)
+	assertEqual(t, info.Total, 1)
+	assertEqual(t, got[0].ID, book.ID)
+
+	// NULLs are sorted last
+	got, _, err = ts.Books.GetAll(testUser1.ID, &teal.BookFilter{Page: teal.Page{Sort: "dateCompleted"}})
+	checkErr(t, err)
+	assertEqual(t, got[0].ID, book.ID)
+
+	_, _, err = ts.Books.GetAll(testUser1.ID, &teal.BookFilter{CompletedAfter: day.AddDate(0, 0, 1)})
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+}
+
+func TestGetAllBooksCursor(t *testing.T) {
+	defer resetDB(testdb)
+
+	// completed books sort before the others, which have no dateCompleted
+	completed := time.Date(2022, 5, 14, 18, 30, 0, 0, time.UTC)
+	for i, title := range []string{"Dune", "Children of Dune"} {
+		_, err := ts.Books.Create(testUser1.ID, &teal.Book{
+			Title:         title,
+			ISBN:          fmt.Sprintf("101%d", i),
+			Author:        []string{"Frank Herbert"},
+			Rating:        5,
+			State:         "read",
+			DateCompleted: sql.NullTime{Time: completed, Valid: true},
+		})
+		checkErr(t, err)
+	}
+
+	var sorts []string
+	for _, field := range teal.BookSortFields {
+		sorts = append(sorts, field, "-"+field)
+	}
+
+	for _, sort := range sorts {
+		for _, limit := range []int{1, 3} {
+			t.Run(fmt.Sprintf("%s/%d", sort, limit), func(t *testing.T) {
+				all, _, err := ts.Books.GetAll(testUser1.ID, &teal.BookFilter{Page: teal.Page{Sort: sort}})
+				checkErr(t, err)
+				var want []int64
+				for _, b := range all {
+					want = append(want, b.ID)
+				}
+
+				// pages in the same order as a single page, including NULLs
+				var ids []int64
+				f := &teal.BookFilter{Page: teal.Page{Sort: sort, Limit: limit}}
+				for {
+					got, info, err := ts.Books.GetAll(testUser1.ID, f)
+					checkErr(t, err)
+					assertEqual(t, info.Total, len(want))
+					for _, b := range got {
+						ids = append(ids, b.ID)
+					}
+					if info.Next == nil {
+						break
+					}
+					assertEqual(t, info.Next.Sort, sort)
+					f.After = info.Next
+				}
+				if !reflect.DeepEqual(ids, want) {
+					t.Errorf("got %v, want %v", ids, want)
+				}
+			})
+		}
+	}
+}
+
+func TestGetAllBooksCursorAfterDelete(t *testing.T) {
+	defer resetDB(testdb)
+
+	f := &teal.BookFilter{Page: teal.Page{Sort: "title", Limit: 2}}
+	got, info, err := ts.Books.GetAll(testUser1.ID, f)
+	checkErr(t, err)
+	assertEqual(t, got[0].ID, testBook1.ID)
+
+	// the next page does not shift when an earlier book is deleted
+	err = ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
+	checkErr(t, err)
+
+	f.After = info.Next
+	got, info, err = ts.Books.GetAll(testUser1.ID, f)
+	checkErr(t, err)
+	assertEqual(t, info.Total, 3)
+	assertEqual(t, len(got), 2)
+	assertEqual(t, got[0].ID, int64(4))
+	assertEqual(t, got[1].ID, int64(2))
+	if info.Next != nil {
+		t.Errorf("got next cursor %v on the last page", info.Next)
+	}
+}
+
+func TestGetAllBooksUnknownSort(t *testing.T) {
+	_, _, err := ts.Books.GetAll(testUser1.ID, &teal.BookFilter{Page: teal.Page{Sort: "isbn"}})
+	if err == nil {
+		t.Errorf("expected err: cannot sort by isbn")
+	}
+}
+
+func TestSortColumns(t *testing.T) {
+	for _, f := range teal.BookSortFields {
+		if _, ok := bookSortColumns[f]; !ok {
+			t.Errorf("no column for book sort field %q", f)
+		}
+	}
+	for _, f := range teal.AuthorSortFields {
+		if _, ok := authorSortColumns[f]; !ok {
+			t.Errorf("no column for author sort field %q", f)
+		}
+	}
+}
+
+func TestGetAllAuthorsPage(t *testing.T) {
+	resetDB(testdb)
+
+	f := &teal.AuthorFilter{Page: teal.Page{Sort: "name", Limit: 2}}
+	_, info, err := ts.Authors.GetAll(testUser1.ID, f)
+	checkErr(t, err)
+
+	f.After = info.Next
+	got, info, err := ts.Authors.GetAll(testUser1.ID, f)
+	checkErr(t, err)
+
+	assertEqual(t, info.Total, 5)
+	assertEqual(t, len(got), 2)
+	assertEqual(t, got[0].Name, testAuthor2.Name)
+	assertEqual(t, got[1].Name, testAuthor4.Name)
+}
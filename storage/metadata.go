@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// Cache of book metadata looked up from providers
+type MetadataStore struct {
+	db *sqlx.DB
+}
+
+type metadataRow struct {
+	ISBN      string    `db:"isbn"`
+	Provider  string    `db:"provider"`
+	Data      []byte    `db:"data"`
+	DateAdded time.Time `db:"dateAdded"`
+}
+
+// Retrieve the cached metadata of an ISBN-13
+func (s *MetadataStore) Get(isbn string) (*teal.Metadata, error) {
+	var row metadataRow
+	stmt := `SELECT * FROM metadata WHERE isbn=$1;`
+
+	err := s.db.Get(&row, stmt, isbn)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve metadata isbn %q failed: %v", isbn, err)
+	}
+
+	var m teal.Metadata
+	if err := json.Unmarshal(row.Data, &m); err != nil {
+		return nil, fmt.Errorf("db: invalid metadata isbn %q: %v", isbn, err)
+	}
+	m.DateAdded = row.DateAdded
+	return &m, nil
+}
+
+// Cache the metadata of an ISBN-13, replacing any cached before
+func (s *MetadataStore) Put(m *teal.Metadata) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("db: invalid metadata isbn %q: %v", m.ISBN, err)
+	}
+
+	return Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+		stmt := `INSERT INTO metadata (isbn, provider, data, dateAdded)
+			VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+			ON CONFLICT (isbn) DO UPDATE SET
+			provider=excluded.provider,
+			data=excluded.data,
+			dateAdded=excluded.dateAdded;`
+		if _, err := tx.Exec(stmt, m.ISBN, m.Provider, data); err != nil {
+			return fmt.Errorf("db: cache metadata isbn %q failed: %v", m.ISBN, err)
+		}
+		return nil
+	})
+}
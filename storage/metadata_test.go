@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func TestMetadataCache(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Metadata.Get("9780316129084")
+	if err != teal.ErrDoesNotExist {
+		t.Fatalf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	want := &teal.Metadata{
+		ISBN:        "9780316129084",
+		Provider:    "openlibrary",
+		Title:       "Leviathan Wakes",
+		Author:      []string{"James S.A. Corey"},
+		Description: "Humanity has colonized the solar system",
+		NumOfPages:  561,
+		CoverURL:    "https://covers.openlibrary.org/b/id/1-L.jpg",
+	}
+	checkErr(t, ts.Metadata.Put(want))
+
+	got, err := ts.Metadata.Get(want.ISBN)
+	checkErr(t, err)
+	if time.Since(got.DateAdded) > time.Minute {
+		t.Errorf("got date added %v, want now", got.DateAdded)
+	}
+	got.DateAdded = time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(want))
+	}
+
+	// metadata is replaced
+	want.Provider = "googlebooks"
+	want.NumOfPages = 592
+	checkErr(t, ts.Metadata.Put(want))
+
+	got, err = ts.Metadata.Get(want.ISBN)
+	checkErr(t, err)
+	assertEqual(t, got.Provider, "googlebooks")
+	assertEqual(t, got.NumOfPages, 592)
+}
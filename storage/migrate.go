@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+var (
+	ErrSchemaBehind = errors.New("db: database schema is behind")
+	ErrSchemaAhead  = errors.New("db: database schema is newer than the known migrations")
+	ErrNoBookOwner  = errors.New("db: books without an owner")
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version     INTEGER NOT NULL PRIMARY KEY,
+	name        TEXT NOT NULL,
+	dateApplied TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+var migrationRgx = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version     int
+	Name        string
+	Applied     bool
+	DateApplied sql.NullTime
+}
+
+// Migrator applies and rolls back versioned migrations, and records the
+// applied versions in the schema_migrations table
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+
+	// Username of the user that books without an owner are given to
+	Owner string
+}
+
+// Go steps that run before the up SQL of the migration of the same name, in
+// the same transaction
+var upSteps = map[string]func(m *Migrator, tx *sqlx.Tx) error{
+	"book_owners_required": assignBookOwners,
+	"isbn_backfill":        backfillISBNs,
+}
+
+func NewMigrator(db *sqlx.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("db: create schema_migrations table failed: %v", err)
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Read migration files in fsys, sorted by version. Every version must have both
+// an up and down migration
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("db: read migrations failed: %v", err)
+	}
+
+	versions := make(map[int]*Migration)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		match := migrationRgx.FindStringSubmatch(f.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("db: invalid migration version %q", f.Name())
+		}
+
+		query, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("db: read migration %q failed: %v", f.Name(), err)
+		}
+
+		m, ok := versions[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			versions[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("db: duplicate migration version %d: %q, %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(query)
+		} else {
+			m.Down = string(query)
+		}
+	}
+
+	var migrations []*Migration
+	for _, m := range versions {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("db: migration %d_%s requires both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Current version of the database. Returns 0 if no migrations are applied
+func (m *Migrator) Version() (int, error) {
+	var version int
+	stmt := `SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`
+	if err := m.db.Get(&version, stmt); err != nil {
+		return -1, fmt.Errorf("db: retrieve schema version failed: %v", err)
+	}
+	return version, nil
+}
+
+// Latest known migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Check that the database is at the latest migration version
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	latest := m.Latest()
+	switch {
+	case version < latest:
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrSchemaBehind, version, latest)
+	case version > latest:
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrSchemaAhead, version, latest)
+	}
+	return nil
+}
+
+// Apply all pending migrations
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Roll back the most recently applied migration
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+
+	target := 0
+	for _, mg := range m.migrations {
+		if mg.Version < version {
+			target = mg.Version
+		}
+	}
+	return m.To(target)
+}
+
+// Migrate up or down to the given version. Version 0 rolls back all migrations
+func (m *Migrator) To(version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("db: migration version %d does not exist", version)
+	}
+
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version >= current {
+		for _, mg := range m.migrations {
+			if mg.Version > current && mg.Version <= version {
+				if err := m.apply(mg, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if mg.Version <= current && mg.Version > version {
+			if err := m.apply(mg, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// List all known migrations and whether they have been applied
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	var applied []struct {
+		Version     int
+		DateApplied sql.NullTime `db:"dateApplied"`
+	}
+	stmt := `SELECT version, dateApplied FROM schema_migrations;`
+	if err := m.db.Select(&applied, stmt); err != nil {
+		return nil, fmt.Errorf("db: retrieve applied migrations failed: %v", err)
+	}
+
+	dates := make(map[int]sql.NullTime)
+	for _, a := range applied {
+		dates[a.Version] = a.DateApplied
+	}
+
+	var status []*MigrationStatus
+	for _, mg := range m.migrations {
+		date, ok := dates[mg.Version]
+		status = append(status, &MigrationStatus{
+			Version:     mg.Version,
+			Name:        mg.Name,
+			Applied:     ok,
+			DateApplied: date,
+		})
+	}
+	return status, nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg
+		}
+	}
+	return nil
+}
+
+// run a single migration and update schema_migrations in one transaction
+func (m *Migrator) apply(mg *Migration, up bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+
+	if err := m.runMigration(tx, mg, up); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) runMigration(tx *sqlx.Tx, mg *Migration, up bool) error {
+	if up {
+		if step, ok := upSteps[mg.Name]; ok {
+			if err := step(m, tx); err != nil {
+				return fmt.Errorf("db: migration %d_%s up failed: %w", mg.Version, mg.Name, err)
+			}
+		}
+		if _, err := tx.Exec(mg.Up); err != nil {
+			return fmt.Errorf("db: migration %d_%s up failed: %v", mg.Version, mg.Name, err)
+		}
+		stmt := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`
+		if _, err := tx.Exec(stmt, mg.Version, mg.Name); err != nil {
+			return fmt.Errorf("db: record migration %d failed: %v", mg.Version, err)
+		}
+		return nil
+	}
+
+	if _, err := tx.Exec(mg.Down); err != nil {
+		return fmt.Errorf("db: migration %d_%s down failed: %v", mg.Version, mg.Name, err)
+	}
+	stmt := `DELETE FROM schema_migrations WHERE version=$1;`
+	if _, err := tx.Exec(stmt, mg.Version); err != nil {
+		return fmt.Errorf("db: remove migration %d failed: %v", mg.Version, err)
+	}
+	return nil
+}
+
+// give books without an owner to the Owner user
+func assignBookOwners(m *Migrator, tx *sqlx.Tx) error {
+	var count int
+	stmt := `SELECT COUNT(*) FROM books WHERE user_id IS NULL;`
+	if err := tx.Get(&count, stmt); err != nil {
+		return fmt.Errorf("count books without an owner failed: %v", err)
+	}
+	if count == 0 {
+		return nil
+	}
+	if m.Owner == "" {
+		return fmt.Errorf("%w: %d books need an owner", ErrNoBookOwner, count)
+	}
+
+	var id int64
+	stmt = `SELECT id FROM users WHERE username=$1;`
+	err := tx.Get(&id, stmt, m.Owner)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("owner %q does not exist", m.Owner)
+	}
+	if err != nil {
+		return fmt.Errorf("retrieve owner %q failed: %v", m.Owner, err)
+	}
+
+	stmt = `UPDATE books SET user_id=$1 WHERE user_id IS NULL;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("assign books to %q failed: %v", m.Owner, err)
+	}
+	return nil
+}
+
+// store the ISBNs of all books as ISBN-13, together with their ISBN-10. When a
+// user has books with the same ISBN in different forms, the book already stored
+// as ISBN-13, or else the oldest, is kept and the others are moved to the trash
+func backfillISBNs(m *Migrator, tx *sqlx.Tx) error {
+	var books []struct {
+		ID      int64
+		UserID  int64 `db:"user_id"`
+		ISBN    string
+		ISBN10  string
+		Deleted bool
+	}
+	stmt := `SELECT id, user_id, isbn, isbn10, dateDeleted IS NOT NULL AS deleted
+		FROM books ORDER BY id;`
+	if err := tx.Select(&books, stmt); err != nil {
+		return fmt.Errorf("retrieve books failed: %v", err)
+	}
+
+	type key struct {
+		userID int64
+		isbn   string
+	}
+	kept := make(map[key]int64)
+	for _, b := range books {
+		if !b.Deleted && teal.NormalizeISBN(b.ISBN) == b.ISBN {
+			kept[key{b.UserID, b.ISBN}] = b.ID
+		}
+	}
+
+	for _, b := range books {
+		isbn := teal.NormalizeISBN(b.ISBN)
+		isbn10 := teal.ISBN10(isbn)
+		if isbn == b.ISBN && isbn10 == b.ISBN10 {
+			continue
+		}
+
+		k := key{b.UserID, isbn}
+		if id, ok := kept[k]; !b.Deleted && ok && id != b.ID {
+			stmt := `UPDATE books SET isbn=$1, isbn10=$2, dateDeleted=CURRENT_TIMESTAMP,
+				version=version+1 WHERE id=$3;`
+			if _, err := tx.Exec(stmt, isbn, isbn10, b.ID); err != nil {
+				return fmt.Errorf("move book %d to the trash failed: %v", b.ID, err)
+			}
+			log.Printf("Book %d has the ISBN %s of book %d, moved to the trash", b.ID, isbn, id)
+			continue
+		}
+		if !b.Deleted {
+			kept[k] = b.ID
+		}
+
+		stmt := `UPDATE books SET isbn=$1, isbn10=$2 WHERE id=$3;`
+		if _, err := tx.Exec(stmt, isbn, isbn10, b.ID); err != nil {
+			return fmt.Errorf("update isbn of book %d failed: %v", b.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,706 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+	"github.com/kencx/teal/migrations"
+)
+
+const testMigrateDSN = "./migrate_test.db"
+
+var testMigrations = fstest.MapFS{
+	"0001_init.up.sql":   {Data: []byte(`CREATE TABLE foo (id INTEGER PRIMARY KEY);`)},
+	"0001_init.down.sql": {Data: []byte(`DROP TABLE foo;`)},
+	"0002_bar.up.sql":    {Data: []byte(`CREATE TABLE bar (id INTEGER PRIMARY KEY);`)},
+	"0002_bar.down.sql":  {Data: []byte(`DROP TABLE bar;`)},
+	"0010_baz.up.sql":    {Data: []byte(`CREATE TABLE baz (id INTEGER PRIMARY KEY);`)},
+	"0010_baz.down.sql":  {Data: []byte(`DROP TABLE baz;`)},
+	"testdata.sql":       {Data: []byte(`INSERT INTO foo (id) VALUES (1);`)},
+	"README.md":          {Data: []byte(`not a migration`)},
+}
+
+func openMigrateDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := Open(testMigrateDSN)
+	checkErr(t, err)
+	t.Cleanup(func() {
+		Close(db)
+		os.Remove(testMigrateDSN)
+	})
+	return db
+}
+
+func tableExists(t *testing.T, db *sqlx.DB, name string) bool {
+	t.Helper()
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=$1;`, name)
+	checkErr(t, err)
+	return count > 0
+}
+
+func TestLoadMigrations(t *testing.T) {
+	got, err := LoadMigrations(testMigrations)
+	checkErr(t, err)
+
+	assertEqual(t, len(got), 3)
+	assertEqual(t, got[0].Version, 1)
+	assertEqual(t, got[0].Name, "init")
+	assertEqual(t, got[1].Version, 2)
+	assertEqual(t, got[2].Version, 10)
+	assertEqual(t, got[2].Down, `DROP TABLE baz;`)
+}
+
+func TestLoadMigrationsMissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte(`SELECT 1;`)},
+		"0001_init.down.sql": {Data: []byte(`SELECT 1;`)},
+		"0002_foo.down.sql":  {Data: []byte(`SELECT 1;`)},
+	}
+	_, err := LoadMigrations(fsys)
+	if err == nil {
+		t.Fatalf("expected err: migration requires both up and down files")
+	}
+}
+
+func TestLoadMigrationsDuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte(`SELECT 1;`)},
+		"0001_init.down.sql": {Data: []byte(`SELECT 1;`)},
+		"0001_foo.up.sql":    {Data: []byte(`SELECT 1;`)},
+		"0001_foo.down.sql":  {Data: []byte(`SELECT 1;`)},
+	}
+	_, err := LoadMigrations(fsys)
+	if err == nil {
+		t.Fatalf("expected err: duplicate migration version")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	var want []*Migration
+	for _, driver := range []string{SQLITE, POSTGRES} {
+		fsys, err := migrations.For(driver)
+		checkErr(t, err)
+
+		got, err := LoadMigrations(fsys)
+		checkErr(t, err)
+
+		if len(got) == 0 {
+			t.Fatalf("no embedded %s migrations found", driver)
+		}
+		for i, m := range got {
+			if m.Version != i+1 {
+				t.Errorf("got %s migration version %d, want %d", driver, m.Version, i+1)
+			}
+		}
+
+		// every driver must have the same migrations
+		if want == nil {
+			want = got
+			continue
+		}
+		assertEqual(t, len(got), len(want))
+		for i := range want {
+			assertEqual(t, got[i].Name, want[i].Name)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := NewMigrator(db, testMigrations)
+	checkErr(t, err)
+	assertEqual(t, m.Latest(), 10)
+
+	t.Run("behind", func(t *testing.T) {
+		err := m.Check()
+		if !errors.Is(err, ErrSchemaBehind) {
+			t.Fatalf("got %v, want %v", err, ErrSchemaBehind)
+		}
+	})
+
+	t.Run("to", func(t *testing.T) {
+		checkErr(t, m.To(2))
+
+		version, err := m.Version()
+		checkErr(t, err)
+		assertEqual(t, version, 2)
+		assertEqual(t, tableExists(t, db, "bar"), true)
+		assertEqual(t, tableExists(t, db, "baz"), false)
+	})
+
+	t.Run("to unknown version", func(t *testing.T) {
+		if err := m.To(5); err == nil {
+			t.Fatalf("expected err: migration version does not exist")
+		}
+	})
+
+	t.Run("up", func(t *testing.T) {
+		checkErr(t, m.Up())
+		checkErr(t, m.Check())
+		assertEqual(t, tableExists(t, db, "baz"), true)
+
+		// no-op when up to date
+		checkErr(t, m.Up())
+	})
+
+	t.Run("status", func(t *testing.T) {
+		checkErr(t, m.Down())
+
+		status, err := m.Status()
+		checkErr(t, err)
+		assertEqual(t, len(status), 3)
+		assertEqual(t, status[1].Name, "bar")
+		assertEqual(t, status[1].Applied, true)
+		assertEqual(t, status[1].DateApplied.Valid, true)
+		assertEqual(t, status[2].Applied, false)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		checkErr(t, m.Down())
+
+		version, err := m.Version()
+		checkErr(t, err)
+		assertEqual(t, version, 1)
+		assertEqual(t, tableExists(t, db, "bar"), false)
+		assertEqual(t, tableExists(t, db, "foo"), true)
+	})
+
+	t.Run("down to zero", func(t *testing.T) {
+		checkErr(t, m.To(0))
+		checkErr(t, m.Down())
+
+		version, err := m.Version()
+		checkErr(t, err)
+		assertEqual(t, version, 0)
+		assertEqual(t, tableExists(t, db, "foo"), false)
+	})
+}
+
+func TestMigratorAhead(t *testing.T) {
+	db := openMigrateDB(t)
+
+	_, err := db.Exec(createMigrationsTable)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (99, 'future');`)
+	checkErr(t, err)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+
+	err = m.Check()
+	if !errors.Is(err, ErrSchemaAhead) {
+		t.Fatalf("got %v, want %v", err, ErrSchemaAhead)
+	}
+}
+
+func TestMigratorRollbackOnFailure(t *testing.T) {
+	db := openMigrateDB(t)
+
+	fsys := fstest.MapFS{
+		"0001_init.up.sql":   {Data: []byte(`CREATE TABLE foo (id INTEGER PRIMARY KEY);`)},
+		"0001_init.down.sql": {Data: []byte(`DROP TABLE foo;`)},
+		"0002_fail.up.sql":   {Data: []byte(`CREATE TABLE bar (id INTEGER); INSERT INTO qux VALUES (1);`)},
+		"0002_fail.down.sql": {Data: []byte(`DROP TABLE bar;`)},
+	}
+	m, err := NewMigrator(db, fsys)
+	checkErr(t, err)
+
+	if err := m.Up(); err == nil {
+		t.Fatalf("expected err: no such table")
+	}
+
+	version, err := m.Version()
+	checkErr(t, err)
+	assertEqual(t, version, 1)
+	assertEqual(t, tableExists(t, db, "bar"), false)
+}
+
+func TestMigrateReadingState(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(5))
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn, state) VALUES
+		('Leviathan Wakes', '1', 'read'),
+		('Red Rising', '2', 'unread'),
+		('Many Authors', '3', 'foo');`)
+	checkErr(t, err)
+
+	checkErr(t, m.To(6))
+
+	var states []string
+	checkErr(t, db.Select(&states, `SELECT state FROM books ORDER BY id;`))
+	if want := []string{"read", "want-to-read", "want-to-read"}; !reflect.DeepEqual(states, want) {
+		t.Errorf("got %v, want %v", states, want)
+	}
+
+	// new books default to want-to-read, invalid states are rejected
+	_, err = db.Exec(`INSERT INTO books (title, isbn) VALUES ('New Book', '4');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (title, isbn, state) VALUES ('Bad Book', '5', 'unread');`)
+	if err == nil {
+		t.Errorf("expected err: check constraint failed")
+	}
+
+	checkErr(t, m.To(5))
+	states = nil
+	checkErr(t, db.Select(&states, `SELECT state FROM books ORDER BY id;`))
+	if want := []string{"read", "unread", "unread", "unread"}; !reflect.DeepEqual(states, want) {
+		t.Errorf("got %v, want %v", states, want)
+	}
+}
+
+func TestMigrateReadingHistory(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(6))
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn, state) VALUES
+		('Leviathan Wakes', '1', 'read'),
+		('Red Rising', '2', 'want-to-read'),
+		('Many Authors', '3', 'reading');`)
+	checkErr(t, err)
+
+	checkErr(t, m.To(7))
+
+	var outcomes []sql.NullString
+	checkErr(t, db.Select(&outcomes, `SELECT outcome FROM reading_sessions ORDER BY book_id;`))
+	want := []sql.NullString{{String: "read", Valid: true}, {}}
+	if !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("got %v, want %v", outcomes, want)
+	}
+
+	checkErr(t, m.To(6))
+}
+
+func TestMigrateBookOwners(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(7))
+
+	_, err = db.Exec(`INSERT INTO users (name, username, hashed_password) VALUES
+		('John Doe', 'johndoe', 'abc'),
+		('Ben Adams', 'benadams', 'abc');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (title, isbn) VALUES
+		('Leviathan Wakes', '1'),
+		('Red Rising', '2');`)
+	checkErr(t, err)
+
+	checkErr(t, m.To(8))
+
+	// existing books are given to the first user
+	var owners []int64
+	checkErr(t, db.Select(&owners, `SELECT user_id FROM books ORDER BY id;`))
+	if want := []int64{1, 1}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("got %v, want %v", owners, want)
+	}
+
+	// the same isbn can be added by another user, but not twice by the same user
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn) VALUES (2, 'Leviathan Wakes', '1');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn) VALUES (1, 'Leviathan Wakes', '1');`)
+	if err == nil {
+		t.Errorf("expected err: unique constraint failed")
+	}
+
+	_, err = db.Exec(`DELETE FROM books WHERE user_id=2;`)
+	checkErr(t, err)
+	checkErr(t, m.To(7))
+	assertEqual(t, tableExists(t, db, "books"), true)
+}
+
+func TestMigrateBookOwnersRequired(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(18))
+
+	// books added before books had owners, when there were no users
+	_, err = db.Exec(`INSERT INTO books (title, isbn) VALUES
+		('Leviathan Wakes', '1'),
+		('Red Rising', '2');`)
+	checkErr(t, err)
+
+	err = m.To(19)
+	if !errors.Is(err, ErrNoBookOwner) {
+		t.Fatalf("got %v, want %v", err, ErrNoBookOwner)
+	}
+
+	m.Owner = "johndoe"
+	if err := m.To(19); err == nil {
+		t.Fatalf("expected err: owner does not exist")
+	}
+
+	// registering a user does not take the books
+	users := &UserStore{db}
+	user, err := users.Create(&teal.User{Name: "John Doe", Username: "johndoe", HashedPassword: []byte("abc")})
+	checkErr(t, err)
+	_, err = users.Create(&teal.User{Name: "Ben Adams", Username: "benadams", HashedPassword: []byte("abc")})
+	checkErr(t, err)
+
+	var count int
+	checkErr(t, db.Get(&count, `SELECT COUNT(*) FROM books WHERE user_id IS NULL;`))
+	assertEqual(t, count, 2)
+
+	checkErr(t, m.To(19))
+
+	var owners []int64
+	checkErr(t, db.Select(&owners, `SELECT user_id FROM books ORDER BY id;`))
+	if want := []int64{user.ID, user.ID}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("got %v, want %v", owners, want)
+	}
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn) VALUES ('Golden Son', '3');`)
+	if err == nil {
+		t.Errorf("expected err: NOT NULL constraint failed")
+	}
+	checkErr(t, m.To(18))
+}
+
+func TestMigrateTrash(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(9))
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn, dateDeleted) VALUES
+		('Leviathan Wakes', '1', NULL),
+		('Red Rising', '2', CURRENT_TIMESTAMP);`)
+	checkErr(t, err)
+
+	// books in the trash are restored
+	checkErr(t, m.To(8))
+
+	var count int
+	checkErr(t, db.Get(&count, `SELECT COUNT(*) FROM books;`))
+	assertEqual(t, count, 2)
+}
+
+func TestMigrateTrashISBN(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(18))
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn, user_id, dateDeleted) VALUES
+		('Leviathan Wakes', '1', 1, CURRENT_TIMESTAMP),
+		('Leviathan Wakes', '1', 1, NULL);`)
+	checkErr(t, err)
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn, user_id) VALUES ('Leviathan Wakes', '1', 1);`)
+	if !isUniqueViolation(err) {
+		t.Errorf("got %v, want unique violation", err)
+	}
+}
+
+func TestMigrateVersions(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(10))
+
+	_, err = db.Exec(`INSERT INTO books (title, isbn) VALUES ('Leviathan Wakes', '1');`)
+	checkErr(t, err)
+
+	// existing books start at version 1
+	checkErr(t, m.To(11))
+
+	var version int64
+	checkErr(t, db.Get(&version, `SELECT version FROM books WHERE isbn='1';`))
+	assertEqual(t, version, 1)
+
+	checkErr(t, m.To(10))
+	err = db.Get(&version, `SELECT version FROM books WHERE isbn='1';`)
+	if err == nil {
+		t.Errorf("expected error: version column dropped")
+	}
+}
+
+func TestMigrateISBN10(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(11))
+
+	_, err = db.Exec(`INSERT INTO users (name, username, hashed_password) VALUES
+		('John Doe', 'johndoe', 'hash');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn) VALUES
+		(1, 'Leviathan Wakes', '978-0-316-12908-4'),
+		(1, 'Dune', '978-0441013593'),
+		(1, 'Dune', '9780441013593');`)
+	checkErr(t, err)
+
+	// hyphens are removed unless the stripped ISBN exists
+	checkErr(t, m.To(12))
+
+	var isbns []string
+	checkErr(t, db.Select(&isbns, `SELECT isbn FROM books ORDER BY id;`))
+	want := []string{"9780316129084", "978-0441013593", "9780441013593"}
+	for i := range want {
+		assertEqual(t, isbns[i], want[i])
+	}
+
+	var isbn10 string
+	checkErr(t, db.Get(&isbn10, `SELECT isbn10 FROM books LIMIT 1;`))
+
+	checkErr(t, m.To(11))
+	err = db.Get(&isbn10, `SELECT isbn10 FROM books LIMIT 1;`)
+	if err == nil {
+		t.Errorf("expected error: isbn10 column dropped")
+	}
+}
+
+func TestMigrateContributors(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(15))
+
+	_, err = db.Exec(`INSERT INTO users (name, username, hashed_password) VALUES
+		('John Doe', 'johndoe', 'hash');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn) VALUES (1, 'Many Authors', '1');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO authors (name) VALUES ('John Doe'), ('Ken Adams');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books_authors (book_id, author_id) VALUES (1, 2), (1, 1);`)
+	checkErr(t, err)
+
+	// existing authors are ordered as they were created
+	checkErr(t, m.To(16))
+
+	var dest []struct {
+		Author_id int64
+		Role      string
+		Position  int
+	}
+	checkErr(t, db.Select(&dest, `SELECT author_id, role, position FROM books_authors ORDER BY position;`))
+	assertEqual(t, len(dest), 2)
+	for i, d := range dest {
+		assertEqual(t, d.Author_id, int64(i+1))
+		assertEqual(t, d.Role, "author")
+		assertEqual(t, d.Position, i)
+	}
+
+	// other roles are dropped with their authors
+	_, err = db.Exec(`INSERT INTO authors (name) VALUES ('Jane Doe');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books_authors (book_id, author_id, role, position) VALUES (1, 3, 'translator', 2);`)
+	checkErr(t, err)
+
+	checkErr(t, m.To(15))
+	var count int
+	checkErr(t, db.Get(&count, `SELECT COUNT(*) FROM books_authors;`))
+	assertEqual(t, count, 2)
+	checkErr(t, db.Get(&count, `SELECT COUNT(*) FROM authors;`))
+	assertEqual(t, count, 2)
+}
+
+func TestMigrateOwnedCategoriesSeries(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(19))
+
+	_, err = db.Exec(`INSERT INTO users (name, username, hashed_password) VALUES
+		('John Doe', 'johndoe', 'abc'),
+		('Ben Adams', 'benadams', 'abc');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn) VALUES
+		(1, 'Leviathan Wakes', '1'),
+		(2, 'Leviathan Wakes', '1');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO categories (name) VALUES ('Fiction');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO categories (name, parent_id) VALUES ('Sci-Fi', 1);`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO series (name) VALUES ('The Expanse');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books_categories (book_id, category_id) VALUES (1, 2), (2, 2);`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books_series (book_id, series_id, position) VALUES (1, 1, 1), (2, 1, 1);`)
+	checkErr(t, err)
+
+	checkErr(t, m.To(20))
+
+	// every user has a copy, the first user keeps the existing ids
+	var categories []struct {
+		ID        int64
+		User_id   int64
+		Name      string
+		Parent_id sql.NullInt64
+	}
+	checkErr(t, db.Select(&categories, `SELECT id, user_id, name, parent_id FROM categories ORDER BY id;`))
+	if len(categories) != 4 {
+		t.Fatalf("got %d categories, want %d", len(categories), 4)
+	}
+	for _, c := range categories {
+		want := int64(1)
+		if c.ID > 2 {
+			want = 2
+		}
+		assertEqual(t, c.User_id, want)
+		if c.Name == "Sci-Fi" && c.Parent_id.Int64 != c.ID-1 {
+			t.Errorf("got parent %d, want %d", c.Parent_id.Int64, c.ID-1)
+		}
+	}
+
+	var links []int64
+	checkErr(t, db.Select(&links, `SELECT category_id FROM books_categories ORDER BY book_id;`))
+	if want := []int64{2, 4}; !reflect.DeepEqual(links, want) {
+		t.Errorf("got %v, want %v", links, want)
+	}
+	links = nil
+	checkErr(t, db.Select(&links, `SELECT series_id FROM books_series ORDER BY book_id;`))
+	if want := []int64{1, 2}; !reflect.DeepEqual(links, want) {
+		t.Errorf("got %v, want %v", links, want)
+	}
+
+	// names are unique per user
+	_, err = db.Exec(`INSERT INTO series (user_id, name) VALUES (1, 'Red Rising Saga'), (2, 'Red Rising Saga');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO series (user_id, name) VALUES (1, 'Red Rising Saga');`)
+	if err == nil {
+		t.Errorf("expected err: unique constraint failed")
+	}
+
+	// rolling back merges the copies
+	checkErr(t, m.To(19))
+
+	links = nil
+	checkErr(t, db.Select(&links, `SELECT category_id FROM books_categories ORDER BY book_id;`))
+	if want := []int64{2, 2}; !reflect.DeepEqual(links, want) {
+		t.Errorf("got %v, want %v", links, want)
+	}
+	var count int
+	checkErr(t, db.Get(&count, `SELECT COUNT(*) FROM series;`))
+	assertEqual(t, count, 2)
+}
+
+func TestMigrateISBNBackfill(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(20))
+
+	_, err = db.Exec(`INSERT INTO users (name, username, hashed_password) VALUES
+		('John Doe', 'johndoe', 'abc'),
+		('Ben Adams', 'benadams', 'abc');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn, dateDeleted) VALUES
+		(1, 'Leviathan Wakes', '0-316-12908-9', NULL),
+		(1, 'Leviathan Wakes', '9780316129084', NULL),
+		(1, 'Dune', '0441013597', NULL),
+		(1, 'Dune', '978-0-441-01359-3', NULL),
+		(2, 'Leviathan Wakes', '0316129089', NULL),
+		(1, 'Untitled', 'abc', NULL),
+		(1, 'Dune', '0441013597', CURRENT_TIMESTAMP);`)
+	checkErr(t, err)
+
+	checkErr(t, m.To(21))
+
+	var books []struct {
+		ISBN    string
+		ISBN10  string
+		Deleted bool
+	}
+	stmt := `SELECT isbn, isbn10, dateDeleted IS NOT NULL AS deleted FROM books ORDER BY id;`
+	checkErr(t, db.Select(&books, stmt))
+
+	// the book stored as ISBN-13, or else the oldest, is kept
+	want := []struct {
+		ISBN    string
+		ISBN10  string
+		Deleted bool
+	}{
+		{"9780316129084", "0316129089", true},
+		{"9780316129084", "0316129089", false},
+		{"9780441013593", "0441013597", false},
+		{"9780441013593", "0441013597", true},
+		{"9780316129084", "0316129089", false},
+		{"abc", "", false},
+		{"9780441013593", "0441013597", true},
+	}
+	if !reflect.DeepEqual(books, want) {
+		t.Errorf("got %v, want %v", books, want)
+	}
+
+	// books are found by the ISBN-13 alone
+	bs := &BookStore{db}
+	book, err := bs.GetByISBN(1, "0-441-01359-7")
+	checkErr(t, err)
+	assertEqual(t, book.ID, int64(3))
+
+	checkErr(t, m.To(20))
+}
+
+func TestMigrateUserAuthorAliases(t *testing.T) {
+	db := openMigrateDB(t)
+
+	m, err := newTestMigrator(db)
+	checkErr(t, err)
+	checkErr(t, m.To(21))
+
+	_, err = db.Exec(`INSERT INTO users (name, username, hashed_password) VALUES
+		('John Doe', 'johndoe', 'abc'),
+		('Ben Adams', 'benadams', 'abc'),
+		('Jane Doe', 'janedoe', 'abc');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books (user_id, title, isbn) VALUES
+		(1, 'Leviathan Wakes', '1'),
+		(2, 'Leviathan Wakes', '1');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO authors (name) VALUES ('James S.A. Corey');`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO books_authors (book_id, author_id) VALUES (1, 1), (2, 1);`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO author_aliases (name, author_id) VALUES ('S.A. Corey', 1);`)
+	checkErr(t, err)
+
+	// users with books of the author are given its aliases
+	checkErr(t, m.To(22))
+
+	var owners []int64
+	checkErr(t, db.Select(&owners, `SELECT user_id FROM author_aliases ORDER BY user_id;`))
+	if want := []int64{1, 2}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("got %v, want %v", owners, want)
+	}
+
+	// names are unique per user
+	_, err = db.Exec(`INSERT INTO author_aliases (user_id, name, author_id) VALUES (3, 'S.A. Corey', 1);`)
+	checkErr(t, err)
+	_, err = db.Exec(`INSERT INTO author_aliases (user_id, name, author_id) VALUES (3, 'S.A. Corey', 1);`)
+	if err == nil {
+		t.Errorf("expected err: unique constraint failed")
+	}
+
+	// rolling back merges the aliases
+	checkErr(t, m.To(21))
+	var count int
+	checkErr(t, db.Get(&count, `SELECT COUNT(*) FROM author_aliases;`))
+	assertEqual(t, count, 1)
+}
@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// Reading sessions and progress of books. Sessions in progress are started and
+// finished by changes of a book's state, see recordTransition
+type ReadingStore struct {
+	db *sqlx.DB
+}
+
+const sessionColumns = `id, book_id, dateStarted, dateFinished,
+	COALESCE(outcome, '') AS outcome, dateAdded`
+
+// Retrieve all reading sessions of a user's book, oldest first
+func (s *ReadingStore) GetSessions(userID, bookID int64) ([]*teal.ReadingSession, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	if _, err := getBookState(tx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	var sessions []*teal.ReadingSession
+	stmt := `SELECT ` + sessionColumns + ` FROM reading_sessions
+		WHERE book_id=$1
+		ORDER BY dateStarted, id;`
+	if err := tx.Select(&sessions, stmt, bookID); err != nil {
+		return nil, fmt.Errorf("db: retrieve reading sessions of book %d failed: %v", bookID, err)
+	}
+	if len(sessions) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return sessions, nil
+}
+
+// Record a past reading session of a book. The book's state is not changed
+func (s *ReadingStore) CreateSession(userID, bookID int64, r *teal.ReadingSession) (*teal.ReadingSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if _, err := getBookState(tx, userID, bookID); err != nil {
+			return err
+		}
+
+		var err error
+		id, err = insertSession(tx, bookID, r.DateStarted, r.DateFinished.NullTime, r.Outcome)
+		if err != nil {
+			return err
+		}
+		return bumpBookVersions(tx, []int64{bookID})
+
+	}); err != nil {
+		return nil, err
+	}
+	return s.getSession(bookID, id)
+}
+
+// Delete a reading session of a book and its progress
+func (s *ReadingStore) DeleteSession(userID, bookID, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if _, err := getBookState(tx, userID, bookID); err != nil {
+			return err
+		}
+
+		stmt := `DELETE FROM reading_progress WHERE session_id=$1 AND book_id=$2;`
+		if _, err := tx.Exec(stmt, id, bookID); err != nil {
+			return fmt.Errorf("db: delete progress of reading session %d failed: %v", id, err)
+		}
+
+		stmt = `DELETE FROM reading_sessions WHERE id=$1 AND book_id=$2;`
+		res, err := tx.Exec(stmt, id, bookID)
+		if err != nil {
+			return fmt.Errorf("db: delete reading session %d failed: %v", id, err)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: delete reading session %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+		return bumpBookVersions(tx, []int64{bookID})
+	})
+}
+
+// Retrieve all progress updates of a user's book across its reading sessions,
+// oldest first
+func (s *ReadingStore) GetProgress(userID, bookID int64) ([]*teal.Progress, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	if _, err := getBookState(tx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	var progress []*teal.Progress
+	stmt := `SELECT * FROM reading_progress WHERE book_id=$1 ORDER BY id;`
+	if err := tx.Select(&progress, stmt, bookID); err != nil {
+		return nil, fmt.Errorf("db: retrieve progress of book %d failed: %v", bookID, err)
+	}
+	if len(progress) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return progress, nil
+}
+
+// Add a progress update to the current reading session of a book. The book
+// must be in the reading state. The missing page or percentage is computed
+// from the book's number of pages
+func (s *ReadingStore) AddProgress(userID, bookID int64, p *teal.Progress) (*teal.Progress, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		b, err := getBookState(tx, userID, bookID)
+		if err != nil {
+			return err
+		}
+		if b.State != teal.StateReading {
+			return teal.ErrNotReading
+		}
+		if err := p.Complete(b.NumOfPages); err != nil {
+			return err
+		}
+
+		sessionID, err := openSession(tx, bookID, b.DateStarted)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO reading_progress (book_id, session_id, page, percent)
+			VALUES ($1, $2, $3, $4) RETURNING id;`
+		if err := tx.Get(&id, stmt, bookID, sessionID, p.Page, p.Percent); err != nil {
+			return fmt.Errorf("db: insert progress of book %d failed: %v", bookID, err)
+		}
+		return bumpBookVersions(tx, []int64{bookID})
+
+	}); err != nil {
+		return nil, err
+	}
+	return s.getProgress(bookID, id)
+}
+
+// Delete a progress update of a book
+func (s *ReadingStore) DeleteProgress(userID, bookID, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if _, err := getBookState(tx, userID, bookID); err != nil {
+			return err
+		}
+
+		stmt := `DELETE FROM reading_progress WHERE id=$1 AND book_id=$2;`
+		res, err := tx.Exec(stmt, id, bookID)
+		if err != nil {
+			return fmt.Errorf("db: delete progress %d failed: %v", id, err)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: delete progress %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+		return bumpBookVersions(tx, []int64{bookID})
+	})
+}
+
+func (s *ReadingStore) getSession(bookID, id int64) (*teal.ReadingSession, error) {
+	var r teal.ReadingSession
+	stmt := `SELECT ` + sessionColumns + ` FROM reading_sessions WHERE id=$1 AND book_id=$2;`
+	err := s.db.Get(&r, stmt, id, bookID)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve reading session %d failed: %v", id, err)
+	}
+	return &r, nil
+}
+
+func (s *ReadingStore) getProgress(bookID, id int64) (*teal.Progress, error) {
+	var p teal.Progress
+	stmt := `SELECT * FROM reading_progress WHERE id=$1 AND book_id=$2;`
+	err := s.db.Get(&p, stmt, id, bookID)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve progress %d failed: %v", id, err)
+	}
+	return &p, nil
+}
+
+func insertSession(tx *sqlx.Tx, bookID int64, started time.Time, finished sql.NullTime, outcome string) (int64, error) {
+	var id int64
+	stmt := `INSERT INTO reading_sessions (book_id, dateStarted, dateFinished, outcome)
+		VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id;`
+	if err := tx.Get(&id, stmt, bookID, started, finished, outcome); err != nil {
+		return 0, fmt.Errorf("db: insert reading session of book %d failed: %v", bookID, err)
+	}
+	return id, nil
+}
+
+// Retrieve the session in progress of a book, starting a new one if there is
+// none
+func openSession(tx *sqlx.Tx, bookID int64, started sql.NullTime) (int64, error) {
+	var id int64
+	stmt := `SELECT id FROM reading_sessions
+		WHERE book_id=$1 AND dateFinished IS NULL
+		ORDER BY id DESC LIMIT 1;`
+	err := tx.Get(&id, stmt, bookID)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("db: retrieve reading session of book %d failed: %v", bookID, err)
+	}
+
+	if !started.Valid {
+		started = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	return insertSession(tx, bookID, started.Time, sql.NullTime{}, "")
+}
+
+// Start or finish the reading sessions of a book after it moved from one state
+// to b.State:
+//   - reading starts a new session, or continues the session in progress when
+//     resuming from on-hold
+//   - read and did-not-finish finish the session in progress with that outcome
+//   - want-to-read deletes the session in progress and its progress
+func recordTransition(tx *sqlx.Tx, bookID int64, from string, b *teal.Book, now time.Time) error {
+	switch b.State {
+	case teal.StateReading:
+		if from != teal.StateOnHold {
+			if err := finishSession(tx, bookID, teal.StateDidNotFinish, now); err != nil {
+				return err
+			}
+		}
+		_, err := openSession(tx, bookID, b.DateStarted)
+		return err
+
+	case teal.StateRead, teal.StateDidNotFinish:
+		finished := now
+		if b.State == teal.StateRead && b.DateCompleted.Valid {
+			finished = b.DateCompleted.Time
+		}
+		if _, err := openSession(tx, bookID, b.DateStarted); err != nil {
+			return err
+		}
+		return finishSession(tx, bookID, b.State, finished)
+
+	case teal.StateWantToRead:
+		stmt := `DELETE FROM reading_progress WHERE session_id IN (
+			SELECT id FROM reading_sessions WHERE book_id=$1 AND dateFinished IS NULL);`
+		if _, err := tx.Exec(stmt, bookID); err != nil {
+			return fmt.Errorf("db: delete progress of book %d failed: %v", bookID, err)
+		}
+		stmt = `DELETE FROM reading_sessions WHERE book_id=$1 AND dateFinished IS NULL;`
+		if _, err := tx.Exec(stmt, bookID); err != nil {
+			return fmt.Errorf("db: delete reading session of book %d failed: %v", bookID, err)
+		}
+	}
+	return nil
+}
+
+// finish the session in progress of a book, if any
+func finishSession(tx *sqlx.Tx, bookID int64, outcome string, finished time.Time) error {
+	stmt := `UPDATE reading_sessions
+		SET dateFinished=$1,
+		outcome=$2
+		WHERE book_id=$3 AND dateFinished IS NULL;`
+	if _, err := tx.Exec(stmt, finished, outcome, bookID); err != nil {
+		return fmt.Errorf("db: finish reading session of book %d failed: %v", bookID, err)
+	}
+	return nil
+}
+
+// fill in the latest progress of the session in progress of each given book
+func populateProgress(tx *sqlx.Tx, books []*teal.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	index := make(map[int64]*teal.Book)
+	for _, b := range books {
+		b.Progress = nil
+		ids = append(ids, b.ID)
+		index[b.ID] = b
+	}
+
+	var dest []*teal.Progress
+	stmt := `SELECT p.*
+		FROM reading_progress p
+		JOIN reading_sessions r ON r.id=p.session_id
+		WHERE r.dateFinished IS NULL AND p.book_id IN (?)
+		ORDER BY p.id;`
+	query, args, err := sqlx.In(stmt, ids)
+	if err != nil {
+		return fmt.Errorf("db: retrieve progress of books %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&dest, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: retrieve progress of books %v failed: %v", ids, err)
+	}
+
+	// later updates replace earlier ones
+	for _, p := range dest {
+		if b, ok := index[p.BookID]; ok {
+			b.Progress = p
+		}
+	}
+	return nil
+}
+
+// delete all reading sessions and progress of a book
+func deleteReadingHistory(tx *sqlx.Tx, bookID int64) error {
+	stmt := `DELETE FROM reading_progress WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, bookID); err != nil {
+		return fmt.Errorf("db: delete progress of book %d failed: %v", bookID, err)
+	}
+
+	stmt = `DELETE FROM reading_sessions WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, bookID); err != nil {
+		return fmt.Errorf("db: delete reading sessions of book %d failed: %v", bookID, err)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func assertSessionsEqual(t *testing.T, got, want *teal.ReadingSession) {
+	t.Helper()
+	if got.BookID != want.BookID || !got.DateStarted.Equal(want.DateStarted) ||
+		got.DateFinished.Valid != want.DateFinished.Valid ||
+		!got.DateFinished.Time.Equal(want.DateFinished.Time) || got.Outcome != want.Outcome {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(want))
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestGetSessions(t *testing.T) {
+	resetDB(testdb)
+
+	got, err := ts.Reading.GetSessions(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	if len(got) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(got))
+	}
+	assertSessionsEqual(t, got[0], testSession1)
+}
+
+func TestGetSessionsNone(t *testing.T) {
+	_, err := ts.Reading.GetSessions(testUser1.ID, testBook2.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+
+	_, err = ts.Reading.GetSessions(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	// the reading history of other users' books is not visible
+	_, err = ts.Reading.GetSessions(testUser2.ID, testBook1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	err = ts.Reading.DeleteSession(testUser2.ID, testBook1.ID, testSession1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	defer resetDB(testdb)
+
+	want := &teal.ReadingSession{
+		BookID:       testBook2.ID,
+		DateStarted:  time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		DateFinished: teal.NullTime{NullTime: sql.NullTime{Time: time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC), Valid: true}},
+		Outcome:      teal.StateDidNotFinish,
+	}
+	before, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+
+	got, err := ts.Reading.CreateSession(testUser1.ID, testBook2.ID, want)
+	checkErr(t, err)
+	assertSessionsEqual(t, got, want)
+
+	// the book's state is unchanged, but its version is not
+	book, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	assertEqual(t, book.State, teal.StateWantToRead)
+	assertEqual(t, book.Version, before.Version+1)
+
+	_, err = ts.Reading.CreateSession(testUser1.ID, -1, want)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	err = ts.Reading.DeleteSession(testUser1.ID, testBook1.ID, testSession1.ID)
+	checkErr(t, err)
+
+	_, err = ts.Reading.GetSessions(testUser1.ID, testBook1.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, book.Version, before.Version+1)
+
+	// sessions of other books cannot be deleted
+	err = ts.Reading.DeleteSession(testUser1.ID, testBook2.ID, testSession1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestSessionsFollowState(t *testing.T) {
+	defer resetDB(testdb)
+
+	id := testBook2.ID
+	sessions := func(t *testing.T) []*teal.ReadingSession {
+		t.Helper()
+		got, err := ts.Reading.GetSessions(testUser1.ID, id)
+		if err == teal.ErrNoRows {
+			return nil
+		}
+		checkErr(t, err)
+		return got
+	}
+	updateState := func(t *testing.T, state string) {
+		t.Helper()
+		_, err := ts.Books.UpdateState(testUser1.ID, id, 0, state)
+		checkErr(t, err)
+	}
+
+	t.Run("start reading", func(t *testing.T) {
+		updateState(t, teal.StateReading)
+
+		got := sessions(t)
+		if len(got) != 1 || got[0].DateFinished.Valid {
+			t.Fatalf("got %v, want 1 session in progress", prettyPrint(got))
+		}
+	})
+
+	t.Run("resume from on-hold", func(t *testing.T) {
+		updateState(t, teal.StateOnHold)
+		updateState(t, teal.StateReading)
+
+		got := sessions(t)
+		if len(got) != 1 || got[0].DateFinished.Valid {
+			t.Fatalf("got %v, want 1 session in progress", prettyPrint(got))
+		}
+	})
+
+	t.Run("finish", func(t *testing.T) {
+		updateState(t, teal.StateRead)
+
+		got := sessions(t)
+		if len(got) != 1 || !got[0].DateFinished.Valid {
+			t.Fatalf("got %v, want 1 finished session", prettyPrint(got))
+		}
+		assertEqual(t, got[0].Outcome, teal.StateRead)
+	})
+
+	t.Run("reread", func(t *testing.T) {
+		updateState(t, teal.StateReading)
+
+		got := sessions(t)
+		if len(got) != 2 {
+			t.Fatalf("got %d sessions, want 2", len(got))
+		}
+		assertEqual(t, got[0].Outcome, teal.StateRead)
+		assertEqual(t, got[1].DateFinished.Valid, false)
+	})
+
+	t.Run("stop reading", func(t *testing.T) {
+		updateState(t, teal.StateWantToRead)
+
+		got := sessions(t)
+		if len(got) != 1 {
+			t.Fatalf("got %d sessions, want 1", len(got))
+		}
+		assertEqual(t, got[0].Outcome, teal.StateRead)
+	})
+}
+
+func TestCreateBookStartsSession(t *testing.T) {
+	defer resetDB(testdb)
+
+	book, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Dune",
+		ISBN:   "1011",
+		Author: []string{"Frank Herbert"},
+		State:  teal.StateRead,
+	})
+	checkErr(t, err)
+
+	got, err := ts.Reading.GetSessions(testUser1.ID, book.ID)
+	checkErr(t, err)
+	if len(got) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(got))
+	}
+	assertEqual(t, got[0].Outcome, teal.StateRead)
+	assertEqual(t, got[0].DateFinished.Valid, true)
+}
+
+func TestAddProgress(t *testing.T) {
+	defer resetDB(testdb)
+
+	id := testBook2.ID
+
+	_, err := ts.Reading.AddProgress(testUser1.ID, id, &teal.Progress{Page: intPtr(10)})
+	if err != teal.ErrNotReading {
+		t.Fatalf("got %v, want %v", err, teal.ErrNotReading)
+	}
+
+	_, err = ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateReading)
+	checkErr(t, err)
+
+	t.Run("page", func(t *testing.T) {
+		got, err := ts.Reading.AddProgress(testUser1.ID, id, &teal.Progress{Page: intPtr(450)})
+		checkErr(t, err)
+		assertEqual(t, *got.Page, 450)
+		assertEqual(t, *got.Percent, 50.0)
+	})
+
+	t.Run("percent", func(t *testing.T) {
+		got, err := ts.Reading.AddProgress(testUser1.ID, id, &teal.Progress{Percent: floatPtr(60)})
+		checkErr(t, err)
+		assertEqual(t, *got.Page, 540)
+		assertEqual(t, *got.Percent, 60.0)
+	})
+
+	t.Run("page out of range", func(t *testing.T) {
+		_, err := ts.Reading.AddProgress(testUser1.ID, id, &teal.Progress{Page: intPtr(901)})
+		if !errors.Is(err, teal.ErrPageOutOfRange) {
+			t.Errorf("got %v, want %v", err, teal.ErrPageOutOfRange)
+		}
+	})
+
+	t.Run("book embeds latest progress", func(t *testing.T) {
+		book, err := ts.Books.Get(testUser1.ID, id)
+		checkErr(t, err)
+		if book.Progress == nil {
+			t.Fatalf("got no progress, want page 540")
+		}
+		assertEqual(t, *book.Progress.Page, 540)
+	})
+
+	t.Run("progress is kept after finishing", func(t *testing.T) {
+		_, err := ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateRead)
+		checkErr(t, err)
+
+		book, err := ts.Books.Get(testUser1.ID, id)
+		checkErr(t, err)
+		if book.Progress != nil {
+			t.Errorf("got %v, want no progress", prettyPrint(book.Progress))
+		}
+
+		got, err := ts.Reading.GetProgress(testUser1.ID, id)
+		checkErr(t, err)
+		assertEqual(t, len(got), 2)
+	})
+}
+
+func TestDeleteProgress(t *testing.T) {
+	defer resetDB(testdb)
+
+	id := testBook2.ID
+	before, err := ts.Books.UpdateState(testUser1.ID, id, 0, teal.StateReading)
+	checkErr(t, err)
+
+	p, err := ts.Reading.AddProgress(testUser1.ID, id, &teal.Progress{Page: intPtr(100)})
+	checkErr(t, err)
+
+	err = ts.Reading.DeleteProgress(testUser1.ID, id, p.ID)
+	checkErr(t, err)
+
+	// adding and deleting progress both change the book
+	book, err := ts.Books.Get(testUser1.ID, id)
+	checkErr(t, err)
+	assertEqual(t, book.Version, before.Version+2)
+
+	_, err = ts.Reading.GetProgress(testUser1.ID, id)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+
+	err = ts.Reading.DeleteProgress(testUser1.ID, id, p.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestDeleteBookDeletesReadingHistory(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
+	checkErr(t, err)
+
+	// reading history is kept while the book is in the trash
+	var count int
+	err = testdb.Get(&count, `SELECT COUNT(*) FROM reading_sessions WHERE book_id=$1;`, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, count, 1)
+
+	_, err = ts.Trash.Empty(testUser1.ID, time.Now().Add(time.Minute))
+	checkErr(t, err)
+
+	err = testdb.Get(&count, `SELECT COUNT(*) FROM reading_sessions WHERE book_id=$1;`, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, count, 0)
+}
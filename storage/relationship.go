@@ -2,13 +2,12 @@ package storage
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/kencx/teal"
 )
 
-// get list of author names from given isbn
+// get list of author names of the given book, in order
 func (bs *BookStore) GetAuthorsFromBook(id int64) ([]string, error) {
 	tx, err := bs.db.Beginx()
 	if err != nil {
@@ -22,9 +21,10 @@ func (bs *BookStore) GetAuthorsFromBook(id int64) ([]string, error) {
 	stmt := `SELECT a.name
 		FROM books_authors ba
 		JOIN authors a ON a.id=ba.author_id
-		WHERE ba.book_id=$1`
+		WHERE ba.book_id=$1 AND ba.role=$2
+		ORDER BY ba.position, a.id`
 
-	if err := tx.Select(&dest, stmt, id); err != nil {
+	if err := tx.Select(&dest, stmt, id, teal.RoleAuthor); err != nil {
 		return nil, err
 	}
 
@@ -35,88 +35,402 @@ func (bs *BookStore) GetAuthorsFromBook(id int64) ([]string, error) {
 	return authors, nil
 }
 
-func (bs *BookStore) GetByAuthor(name string) ([]*teal.Book, error) {
-	tx, err := bs.db.Beginx()
+// Retrieve all books the named author contributed to in the given role, which
+// defaults to teal.RoleAuthor
+func (bs *BookStore) GetByAuthor(userID int64, name, role string) ([]*teal.Book, error) {
+	return bs.getAllMatching(userID, &teal.BookFilter{Author: name, Role: role})
+}
+
+// link a book of the given user to its contributors in order, replacing its
+// current contributors. A new author row is created for each new contributor
+func linkBookToContributors(tx *sqlx.Tx, userID, book_id int64, contributors []teal.Contributor) error {
+	stmt := `DELETE FROM books_authors WHERE book_id=$1;`
+	if _, err := tx.Exec(stmt, book_id); err != nil {
+		return fmt.Errorf("db: unlink contributors from book %d in books_authors failed: %v", book_id, err)
+	}
+
+	for i, c := range contributors {
+		author_id, err := insertOrGetAuthor(tx, userID, &teal.Author{Name: c.Name})
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO books_authors (book_id, author_id, role, position)
+			VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`
+		if _, err := tx.Exec(stmt, book_id, author_id, c.Role, i); err != nil {
+			return fmt.Errorf("db: link book %d to contributor %d in books_authors failed: %v", book_id, author_id, err)
+		}
+	}
+	return nil
+}
+
+// Retrieve all books in the given category, including books in its
+// subcategories
+func (bs *BookStore) GetByCategory(userID int64, name string) ([]*teal.Book, error) {
+	return bs.getAllMatching(userID, &teal.BookFilter{Category: name})
+}
+
+// Retrieve all books tagged with any of the given tags. If matchAll is true,
+// only books tagged with all of the given tags are retrieved
+func (bs *BookStore) GetByTags(userID int64, tags []string, matchAll bool) ([]*teal.Book, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return bs.getAllMatching(userID, &teal.BookFilter{Tags: tags, MatchAll: matchAll})
+}
+
+// Retrieve all books matching the filter. Unlike GetAll, no matching books is
+// not an error
+func (bs *BookStore) getAllMatching(userID int64, f *teal.BookFilter) ([]*teal.Book, error) {
+	books, _, err := bs.GetAll(userID, f)
+	if err == teal.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+		return nil, err
+	}
+	return books, nil
+}
+
+// fill in the related entities of each given book
+func populateBooks(tx *sqlx.Tx, books []*teal.Book) error {
+	if err := populateContributors(tx, books); err != nil {
+		return err
+	}
+	if err := populateCategories(tx, books); err != nil {
+		return err
+	}
+	if err := populateSeries(tx, books); err != nil {
+		return err
+	}
+	if err := populateTags(tx, books); err != nil {
+		return err
+	}
+	if err := populateProgress(tx, books); err != nil {
+		return err
+	}
+	return nil
+}
+
+// fill in the contributors and author names of each given book, in order
+func populateContributors(tx *sqlx.Tx, books []*teal.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	index := make(map[int64]*teal.Book)
+	for _, b := range books {
+		b.Author = nil
+		b.Contributors = nil
+		ids = append(ids, b.ID)
+		index[b.ID] = b
 	}
-	defer endTx(tx, err)
-	var dest []BookModel
 
-	stmt := `SELECT b.*, group_concat(a.name) as author_string
+	var dest []struct {
+		Book_id int64
+		Name    string
+		Role    string
+	}
+	stmt := `SELECT ba.book_id, a.name, ba.role
 		FROM books_authors ba
-		JOIN books b ON b.id=ba.book_id
 		JOIN authors a ON a.id=ba.author_id
-		WHERE b.id IN (SELECT ba.book_id
-			FROM books_authors ba
-			JOIN authors a ON a.id=ba.author_id
-			WHERE a.name=$1)
-		GROUP BY b.id`
-
-	if err := tx.Select(&dest, stmt, name); err != nil {
-		return nil, err
+		WHERE ba.book_id IN (?)
+		ORDER BY ba.position, a.id;`
+	query, args, err := sqlx.In(stmt, ids)
+	if err != nil {
+		return fmt.Errorf("db: retrieve contributors of books %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&dest, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: retrieve contributors of books %v failed: %v", ids, err)
 	}
 
-	var result []*teal.Book
 	for _, v := range dest {
-		r := v.Book
-		r.Author = strings.Split(v.AuthorString, ",")
-		result = append(result, r)
+		if b, ok := index[v.Book_id]; ok {
+			b.Contributors = append(b.Contributors, teal.Contributor{Name: v.Name, Role: v.Role})
+			if v.Role == teal.RoleAuthor {
+				b.Author = append(b.Author, v.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// retrieve books with the given ids, keyed by id
+func getBooksByID(tx *sqlx.Tx, ids []int64) (map[int64]*teal.Book, error) {
+	result := make(map[int64]*teal.Book)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var books []*teal.Book
+	stmt := `SELECT * FROM books WHERE id IN (?) ORDER BY id;`
+	query, args, err := sqlx.In(stmt, ids)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve books %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&books, tx.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("db: retrieve books %v failed: %v", ids, err)
+	}
+
+	for _, b := range books {
+		result[b.ID] = b
 	}
 
+	if err := populateBooks(tx, books); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-func linkBookToAuthor(tx *sqlx.Tx, book_id, author_id int64) error {
-	stmt := `INSERT or IGNORE INTO books_authors (book_id, author_id) VALUES ($1, $2);`
+// fill in the category names of each given book
+func populateCategories(tx *sqlx.Tx, books []*teal.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	index := make(map[int64]*teal.Book)
+	for _, b := range books {
+		b.Categories = nil
+		ids = append(ids, b.ID)
+		index[b.ID] = b
+	}
 
-	_, err := tx.Exec(stmt, book_id, author_id)
+	var dest []struct {
+		Book_id int64
+		Name    string
+	}
+	stmt := `SELECT bc.book_id, c.name
+		FROM books_categories bc
+		JOIN categories c ON c.id=bc.category_id
+		WHERE bc.book_id IN (?)
+		ORDER BY c.name;`
+	query, args, err := sqlx.In(stmt, ids)
 	if err != nil {
-		return fmt.Errorf("db: link book %d to author %d in books_authors failed: %v", book_id, author_id, err)
+		return fmt.Errorf("db: retrieve categories of books %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&dest, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: retrieve categories of books %v failed: %v", ids, err)
+	}
+
+	for _, v := range dest {
+		if b, ok := index[v.Book_id]; ok {
+			b.Categories = append(b.Categories, v.Name)
+		}
 	}
 	return nil
 }
 
-func linkBookToAuthors(tx *sqlx.Tx, book_id int64, author_ids []int64) error {
+func linkBookToCategories(tx *sqlx.Tx, book_id int64, category_ids []int64) error {
+	if len(category_ids) == 0 {
+		return nil
+	}
+
 	type value struct {
-		Book_id   int64
-		Author_id int64
+		Book_id     int64
+		Category_id int64
 	}
 
 	var args = []*value{}
-	for _, a := range author_ids {
+	for _, c := range category_ids {
 		args = append(args, &value{
-			Book_id:   book_id,
-			Author_id: a,
+			Book_id:     book_id,
+			Category_id: c,
 		})
 	}
 
-	stmt := `INSERT or IGNORE INTO books_authors (book_id, author_id) VALUES (:book_id, :author_id);`
+	stmt := `INSERT INTO books_categories (book_id, category_id) VALUES (:book_id, :category_id) ON CONFLICT DO NOTHING;`
 	_, err := tx.NamedExec(stmt, args)
 	if err != nil {
-		return fmt.Errorf("db: link book %d to authors %d in books_authors failed: %v", book_id, author_ids, err)
+		return fmt.Errorf("db: link book %d to categories %d in books_categories failed: %v", book_id, category_ids, err)
 	}
 	return nil
 }
 
-func unlinkBookFromAuthor(tx *sqlx.Tx, book_id, author_id int64) error {
-	stmt := `DELETE FROM books_authors WHERE book_id=? AND author_id!=?;`
-	_, err := tx.Exec(stmt, book_id, author_id)
+// remove all of the book's categories that are not in category_ids
+func unlinkBookFromCategories(tx *sqlx.Tx, book_id int64, category_ids []int64) error {
+	if len(category_ids) == 0 {
+		stmt := `DELETE FROM books_categories WHERE book_id=$1;`
+		if _, err := tx.Exec(stmt, book_id); err != nil {
+			return fmt.Errorf("db: unlink categories from book %v in books_categories failed: %v", book_id, err)
+		}
+		return nil
+	}
+
+	stmt := `DELETE FROM books_categories WHERE book_id=? AND category_id NOT IN (?);`
+	query, args, err := sqlx.In(stmt, book_id, category_ids)
 	if err != nil {
-		return fmt.Errorf("db: unlink author %v from book %v in book_authors failed: %v", author_id, book_id, err)
+		return fmt.Errorf("db: unlink categories %v from book %v in books_categories failed: %v", category_ids, book_id, err)
+	}
+	_, err = tx.Exec(tx.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("db: unlink categories %v from book %v in books_categories failed: %v", category_ids, book_id, err)
+	}
+	return nil
+}
+
+// fill in the series and series positions of each given book
+func populateSeries(tx *sqlx.Tx, books []*teal.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	index := make(map[int64]*teal.Book)
+	for _, b := range books {
+		b.Series = nil
+		ids = append(ids, b.ID)
+		index[b.ID] = b
+	}
+
+	var dest []struct {
+		Book_id  int64
+		Name     string
+		Position float64
+	}
+	stmt := `SELECT bs.book_id, s.name, bs.position
+		FROM books_series bs
+		JOIN series s ON s.id=bs.series_id
+		WHERE bs.book_id IN (?)
+		ORDER BY s.name;`
+	query, args, err := sqlx.In(stmt, ids)
+	if err != nil {
+		return fmt.Errorf("db: retrieve series of books %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&dest, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: retrieve series of books %v failed: %v", ids, err)
+	}
+
+	for _, v := range dest {
+		if b, ok := index[v.Book_id]; ok {
+			b.Series = append(b.Series, teal.SeriesEntry{Name: v.Name, Position: v.Position})
+		}
+	}
+	return nil
+}
+
+// link a user's book to the given series at their positions, creating new
+// series as required. Existing links not in series are removed
+func linkBookToSeries(tx *sqlx.Tx, userID, book_id int64, series []teal.SeriesEntry) error {
+
+	var series_ids []int64
+	for _, s := range series {
+		id, err := insertOrGetSeries(tx, userID, s.Name)
+		if err != nil {
+			return err
+		}
+		series_ids = append(series_ids, id)
+
+		stmt := `INSERT INTO books_series (book_id, series_id, position) VALUES ($1, $2, $3)
+			ON CONFLICT(book_id, series_id) DO UPDATE SET position=excluded.position;`
+		if _, err := tx.Exec(stmt, book_id, id, s.Position); err != nil {
+			return fmt.Errorf("db: link book %d to series %d in books_series failed: %v", book_id, id, err)
+		}
+	}
+
+	if len(series_ids) == 0 {
+		stmt := `DELETE FROM books_series WHERE book_id=$1;`
+		if _, err := tx.Exec(stmt, book_id); err != nil {
+			return fmt.Errorf("db: unlink series from book %v in books_series failed: %v", book_id, err)
+		}
+		return nil
+	}
+
+	stmt := `DELETE FROM books_series WHERE book_id=? AND series_id NOT IN (?);`
+	query, args, err := sqlx.In(stmt, book_id, series_ids)
+	if err != nil {
+		return fmt.Errorf("db: unlink series %v from book %v in books_series failed: %v", series_ids, book_id, err)
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: unlink series %v from book %v in books_series failed: %v", series_ids, book_id, err)
+	}
+	return nil
+}
+
+// fill in the tags of each given book
+func populateTags(tx *sqlx.Tx, books []*teal.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	index := make(map[int64]*teal.Book)
+	for _, b := range books {
+		b.Tags = nil
+		ids = append(ids, b.ID)
+		index[b.ID] = b
+	}
+
+	var dest []struct {
+		Book_id int64
+		Name    string
+	}
+	stmt := `SELECT bt.book_id, t.name
+		FROM books_tags bt
+		JOIN tags t ON t.id=bt.tag_id
+		WHERE bt.book_id IN (?)
+		ORDER BY t.name;`
+	query, args, err := sqlx.In(stmt, ids)
+	if err != nil {
+		return fmt.Errorf("db: retrieve tags of books %v failed: %v", ids, err)
+	}
+	if err := tx.Select(&dest, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("db: retrieve tags of books %v failed: %v", ids, err)
+	}
+
+	for _, v := range dest {
+		if b, ok := index[v.Book_id]; ok {
+			b.Tags = append(b.Tags, v.Name)
+		}
 	}
 	return nil
 }
 
-func unlinkBookFromAuthors(tx *sqlx.Tx, book_id int64, author_ids []int64) error {
-	stmt := `DELETE FROM books_authors WHERE book_id=? AND author_id NOT IN (?);`
-	query, args, err := sqlx.In(stmt, book_id, author_ids)
+func linkBookToTags(tx *sqlx.Tx, book_id int64, tag_ids []int64) error {
+	if len(tag_ids) == 0 {
+		return nil
+	}
+
+	type value struct {
+		Book_id int64
+		Tag_id  int64
+	}
+
+	var args = []*value{}
+	for _, t := range tag_ids {
+		args = append(args, &value{
+			Book_id: book_id,
+			Tag_id:  t,
+		})
+	}
+
+	stmt := `INSERT INTO books_tags (book_id, tag_id) VALUES (:book_id, :tag_id) ON CONFLICT DO NOTHING;`
+	_, err := tx.NamedExec(stmt, args)
+	if err != nil {
+		return fmt.Errorf("db: link book %d to tags %d in books_tags failed: %v", book_id, tag_ids, err)
+	}
+	return nil
+}
+
+// remove all of the book's tags that are not in tag_ids
+func unlinkBookFromTags(tx *sqlx.Tx, book_id int64, tag_ids []int64) error {
+	if len(tag_ids) == 0 {
+		stmt := `DELETE FROM books_tags WHERE book_id=$1;`
+		if _, err := tx.Exec(stmt, book_id); err != nil {
+			return fmt.Errorf("db: unlink tags from book %v in books_tags failed: %v", book_id, err)
+		}
+		return nil
+	}
+
+	stmt := `DELETE FROM books_tags WHERE book_id=? AND tag_id NOT IN (?);`
+	query, args, err := sqlx.In(stmt, book_id, tag_ids)
 	if err != nil {
-		return fmt.Errorf("db: unlink authors %v from book %v in book_authors failed: %v", author_ids, book_id, err)
+		return fmt.Errorf("db: unlink tags %v from book %v in books_tags failed: %v", tag_ids, book_id, err)
 	}
-	_, err = tx.Exec(query, args...)
+	_, err = tx.Exec(tx.Rebind(query), args...)
 	if err != nil {
-		return fmt.Errorf("db: unlink authors %v from book %v in book_authors failed: %v", author_ids, book_id, err)
+		return fmt.Errorf("db: unlink tags %v from book %v in books_tags failed: %v", tag_ids, book_id, err)
 	}
 	return nil
 }
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// Edit history of books and authors. Revisions are recorded by BookStore,
+// TrashStore and AuthorStore in the transaction of each change
+type RevisionStore struct {
+	db *sqlx.DB
+}
+
+const (
+	entityBook   = "book"
+	entityAuthor = "author"
+)
+
+// book fields that are not part of a revision's changes
+var bookDiffIgnore = []string{"id", "progress", "date_deleted", "version", "isbn10", "cover"}
+
+// author fields that are not part of a revision's changes
+var authorDiffIgnore = []string{"version"}
+
+// Retrieve the revisions of a user's book, most recent first
+func (s *RevisionStore) GetBookHistory(userID, bookID int64) ([]*teal.Revision, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	if _, err := getBookState(tx, userID, bookID); err != nil {
+		return nil, err
+	}
+	return getRevisions(tx, entityBook, bookID)
+}
+
+// Retrieve the revisions of an author, most recent first. The revisions of
+// deleted authors are kept
+func (s *RevisionStore) GetAuthorHistory(authorID int64) ([]*teal.Revision, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	return getRevisions(tx, entityAuthor, authorID)
+}
+
+// Revert a user's book to its details after the given revision. The book's
+// reading state is not changed, see BookStore.Update
+func (s *RevisionStore) RevertBook(userID, bookID, revisionID int64) (*teal.Book, error) {
+	var r teal.Revision
+	stmt := `SELECT * FROM revisions WHERE id=$1 AND entity=$2 AND entity_id=$3;`
+	err := s.db.Get(&r, stmt, revisionID, entityBook, bookID)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve revision %d failed: %v", revisionID, err)
+	}
+
+	var b teal.Book
+	if err := json.Unmarshal(r.Snapshot, &b); err != nil {
+		return nil, fmt.Errorf("db: read revision %d failed: %v", revisionID, err)
+	}
+	b.State = ""
+
+	bs := &BookStore{s.db}
+	return bs.update(userID, bookID, 0, &b, teal.ActionRevert)
+}
+
+func getRevisions(tx *sqlx.Tx, entity string, id int64) ([]*teal.Revision, error) {
+	var revisions []*teal.Revision
+	stmt := `SELECT * FROM revisions WHERE entity=$1 AND entity_id=$2 ORDER BY id DESC;`
+	if err := tx.Select(&revisions, stmt, entity, id); err != nil {
+		return nil, fmt.Errorf("db: retrieve history of %s %d failed: %v", entity, id, err)
+	}
+	if len(revisions) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return revisions, nil
+}
+
+// record a change of an entity from before to after, made by the given user.
+// before is nil for a create, and after is nil for a delete. Updates without
+// changes are not recorded
+func recordRevision(tx *sqlx.Tx, entity string, id, userID int64, action string, before, after interface{}, ignore ...string) error {
+	changes, err := teal.Diff(before, after, ignore...)
+	if err != nil {
+		return fmt.Errorf("db: diff of %s %d failed: %v", entity, id, err)
+	}
+	if action == teal.ActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	// deletes keep the entity before the change
+	if action == teal.ActionDelete {
+		changes = teal.Changes{}
+		after = before
+	}
+
+	snapshot, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("db: snapshot of %s %d failed: %v", entity, id, err)
+	}
+
+	stmt := `INSERT INTO revisions (entity, entity_id, user_id, action, changes, snapshot)
+		VALUES ($1, $2, $3, $4, $5, $6);`
+	user := sql.NullInt64{Int64: userID, Valid: userID > 0}
+	if _, err := tx.Exec(stmt, entity, id, user, action, changes, string(snapshot)); err != nil {
+		return fmt.Errorf("db: insert revision of %s %d failed: %v", entity, id, err)
+	}
+	return nil
+}
+
+func recordBookRevision(tx *sqlx.Tx, id, userID int64, action string, before, after *teal.Book) error {
+	return recordRevision(tx, entityBook, id, userID, action, before, after, bookDiffIgnore...)
+}
+
+func recordAuthorRevision(tx *sqlx.Tx, id, userID int64, action string, before, after *teal.Author) error {
+	return recordRevision(tx, entityAuthor, id, userID, action, before, after, authorDiffIgnore...)
+}
+
+// retrieve a book with its relationships, including books in the trash
+func getBook(tx *sqlx.Tx, id int64) (*teal.Book, error) {
+	var b teal.Book
+	stmt := `SELECT * FROM books WHERE id=$1;`
+	err := tx.Get(&b, stmt, id)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve book %d failed: %v", id, err)
+	}
+	if err := populateBooks(tx, []*teal.Book{&b}); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// retrieve an author with the given user's aliases
+func getAuthor(tx *sqlx.Tx, userID, id int64) (*teal.Author, error) {
+	var a teal.Author
+	stmt := `SELECT * FROM authors WHERE id=$1;`
+	err := tx.Get(&a, stmt, id)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve author %d failed: %v", id, err)
+	}
+	if err := populateAliases(tx, userID, []*teal.Author{&a}); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func deleteRevisions(tx *sqlx.Tx, entity string, id int64) error {
+	stmt := `DELETE FROM revisions WHERE entity=$1 AND entity_id=$2;`
+	if _, err := tx.Exec(stmt, entity, id); err != nil {
+		return fmt.Errorf("db: delete history of %s %d failed: %v", entity, id, err)
+	}
+	return nil
+}
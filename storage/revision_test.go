@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func TestBookHistory(t *testing.T) {
+	defer resetDB(testdb)
+
+	book, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Dune",
+		ISBN:   "2001",
+		Author: []string{"Frank Herbert"},
+	})
+	checkErr(t, err)
+
+	// seeded books have no history
+	_, err = ts.Revisions.GetBookHistory(testUser1.ID, testBook1.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+
+	update := *book
+	update.Title = "Dune Messiah"
+	update.NumOfPages = 256
+	_, err = ts.Books.Update(testUser1.ID, book.ID, 0, &update)
+	checkErr(t, err)
+
+	// updates without changes are not recorded
+	_, err = ts.Books.Update(testUser1.ID, book.ID, 0, &update)
+	checkErr(t, err)
+
+	got, err := ts.Revisions.GetBookHistory(testUser1.ID, book.ID)
+	checkErr(t, err)
+	if len(got) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(got))
+	}
+
+	// most recent first
+	assertEqual(t, got[0].Action, teal.ActionUpdate)
+	assertEqual(t, got[1].Action, teal.ActionCreate)
+	assertEqual(t, *got[0].UserID, testUser1.ID)
+
+	assertEqual(t, len(got[0].Changes), 2)
+	assertChange(t, got[0].Changes["title"], "Dune", "Dune Messiah")
+	assertChange(t, got[0].Changes["num_of_pages"], float64(0), float64(256))
+	assertChange(t, got[1].Changes["title"], nil, "Dune")
+
+	// history of other users' books
+	_, err = ts.Revisions.GetBookHistory(testUser2.ID, book.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestBookHistoryStateAndTrash(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Books.UpdateState(testUser1.ID, testBook2.ID, 0, teal.StateReading)
+	checkErr(t, err)
+	err = ts.Books.Delete(testUser1.ID, testBook2.ID, 0)
+	checkErr(t, err)
+
+	// history of trashed books is hidden
+	_, err = ts.Revisions.GetBookHistory(testUser1.ID, testBook2.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	_, err = ts.Trash.Restore(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+
+	got, err := ts.Revisions.GetBookHistory(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	if len(got) != 3 {
+		t.Fatalf("got %d revisions, want 3", len(got))
+	}
+	assertEqual(t, got[0].Action, teal.ActionRestore)
+	assertEqual(t, got[1].Action, teal.ActionDelete)
+	assertEqual(t, got[2].Action, teal.ActionUpdate)
+	assertChange(t, got[2].Changes["state"], teal.StateWantToRead, teal.StateReading)
+
+	// history is removed with the book
+	err = ts.Books.Delete(testUser1.ID, testBook2.ID, 0)
+	checkErr(t, err)
+	_, err = ts.Trash.Empty(testUser1.ID, time.Now().Add(time.Minute))
+	checkErr(t, err)
+
+	var count int
+	err = testdb.Get(&count, `SELECT COUNT(*) FROM revisions WHERE entity_id=$1;`, testBook2.ID)
+	checkErr(t, err)
+	assertEqual(t, count, 0)
+}
+
+func TestRevertBook(t *testing.T) {
+	defer resetDB(testdb)
+
+	book, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Dune",
+		ISBN:   "2001",
+		Author: []string{"Frank Herbert"},
+	})
+	checkErr(t, err)
+
+	update := *book
+	update.Title = "Dune Messiah"
+	update.Author = []string{"Brian Herbert"}
+	_, err = ts.Books.Update(testUser1.ID, book.ID, 0, &update)
+	checkErr(t, err)
+
+	history, err := ts.Revisions.GetBookHistory(testUser1.ID, book.ID)
+	checkErr(t, err)
+	created := history[len(history)-1]
+
+	// revisions of other users' books
+	_, err = ts.Revisions.RevertBook(testUser2.ID, book.ID, created.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	// revisions of other books
+	_, err = ts.Revisions.RevertBook(testUser1.ID, testBook1.ID, created.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	got, err := ts.Revisions.RevertBook(testUser1.ID, book.ID, created.ID)
+	checkErr(t, err)
+	assertEqual(t, got.Title, "Dune")
+	assertEqual(t, len(got.Author), 1)
+	assertEqual(t, got.Author[0], "Frank Herbert")
+
+	history, err = ts.Revisions.GetBookHistory(testUser1.ID, book.ID)
+	checkErr(t, err)
+	assertEqual(t, history[0].Action, teal.ActionRevert)
+	assertChange(t, history[0].Changes["title"], "Dune Messiah", "Dune")
+}
+
+func TestAuthorHistory(t *testing.T) {
+	defer resetDB(testdb)
+
+	author, err := ts.Authors.Create(testUser1.ID, &teal.Author{Name: "Ursula K. Le Guin"})
+	checkErr(t, err)
+
+	// existing authors are not recreated
+	_, err = ts.Authors.Create(testUser1.ID, &teal.Author{Name: "Ursula K. Le Guin"})
+	checkErr(t, err)
+
+	_, err = ts.Authors.Update(testUser2.ID, author.ID, 0, &teal.Author{Name: "Ursula Le Guin"})
+	checkErr(t, err)
+
+	got, err := ts.Revisions.GetAuthorHistory(author.ID)
+	checkErr(t, err)
+	if len(got) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(got))
+	}
+	assertEqual(t, got[0].Action, teal.ActionUpdate)
+	assertEqual(t, *got[0].UserID, testUser2.ID)
+	assertChange(t, got[0].Changes["name"], "Ursula K. Le Guin", "Ursula Le Guin")
+	assertEqual(t, got[1].Action, teal.ActionCreate)
+
+	_, err = ts.Revisions.GetAuthorHistory(-1)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+}
+
+func TestAuthorHistoryKeptOnDelete(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Authors.Delete(testUser1.ID, testAuthor1.ID)
+	checkErr(t, err)
+
+	got, err := ts.Revisions.GetAuthorHistory(testAuthor1.ID)
+	checkErr(t, err)
+	if len(got) != 1 {
+		t.Fatalf("got %d revisions, want 1", len(got))
+	}
+	assertEqual(t, got[0].Action, teal.ActionDelete)
+	assertEqual(t, len(got[0].Changes), 0)
+}
+
+func assertChange(t *testing.T, got teal.Change, from, to interface{}) {
+	t.Helper()
+	want := teal.Change{From: from, To: to}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
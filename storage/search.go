@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unsafe"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// Full-text search index. SQLite uses the books_fts virtual table, which is
+// FTS5 when go-sqlite3 is built with the sqlite_fts5 tag and FTS4 otherwise.
+// PostgreSQL uses the books_search table with a tsvector column.
+//
+// The index is keyed by book id and must be updated in the same transaction
+// as any change to a book's title, description or authors.
+const (
+	SearchFTS5     = "fts5"
+	SearchFTS4     = "fts4"
+	SearchPostgres = "postgres"
+)
+
+// Highlighted terms are delimited by these control characters in SQL. They
+// are replaced by <mark></mark> after the text is HTML escaped, see markup
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+var markReplacer = strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>")
+
+// title, description and author weights. Keep in sync with fts5Rank
+var searchWeights = []float64{10.0, 1.0, 5.0}
+
+const fts5Rank = `bm25(books_fts, 10.0, 1.0, 5.0)`
+
+const fts5SearchColumns = `b.*, -` + fts5Rank + ` AS rank,
+	highlight(books_fts, 0, '` + markStart + `', '` + markEnd + `') AS highlight_title,
+	snippet(books_fts, 1, '` + markStart + `', '` + markEnd + `', '...', 24) AS highlight_description,
+	highlight(books_fts, 2, '` + markStart + `', '` + markEnd + `') AS highlight_author`
+
+// FTS4 has no built-in ranking function. Results are ranked by fts4Rank
+const fts4SearchColumns = `b.*, matchinfo(books_fts, 'pcx') AS matchinfo,
+	snippet(books_fts, '` + markStart + `', '` + markEnd + `', '...', 0, -64) AS highlight_title,
+	snippet(books_fts, '` + markStart + `', '` + markEnd + `', '...', 1, 24) AS highlight_description,
+	snippet(books_fts, '` + markStart + `', '` + markEnd + `', '...', 2, -64) AS highlight_author`
+
+const postgresSearchColumns = `b.*, ts_rank(s.document, q) AS rank,
+	ts_headline('simple', s.title, q, 'StartSel=` + markStart + `, StopSel=` + markEnd + `, HighlightAll=true') AS highlight_title,
+	ts_headline('simple', s.description, q, 'StartSel=` + markStart + `, StopSel=` + markEnd + `, MaxWords=24, MinWords=8') AS highlight_description,
+	ts_headline('simple', s.author, q, 'StartSel=` + markStart + `, StopSel=` + markEnd + `, HighlightAll=true') AS highlight_author`
+
+type searchRow struct {
+	*teal.Book
+	Rank                 float64 `db:"rank"`
+	HighlightTitle       string  `db:"highlight_title"`
+	HighlightDescription string  `db:"highlight_description"`
+	HighlightAuthor      string  `db:"highlight_author"`
+	MatchInfo            []byte  `db:"matchinfo"`
+}
+
+// Search a user's books matching the filter by title, description and author
+// names. Every term in f.Query must match, and terms match as prefixes. Results
+// are ordered by rank, most relevant first, and then by id. Returns a page of
+// results, the total number of matches and the cursor of the next page
+func (bs *BookStore) Search(userID int64, f *teal.BookFilter) ([]*teal.SearchResult, teal.PageInfo, error) {
+	terms := searchTerms(f.Query)
+	if len(terms) == 0 {
+		return nil, teal.PageInfo{}, teal.ErrNoRows
+	}
+
+	tx, err := bs.db.Beginx()
+	if err != nil {
+		return nil, teal.PageInfo{}, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	index, err := searchIndex(tx)
+	if err != nil {
+		return nil, teal.PageInfo{}, err
+	}
+
+	w := bookWhere(userID, f)
+	var columns, from, order string
+	var args []interface{}
+	switch index {
+	case SearchPostgres:
+		columns = postgresSearchColumns
+		from = `FROM books_search s
+			JOIN books b ON b.id=s.book_id,
+			to_tsquery('simple', ?) q`
+		w.add(`s.document @@ q`)
+		// the query placeholder comes before the WHERE clause
+		args = append([]interface{}{strings.Join(terms, ":* & ") + ":*"}, w.args...)
+		order = ` ORDER BY rank DESC, b.id`
+	case SearchFTS5:
+		columns = fts5SearchColumns
+		from = `FROM books_fts JOIN books b ON b.id=books_fts.rowid`
+		w.add(`books_fts MATCH ?`, strings.Join(terms, "* ")+"*")
+		args = w.args
+		order = ` ORDER BY ` + fts5Rank + `, b.id`
+	default:
+		columns = fts4SearchColumns
+		from = `FROM books_fts JOIN books b ON b.id=books_fts.rowid`
+		w.add(`books_fts MATCH ?`, strings.Join(terms, "* ")+"*")
+		args = w.args
+		order = ` ORDER BY b.id`
+	}
+
+	query, args, err := sqlx.In(`SELECT `+columns+` `+from+w.String()+order, args...)
+	if err != nil {
+		return nil, teal.PageInfo{}, fmt.Errorf("db: search books %q failed: %v", f.Query, err)
+	}
+
+	var rows []*searchRow
+	if err := tx.Select(&rows, tx.Rebind(query), args...); err != nil {
+		return nil, teal.PageInfo{}, fmt.Errorf("db: search books %q failed: %v", f.Query, err)
+	}
+
+	if index == SearchFTS4 {
+		for _, r := range rows {
+			r.Rank = fts4Rank(r.MatchInfo)
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].Rank > rows[j].Rank
+		})
+	}
+
+	// FTS4 results are ranked here, so all backends page the ranked results
+	info := teal.PageInfo{Total: len(rows)}
+	rows, err = rowsAfter(rows, f.After)
+	if err != nil {
+		return nil, teal.PageInfo{}, err
+	}
+	if len(rows) == 0 {
+		return nil, info, teal.ErrNoRows
+	}
+	if f.Limit > 0 && len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		last := rows[f.Limit-1]
+		rank := strconv.FormatFloat(last.Rank, 'g', -1, 64)
+		info.Next = &teal.Cursor{Sort: teal.SortRank, Value: &rank, ID: last.ID}
+	}
+
+	books := make([]*teal.Book, len(rows))
+	results := make([]*teal.SearchResult, len(rows))
+	for i, r := range rows {
+		books[i] = r.Book
+		results[i] = &teal.SearchResult{
+			Book: r.Book,
+			Rank: r.Rank,
+			Highlight: teal.Highlight{
+				Title:       markup(r.HighlightTitle),
+				Description: markup(r.HighlightDescription),
+				Author:      markup(r.HighlightAuthor),
+			},
+		}
+	}
+
+	if err := populateBooks(tx, books); err != nil {
+		return nil, teal.PageInfo{}, err
+	}
+	return results, info, nil
+}
+
+// rows after cursor c, given rows ordered by rank, most relevant first, and id
+func rowsAfter(rows []*searchRow, c *teal.Cursor) ([]*searchRow, error) {
+	if c == nil {
+		return rows, nil
+	}
+	if c.Value == nil {
+		return nil, fmt.Errorf("db: search cursor has no rank")
+	}
+
+	rank, err := strconv.ParseFloat(*c.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("db: search cursor has invalid rank %q", *c.Value)
+	}
+
+	i := sort.Search(len(rows), func(i int) bool {
+		r := rows[i]
+		return r.Rank < rank || (r.Rank == rank && r.ID > c.ID)
+	})
+	return rows[i:], nil
+}
+
+// HTML escape highlighted text, and wrap the highlighted terms in <mark></mark>
+func markup(s string) string {
+	return markReplacer.Replace(html.EscapeString(s))
+}
+
+// Split a search query into lowercase terms of letters and digits. All other
+// characters are dropped so terms are safe to use in MATCH and to_tsquery
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return terms
+}
+
+// Rank a FTS4 result from matchinfo(books_fts, 'pcx'): the number of phrases
+// p and columns c, followed by 3 integers for each phrase and column. These are
+// the hits in this row, hits in all rows and number of rows with hits. Each
+// column's share of a phrase's hits is weighted by searchWeights
+func fts4Rank(info []byte) float64 {
+	// matchinfo is an array of unsigned 32-bit integers in native byte order
+	n := make([]uint32, len(info)/4)
+	for i := range n {
+		n[i] = nativeEndian.Uint32(info[i*4:])
+	}
+	if len(n) < 2 {
+		return 0
+	}
+
+	var rank float64
+	phrases, cols := int(n[0]), int(n[1])
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < cols && c < len(searchWeights); c++ {
+			i := 2 + 3*(p*cols+c)
+			if i+1 >= len(n) || n[i+1] == 0 {
+				continue
+			}
+			rank += searchWeights[c] * float64(n[i]) / float64(n[i+1])
+		}
+	}
+	return rank
+}
+
+// byte order of this machine
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// Type of full-text index in the database: SearchFTS5, SearchFTS4 or
+// SearchPostgres
+func SearchIndex(db *sqlx.DB) (string, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return "", fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	return searchIndex(tx)
+}
+
+func searchIndex(tx *sqlx.Tx) (string, error) {
+	if tx.DriverName() == POSTGRES {
+		return SearchPostgres, nil
+	}
+
+	var stmt string
+	err := tx.Get(&stmt, `SELECT sql FROM sqlite_master WHERE name='books_fts';`)
+	if err != nil {
+		return "", fmt.Errorf("db: retrieve search index failed: %v", err)
+	}
+	if strings.Contains(strings.ToLower(stmt), "fts5") {
+		return SearchFTS5, nil
+	}
+	return SearchFTS4, nil
+}
+
+// Add or replace a book in the search index
+func indexBook(tx *sqlx.Tx, id int64) error {
+	if err := unindexBook(tx, id); err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO books_fts (rowid, title, description, author)
+		SELECT b.id, b.title, COALESCE(b.description, ''),
+			COALESCE((SELECT GROUP_CONCAT(a.name, ' ')
+				FROM books_authors ba
+				JOIN authors a ON a.id=ba.author_id
+				WHERE ba.book_id=b.id AND ba.role='author'), '')
+		FROM books b WHERE b.id=$1;`
+	if tx.DriverName() == POSTGRES {
+		stmt = `INSERT INTO books_search (book_id, title, description, author, document)
+			SELECT id, title, description, author,
+				setweight(to_tsvector('simple', title), 'A') ||
+				setweight(to_tsvector('simple', author), 'B') ||
+				setweight(to_tsvector('simple', description), 'C')
+			FROM (
+				SELECT b.id, b.title, COALESCE(b.description, '') AS description,
+					COALESCE((SELECT STRING_AGG(a.name, ' ' ORDER BY ba.position)
+						FROM books_authors ba
+						JOIN authors a ON a.id=ba.author_id
+						WHERE ba.book_id=b.id AND ba.role='author'), '') AS author
+				FROM books b WHERE b.id=$1
+			) AS books_text;`
+	}
+
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: index book %d failed: %v", id, err)
+	}
+	return nil
+}
+
+// Remove a book from the search index
+func unindexBook(tx *sqlx.Tx, id int64) error {
+	stmt := `DELETE FROM books_fts WHERE rowid=$1;`
+	if tx.DriverName() == POSTGRES {
+		stmt = `DELETE FROM books_search WHERE book_id=$1;`
+	}
+
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: remove book %d from search index failed: %v", id, err)
+	}
+	return nil
+}
+
+// Reindex the given books, eg. after an author is renamed or deleted
+func indexBooks(tx *sqlx.Tx, ids []int64) error {
+	for _, id := range ids {
+		if err := indexBook(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuild the search index from all books
+func rebuildSearchIndex(db *sqlx.DB) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+
+	var ids []int64
+	if err := tx.Select(&ids, `SELECT id FROM books ORDER BY id;`); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("db: retrieve all books failed: %v", err)
+	}
+	if err := indexBooks(tx, ids); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
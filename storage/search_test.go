@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kencx/teal"
+)
+
+func searchIDs(t *testing.T, query string) []int64 {
+	t.Helper()
+
+	got, _, err := ts.Books.Search(testUser1.ID, &teal.BookFilter{Query: query})
+	if err == teal.ErrNoRows {
+		return nil
+	}
+	checkErr(t, err)
+
+	var ids []int64
+	for _, r := range got {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "expanse leviathan", want: []string{"expanse", "leviathan"}},
+		{query: "  Leviathan   WAKES ", want: []string{"leviathan", "wakes"}},
+		{query: `S.A. "Corey" OR -NEAR*`, want: []string{"s", "a", "corey", "or", "near"}},
+		{query: "1984", want: []string{"1984"}},
+		{query: " *\"- ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := searchTerms(tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchBooks(t *testing.T) {
+	resetDB(testdb)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int64
+	}{
+		{name: "title", query: "leviathan", want: []int64{testBook1.ID}},
+		{name: "case insensitive", query: "RED rising", want: []int64{testBook2.ID}},
+		{name: "prefix", query: "levi wak", want: []int64{testBook1.ID}},
+		{name: "author", query: "corey", want: []int64{testBook1.ID}},
+		{name: "title and author", query: "many doe", want: []int64{testBook3.ID}},
+		{name: "all terms must match", query: "leviathan brown", want: nil},
+		{name: "no match", query: "foobar", want: nil},
+		{name: "query syntax is ignored", query: `leviathan" OR "red`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIDs(t, tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchBooksEmptyQuery(t *testing.T) {
+	_, _, err := ts.Books.Search(testUser1.ID, &teal.BookFilter{Query: " - "})
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+}
+
+func TestSearchBooksRankAndHighlight(t *testing.T) {
+	defer resetDB(testdb)
+
+	desc, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:       "Caliban's War",
+		Description: teal.NullString{NullString: sql.NullString{String: "The sequel to Leviathan Wakes", Valid: true}},
+		ISBN:        "1010",
+		Author:      []string{"S.A. Corey"},
+	})
+	checkErr(t, err)
+
+	got, info, err := ts.Books.Search(testUser1.ID, &teal.BookFilter{Query: "leviathan"})
+	checkErr(t, err)
+
+	// title matches rank above description matches
+	if len(got) != 2 {
+		t.Fatalf("got %d results, want 2", len(got))
+	}
+	assertEqual(t, info.Total, 2)
+	assertEqual(t, got[0].ID, testBook1.ID)
+	assertEqual(t, got[1].ID, desc.ID)
+	if got[0].Rank <= got[1].Rank {
+		t.Errorf("got rank %v <= %v, want title match ranked first", got[0].Rank, got[1].Rank)
+	}
+
+	// results are full books
+	want, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	if !assertBooksEqual(got[0].Book, want) {
+		t.Errorf("got %v, want %v", prettyPrint(got[0].Book), prettyPrint(want))
+	}
+
+	assertEqual(t, got[0].Highlight.Title, "<mark>Leviathan</mark> Wakes")
+	if !strings.Contains(got[1].Highlight.Description, "<mark>Leviathan</mark>") {
+		t.Errorf("got description highlight %q, want <mark>Leviathan</mark>", got[1].Highlight.Description)
+	}
+}
+
+func TestSearchBooksEscapesHighlight(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:       `<script>alert("Tiamat")</script>`,
+		Description: teal.NullString{NullString: sql.NullString{String: "<img src=x onerror=alert(1)> Tiamat", Valid: true}},
+		ISBN:        "1012",
+		Author:      []string{"<b>Corey</b>"},
+	})
+	checkErr(t, err)
+
+	got, _, err := ts.Books.Search(testUser1.ID, &teal.BookFilter{Query: "tiamat"})
+	checkErr(t, err)
+	if len(got) != 1 {
+		t.Fatalf("got %d results, want 1", len(got))
+	}
+
+	h := got[0].Highlight
+	assertEqual(t, h.Title, `&lt;script&gt;alert(&#34;<mark>Tiamat</mark>&#34;)&lt;/script&gt;`)
+	assertEqual(t, h.Author, `&lt;b&gt;Corey&lt;/b&gt;`)
+	if strings.Contains(h.Description, "<img") {
+		t.Errorf("got unescaped description highlight %q", h.Description)
+	}
+}
+
+func TestSearchBooksFilterAndPage(t *testing.T) {
+	defer resetDB(testdb)
+
+	for i, title := range []string{"Caliban's War", "Abaddon's Gate"} {
+		_, err := ts.Books.Create(testUser1.ID, &teal.Book{
+			Title:  title,
+			ISBN:   fmt.Sprintf("102%d", i),
+			Author: []string{"S.A. Corey"},
+			State:  "reading",
+		})
+		checkErr(t, err)
+	}
+
+	// other filters apply to the matches
+	got, info, err := ts.Books.Search(testUser1.ID, &teal.BookFilter{Query: "corey", State: "read"})
+	checkErr(t, err)
+	assertEqual(t, info.Total, 1)
+	assertEqual(t, got[0].ID, testBook1.ID)
+
+	all, _, err := ts.Books.Search(testUser1.ID, &teal.BookFilter{Query: "corey"})
+	checkErr(t, err)
+	var want []int64
+	for _, r := range all {
+		want = append(want, r.ID)
+	}
+
+	// pages of the ranked matches continue after the cursor
+	var ids []int64
+	f := &teal.BookFilter{Query: "corey", Page: teal.Page{Limit: 2}}
+	for {
+		got, info, err = ts.Books.Search(testUser1.ID, f)
+		checkErr(t, err)
+		assertEqual(t, info.Total, 3)
+		for _, r := range got {
+			ids = append(ids, r.ID)
+		}
+		if info.Next == nil {
+			break
+		}
+		assertEqual(t, info.Next.Sort, teal.SortRank)
+		f.After = info.Next
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+}
+
+func TestFTS4RankByteOrder(t *testing.T) {
+	// 1 phrase, 1 column: 2 hits in this row of 4 hits in all rows
+	n := []uint32{1, 1, 2, 4, 2}
+	info := make([]byte, 4*len(n))
+	for i, v := range n {
+		nativeEndian.PutUint32(info[i*4:], v)
+	}
+	assertEqual(t, fts4Rank(info), searchWeights[0]*0.5)
+}
+
+func TestSearchIndexSync(t *testing.T) {
+	defer resetDB(testdb)
+
+	book, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Dune",
+		ISBN:   "1011",
+		Author: []string{"Frank Herbert"},
+	})
+	checkErr(t, err)
+
+	t.Run("create", func(t *testing.T) {
+		got := searchIDs(t, "dune herbert")
+		if !reflect.DeepEqual(got, []int64{book.ID}) {
+			t.Errorf("got %v, want %v", got, []int64{book.ID})
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		book.Title = "Dune Messiah"
+		_, err := ts.Books.Update(testUser1.ID, book.ID, 0, book)
+		checkErr(t, err)
+
+		got := searchIDs(t, "messiah")
+		if !reflect.DeepEqual(got, []int64{book.ID}) {
+			t.Errorf("got %v, want %v", got, []int64{book.ID})
+		}
+	})
+
+	t.Run("rename author", func(t *testing.T) {
+		author, err := ts.Authors.GetByName(testUser1.ID, "Frank Herbert")
+		checkErr(t, err)
+
+		_, err = ts.Authors.Update(testUser1.ID, author.ID, 0, &teal.Author{Name: "F. Herbert"})
+		checkErr(t, err)
+
+		if got := searchIDs(t, "frank"); got != nil {
+			t.Errorf("got %v, want no results", got)
+		}
+		got := searchIDs(t, "herbert")
+		if !reflect.DeepEqual(got, []int64{book.ID}) {
+			t.Errorf("got %v, want %v", got, []int64{book.ID})
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := ts.Books.Delete(testUser1.ID, book.ID, 0)
+		checkErr(t, err)
+
+		if got := searchIDs(t, "dune"); got != nil {
+			t.Errorf("got %v, want no results", got)
+		}
+	})
+}
@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+	tcontext "github.com/kencx/teal/context"
+)
+
+type SeriesStore struct {
+	db *sqlx.DB
+}
+
+const seriesColumns = `id, name, dateAdded, dateUpdated`
+
+// Retrieve a user's series and its books in reading order
+func (s *SeriesStore) Get(userID, id int64) (*teal.Series, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var series teal.Series
+	stmt := `SELECT ` + seriesColumns + ` FROM series WHERE id=$1 AND user_id=$2;`
+	err = tx.QueryRowx(stmt, id, userID).StructScan(&series)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve series %d failed: %v", id, err)
+	}
+
+	books, err := getBooksInSeries(tx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	series.Books = books
+	return &series, nil
+}
+
+func (s *SeriesStore) GetByName(userID int64, name string) (*teal.Series, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var series teal.Series
+	stmt := `SELECT ` + seriesColumns + ` FROM series WHERE name=$1 AND user_id=$2;`
+	err = tx.QueryRowx(stmt, name, userID).StructScan(&series)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve series %q failed: %v", name, err)
+	}
+
+	books, err := getBooksInSeries(tx, userID, series.ID)
+	if err != nil {
+		return nil, err
+	}
+	series.Books = books
+	return &series, nil
+}
+
+func (s *SeriesStore) GetAll(userID int64) ([]*teal.Series, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var series []*teal.Series
+	stmt := `SELECT ` + seriesColumns + ` FROM series WHERE user_id=$1 ORDER BY id;`
+	err = tx.Select(&series, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve all series failed: %v", err)
+	}
+	if len(series) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return series, nil
+}
+
+// Retrieve the first book of a user in reading order that has not been read,
+// for each series. Series that have been read completely are omitted
+func (s *SeriesStore) GetNextUnread(userID int64) ([]*teal.NextInSeries, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var dest []struct {
+		Series_id int64
+		Name      string
+		Position  float64
+		Book_id   int64
+	}
+	stmt := `SELECT bs.series_id, s.name, bs.position, bs.book_id
+		FROM books_series bs
+		JOIN series s ON s.id=bs.series_id
+		JOIN books b ON b.id=bs.book_id
+		WHERE b.user_id=$1 AND b.dateDeleted IS NULL
+			AND b.state NOT IN ('read', 'did-not-finish')
+		ORDER BY s.name, bs.series_id, bs.position, bs.book_id;`
+	if err := tx.Select(&dest, stmt, userID); err != nil {
+		return nil, fmt.Errorf("db: retrieve next unread in series failed: %v", err)
+	}
+
+	var result []*teal.NextInSeries
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, v := range dest {
+		// rows are sorted by position, the first row of each series is the next unread
+		if seen[v.Series_id] {
+			continue
+		}
+		seen[v.Series_id] = true
+		result = append(result, &teal.NextInSeries{
+			SeriesID: v.Series_id,
+			Series:   v.Name,
+			Position: v.Position,
+			Book:     &teal.Book{ID: v.Book_id},
+		})
+		ids = append(ids, v.Book_id)
+	}
+	if len(result) == 0 {
+		return nil, teal.ErrNoRows
+	}
+
+	books, err := getBooksByID(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range result {
+		if b, ok := books[r.Book.ID]; ok {
+			r.Book = b
+		}
+	}
+	return result, nil
+}
+
+func (s *SeriesStore) Create(userID int64, series *teal.Series) (*teal.Series, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		var id int64
+		stmt := `INSERT INTO series (user_id, name) VALUES ($1, $2) RETURNING id;`
+		err := tx.QueryRowx(stmt, userID, series.Name).Scan(&id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return teal.ErrDuplicateSeries
+			}
+			return fmt.Errorf("db: insert to series table failed: %v", err)
+		}
+		// save id to context for querying later
+		ctx = tcontext.WithSeriesID(ctx, id)
+		return nil
+
+	}); err != nil {
+		return nil, err
+	}
+
+	id, err := tcontext.GetSeriesID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// query series after transaction committed
+	result, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *SeriesStore) Update(userID, id int64, series *teal.Series) (*teal.Series, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		stmt := `UPDATE series
+			SET name=$1,
+			dateUpdated=CURRENT_TIMESTAMP
+			WHERE id=$2 AND user_id=$3;`
+		res, err := tx.Exec(stmt, series.Name, id, userID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return teal.ErrDuplicateSeries
+			}
+			return fmt.Errorf("db: update series %d failed: %v", id, err)
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: update series %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+
+		ids, err := getBookIDsFromSeries(tx, id)
+		if err != nil {
+			return err
+		}
+		return bumpBookVersions(tx, ids)
+
+	}); err != nil {
+		return nil, err
+	}
+
+	result, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete a series and its book relationships. The books are not deleted
+func (s *SeriesStore) Delete(userID, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		stmt := `DELETE FROM series WHERE id=$1 AND user_id=$2;`
+		res, err := tx.Exec(stmt, id, userID)
+		if err != nil {
+			return fmt.Errorf("db: delete series %d failed: %v", id, err)
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: delete series %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+
+		ids, err := getBookIDsFromSeries(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := bumpBookVersions(tx, ids); err != nil {
+			return err
+		}
+
+		stmt = `DELETE FROM books_series WHERE series_id=$1;`
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("db: delete series %d from books_series failed: %v", id, err)
+		}
+		return nil
+
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getBookIDsFromSeries(tx *sqlx.Tx, id int64) ([]int64, error) {
+
+	var ids []int64
+	stmt := `SELECT book_id FROM books_series WHERE series_id=$1 ORDER BY book_id;`
+	if err := tx.Select(&ids, stmt, id); err != nil {
+		return nil, fmt.Errorf("db: retrieve books of series %d failed: %v", id, err)
+	}
+	return ids, nil
+}
+
+// retrieve a user's books in series, ordered by position
+func getBooksInSeries(tx *sqlx.Tx, userID, id int64) ([]*teal.Book, error) {
+	var books []*teal.Book
+	stmt := `SELECT b.*
+		FROM books b
+		INNER JOIN books_series bs ON bs.book_id=b.id
+		WHERE bs.series_id=$1 AND b.user_id=$2 AND b.dateDeleted IS NULL
+		ORDER BY bs.position, b.id;`
+
+	if err := tx.Select(&books, stmt, id, userID); err != nil {
+		return nil, fmt.Errorf("db: retrieve books in series %d failed: %v", id, err)
+	}
+
+	if err := populateBooks(tx, books); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// insert a user's series. If already exists, return series id
+func insertOrGetSeries(tx *sqlx.Tx, userID int64, name string) (int64, error) {
+
+	var id int64
+	stmt := `INSERT INTO series (user_id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING RETURNING id;`
+	err := tx.Get(&id, stmt, userID, name)
+
+	// no rows inserted, query to get existing id
+	if err == sql.ErrNoRows {
+		// series.name is unique per user
+		stmt := `SELECT id FROM series WHERE user_id=$1 AND name=$2;`
+		if err := tx.Get(&id, stmt, userID, name); err != nil {
+			return -1, fmt.Errorf("db: query existing series failed: %v", err)
+		}
+		return id, nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("db: insert to series table failed: %v", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kencx/teal"
+)
+
+func TestGetSeries(t *testing.T) {
+	resetDB(testdb)
+
+	got, err := ts.Series.Get(testUser1.ID, testSeries1.ID)
+	checkErr(t, err)
+
+	if got.ID != testSeries1.ID || got.Name != testSeries1.Name {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(testSeries1))
+	}
+
+	if len(got.Books) != 1 {
+		t.Fatalf("got %d books, want %d books", len(got.Books), 1)
+	}
+	assertEqual(t, got.Books[0].ID, testBook1.ID)
+	if !reflect.DeepEqual(got.Books[0].Series, []teal.SeriesEntry{{Name: testSeries1.Name, Position: 1}}) {
+		t.Errorf("got %v, want series %q", got.Books[0].Series, testSeries1.Name)
+	}
+
+	// series are owned by a user
+	_, err = ts.Series.Get(testUser2.ID, testSeries1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetSeriesByName(t *testing.T) {
+	got, err := ts.Series.GetByName(testUser1.ID, testSeries2.Name)
+	checkErr(t, err)
+
+	if got.ID != testSeries2.ID || got.Name != testSeries2.Name {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(testSeries2))
+	}
+}
+
+func TestGetSeriesNotExists(t *testing.T) {
+	got, err := ts.Series.Get(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
+
+func TestGetAllSeries(t *testing.T) {
+	got, err := ts.Series.GetAll(testUser1.ID)
+	checkErr(t, err)
+
+	want := []*teal.Series{testSeries1, testSeries2}
+	if len(got) != len(want) {
+		t.Fatalf("got %d series, want %d series", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID || got[i].Name != want[i].Name {
+			t.Errorf("got %v, want %v", prettyPrint(got[i]), prettyPrint(want[i]))
+		}
+	}
+}
+
+func TestGetSeriesBooksInOrder(t *testing.T) {
+	defer resetDB(testdb)
+
+	books := []*teal.Book{{
+		Title:  "Caliban's War",
+		ISBN:   "1101",
+		Author: []string{"S.A. Corey"},
+		Series: []teal.SeriesEntry{{Name: testSeries1.Name, Position: 2}},
+	}, {
+		Title:  "Gods of Risk",
+		ISBN:   "1102",
+		Author: []string{"S.A. Corey"},
+		Series: []teal.SeriesEntry{{Name: testSeries1.Name, Position: 2.5}},
+	}, {
+		Title:  "The Churn",
+		ISBN:   "1103",
+		Author: []string{"S.A. Corey"},
+		Series: []teal.SeriesEntry{{Name: testSeries1.Name, Position: 0.5}},
+	}}
+	for _, b := range books {
+		_, err := ts.Books.Create(testUser1.ID, b)
+		checkErr(t, err)
+	}
+
+	got, err := ts.Series.Get(testUser1.ID, testSeries1.ID)
+	checkErr(t, err)
+
+	want := []string{"The Churn", "Leviathan Wakes", "Caliban's War", "Gods of Risk"}
+	var titles []string
+	for _, b := range got.Books {
+		titles = append(titles, b.Title)
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("got %v, want %v", titles, want)
+	}
+}
+
+func TestGetNextUnreadInSeries(t *testing.T) {
+	defer resetDB(testdb)
+
+	books := []*teal.Book{{
+		Title:  "Caliban's War",
+		ISBN:   "1101",
+		Author: []string{"S.A. Corey"},
+		State:  "want-to-read",
+		Series: []teal.SeriesEntry{{Name: testSeries1.Name, Position: 2}},
+	}, {
+		Title:  "Gods of Risk",
+		ISBN:   "1102",
+		Author: []string{"S.A. Corey"},
+		State:  "want-to-read",
+		Series: []teal.SeriesEntry{{Name: testSeries1.Name, Position: 2.5}},
+	}}
+	for _, b := range books {
+		_, err := ts.Books.Create(testUser1.ID, b)
+		checkErr(t, err)
+	}
+
+	got, err := ts.Series.GetNextUnread(testUser1.ID)
+	checkErr(t, err)
+
+	// sorted by series name
+	want := []struct {
+		series   string
+		position float64
+		title    string
+	}{
+		{testSeries2.Name, 1, "Red Rising"},
+		{testSeries1.Name, 2, "Caliban's War"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d series, want %d series", len(got), len(want))
+	}
+	for i := range got {
+		assertEqual(t, got[i].Series, want[i].series)
+		assertEqual(t, got[i].Position, want[i].position)
+		assertEqual(t, got[i].Book.Title, want[i].title)
+	}
+}
+
+func TestGetNextUnreadInSeriesAllRead(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Series.Delete(testUser1.ID, testSeries2.ID)
+	checkErr(t, err)
+
+	_, err = ts.Series.GetNextUnread(testUser1.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCreateSeries(t *testing.T) {
+	defer resetDB(testdb)
+
+	want := &teal.Series{Name: "Dune Chronicles"}
+	got, err := ts.Series.Create(testUser1.ID, want)
+	checkErr(t, err)
+
+	if got.Name != want.Name {
+		t.Errorf("got %v, want %v", got.Name, want.Name)
+	}
+}
+
+func TestCreateSeriesDuplicate(t *testing.T) {
+	_, err := ts.Series.Create(testUser1.ID, &teal.Series{Name: testSeries1.Name})
+	if err != teal.ErrDuplicateSeries {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdateSeries(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	want := &teal.Series{Name: "The Expanse Series"}
+	got, err := ts.Series.Update(testUser1.ID, testSeries1.ID, want)
+	checkErr(t, err)
+
+	if got.Name != want.Name {
+		t.Errorf("got %v, want %v", got.Name, want.Name)
+	}
+	if len(got.Books) != 1 {
+		t.Errorf("got %d books, want %d books", len(got.Books), 1)
+	}
+
+	// the book's series changed
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, book.Version, before.Version+1)
+}
+
+func TestSeriesOtherUser(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := ts.Series.GetAll(testUser2.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ts.Series.GetByName(testUser2.ID, testSeries1.Name)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ts.Series.Update(testUser2.ID, testSeries1.ID, &teal.Series{Name: "Foo"})
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = ts.Series.Delete(testUser2.ID, testSeries1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// names are unique per user
+	got, err := ts.Series.Create(testUser2.ID, &teal.Series{Name: testSeries1.Name})
+	checkErr(t, err)
+	if got.ID == testSeries1.ID {
+		t.Errorf("got series %d of another user", got.ID)
+	}
+
+	want, err := ts.Series.Get(testUser1.ID, testSeries1.ID)
+	checkErr(t, err)
+	assertEqual(t, want.Name, testSeries1.Name)
+	assertEqual(t, len(want.Books), 1)
+}
+
+func TestUpdateSeriesNotExists(t *testing.T) {
+	_, err := ts.Series.Update(testUser1.ID, -1, &teal.Series{Name: "Foo"})
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDeleteSeries(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	err = ts.Series.Delete(testUser1.ID, testSeries1.ID)
+	checkErr(t, err)
+
+	_, err = ts.Series.Get(testUser1.ID, testSeries1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("expected error, series %d not deleted", testSeries1.ID)
+	}
+
+	// book still exists without series
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	if len(book.Series) != 0 {
+		t.Errorf("got %v, want no series", book.Series)
+	}
+	assertEqual(t, book.Version, before.Version+1)
+}
+
+func TestDeleteSeriesNotExists(t *testing.T) {
+	err := ts.Series.Delete(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdateBookSeriesPosition(t *testing.T) {
+	defer resetDB(testdb)
+
+	want, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	want.Series = []teal.SeriesEntry{{Name: testSeries1.Name, Position: 1.5}}
+
+	_, err = ts.Books.Update(testUser1.ID, want.ID, 0, want)
+	checkErr(t, err)
+
+	got, err := ts.Books.Get(testUser1.ID, want.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(got.Series, want.Series) {
+		t.Errorf("got %v, want %v", got.Series, want.Series)
+	}
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
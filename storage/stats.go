@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+type StatsStore struct {
+	db *sqlx.DB
+}
+
+// number of authors in teal.Stats.TopAuthors
+const topAuthorsLimit = 10
+
+const statsColumns = `b.id, b.numOfPages, b.rating, b.state, b.dateAdded, b.dateStarted, b.dateCompleted`
+
+// columns of a read, followed by its start and completion dates
+const readColumns = `b.id, b.numOfPages, b.dateAdded`
+
+// Compute the statistics of a user's books in the date range of f. A nil
+// filter covers all books
+func (s *StatsStore) Get(userID int64, f *teal.StatsFilter) (*teal.Stats, error) {
+	if f == nil {
+		f = &teal.StatsFilter{}
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	added := &where{}
+	added.add(`b.user_id=?`, userID)
+	added.add(`b.dateDeleted IS NULL`)
+	addDateRange(added, "b.dateAdded", f.From, f.To)
+
+	var books []*teal.Book
+	query, args, err := sqlx.In(`SELECT `+statsColumns+` FROM books b`+added.String()+` ORDER BY b.id;`, added.args...)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve books for stats failed: %v", err)
+	}
+	if err := tx.Select(&books, tx.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("db: retrieve books for stats failed: %v", err)
+	}
+
+	// every finished session with the read outcome is a read of its book.
+	// Books without sessions are read once, on their completion date
+	sessions := &where{}
+	sessions.add(`b.user_id=?`, userID)
+	sessions.add(`b.dateDeleted IS NULL`)
+	sessions.add(`r.outcome=?`, teal.StateRead)
+	sessions.add(`r.dateFinished IS NOT NULL`)
+	addDateRange(sessions, "r.dateFinished", f.From, f.To)
+
+	completed := &where{}
+	completed.add(`b.user_id=?`, userID)
+	completed.add(`b.dateDeleted IS NULL`)
+	completed.add(`b.state=?`, teal.StateRead)
+	completed.add(`b.dateCompleted IS NOT NULL`)
+	completed.add(`NOT EXISTS (SELECT 1 FROM reading_sessions r WHERE r.book_id=b.id)`)
+	addDateRange(completed, "b.dateCompleted", f.From, f.To)
+
+	var reads []*teal.Book
+	query, args, err = sqlx.In(`SELECT `+readColumns+`, r.dateStarted, r.dateFinished AS dateCompleted
+		FROM books b
+		JOIN reading_sessions r ON r.book_id=b.id`+sessions.String()+`
+		UNION ALL
+		SELECT `+readColumns+`, b.dateStarted, b.dateCompleted
+		FROM books b`+completed.String()+`
+		ORDER BY dateCompleted, id;`, append(sessions.args, completed.args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve reads for stats failed: %v", err)
+	}
+	if err := tx.Select(&reads, tx.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("db: retrieve reads for stats failed: %v", err)
+	}
+
+	authors := []*teal.AuthorCount{}
+	if ids := readBookIDs(reads); len(ids) > 0 {
+		query, args, err = sqlx.In(`SELECT a.id, a.name, COUNT(*) AS books
+			FROM books_authors ba
+			JOIN authors a ON a.id=ba.author_id
+			WHERE ba.role='author' AND ba.book_id IN (?)
+			GROUP BY a.id, a.name
+			ORDER BY books DESC, a.name
+			LIMIT `+fmt.Sprint(topAuthorsLimit)+`;`, ids)
+		if err != nil {
+			return nil, fmt.Errorf("db: retrieve most read authors failed: %v", err)
+		}
+		if err := tx.Select(&authors, tx.Rebind(query), args...); err != nil {
+			return nil, fmt.Errorf("db: retrieve most read authors failed: %v", err)
+		}
+	}
+
+	stats := libraryStats(books)
+	addReadingStats(stats, reads)
+	stats.TopAuthors = authors
+	return stats, nil
+}
+
+// distinct ids of the books of reads
+func readBookIDs(reads []*teal.Book) []int64 {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, b := range reads {
+		if !seen[b.ID] {
+			seen[b.ID] = true
+			ids = append(ids, b.ID)
+		}
+	}
+	return ids
+}
+
+// counts by state and ratings of books. Books with a rating of 0 are unrated
+func libraryStats(books []*teal.Book) *teal.Stats {
+	stats := &teal.Stats{
+		Books:   len(books),
+		States:  make(map[string]int),
+		Ratings: []teal.RatingCount{},
+	}
+	for _, state := range teal.States {
+		stats.States[state] = 0
+	}
+
+	ratings := make(map[int]int)
+	var rated, total int
+	for _, b := range books {
+		stats.States[b.State]++
+		if b.Rating > 0 {
+			ratings[b.Rating]++
+			rated++
+			total += b.Rating
+		}
+	}
+
+	for rating, count := range ratings {
+		stats.Ratings = append(stats.Ratings, teal.RatingCount{Rating: rating, Books: count})
+	}
+	sort.Slice(stats.Ratings, func(i, j int) bool {
+		return stats.Ratings[i].Rating < stats.Ratings[j].Rating
+	})
+
+	if rated > 0 {
+		stats.AverageRating = round(float64(total) / float64(rated))
+	}
+	return stats
+}
+
+// books and pages read per period, time to complete and reading speed of the
+// given reads, ordered by completion date. Rereads count as books read, but
+// only the first read of a book counts towards the time to complete
+func addReadingStats(stats *teal.Stats, books []*teal.Book) {
+	reread := make(map[int64]bool)
+
+	stats.ReadByMonth = []teal.PeriodCount{}
+	stats.ReadByYear = []teal.PeriodCount{}
+
+	var daysToComplete float64
+	var completed int
+	var speedPages int
+	var speedDays float64
+
+	for _, b := range books {
+		completedAt := b.DateCompleted.Time
+		stats.Read++
+		stats.PagesRead += b.NumOfPages
+
+		stats.ReadByMonth = addToPeriod(stats.ReadByMonth, completedAt.Format("2006-01"), b.NumOfPages)
+		stats.ReadByYear = addToPeriod(stats.ReadByYear, completedAt.Format("2006"), b.NumOfPages)
+
+		if !reread[b.ID] && b.DateAdded.Valid && !completedAt.Before(b.DateAdded.Time) {
+			daysToComplete += completedAt.Sub(b.DateAdded.Time).Hours() / 24
+			completed++
+		}
+		reread[b.ID] = true
+
+		// books read within a day count as one day
+		if b.DateStarted.Valid && b.NumOfPages > 0 && !completedAt.Before(b.DateStarted.Time) {
+			speedPages += b.NumOfPages
+			speedDays += math.Max(completedAt.Sub(b.DateStarted.Time).Hours()/24, 1)
+		}
+	}
+
+	if completed > 0 {
+		stats.AverageDaysToComplete = round(daysToComplete / float64(completed))
+	}
+	if speedDays > 0 {
+		stats.PagesPerDay = round(float64(speedPages) / speedDays)
+	}
+}
+
+// periods are added in order, so a book is either in the last period or a new
+// one
+func addToPeriod(periods []teal.PeriodCount, period string, pages int) []teal.PeriodCount {
+	if n := len(periods); n > 0 && periods[n-1].Period == period {
+		periods[n-1].Books++
+		periods[n-1].Pages += pages
+		return periods
+	}
+	return append(periods, teal.PeriodCount{Period: period, Books: 1, Pages: pages})
+}
+
+// round to 2 decimal places
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func nullTime(year int, month time.Month, day int) sql.NullTime {
+	return sql.NullTime{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+func TestGetStatsLibrary(t *testing.T) {
+	resetDB(testdb)
+
+	got, err := ts.Stats.Get(testUser1.ID, nil)
+	checkErr(t, err)
+
+	assertEqual(t, got.Books, len(allBooks))
+	assertEqual(t, got.States[teal.StateRead], 1)
+	assertEqual(t, got.States[teal.StateWantToRead], 3)
+	assertEqual(t, got.States[teal.StateReading], 0)
+	assertEqual(t, got.AverageRating, 4.5)
+
+	want := []teal.RatingCount{{Rating: 4, Books: 1}, {Rating: 5, Books: 1}}
+	if !reflect.DeepEqual(got.Ratings, want) {
+		t.Errorf("got %v, want %v", got.Ratings, want)
+	}
+
+	// the read book is counted by its reading session
+	assertEqual(t, got.Read, 1)
+	wantMonths := []teal.PeriodCount{{Period: "2022-01", Books: 1, Pages: 250}}
+	if !reflect.DeepEqual(got.ReadByMonth, wantMonths) {
+		t.Errorf("got %v, want %v", got.ReadByMonth, wantMonths)
+	}
+	assertEqual(t, len(got.TopAuthors), 1)
+
+	// books without sessions or a completion date are not counted as read
+	_, err = testdb.Exec(`DELETE FROM reading_sessions;`)
+	checkErr(t, err)
+	got, err = ts.Stats.Get(testUser1.ID, nil)
+	checkErr(t, err)
+	assertEqual(t, got.Read, 0)
+	assertEqual(t, len(got.ReadByMonth), 0)
+	assertEqual(t, len(got.TopAuthors), 0)
+}
+
+func TestGetStatsReading(t *testing.T) {
+	defer resetDB(testdb)
+
+	_, err := testdb.Exec(`DELETE FROM reading_sessions;`)
+	checkErr(t, err)
+	dune, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:         "Dune",
+		ISBN:          "1011",
+		Author:        []string{"Frank Herbert"},
+		NumOfPages:    400,
+		State:         teal.StateRead,
+		DateAdded:     nullTime(2022, 1, 1),
+		DateStarted:   nullTime(2022, 1, 10),
+		DateCompleted: nullTime(2022, 1, 20),
+	})
+	checkErr(t, err)
+	messiah, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:         "Dune Messiah",
+		ISBN:          "1012",
+		Author:        []string{"Frank Herbert"},
+		NumOfPages:    200,
+		State:         teal.StateRead,
+		DateAdded:     nullTime(2022, 1, 1),
+		DateStarted:   nullTime(2022, 2, 1),
+		DateCompleted: nullTime(2022, 2, 1),
+	})
+	checkErr(t, err)
+
+	t.Run("all", func(t *testing.T) {
+		got, err := ts.Stats.Get(testUser1.ID, nil)
+		checkErr(t, err)
+
+		assertEqual(t, got.Read, 2)
+		assertEqual(t, got.PagesRead, 600)
+
+		wantMonths := []teal.PeriodCount{
+			{Period: "2022-01", Books: 1, Pages: 400},
+			{Period: "2022-02", Books: 1, Pages: 200},
+		}
+		if !reflect.DeepEqual(got.ReadByMonth, wantMonths) {
+			t.Errorf("got %v, want %v", got.ReadByMonth, wantMonths)
+		}
+		wantYears := []teal.PeriodCount{{Period: "2022", Books: 2, Pages: 600}}
+		if !reflect.DeepEqual(got.ReadByYear, wantYears) {
+			t.Errorf("got %v, want %v", got.ReadByYear, wantYears)
+		}
+
+		if len(got.TopAuthors) != 1 {
+			t.Fatalf("got %d top authors, want 1", len(got.TopAuthors))
+		}
+		assertEqual(t, got.TopAuthors[0].Name, "Frank Herbert")
+		assertEqual(t, got.TopAuthors[0].Books, 2)
+
+		// 19 and 31 days from added to completed
+		assertEqual(t, got.AverageDaysToComplete, 25.0)
+		// 600 pages in 10 days and 1 day
+		assertEqual(t, got.PagesPerDay, 54.55)
+	})
+
+	t.Run("date range", func(t *testing.T) {
+		f := &teal.StatsFilter{
+			From: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2022, 2, 28, 0, 0, 0, 0, time.UTC),
+		}
+		got, err := ts.Stats.Get(testUser1.ID, f)
+		checkErr(t, err)
+
+		// no books were added in February
+		assertEqual(t, got.Books, 0)
+		assertEqual(t, got.Read, 1)
+		assertEqual(t, got.PagesRead, 200)
+		assertEqual(t, got.PagesPerDay, 200.0)
+	})
+
+	t.Run("rereads", func(t *testing.T) {
+		_, err := ts.Reading.CreateSession(testUser1.ID, dune.ID, &teal.ReadingSession{
+			DateStarted:  time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			DateFinished: teal.NullTime{NullTime: nullTime(2022, 3, 5)},
+			Outcome:      teal.StateRead,
+		})
+		checkErr(t, err)
+		// sessions that were not finished as read are not reads
+		_, err = ts.Reading.CreateSession(testUser1.ID, dune.ID, &teal.ReadingSession{
+			DateStarted:  time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC),
+			DateFinished: teal.NullTime{NullTime: nullTime(2022, 4, 5)},
+			Outcome:      teal.StateDidNotFinish,
+		})
+		checkErr(t, err)
+
+		got, err := ts.Stats.Get(testUser1.ID, nil)
+		checkErr(t, err)
+
+		assertEqual(t, got.Read, 3)
+		assertEqual(t, got.PagesRead, 1000)
+		wantYears := []teal.PeriodCount{{Period: "2022", Books: 3, Pages: 1000}}
+		if !reflect.DeepEqual(got.ReadByYear, wantYears) {
+			t.Errorf("got %v, want %v", got.ReadByYear, wantYears)
+		}
+		// authors count each book once
+		assertEqual(t, got.TopAuthors[0].Books, 2)
+		// only the first read counts towards the time to complete
+		assertEqual(t, got.AverageDaysToComplete, 25.0)
+		// 1000 pages in 10, 1 and 4 days
+		assertEqual(t, got.PagesPerDay, 66.67)
+	})
+
+	t.Run("without sessions", func(t *testing.T) {
+		_, err := testdb.Exec(`DELETE FROM reading_sessions WHERE book_id=$1;`, messiah.ID)
+		checkErr(t, err)
+
+		// the completion date is used when a book has no sessions
+		got, err := ts.Stats.Get(testUser1.ID, nil)
+		checkErr(t, err)
+		assertEqual(t, got.Read, 3)
+		assertEqual(t, got.PagesRead, 1000)
+	})
+}
@@ -1,12 +1,16 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 )
 
 const (
@@ -15,16 +19,34 @@ const (
 )
 
 type Store struct {
-	Books   *BookStore
-	Authors *AuthorStore
-	Users   *UserStore
+	Books      *BookStore
+	Authors    *AuthorStore
+	Categories *CategoryStore
+	Series     *SeriesStore
+	Tags       *TagStore
+	Reading    *ReadingStore
+	Stats      *StatsStore
+	Trash      *TrashStore
+	Revisions  *RevisionStore
+	Metadata   *MetadataStore
+	Files      *FileStore
+	Users      *UserStore
 }
 
 func NewStore(db *sqlx.DB) *Store {
 	return &Store{
-		Books:   &BookStore{db},
-		Authors: &AuthorStore{db},
-		Users:   &UserStore{db},
+		Books:      &BookStore{db},
+		Authors:    &AuthorStore{db},
+		Categories: &CategoryStore{db},
+		Series:     &SeriesStore{db},
+		Tags:       &TagStore{db},
+		Reading:    &ReadingStore{db},
+		Stats:      &StatsStore{db},
+		Trash:      &TrashStore{db},
+		Revisions:  &RevisionStore{db},
+		Metadata:   &MetadataStore{db},
+		Files:      &FileStore{db},
+		Users:      &UserStore{db},
 	}
 }
 
@@ -32,24 +54,51 @@ func (s *Store) GetDB() *sqlx.DB {
 	return s.Books.db
 }
 
-func Open(path string) (*sqlx.DB, error) {
-	if path == "" {
+// Open a database connection. The driver is chosen from the DSN scheme.
+// postgres:// and postgresql:// use PostgreSQL. sqlite:// or a plain file path
+// use SQLite
+func Open(dsn string) (*sqlx.DB, error) {
+	if dsn == "" {
 		return nil, fmt.Errorf("db: connection string required")
 	}
 
-	db, err := sqlx.Open(SQLITE, path)
+	driver, source, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlx.Open(driver, source)
 	if err != nil {
 		return nil, fmt.Errorf("db: failed to open: %w", err)
 	}
 	if err = db.Ping(); err != nil {
 		return nil, fmt.Errorf("db: failed to connect: %w", err)
 	}
-	// if err := createTable(db); err != nil {
-	// 	return nil, err
-	// }
+
+	if driver == POSTGRES {
+		// PostgreSQL folds unquoted column names to lower case
+		db.Mapper = reflectx.NewMapperTagFunc("db", strings.ToLower, strings.ToLower)
+	}
 	return db, nil
 }
 
+// parse DSN into driver name and driver specific data source
+func parseDSN(dsn string) (string, string, error) {
+	scheme, rest, found := strings.Cut(dsn, "://")
+	if !found {
+		return SQLITE, dsn, nil
+	}
+
+	switch scheme {
+	case "postgres", "postgresql":
+		return POSTGRES, dsn, nil
+	case "sqlite", "sqlite3":
+		return SQLITE, rest, nil
+	default:
+		return "", "", fmt.Errorf("db: unsupported database %q", scheme)
+	}
+}
+
 func Close(db *sqlx.DB) error {
 	if db != nil {
 		return db.Close()
@@ -57,15 +106,6 @@ func Close(db *sqlx.DB) error {
 	return fmt.Errorf("db: db is nil")
 }
 
-// TODO replace with migration file
-// Hard coded file path does not work with tests and binary at the same time
-func createTable(db *sqlx.DB) error {
-	if err := ExecFile(db, "../../migrations/schema.sql"); err != nil {
-		return fmt.Errorf("%v: %s", err, CREATE_TABLES)
-	}
-	return nil
-}
-
 func ExecFile(db *sqlx.DB, filePath string) error {
 	query, err := ioutil.ReadFile(filePath)
 	if err != nil {
@@ -78,3 +118,18 @@ func ExecFile(db *sqlx.DB, filePath string) error {
 	log.Printf("File %s loaded", filePath)
 	return nil
 }
+
+// check if err is caused by a unique constraint
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return false
+}
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+type TagStore struct {
+	db *sqlx.DB
+}
+
+// tags are shared by all users, but are counted in the books of the user $1
+const tagColumns = `t.id, t.name, t.dateAdded, t.dateUpdated,
+	(SELECT COUNT(*) FROM books_tags bt
+		JOIN books b ON b.id=bt.book_id
+		WHERE bt.tag_id=t.id AND b.user_id=$1 AND b.dateDeleted IS NULL) AS count`
+
+// Retrieve a tag with its number of books of the given user
+func (s *TagStore) Get(userID, id int64) (*teal.Tag, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var tag teal.Tag
+	stmt := `SELECT ` + tagColumns + ` FROM tags t WHERE t.id=$2;`
+	err = tx.QueryRowx(stmt, userID, id).StructScan(&tag)
+	if err == sql.ErrNoRows {
+		return nil, teal.ErrDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve tag %d failed: %v", id, err)
+	}
+	return &tag, nil
+}
+
+// Retrieve all tags with their number of books of the given user
+func (s *TagStore) GetAll(userID int64) ([]*teal.Tag, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var tags []*teal.Tag
+	stmt := `SELECT ` + tagColumns + ` FROM tags t ORDER BY t.name;`
+	err = tx.Select(&tags, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db: retrieve all tags failed: %v", err)
+	}
+	if len(tags) == 0 {
+		return nil, teal.ErrNoRows
+	}
+	return tags, nil
+}
+
+// Rename a tag. Returns teal.ErrSharedItem if books of other users have the tag
+func (s *TagStore) Update(userID, id int64, t *teal.Tag) (*teal.Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		if err := checkSharedTag(tx, userID, id); err != nil {
+			return err
+		}
+
+		stmt := `UPDATE tags
+			SET name=$1,
+			dateUpdated=CURRENT_TIMESTAMP
+			WHERE id=$2;`
+		res, err := tx.Exec(stmt, strings.TrimSpace(t.Name), id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return teal.ErrDuplicateTag
+			}
+			return fmt.Errorf("db: update tag %d failed: %v", id, err)
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: update tag %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+
+		ids, err := getBookIDsFromTag(tx, id)
+		if err != nil {
+			return err
+		}
+		return bumpBookVersions(tx, ids)
+
+	}); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Merge tag id into tag into. All books tagged with id are tagged with into
+// instead, and tag id is deleted. Returns teal.ErrSharedItem if books of other
+// users have tag id
+func (s *TagStore) Merge(userID, id, into int64) (*teal.Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		var count int
+		stmt := `SELECT COUNT(*) FROM tags WHERE id IN ($1, $2);`
+		if err := tx.Get(&count, stmt, id, into); err != nil {
+			return fmt.Errorf("db: retrieve tags %d, %d failed: %v", id, into, err)
+		}
+		if count != 2 {
+			return teal.ErrDoesNotExist
+		}
+		if err := checkSharedTag(tx, userID, id); err != nil {
+			return err
+		}
+
+		ids, err := getBookIDsFromTag(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := bumpBookVersions(tx, ids); err != nil {
+			return err
+		}
+
+		stmt = `INSERT INTO books_tags (book_id, tag_id)
+			SELECT book_id, CAST($1 AS BIGINT) FROM books_tags WHERE tag_id=$2
+			ON CONFLICT DO NOTHING;`
+		if _, err := tx.Exec(stmt, into, id); err != nil {
+			return fmt.Errorf("db: merge tag %d into %d failed: %v", id, into, err)
+		}
+
+		return deleteTag(tx, id)
+
+	}); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.Get(userID, into)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Delete a tag and remove it from all books. The books are not deleted.
+// Returns teal.ErrSharedItem if books of other users have the tag
+func (s *TagStore) Delete(userID, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+		if err := checkSharedTag(tx, userID, id); err != nil {
+			return err
+		}
+
+		ids, err := getBookIDsFromTag(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := bumpBookVersions(tx, ids); err != nil {
+			return err
+		}
+		return deleteTag(tx, id)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns teal.ErrSharedItem if books of users other than userID, including
+// books in their trash, have the tag
+func checkSharedTag(tx *sqlx.Tx, userID, id int64) error {
+
+	var count int
+	stmt := `SELECT COUNT(*) FROM books_tags bt
+		JOIN books b ON b.id=bt.book_id
+		WHERE bt.tag_id=$1 AND b.user_id<>$2;`
+	if err := tx.Get(&count, stmt, id, userID); err != nil {
+		return fmt.Errorf("db: retrieve books of tag %d failed: %v", id, err)
+	}
+	if count > 0 {
+		return teal.ErrSharedItem
+	}
+	return nil
+}
+
+func getBookIDsFromTag(tx *sqlx.Tx, id int64) ([]int64, error) {
+
+	var ids []int64
+	stmt := `SELECT book_id FROM books_tags WHERE tag_id=$1 ORDER BY book_id;`
+	if err := tx.Select(&ids, stmt, id); err != nil {
+		return nil, fmt.Errorf("db: retrieve books of tag %d failed: %v", id, err)
+	}
+	return ids, nil
+}
+
+func deleteTag(tx *sqlx.Tx, id int64) error {
+
+	stmt := `DELETE FROM books_tags WHERE tag_id=$1;`
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return fmt.Errorf("db: delete tag %d from books_tags failed: %v", id, err)
+	}
+
+	stmt = `DELETE FROM tags WHERE id=$1;`
+	res, err := tx.Exec(stmt, id)
+	if err != nil {
+		return fmt.Errorf("db: delete tag %d failed: %v", id, err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("db: delete tag %d failed: %v", id, err)
+	}
+	if count == 0 {
+		return teal.ErrDoesNotExist
+	}
+	return nil
+}
+
+// insert tag. If already exists, return tag id
+func insertOrGetTag(tx *sqlx.Tx, name string) (int64, error) {
+
+	var id int64
+	stmt := `INSERT INTO tags (name) VALUES ($1) ON CONFLICT DO NOTHING RETURNING id;`
+	err := tx.Get(&id, stmt, name)
+
+	// no rows inserted, query to get existing id
+	if err == sql.ErrNoRows {
+		// tags.name is unique
+		stmt := `SELECT id FROM tags WHERE name=$1;`
+		if err := tx.Get(&id, stmt, name); err != nil {
+			return -1, fmt.Errorf("db: query existing tag failed: %v", err)
+		}
+		return id, nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("db: insert to tags table failed: %v", err)
+	}
+	return id, nil
+}
+
+func insertOrGetTags(tx *sqlx.Tx, names []string) ([]int64, error) {
+
+	var ids []int64
+	for _, name := range names {
+		id, err := insertOrGetTag(tx, strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kencx/teal"
+)
+
+func TestGetTag(t *testing.T) {
+	resetDB(testdb)
+
+	got, err := ts.Tags.Get(testUser1.ID, testTag1.ID)
+	checkErr(t, err)
+
+	if !assertTagsEqual(got, testTag1) {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(testTag1))
+	}
+}
+
+func TestGetTagNotExists(t *testing.T) {
+	got, err := ts.Tags.Get(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
+
+func TestGetAllTags(t *testing.T) {
+	got, err := ts.Tags.GetAll(testUser1.ID)
+	checkErr(t, err)
+
+	// sorted by name
+	want := []*teal.Tag{testTag2, testTag1, testTag3}
+	if len(got) != len(want) {
+		t.Fatalf("got %d tags, want %d tags", len(got), len(want))
+	}
+	for i := range got {
+		if !assertTagsEqual(got[i], want[i]) {
+			t.Errorf("got %v, want %v", prettyPrint(got[i]), prettyPrint(want[i]))
+		}
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+
+	got, err := ts.Tags.Update(testUser1.ID, testTag1.ID, &teal.Tag{Name: "outer space"})
+	checkErr(t, err)
+
+	assertEqual(t, got.Name, "outer space")
+	assertEqual(t, got.Count, testTag1.Count)
+
+	book, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(book.Tags, []string{"outer space"}) {
+		t.Errorf("got %v, want %v", book.Tags, []string{"outer space"})
+	}
+	assertEqual(t, book.Version, before.Version+1)
+}
+
+func TestRenameTagExisting(t *testing.T) {
+	_, err := ts.Tags.Update(testUser1.ID, testTag1.ID, &teal.Tag{Name: testTag2.Name})
+	if err != teal.ErrDuplicateTag {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRenameTagNotExists(t *testing.T) {
+	_, err := ts.Tags.Update(testUser1.ID, -1, &teal.Tag{Name: "foo"})
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	defer resetDB(testdb)
+
+	before, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+
+	// Leviathan Wakes is already tagged with both
+	got, err := ts.Tags.Merge(testUser1.ID, testTag2.ID, testTag1.ID)
+	checkErr(t, err)
+
+	assertEqual(t, got.Name, testTag1.Name)
+	assertEqual(t, got.Count, 2)
+
+	_, err = ts.Tags.Get(testUser1.ID, testTag2.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("expected error, tag %d not deleted", testTag2.ID)
+	}
+
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(book.Tags, []string{testTag1.Name}) {
+		t.Errorf("got %v, want %v", book.Tags, []string{testTag1.Name})
+	}
+	assertEqual(t, book.Version, before.Version+1)
+}
+
+func TestMergeTagsIntoUnused(t *testing.T) {
+	defer resetDB(testdb)
+
+	got, err := ts.Tags.Merge(testUser1.ID, testTag1.ID, testTag3.ID)
+	checkErr(t, err)
+
+	assertEqual(t, got.Name, testTag3.Name)
+	assertEqual(t, got.Count, 2)
+}
+
+func TestMergeTagsNotExists(t *testing.T) {
+	_, err := ts.Tags.Merge(testUser1.ID, testTag1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Tags.Delete(testUser1.ID, testTag1.ID)
+	checkErr(t, err)
+
+	_, err = ts.Tags.Get(testUser1.ID, testTag1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("expected error, tag %d not deleted", testTag1.ID)
+	}
+
+	book, err := ts.Books.Get(testUser1.ID, testBook2.ID)
+	checkErr(t, err)
+	if len(book.Tags) != 0 {
+		t.Errorf("got %v, want no tags", book.Tags)
+	}
+}
+
+func TestDeleteTagNotExists(t *testing.T) {
+	err := ts.Tags.Delete(testUser1.ID, -1)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTagsOfOtherUsers(t *testing.T) {
+	defer resetDB(testdb)
+
+	// tags are counted in the user's books only
+	got, err := ts.Tags.Get(testUser2.ID, testTag1.ID)
+	checkErr(t, err)
+	assertEqual(t, got.Count, 0)
+
+	_, err = ts.Books.Create(testUser2.ID, &teal.Book{
+		Title:  "Dune",
+		ISBN:   "1007",
+		Author: []string{"Frank Herbert"},
+		Tags:   []string{"space"},
+	})
+	checkErr(t, err)
+
+	got, err = ts.Tags.Get(testUser2.ID, testTag1.ID)
+	checkErr(t, err)
+	assertEqual(t, got.Count, 1)
+
+	got, err = ts.Tags.Get(testUser1.ID, testTag1.ID)
+	checkErr(t, err)
+	assertEqual(t, got.Count, 2)
+
+	// tags on books of other users cannot be changed
+	_, err = ts.Tags.Update(testUser1.ID, testTag1.ID, &teal.Tag{Name: "outer space"})
+	if err != teal.ErrSharedItem {
+		t.Errorf("got %v, want %v", err, teal.ErrSharedItem)
+	}
+	_, err = ts.Tags.Merge(testUser2.ID, testTag1.ID, testTag3.ID)
+	if err != teal.ErrSharedItem {
+		t.Errorf("got %v, want %v", err, teal.ErrSharedItem)
+	}
+	err = ts.Tags.Delete(testUser1.ID, testTag1.ID)
+	if err != teal.ErrSharedItem {
+		t.Errorf("got %v, want %v", err, teal.ErrSharedItem)
+	}
+}
+
+func TestGetBooksByTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		matchAll bool
+		want     []int64
+	}{{
+		name: "single tag",
+		tags: []string{"space"},
+		want: []int64{testBook1.ID, testBook2.ID},
+	}, {
+		name: "match any",
+		tags: []string{"favourite", "to-lend"},
+		want: []int64{testBook1.ID},
+	}, {
+		name:     "match all",
+		tags:     []string{"space", "favourite"},
+		matchAll: true,
+		want:     []int64{testBook1.ID},
+	}, {
+		name:     "match all with duplicates",
+		tags:     []string{"space", "space"},
+		matchAll: true,
+		want:     []int64{testBook1.ID, testBook2.ID},
+	}, {
+		name:     "match all with no books",
+		tags:     []string{"space", "to-lend"},
+		matchAll: true,
+		want:     nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.Books.GetByTags(testUser1.ID, tt.tags, tt.matchAll)
+			checkErr(t, err)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d books, want %d books", len(got), len(tt.want))
+			}
+			for i := range got {
+				assertEqual(t, got[i].ID, tt.want[i])
+			}
+		})
+	}
+}
+
+func TestCreateBookWithTags(t *testing.T) {
+	defer resetDB(testdb)
+
+	want := &teal.Book{
+		Title:  "Dune",
+		ISBN:   "1006",
+		Author: []string{"Frank Herbert"},
+		Tags:   []string{"classic", "space"},
+	}
+
+	_, err := ts.Books.Create(testUser1.ID, want)
+	checkErr(t, err)
+
+	got, err := ts.Books.Get(testUser1.ID, want.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(got.Tags, want.Tags) {
+		t.Errorf("got %v, want %v", got.Tags, want.Tags)
+	}
+
+	tag, err := ts.Tags.Get(testUser1.ID, testTag1.ID)
+	checkErr(t, err)
+	assertEqual(t, tag.Count, 3)
+}
+
+func TestUpdateBookTags(t *testing.T) {
+	defer resetDB(testdb)
+
+	want, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	want.Tags = []string{"reread", "space"}
+
+	_, err = ts.Books.Update(testUser1.ID, want.ID, 0, want)
+	checkErr(t, err)
+
+	got, err := ts.Books.Get(testUser1.ID, want.ID)
+	checkErr(t, err)
+	if !reflect.DeepEqual(got.Tags, want.Tags) {
+		t.Errorf("got %v, want %v", got.Tags, want.Tags)
+	}
+}
+
+func assertTagsEqual(a, b *teal.Tag) bool {
+	return a.ID == b.ID && a.Name == b.Name && a.Count == b.Count
+}
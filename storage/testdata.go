@@ -1,56 +1,10 @@
 package storage
 
 import (
-	teal "github.com/kencx/teal"
-)
-
-const (
-	DROP_ALL = `DROP TABLE IF EXISTS books;
-				DROP TABLE IF EXISTS authors;
-				DROP TABLE IF EXISTS books_authors;
-				DROP TABLE IF EXISTS users;
-				DROP TABLE IF EXISTS apikeys;`
-
-	CREATE_TABLES = `CREATE TABLE IF NOT EXISTS books (
-			id            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-			title         TEXT NOT NULL,
-			description   TEXT,
-			isbn          TEXT NOT NULL UNIQUE,
-			numOfPages    INTEGER DEFAULT 0,
-			rating        INTEGER DEFAULT 0,
-			state         TEXT NOT NULL DEFAULT "unread",
-			dateAdded     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			dateUpdated   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			dateCompleted TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS authors (
-			id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE
-		);
-
-		CREATE TABLE IF NOT EXISTS books_authors (
-			book_id INTEGER REFERENCES books(id),
-			author_id INTEGER REFERENCES authors(id),
-			PRIMARY KEY(book_id, author_id)
-		);
-
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			username VARCHAR(255) NOT NULL UNIQUE,
-			hashed_password CHAR(60) NOT NULL,
-			role TEXT NOT NULL DEFAULT "user",
-			dateAdded TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);
+	"database/sql"
+	"time"
 
-		CREATE TABLE IF NOT EXISTS apikeys (
-			id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-			userId INTEGER NOT NULL,
-			hashed_key TEXT NOT NULL UNIQUE,
-			dateAdded TIMESTAMP NOT NULL,
-			dateExpired TIMESTAMP NOT NULL
-		);`
+	teal "github.com/kencx/teal"
 )
 
 // structs here are in testdata.sql
@@ -65,6 +19,9 @@ var (
 		Rating:     5,
 		State:      "read",
 		Author:     []string{"S.A. Corey"},
+		Categories: []string{"Sci-Fi"},
+		Series:     []teal.SeriesEntry{{Name: "The Expanse", Position: 1}},
+		Tags:       []string{"favourite", "space"},
 	}
 	testBook2 = &teal.Book{
 		ID:         2,
@@ -72,43 +29,98 @@ var (
 		ISBN:       "2",
 		NumOfPages: 900,
 		Rating:     4,
-		State:      "unread",
+		State:      "want-to-read",
 		Author:     []string{"Pierce Brown"},
+		Categories: []string{"Sci-Fi"},
+		Series:     []teal.SeriesEntry{{Name: "Red Rising Saga", Position: 1}},
+		Tags:       []string{"space"},
 	}
 	testBook3 = &teal.Book{
 		ID:     3,
 		Title:  "Many Authors",
 		ISBN:   "3",
-		State:  "unread",
+		State:  "want-to-read",
 		Author: []string{"John Doe", "Regina Phallange", "Ken Adams"},
 	}
 	testBook4 = &teal.Book{
 		ID:     4,
 		Title:  "New Book",
 		ISBN:   "4",
-		State:  "unread",
+		State:  "want-to-read",
 		Author: []string{"John Doe"},
 	}
 
 	testAuthor1 = &teal.Author{
-		ID:   1,
-		Name: "S.A. Corey",
+		ID:      1,
+		Name:    "S.A. Corey",
+		Version: 1,
 	}
 	testAuthor2 = &teal.Author{
-		ID:   2,
-		Name: "Pierce Brown",
+		ID:      2,
+		Name:    "Pierce Brown",
+		Version: 1,
 	}
 	testAuthor3 = &teal.Author{
-		ID:   3,
-		Name: "John Doe",
+		ID:      3,
+		Name:    "John Doe",
+		Version: 1,
 	}
 	testAuthor4 = &teal.Author{
-		ID:   4,
-		Name: "Regina Phallange",
+		ID:      4,
+		Name:    "Regina Phallange",
+		Version: 1,
 	}
 	testAuthor5 = &teal.Author{
-		ID:   5,
-		Name: "Ken Adams",
+		ID:      5,
+		Name:    "Ken Adams",
+		Version: 1,
+	}
+
+	testCategory1 = &teal.Category{
+		ID:   1,
+		Name: "Fiction",
+	}
+	testCategory2 = &teal.Category{
+		ID:       2,
+		Name:     "Sci-Fi",
+		ParentID: 1,
+	}
+	testCategory3 = &teal.Category{
+		ID:   3,
+		Name: "Non-Fiction",
+	}
+
+	testSeries1 = &teal.Series{
+		ID:   1,
+		Name: "The Expanse",
+	}
+	testSeries2 = &teal.Series{
+		ID:   2,
+		Name: "Red Rising Saga",
+	}
+
+	testTag1 = &teal.Tag{
+		ID:    1,
+		Name:  "space",
+		Count: 2,
+	}
+	testTag2 = &teal.Tag{
+		ID:    2,
+		Name:  "favourite",
+		Count: 1,
+	}
+	testTag3 = &teal.Tag{
+		ID:    3,
+		Name:  "to-lend",
+		Count: 0,
+	}
+
+	testSession1 = &teal.ReadingSession{
+		ID:           1,
+		BookID:       1,
+		DateStarted:  time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+		DateFinished: teal.NullTime{NullTime: sql.NullTime{Time: time.Date(2022, 1, 20, 0, 0, 0, 0, time.UTC), Valid: true}},
+		Outcome:      "read",
 	}
 
 	testUser1 = &teal.User{
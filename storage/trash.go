@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kencx/teal"
+)
+
+// Books moved to the trash by BookStore.Delete. Trashed books are hidden from
+// all other queries until they are restored or purged
+type TrashStore struct {
+	db *sqlx.DB
+}
+
+// Retrieve a user's books in the trash, most recently deleted first
+func (s *TrashStore) GetAll(userID int64) ([]*teal.Book, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to start transaction: %v", err)
+	}
+	defer endTx(tx, err)
+
+	var books []*teal.Book
+	stmt := `SELECT * FROM books
+		WHERE user_id=$1 AND dateDeleted IS NOT NULL
+		ORDER BY dateDeleted DESC, id DESC;`
+	if err := tx.Select(&books, stmt, userID); err != nil {
+		return nil, fmt.Errorf("db: retrieve trash failed: %v", err)
+	}
+	if len(books) == 0 {
+		return nil, teal.ErrNoRows
+	}
+
+	if err := populateBooks(tx, books); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// Restore a user's book from the trash, together with its authors,
+// relationships and reading history. Returns teal.ErrDuplicateBook if the user
+// has another book with the same ISBN
+func (s *TrashStore) Restore(userID, id int64) (*teal.Book, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		before, err := getBook(tx, id)
+		if err != nil {
+			return err
+		}
+		// books of other users are not found, whatever their ISBN
+		if before.UserID != userID || before.DateDeleted == nil {
+			return teal.ErrDoesNotExist
+		}
+
+		if err := checkDuplicateISBN(tx, userID, id, before.ISBN); err != nil {
+			return err
+		}
+
+		stmt := `UPDATE books SET dateDeleted=NULL, version=version+1
+			WHERE id=$1 AND user_id=$2 AND dateDeleted IS NOT NULL;`
+		res, err := tx.Exec(stmt, id, userID)
+		if err != nil {
+			return fmt.Errorf("db: restore book %d failed: %v", id, err)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("db: restore book %d failed: %v", id, err)
+		}
+		if count == 0 {
+			return teal.ErrDoesNotExist
+		}
+
+		after, err := getBook(tx, id)
+		if err != nil {
+			return err
+		}
+		return recordBookRevision(tx, id, userID, teal.ActionRestore, before, after)
+
+	}); err != nil {
+		return nil, err
+	}
+
+	bs := &BookStore{s.db}
+	return bs.Get(userID, id)
+}
+
+// Permanently delete a user's books that were moved to the trash before the
+// given time. Returns the number of books purged
+func (s *TrashStore) Empty(userID int64, before time.Time) (int, error) {
+	w := &where{}
+	w.add(`user_id=?`, userID)
+	return s.purge(w, before)
+}
+
+// Permanently delete the books of all users that were moved to the trash
+// before the given time. Returns the number of books purged
+func (s *TrashStore) Purge(before time.Time) (int, error) {
+	return s.purge(&where{}, before)
+}
+
+func (s *TrashStore) purge(w *where, before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	w.add(`dateDeleted IS NOT NULL`)
+	w.add(`dateDeleted<?`, before.UTC())
+
+	var ids []int64
+	if err := Tx(s.db, ctx, func(tx *sqlx.Tx) error {
+
+		query, args, err := sqlx.In(`SELECT id FROM books`+w.String()+` ORDER BY id;`, w.args...)
+		if err != nil {
+			return fmt.Errorf("db: retrieve trash failed: %v", err)
+		}
+		if err := tx.Select(&ids, tx.Rebind(query), args...); err != nil {
+			return fmt.Errorf("db: retrieve trash failed: %v", err)
+		}
+
+		for _, id := range ids {
+			if err := purgeBook(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	}); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
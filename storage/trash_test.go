@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kencx/teal"
+)
+
+func TestGetTrashEmpty(t *testing.T) {
+	_, err := ts.Trash.GetAll(testUser1.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+}
+
+func TestDeleteBookMovesToTrash(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
+	checkErr(t, err)
+
+	// trashed books are hidden
+	_, err = ts.Books.Get(testUser1.ID, testBook1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	books, info, err := ts.Books.GetAll(testUser1.ID, nil)
+	checkErr(t, err)
+	assertEqual(t, info.Total, len(allBooks)-1)
+	for _, b := range books {
+		if b.ID == testBook1.ID {
+			t.Errorf("got trashed book %d in books", b.ID)
+		}
+	}
+	err = ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	got, err := ts.Trash.GetAll(testUser1.ID)
+	checkErr(t, err)
+	if len(got) != 1 {
+		t.Fatalf("got %d books in trash, want 1", len(got))
+	}
+	assertEqual(t, got[0].ID, testBook1.ID)
+	if got[0].DateDeleted == nil {
+		t.Errorf("got nil date deleted")
+	}
+	if len(got[0].Author) == 0 {
+		t.Errorf("got no authors for trashed book")
+	}
+
+	// trash of other users
+	_, err = ts.Trash.GetAll(testUser2.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+}
+
+func TestRestoreBook(t *testing.T) {
+	defer resetDB(testdb)
+
+	want, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	err = ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
+	checkErr(t, err)
+
+	// books not in the trash or of other users cannot be restored, even when
+	// the other user has a book with the same ISBN
+	_, err = ts.Trash.Restore(testUser2.ID, testBook1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	other, err := ts.Books.Create(testUser2.ID, &teal.Book{
+		Title:  "Leviathan Wakes",
+		ISBN:   want.ISBN,
+		Author: []string{"S.A. Corey"},
+	})
+	checkErr(t, err)
+	_, err = ts.Trash.Restore(testUser2.ID, testBook1.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	// the down migration rejects duplicate ISBNs
+	checkErr(t, ts.Books.Delete(testUser2.ID, other.ID, 0))
+	_, err = ts.Trash.Empty(testUser2.ID, time.Now().Add(time.Hour))
+	checkErr(t, err)
+	_, err = ts.Trash.Restore(testUser1.ID, testBook2.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+
+	got, err := ts.Trash.Restore(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	if !assertBooksEqual(got, want) {
+		t.Errorf("got %v, want %v", prettyPrint(got), prettyPrint(want))
+	}
+	if got.DateDeleted != nil {
+		t.Errorf("got date deleted %v, want nil", got.DateDeleted)
+	}
+
+	sessions, err := ts.Reading.GetSessions(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	assertEqual(t, len(sessions), 1)
+
+	_, err = ts.Trash.GetAll(testUser1.ID)
+	if err != teal.ErrNoRows {
+		t.Errorf("got %v, want %v", err, teal.ErrNoRows)
+	}
+}
+
+func TestCreateBookInTrash(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Books.Delete(testUser1.ID, 1, 0)
+	checkErr(t, err)
+
+	// books in the trash do not count towards unique ISBNs
+	got, err := ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Leviathan Wakes",
+		ISBN:   "1",
+		Author: []string{"S.A. Corey"},
+	})
+	checkErr(t, err)
+	assertEqual(t, got.ISBN, "1")
+
+	_, err = ts.Books.Create(testUser1.ID, &teal.Book{
+		Title:  "Leviathan Wakes",
+		ISBN:   "1",
+		Author: []string{"S.A. Corey"},
+	})
+	if err != teal.ErrDuplicateBook {
+		t.Errorf("got %v, want %v", err, teal.ErrDuplicateBook)
+	}
+
+	// the trashed book cannot be restored while the new book exists
+	_, err = ts.Trash.Restore(testUser1.ID, 1)
+	if err != teal.ErrDuplicateBook {
+		t.Errorf("got %v, want %v", err, teal.ErrDuplicateBook)
+	}
+
+	// the down migration rejects duplicate ISBNs
+	_, err = ts.Trash.Empty(testUser1.ID, time.Now().Add(time.Hour))
+	checkErr(t, err)
+}
+
+func TestEmptyTrash(t *testing.T) {
+	defer resetDB(testdb)
+
+	book, err := ts.Books.Get(testUser1.ID, testBook1.ID)
+	checkErr(t, err)
+	err = ts.Books.Delete(testUser1.ID, book.ID, 0)
+	checkErr(t, err)
+
+	// books deleted after the given time are kept
+	count, err := ts.Trash.Empty(testUser1.ID, time.Now().Add(-time.Hour))
+	checkErr(t, err)
+	assertEqual(t, count, 0)
+
+	// trash of other users is kept
+	count, err = ts.Trash.Empty(testUser2.ID, time.Now().Add(time.Minute))
+	checkErr(t, err)
+	assertEqual(t, count, 0)
+
+	count, err = ts.Trash.Empty(testUser1.ID, time.Now().Add(time.Minute))
+	checkErr(t, err)
+	assertEqual(t, count, 1)
+
+	_, err = ts.Trash.Restore(testUser1.ID, book.ID)
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+	// authors without books are deleted
+	_, err = ts.Authors.GetByName(testUser1.ID, book.Author[0])
+	if err != teal.ErrDoesNotExist {
+		t.Errorf("got %v, want %v", err, teal.ErrDoesNotExist)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	defer resetDB(testdb)
+
+	err := ts.Books.Delete(testUser1.ID, testBook1.ID, 0)
+	checkErr(t, err)
+	err = ts.Books.Delete(testUser1.ID, testBook2.ID, 0)
+	checkErr(t, err)
+
+	_, err = testdb.Exec(`UPDATE books SET dateDeleted='2022-01-01 00:00:00' WHERE id=$1;`, testBook1.ID)
+	checkErr(t, err)
+
+	// the time is compared in UTC, whatever its location
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	count, err := ts.Trash.Purge(time.Date(2022, 1, 1, 7, 0, 0, 0, loc))
+	checkErr(t, err)
+	assertEqual(t, count, 0)
+
+	count, err = ts.Trash.Purge(time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+	checkErr(t, err)
+	assertEqual(t, count, 1)
+
+	got, err := ts.Trash.GetAll(testUser1.ID)
+	checkErr(t, err)
+	if len(got) != 1 {
+		t.Fatalf("got %d books in trash, want 1", len(got))
+	}
+	assertEqual(t, got[0].ID, testBook2.ID)
+}
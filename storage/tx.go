@@ -16,11 +16,20 @@ func Tx(db *sqlx.DB, ctx context.Context, fn txFn) error {
 	if err != nil {
 		return fmt.Errorf("db: failed to start transaction: %v", err)
 	}
-	defer endTx(tx, err)
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
 
 	if err = fn(tx); err != nil {
+		tx.Rollback()
 		return err
 	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("db: failed to commit transaction: %v", err)
+	}
 	return nil
 }
 
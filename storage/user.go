@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -24,7 +23,7 @@ func (s *UserStore) Get(id int64) (*teal.User, error) {
 	defer endTx(tx, err)
 
 	var user teal.User
-	stmt := `SELECT id, name, username, hashed_password, role, dateAdded
+	stmt := `SELECT id, name, username, hashed_password, role, dateAdded, version
 	FROM users WHERE id=$1;`
 	err = tx.QueryRowx(stmt, id).Scan(
 		&user.ID,
@@ -33,6 +32,7 @@ func (s *UserStore) Get(id int64) (*teal.User, error) {
 		&user.HashedPassword,
 		&user.Role,
 		&user.DateAdded,
+		&user.Version,
 	)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrDoesNotExist
@@ -51,7 +51,7 @@ func (s *UserStore) GetByUsername(name string) (*teal.User, error) {
 	defer endTx(tx, err)
 
 	var user teal.User
-	stmt := `SELECT id, name, username, hashed_password, role, dateAdded
+	stmt := `SELECT id, name, username, hashed_password, role, dateAdded, version
 	FROM users WHERE username=$1;`
 	err = tx.QueryRowx(stmt, name).Scan(
 		&user.ID,
@@ -60,6 +60,7 @@ func (s *UserStore) GetByUsername(name string) (*teal.User, error) {
 		&user.HashedPassword,
 		&user.Role,
 		&user.DateAdded,
+		&user.Version,
 	)
 	if err == sql.ErrNoRows {
 		return nil, teal.ErrDoesNotExist
@@ -91,7 +92,7 @@ func (s *UserStore) Create(u *teal.User) (*teal.User, error) {
 		u.DateAdded).StructScan(u)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint") {
+		if isUniqueViolation(err) {
 			return nil, teal.ErrDuplicateUsername
 		} else {
 			return nil, fmt.Errorf("db: insert to users table failed: %v", err)
@@ -115,7 +116,8 @@ func (s *UserStore) Update(id int64, u *teal.User) (*teal.User, error) {
 	username=$2,
 	hashed_password=$3,
 	role=$4,
-	dateAdded=$5
+	dateAdded=$5,
+	version=version+1
 	WHERE id=$6`
 
 	res, err := tx.Exec(stmt,
@@ -127,7 +129,7 @@ func (s *UserStore) Update(id int64, u *teal.User) (*teal.User, error) {
 		id)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint") {
+		if isUniqueViolation(err) {
 			return nil, teal.ErrDuplicateUsername
 		} else {
 			return nil, fmt.Errorf("db: update user %d failed: %v", id, err)
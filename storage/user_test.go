@@ -86,6 +86,22 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+func TestUpdateUserVersion(t *testing.T) {
+	defer resetDB(testdb)
+
+	u, err := ts.Users.Get(testUser2.ID)
+	checkErr(t, err)
+	assertEqual(t, u.Version, 1)
+
+	u.Name = "Foo Bar"
+	_, err = ts.Users.Update(u.ID, u)
+	checkErr(t, err)
+
+	got, err := ts.Users.Get(testUser2.ID)
+	checkErr(t, err)
+	assertEqual(t, got.Version, 2)
+}
+
 func TestUpdateUserExistingUsername(t *testing.T) {
 	want := testUser1
 	want.Username = testUser2.Username
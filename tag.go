@@ -1,17 +1,32 @@
 package teal
 
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/kencx/teal/validator"
+)
+
 type Tag struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Books       []Book `json:"books,omitempty"`
-	DateAdded   string `json:"-"`
-	DateUpdated string `json:"-"`
+	ID          int64        `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Count       int          `json:"count" db:"count"`
+	DateAdded   sql.NullTime `json:"-" db:"dateAdded"`
+	DateUpdated sql.NullTime `json:"-" db:"dateUpdated"`
 }
 
 type TagService interface {
-	GetTags() ([]*Tag, error)
-	GetTag(id int) (*Tag, error)
-	CreateTag(b *Tag) error
-	UpdateTag(id int) error
-	DeleteTag(id int) error
+	Get(userID, id int64) (*Tag, error)
+	GetAll(userID int64) ([]*Tag, error)
+	Update(userID, id int64, t *Tag) (*Tag, error)
+	Merge(userID, id, into int64) (*Tag, error)
+	Delete(userID, id int64) error
+}
+
+func (t Tag) String() string {
+	return fmt.Sprintf(`[name=%s count=%d]`, t.Name, t.Count)
+}
+
+func (t *Tag) Validate(v *validator.Validator) {
+	v.Check(t.Name != "", "name", "value is missing")
 }
@@ -0,0 +1,10 @@
+package teal
+
+import "time"
+
+// Deleted books are kept in a trash until they are restored or purged
+type TrashService interface {
+	GetAll(userID int64) ([]*Book, error)
+	Restore(userID, id int64) (*Book, error)
+	Empty(userID int64, before time.Time) (int, error)
+}
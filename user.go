@@ -18,6 +18,7 @@ type User struct {
 	Role           string       `json:"role"`
 	LastLogin      sql.NullTime `json:"-"`
 	DateAdded      time.Time    `json:"-"`
+	Version        int64        `json:"version"`
 }
 
 // Destination struct for POST user requests
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlBreakRgx = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTagRgx   = regexp.MustCompile(`<[^>]*>`)
+	blankRgx     = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// Plain text of an HTML fragment, such as a book description. Tags are
+// removed, keeping paragraphs as lines
+func HTMLText(s string) string {
+	s = htmlBreakRgx.ReplaceAllString(s, "\n")
+	s = htmlTagRgx.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankRgx.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}